@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"backend/internal/config"
+	"backend/internal/location"
+	"backend/internal/service"
+)
+
+// runGeocodeCommand implements the "geocode-farmers" subcommand, which places
+// the farmers saved without coordinates at their pincode centroid. Run it after
+// adding a centroid file to location.directoryFiles.
+func runGeocodeCommand(cfg *config.Config) {
+	stores, err := initializeStores(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize stores: %v", err)
+	}
+	locations, err := location.LoadFiles(cfg.Location.DirectoryFiles)
+	if err != nil {
+		log.Fatalf("Failed to load location directory: %v", err)
+	}
+	policy, err := loadContactPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid contact policy: %v", err)
+	}
+	cipher, err := loadPIICipher(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up PII encryption: %v", err)
+	}
+	services := service.NewServices(stores, locations, policy, cipher, erasureKey(cfg))
+
+	placed, err := services.Farmer.GeocodeFarmers(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to geocode farmers after placing %d: %v", placed, err)
+	}
+	fmt.Printf("%d farmers placed\n", placed)
+}
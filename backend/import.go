@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"backend/internal/config"
+	"backend/internal/imports"
+	"backend/internal/location"
+	"backend/internal/service"
+)
+
+const importUsage = `Usage:
+  backend import-farmers -file PATH [-out PATH] [-format csv|xlsx]`
+
+// runImportCommand implements the "import-farmers" subcommand, the command
+// line counterpart of POST /farmers/import.
+func runImportCommand(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("import-farmers", flag.ExitOnError)
+	file := flags.String("file", "", "CSV or XLSX file to import")
+	out := flags.String("out", "", "where to write the result file (default: next to the input)")
+	format := flags.String("format", "", "file format, taken from the file name when not set")
+	flags.Parse(args)
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, importUsage)
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = string(imports.FormatOf(*file))
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(*file, "."+*format) + ".results.csv"
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer input.Close()
+
+	rows, err := imports.ReadSheet(input, imports.Format(*format))
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+
+	stores, err := initializeStores(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize stores: %v", err)
+	}
+	locations, err := location.LoadFiles(cfg.Location.DirectoryFiles)
+	if err != nil {
+		log.Fatalf("Failed to load location directory: %v", err)
+	}
+	policy, err := loadContactPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid contact policy: %v", err)
+	}
+	cipher, err := loadPIICipher(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up PII encryption: %v", err)
+	}
+	services := service.NewServices(stores, locations, policy, cipher, erasureKey(cfg))
+	importer := imports.NewImporter(services.Farmer, locations)
+
+	report, err := importer.Import(context.Background(), rows)
+	if err != nil {
+		log.Fatalf("Failed to import %s: %v", *file, err)
+	}
+
+	output, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+	defer output.Close()
+	if err := report.WriteCSV(output); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+
+	fmt.Printf("%d created, %d updated, %d skipped, %d errors, results written to %s\n",
+		report.Created, report.Updated, report.Skipped, report.Errored, *out)
+}
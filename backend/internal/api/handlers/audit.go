@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"net/http"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAudit - Retrieve one page of the change history of a farmer, CCE or ticket
+func (h *AuditHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	entity := r.URL.Query().Get("entity")
+	id := r.URL.Query().Get("id")
+
+	if entity == "" || id == "" {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Entity and ID are required")
+		return
+	}
+	if entity != service.EntityFarmer && entity != service.EntityCCE && entity != service.EntityTicket && entity != service.EntityPartner &&
+		entity != service.EntityCrop && entity != service.EntityVariety {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid entity")
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	records, nextCursor, err := h.auditService.History(r.Context(), entity, id, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get audit records")
+		return
+	}
+
+	writeList(w, records, nextCursor)
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type CatalogHandler struct {
+	catalogService *service.CatalogService
+}
+
+func NewCatalogHandler(catalogService *service.CatalogService) *CatalogHandler {
+	return &CatalogHandler{catalogService: catalogService}
+}
+
+// CreateCrop - Add a crop to the catalog
+func (h *CatalogHandler) CreateCrop(w http.ResponseWriter, r *http.Request) {
+	var crop models.Crop
+	if err := json.NewDecoder(r.Body).Decode(&crop); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err := h.catalogService.CreateCrop(r.Context(), &crop)
+	if writeCatalogError(w, err, "Crop", "Failed to add crop") {
+		return
+	}
+
+	setETag(w, crop.Version)
+	utils.RespondWithJSON(w, http.StatusCreated, crop)
+}
+
+// GetCrops - Retrieve one page of crops
+func (h *CatalogHandler) GetCrops(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	crops, nextCursor, err := h.catalogService.ListCrops(r.Context(), page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list crops")
+		return
+	}
+
+	writeList(w, crops, nextCursor)
+}
+
+// GetCrop - Retrieve crop by ID
+func (h *CatalogHandler) GetCrop(w http.ResponseWriter, r *http.Request) {
+	crop, err := h.catalogService.GetCrop(r.Context(), mux.Vars(r)["id"])
+	if writeCatalogError(w, err, "Crop", "Failed to get crop") {
+		return
+	}
+
+	setETag(w, crop.Version)
+	utils.RespondWithJSON(w, http.StatusOK, crop)
+}
+
+// UpdateCrop - Replace the name and aliases of a crop
+func (h *CatalogHandler) UpdateCrop(w http.ResponseWriter, r *http.Request) {
+	var crop models.Crop
+	if err := json.NewDecoder(r.Body).Decode(&crop); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	existing, err := h.catalogService.GetCrop(r.Context(), mux.Vars(r)["id"])
+	if writeCatalogError(w, err, "Crop", "Failed to get crop") {
+		return
+	}
+	if !checkIfMatch(w, r, existing.Version) {
+		return
+	}
+
+	crop.ID = existing.ID
+	crop.Version = existing.Version
+	err = h.catalogService.UpdateCrop(r.Context(), &crop)
+	if writeCatalogError(w, err, "Crop", "Failed to update crop") {
+		return
+	}
+
+	setETag(w, crop.Version)
+	utils.RespondWithJSON(w, http.StatusOK, crop)
+}
+
+// DeleteCrop - Remove a crop no variety, farmer or ticket refers to
+func (h *CatalogHandler) DeleteCrop(w http.ResponseWriter, r *http.Request) {
+	err := h.catalogService.DeleteCrop(r.Context(), mux.Vars(r)["id"])
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Crop still has varieties, farmers growing it or tickets about it")
+		return
+	}
+	if writeCatalogError(w, err, "Crop", "Failed to delete crop") {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRecommendedVarieties - Retrieve the varieties of a crop recommended for
+// a ?season= in a ?state= and ?district=, each optional
+func (h *CatalogHandler) GetRecommendedVarieties(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	varieties, err := h.catalogService.RecommendVarieties(r.Context(), mux.Vars(r)["id"], query.Get("season"), query.Get("state"), query.Get("district"))
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid season")
+		return
+	}
+	if writeCatalogError(w, err, "Crop", "Failed to recommend varieties") {
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, varieties)
+}
+
+// CreateVariety - Add a variety or hybrid, with its SKUs, to the catalog
+func (h *CatalogHandler) CreateVariety(w http.ResponseWriter, r *http.Request) {
+	var variety models.Variety
+	if err := json.NewDecoder(r.Body).Decode(&variety); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err := h.catalogService.CreateVariety(r.Context(), &variety)
+	if writeCatalogError(w, err, "Variety", "Failed to add variety") {
+		return
+	}
+
+	setETag(w, variety.Version)
+	utils.RespondWithJSON(w, http.StatusCreated, variety)
+}
+
+// GetVarieties - Retrieve one page of varieties, of one ?crop= if given
+func (h *CatalogHandler) GetVarieties(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	varieties, nextCursor, err := h.catalogService.ListVarieties(r.Context(), r.URL.Query().Get("crop"), page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list varieties")
+		return
+	}
+
+	writeList(w, varieties, nextCursor)
+}
+
+// GetVariety - Retrieve variety by ID
+func (h *CatalogHandler) GetVariety(w http.ResponseWriter, r *http.Request) {
+	variety, err := h.catalogService.GetVariety(r.Context(), mux.Vars(r)["id"])
+	if writeCatalogError(w, err, "Variety", "Failed to get variety") {
+		return
+	}
+
+	setETag(w, variety.Version)
+	utils.RespondWithJSON(w, http.StatusOK, variety)
+}
+
+// UpdateVariety - Replace a variety and its SKUs
+func (h *CatalogHandler) UpdateVariety(w http.ResponseWriter, r *http.Request) {
+	var variety models.Variety
+	if err := json.NewDecoder(r.Body).Decode(&variety); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	existing, err := h.catalogService.GetVariety(r.Context(), mux.Vars(r)["id"])
+	if writeCatalogError(w, err, "Variety", "Failed to get variety") {
+		return
+	}
+	if !checkIfMatch(w, r, existing.Version) {
+		return
+	}
+
+	variety.ID = existing.ID
+	variety.Version = existing.Version
+	err = h.catalogService.UpdateVariety(r.Context(), &variety)
+	if writeCatalogError(w, err, "Variety", "Failed to update variety") {
+		return
+	}
+
+	setETag(w, variety.Version)
+	utils.RespondWithJSON(w, http.StatusOK, variety)
+}
+
+// DeleteVariety - Remove a variety from the catalog
+func (h *CatalogHandler) DeleteVariety(w http.ResponseWriter, r *http.Request) {
+	err := h.catalogService.DeleteVariety(r.Context(), mux.Vars(r)["id"])
+	if writeCatalogError(w, err, "Variety", "Failed to delete variety") {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeCatalogError writes the response for a failed catalog call, naming the
+// entity in a 404, and reports whether there was an error.
+func writeCatalogError(w http.ResponseWriter, err error, entity, failure string) bool {
+	var invalidCatalog *service.CatalogError
+	var invalidPlace *location.InvalidPlaceError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &invalidCatalog):
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidCatalog.Error())
+	case errors.As(err, &invalidPlace):
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidPlace.Error())
+	case err == errors.ErrInvalidInput:
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid crop: a name and an ID of lower case words joined by dashes are required")
+	case err == errors.ErrNotFound:
+		errors.WriteJSONError(w, http.StatusNotFound, entity+" not found")
+	case err == errors.ErrConflict:
+		errors.WriteJSONError(w, http.StatusConflict, entity+" ID, name or alias is taken")
+	case err == errors.ErrVersionConflict:
+		errors.WriteJSONError(w, http.StatusPreconditionFailed, "Resource has been modified")
+	default:
+		errors.WriteJSONError(w, http.StatusInternalServerError, failure)
+	}
+	return true
+}
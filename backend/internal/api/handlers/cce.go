@@ -3,19 +3,14 @@ package handlers
 import (
 	"backend/internal/models"
 	"backend/internal/service"
-	"context"
+	"backend/pkg/auth"
+	"backend/pkg/errors"
 	"encoding/json"
 	"net/http"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gorilla/mux"
 )
 
-const CceTableName = "CCEs"
-
 type CCEHandler struct {
 	cceService *service.CCEService
 }
@@ -29,49 +24,35 @@ func (h *CCEHandler) GetCCE(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	cceID := vars["id"]
 
-	result, err := dbClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(CceTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: cceID},
-		},
-	})
-	if err != nil || result.Item == nil {
-		http.Error(w, "CCE not found", http.StatusNotFound)
-		return
-	}
-
-	var cce models.CCE
-	err = attributevalue.UnmarshalMap(result.Item, &cce)
+	cce, err := h.cceService.GetCCE(r.Context(), cceID)
 	if err != nil {
-		http.Error(w, "Failed to unmarshal CCE data", http.StatusInternalServerError)
+		http.Error(w, "CCE not found", http.StatusNotFound)
 		return
 	}
 
+	setETag(w, cce.Version)
 	json.NewEncoder(w).Encode(cce)
 }
 
-// GetCCEs - Retrieve all CCEs
+// GetCCEs - Retrieve one page of CCEs
 func (h *CCEHandler) GetCCEs(w http.ResponseWriter, r *http.Request) {
-	result, err := dbClient.Scan(context.TODO(), &dynamodb.ScanInput{
-		TableName: aws.String(CceTableName),
-	})
+	page, err := parsePage(r)
 	if err != nil {
-		http.Error(w, "Failed to scan CCEs", http.StatusInternalServerError)
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
 		return
 	}
 
-	var cces []models.CCE
-	for _, item := range result.Items {
-		var cce models.CCE
-		err = attributevalue.UnmarshalMap(item, &cce)
-		if err != nil {
-			http.Error(w, "Failed to unmarshal CCE data", http.StatusInternalServerError)
-			return
-		}
-		cces = append(cces, cce)
+	cces, nextCursor, err := h.cceService.ListCCEs(r.Context(), page)
+	if err == errors.ErrInvalidCursor {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to scan CCEs", http.StatusInternalServerError)
+		return
 	}
 
-	json.NewEncoder(w).Encode(cces)
+	writeList(w, cces, nextCursor)
 }
 
 // CreateCCE - Add new CCE
@@ -83,13 +64,11 @@ func (h *CCEHandler) CreateCCE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = dbClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(CceTableName),
-		Item: map[string]types.AttributeValue{
-			"ID":   &types.AttributeValueMemberS{Value: cce.ID},
-			"Name": &types.AttributeValueMemberS{Value: cce.Name},
-		},
-	})
+	err = h.cceService.CreateCCE(r.Context(), &cce)
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "CCE ID is taken")
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to add CCE", http.StatusInternalServerError)
 		return
@@ -111,21 +90,13 @@ func (h *CCEHandler) UpdateCCE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := dbClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(CceTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: cceID},
-		},
-	})
-	if err != nil || result.Item == nil {
+	existingCCE, err := h.cceService.GetCCE(r.Context(), cceID)
+	if err != nil {
 		http.Error(w, "CCE not found", http.StatusNotFound)
 		return
 	}
 
-	var existingCCE models.CCE
-	err = attributevalue.UnmarshalMap(result.Item, &existingCCE)
-	if err != nil {
-		http.Error(w, "Failed to unmarshal CCE data", http.StatusInternalServerError)
+	if !checkIfMatch(w, r, existingCCE.Version) {
 		return
 	}
 
@@ -134,18 +105,21 @@ func (h *CCEHandler) UpdateCCE(w http.ResponseWriter, r *http.Request) {
 		existingCCE.Name = newCCE.Name
 	}
 
-	_, err = dbClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(CceTableName),
-		Item: map[string]types.AttributeValue{
-			"ID":   &types.AttributeValueMemberS{Value: existingCCE.ID},
-			"Name": &types.AttributeValueMemberS{Value: existingCCE.Name},
-		},
-	})
+	err = h.cceService.UpdateCCE(r.Context(), existingCCE)
+	if err == errors.ErrVersionConflict {
+		errors.WriteJSONError(w, http.StatusPreconditionFailed, "Resource has been modified")
+		return
+	}
+	if err == errors.ErrNotFound {
+		http.Error(w, "CCE not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update CCE", http.StatusInternalServerError)
 		return
 	}
 
+	setETag(w, existingCCE.Version)
 	w.Write([]byte("CCE updated successfully"))
 }
 
@@ -154,12 +128,11 @@ func (h *CCEHandler) DeleteCCE(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	cceID := vars["id"]
 
-	_, err := dbClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(CceTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: cceID},
-		},
-	})
+	err := h.cceService.DeleteCCE(r.Context(), cceID, auth.UserID(r.Context()))
+	if err == errors.ErrNotFound {
+		http.Error(w, "CCE not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to delete CCE", http.StatusInternalServerError)
 		return
@@ -167,3 +140,42 @@ func (h *CCEHandler) DeleteCCE(w http.ResponseWriter, r *http.Request) {
 
 	w.Write([]byte("CCE deleted successfully"))
 }
+
+// GetDeletedCCEs - Retrieve one page of deleted CCEs
+func (h *CCEHandler) GetDeletedCCEs(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	cces, nextCursor, err := h.cceService.ListDeletedCCEs(r.Context(), page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list deleted CCEs")
+		return
+	}
+
+	writeList(w, cces, nextCursor)
+}
+
+// RestoreCCE - Take a deleted CCE out of the trash
+func (h *CCEHandler) RestoreCCE(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cceID := vars["id"]
+
+	err := h.cceService.RestoreCCE(r.Context(), cceID)
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Deleted CCE not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to restore CCE")
+		return
+	}
+
+	w.Write([]byte("CCE restored successfully"))
+}
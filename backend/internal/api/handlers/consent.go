@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Recorded for opt-outs that do not say where they came from.
+const (
+	defaultOptOutSource = "stop-reply"
+	defaultOptOutReason = "STOP"
+)
+
+type ConsentHandler struct {
+	consentService *service.ConsentService
+	farmerService  *service.FarmerService
+}
+
+func NewConsentHandler(consentService *service.ConsentService, farmerService *service.FarmerService) *ConsentHandler {
+	return &ConsentHandler{consentService: consentService, farmerService: farmerService}
+}
+
+// GetConsent - Retrieve the current consent of a farmer, by channel
+func (h *ConsentHandler) GetConsent(w http.ResponseWriter, r *http.Request) {
+	farmer, err := h.farmerService.GetFarmer(r.Context(), mux.Vars(r)["id"])
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get consent")
+		return
+	}
+
+	consent := farmer.Consent
+	if consent == nil {
+		consent = map[string]models.ChannelConsent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(consent)
+}
+
+// GetConsentHistory - Retrieve one page of the consent changes of a farmer
+func (h *ConsentHandler) GetConsentHistory(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	farmer, err := h.farmerService.GetFarmer(r.Context(), mux.Vars(r)["id"])
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get consent history")
+		return
+	}
+
+	events, nextCursor, err := h.consentService.History(r.Context(), farmer.ID, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get consent history")
+		return
+	}
+
+	writeList(w, events, nextCursor)
+}
+
+// SetConsent - Grant or withdraw a farmer's consent on one channel
+//
+// The body is {"status": "granted"|"revoked", "source": "...", "reason":
+// "..."}. source says where the farmer gave or withdrew consent and is
+// required.
+func (h *ConsentHandler) SetConsent(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Status string `json:"status"`
+		Source string `json:"source"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vars := mux.Vars(r)
+	farmer, err := h.consentService.SetConsent(r.Context(), vars["id"], service.ConsentChange{
+		Channels: []string{vars["channel"]},
+		Status:   body.Status,
+		Source:   body.Source,
+		Reason:   body.Reason,
+	})
+	writeConsentResult(w, farmer, err)
+}
+
+// OptOut - Withdraw consent for the farmer holding a contact number
+//
+// Meant for inbound "STOP" replies. The body is {"contact": "...", "channel":
+// "...", "source": "...", "reason": "..."}; without a channel the farmer is
+// opted out of every channel.
+func (h *ConsentHandler) OptOut(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Contact string `json:"contact"`
+		Channel string `json:"channel"`
+		Source  string `json:"source"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if body.Contact == "" {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Contact is required")
+		return
+	}
+
+	change := service.ConsentChange{Source: body.Source, Reason: body.Reason}
+	if change.Source == "" {
+		change.Source = defaultOptOutSource
+	}
+	if change.Reason == "" {
+		change.Reason = defaultOptOutReason
+	}
+	if body.Channel != "" {
+		change.Channels = []string{body.Channel}
+	}
+
+	farmer, err := h.consentService.OptOut(r.Context(), body.Contact, change)
+	writeConsentResult(w, farmer, err)
+}
+
+// ExportConsentHistory - Download the consent changes between two dates as CSV
+func (h *ConsentHandler) ExportConsentHistory(w http.ResponseWriter, r *http.Request) {
+	start, err := time.Parse(time.RFC3339, r.URL.Query().Get("startDate"))
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid start date format")
+		return
+	}
+	end, err := time.Parse(time.RFC3339, r.URL.Query().Get("endDate"))
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid end date format")
+		return
+	}
+
+	name := fmt.Sprintf("consent-history-%s-%s.csv", start.UTC().Format("20060102"), end.UTC().Format("20060102"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	if err := h.consentService.ExportHistory(r.Context(), w, start, end); err != nil {
+		// ExportHistory reads every event before it writes, so a failed read
+		// still leaves room for an error response.
+		w.Header().Del("Content-Disposition")
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to export consent history")
+	}
+}
+
+func writeConsentResult(w http.ResponseWriter, farmer *models.Farmer, err error) {
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid channel, status or source")
+		return
+	}
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err == service.ErrFarmerErased {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer was erased")
+		return
+	}
+	if err == errors.ErrVersionConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer is being edited, try again")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to update consent")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(farmer.Consent)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type ContactHandler struct {
+	farmerService *service.FarmerService
+}
+
+func NewContactHandler(farmerService *service.FarmerService) *ContactHandler {
+	return &ContactHandler{farmerService: farmerService}
+}
+
+// GetContacts - Retrieve the numbers a farmer is reached on
+func (h *ContactHandler) GetContacts(w http.ResponseWriter, r *http.Request) {
+	contacts, err := h.farmerService.GetContacts(r.Context(), mux.Vars(r)["id"])
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get contacts")
+		return
+	}
+
+	if contacts == nil {
+		contacts = []models.FarmerContact{}
+	}
+	utils.RespondWithJSON(w, http.StatusOK, contacts)
+}
+
+// AddContact - Add a number, such as a son's, to a farmer
+//
+// The body is {"number", "name", "relationship", "language", "primary"}. The
+// number then finds the farmer wherever a contact does.
+func (h *ContactHandler) AddContact(w http.ResponseWriter, r *http.Request) {
+	var contact models.FarmerContact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	farmer, err := h.farmerService.AddContact(r.Context(), mux.Vars(r)["id"], contact)
+	writeContactsResult(w, http.StatusCreated, farmer, err)
+}
+
+// UpdateContact - Change a contact of a farmer, including its number
+func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	var contact models.FarmerContact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	vars := mux.Vars(r)
+	farmer, err := h.farmerService.UpdateContact(r.Context(), vars["id"], vars["number"], contact)
+	writeContactsResult(w, http.StatusOK, farmer, err)
+}
+
+// RemoveContact - Remove a number from a farmer
+func (h *ContactHandler) RemoveContact(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	farmer, err := h.farmerService.RemoveContact(r.Context(), vars["id"], vars["number"])
+	writeContactsResult(w, http.StatusOK, farmer, err)
+}
+
+func writeContactsResult(w http.ResponseWriter, status int, farmer *models.Farmer, err error) {
+	var taken *store.ContactTakenError
+	if errors.As(err, &taken) {
+		writeContactTaken(w, taken)
+		return
+	}
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid number or relationship")
+		return
+	}
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer or contact not found")
+		return
+	}
+	if err == service.ErrFarmerErased {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer was erased")
+		return
+	}
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Make another contact primary first")
+		return
+	}
+	if err == errors.ErrVersionConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer is being edited, try again")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to update contacts")
+		return
+	}
+
+	contacts := farmer.Contacts
+	if contacts == nil {
+		contacts = []models.FarmerContact{}
+	}
+	setETag(w, farmer.Version)
+	utils.RespondWithJSON(w, status, contacts)
+}
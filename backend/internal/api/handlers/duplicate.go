@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/pkg/auth"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type DuplicateHandler struct {
+	duplicateService *service.DuplicateService
+}
+
+func NewDuplicateHandler(duplicateService *service.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{duplicateService: duplicateService}
+}
+
+// GetDuplicates - Retrieve one page of the duplicate review queue
+//
+// ?status= is pending (the default), merged or dismissed.
+func (h *DuplicateHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.DuplicateStatusPending
+	}
+
+	candidates, nextCursor, err := h.duplicateService.ListCandidates(r.Context(), status, page)
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list duplicates")
+		return
+	}
+
+	writeList(w, candidates, nextCursor)
+}
+
+// DismissDuplicate - Mark a queued pair as two different farmers
+func (h *DuplicateHandler) DismissDuplicate(w http.ResponseWriter, r *http.Request) {
+	err := h.duplicateService.DismissCandidate(r.Context(), mux.Vars(r)["id"], auth.UserID(r.Context()))
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Duplicate not found")
+		return
+	}
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Duplicate was already reviewed")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to dismiss duplicate")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeFarmer - Merge a duplicate farmer into the farmer in the path
+//
+// The body names the duplicate as {"duplicateId": "..."}. Its tickets and
+// shoots move to the farmer, and its ID keeps resolving to the farmer.
+func (h *DuplicateHandler) MergeFarmer(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		DuplicateID string `json:"duplicateId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	farmer, err := h.duplicateService.MergeFarmers(r.Context(), mux.Vars(r)["id"], body.DuplicateID, auth.UserID(r.Context()))
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "duplicateId must name another farmer")
+		return
+	}
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer was already merged")
+		return
+	}
+	if err == service.ErrFarmerErased {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer was erased")
+		return
+	}
+	if errors.Is(err, errors.ErrVersionConflict) {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer was modified during the merge, try again")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to merge farmers")
+		return
+	}
+
+	setETag(w, farmer.Version)
+	utils.RespondWithJSON(w, http.StatusOK, farmer)
+}
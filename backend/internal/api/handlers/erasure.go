@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type ErasureHandler struct {
+	erasureService *service.ErasureService
+}
+
+func NewErasureHandler(erasureService *service.ErasureService) *ErasureHandler {
+	return &ErasureHandler{erasureService: erasureService}
+}
+
+// EraseFarmer - Erase a farmer's personal data and return the signed
+// certificate. The body, optional, gives the {"reason"} of the request.
+func (h *ErasureHandler) EraseFarmer(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cert, err := h.erasureService.EraseFarmer(r.Context(), mux.Vars(r)["id"], body.Reason)
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err == errors.ErrVersionConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer is being edited, try again")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to erase farmer")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, cert)
+}
+
+// VerifyCertificate - Check that an erasure certificate was issued here and
+// has not been altered
+func (h *ErasureHandler) VerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	var cert models.ErasureCertificate
+	if err := json.NewDecoder(r.Body).Decode(&cert); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]bool{
+		"valid": h.erasureService.VerifyCertificate(&cert),
+	})
+}
+
+// GetPublicKey - Retrieve the Ed25519 key erasure certificates are signed
+// with, for checking them elsewhere
+func (h *ErasureHandler) GetPublicKey(w http.ResponseWriter, r *http.Request) {
+	keyID, key := h.erasureService.PublicKey()
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"keyId":     keyID,
+		"algorithm": "Ed25519",
+		"publicKey": base64.StdEncoding.EncodeToString(key),
+	})
+}
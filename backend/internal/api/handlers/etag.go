@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"backend/pkg/errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// setETag sends the version of an entity as a strong entity tag.
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// checkIfMatch compares the If-Match header of an update with the current
+// version of the entity. It responds with 428 when the header is missing and
+// 412 when no listed tag matches, and reports whether the update may go ahead.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, version int64) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		errors.WriteJSONError(w, http.StatusPreconditionRequired, "If-Match header is required")
+		return false
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == current {
+			return true
+		}
+	}
+
+	errors.WriteJSONError(w, http.StatusPreconditionFailed, "Resource has been modified")
+	return false
+}
@@ -1,23 +1,21 @@
 package handlers
 
 import (
+	"backend/internal/location"
 	"backend/internal/models"
+	"backend/internal/search"
 	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/auth"
 	"backend/pkg/errors"
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gorilla/mux"
 )
 
-const FarmerTableName = "Farmers"
-
 type FarmerHandler struct {
 	farmerService *service.FarmerService
 }
@@ -35,23 +33,30 @@ func (h *FarmerHandler) CreateFarmer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = dbClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(FarmerTableName),
-		Item: map[string]types.AttributeValue{
-			"ID":       &types.AttributeValueMemberS{Value: farmer.ID},
-			"Name":     &types.AttributeValueMemberS{Value: farmer.Name},
-			"Contact":  &types.AttributeValueMemberS{Value: farmer.Contact},
-			"State":    &types.AttributeValueMemberS{Value: farmer.State},
-			"District": &types.AttributeValueMemberS{Value: farmer.District},
-			"Tehsil":   &types.AttributeValueMemberS{Value: farmer.Tehsil},
-			"Village":  &types.AttributeValueMemberS{Value: farmer.Village},
-			"Pincode":  &types.AttributeValueMemberS{Value: farmer.Pincode},
-			"Address":  &types.AttributeValueMemberS{Value: farmer.Address},
-			"Tag":      &types.AttributeValueMemberS{Value: farmer.Tag},
-			"Crop":     &types.AttributeValueMemberSS{Value: farmer.Crop}, // Use string set for crops
-		},
-	})
-
+	err = h.farmerService.CreateFarmer(r.Context(), &farmer)
+	var invalidPlace *location.InvalidPlaceError
+	if errors.As(err, &invalidPlace) {
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidPlace.Error())
+		return
+	}
+	var invalidCrop *service.CatalogError
+	if errors.As(err, &invalidCrop) {
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidCrop.Error())
+		return
+	}
+	if err == errors.ErrInvalidInput {
+		http.Error(w, "Invalid contact number", http.StatusBadRequest)
+		return
+	}
+	var taken *store.ContactTakenError
+	if errors.As(err, &taken) {
+		writeContactTaken(w, taken)
+		return
+	}
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer ID is taken")
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to add farmer", http.StatusInternalServerError)
 		return
@@ -74,21 +79,13 @@ func (h *FarmerHandler) UpdateFarmer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Retrieve the existing farmer
-	result, err := dbClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(FarmerTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: farmerID},
-		},
-	})
-	if err != nil || result.Item == nil {
+	existingFarmer, err := h.farmerService.GetFarmer(r.Context(), farmerID)
+	if err != nil {
 		http.Error(w, "Farmer not found", http.StatusNotFound)
 		return
 	}
 
-	var existingFarmer models.Farmer
-	err = attributevalue.UnmarshalMap(result.Item, &existingFarmer)
-	if err != nil {
-		http.Error(w, "Failed to unmarshal farmer data", http.StatusInternalServerError)
+	if !checkIfMatch(w, r, existingFarmer.Version) {
 		return
 	}
 
@@ -123,29 +120,50 @@ func (h *FarmerHandler) UpdateFarmer(w http.ResponseWriter, r *http.Request) {
 	if len(newFarmer.Crop) > 0 {
 		existingFarmer.Crop = newFarmer.Crop
 	}
+	if newFarmer.Coordinates != nil {
+		// Coordinates with source "pincode" go back to the pincode centroid.
+		existingFarmer.Coordinates = newFarmer.Coordinates
+	}
 
-	// Update the farmer in DynamoDB
-	_, err = dbClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(FarmerTableName),
-		Item: map[string]types.AttributeValue{
-			"ID":       &types.AttributeValueMemberS{Value: existingFarmer.ID},
-			"Name":     &types.AttributeValueMemberS{Value: existingFarmer.Name},
-			"Contact":  &types.AttributeValueMemberS{Value: existingFarmer.Contact},
-			"State":    &types.AttributeValueMemberS{Value: existingFarmer.State},
-			"District": &types.AttributeValueMemberS{Value: existingFarmer.District},
-			"Tehsil":   &types.AttributeValueMemberS{Value: existingFarmer.Tehsil},
-			"Village":  &types.AttributeValueMemberS{Value: existingFarmer.Village},
-			"Pincode":  &types.AttributeValueMemberS{Value: existingFarmer.Pincode},
-			"Address":  &types.AttributeValueMemberS{Value: existingFarmer.Address},
-			"Tag":      &types.AttributeValueMemberS{Value: existingFarmer.Tag},
-			"Crop":     &types.AttributeValueMemberSS{Value: existingFarmer.Crop},
-		},
-	})
+	// Save the updated farmer
+	err = h.farmerService.UpdateFarmer(r.Context(), existingFarmer)
+	var invalidPlace *location.InvalidPlaceError
+	if errors.As(err, &invalidPlace) {
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidPlace.Error())
+		return
+	}
+	var invalidCrop *service.CatalogError
+	if errors.As(err, &invalidCrop) {
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidCrop.Error())
+		return
+	}
+	if err == errors.ErrInvalidInput {
+		http.Error(w, "Invalid contact number", http.StatusBadRequest)
+		return
+	}
+	var taken *store.ContactTakenError
+	if errors.As(err, &taken) {
+		writeContactTaken(w, taken)
+		return
+	}
+	if err == service.ErrFarmerErased {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer was erased")
+		return
+	}
+	if err == errors.ErrVersionConflict {
+		errors.WriteJSONError(w, http.StatusPreconditionFailed, "Resource has been modified")
+		return
+	}
+	if err == errors.ErrNotFound {
+		http.Error(w, "Farmer not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update farmer", http.StatusInternalServerError)
 		return
 	}
 
+	setETag(w, existingFarmer.Version)
 	fmt.Fprintf(w, "Farmer updated successfully")
 }
 
@@ -154,25 +172,13 @@ func (h *FarmerHandler) GetFarmer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	farmerID := vars["id"]
 
-	result, err := dbClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(FarmerTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: farmerID},
-		},
-	})
-
-	if err != nil || result.Item == nil {
-		http.Error(w, "Farmer not found", http.StatusNotFound)
-		return
-	}
-
-	var farmer models.Farmer
-	err = attributevalue.UnmarshalMap(result.Item, &farmer)
+	farmer, err := h.farmerService.GetFarmer(r.Context(), farmerID)
 	if err != nil {
-		http.Error(w, "Failed to unmarshal farmer data", http.StatusInternalServerError)
+		http.Error(w, "Farmer not found", http.StatusNotFound)
 		return
 	}
 
+	setETag(w, farmer.Version)
 	json.NewEncoder(w).Encode(farmer)
 }
 
@@ -187,32 +193,69 @@ func (h *FarmerHandler) GetFarmerByContact(w http.ResponseWriter, r *http.Reques
 	}
 
 	farmer, err := h.farmerService.GetFarmerByContact(r.Context(), contact)
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid contact number")
+		return
+	}
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get farmer")
 		return
 	}
 
+	setETag(w, farmer.Version)
 	json.NewEncoder(w).Encode(farmer)
 }
 
-// GetFarmers - Retieve farmers by filters{crop, village, etc}
-func (h *FarmerHandler) GetFarmers(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
+// SearchFarmers - Retrieve one page of farmers matching a search
+//
+// q searches the farmer's name. The filters state, district, tehsil, village,
+// pincode, tag and crop accept several values, repeated or comma separated,
+// and match any of them; cropMatch=all asks for every listed crop instead.
+// sort is relevance, name, createdAt or updatedAt, with a leading "-" for
+// descending order.
+func (h *FarmerHandler) SearchFarmers(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
 	queryParams := r.URL.Query()
-	filters := map[string]string{
-		"crop":     queryParams.Get("crop"),
-		"district": queryParams.Get("district"),
-		"village":  queryParams.Get("village"),
-		"pincode":  queryParams.Get("pincode"),
+	query := search.Query{
+		Name:     queryParams.Get("q"),
+		Filters:  make(map[string][]string),
+		AllCrops: queryParams.Get("cropMatch") == "all",
+		Sort:     queryParams.Get("sort"),
+	}
+	for _, field := range search.Fields {
+		for _, param := range queryParams[field] {
+			for _, value := range strings.Split(param, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					query.Filters[field] = append(query.Filters[field], value)
+				}
+			}
+		}
 	}
 
-	farmers, err := h.farmerService.ListFarmersWithFilters(r.Context(), filters)
+	farmers, nextCursor, err := h.farmerService.SearchFarmers(r.Context(), query, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if errors.Is(err, errors.ErrInvalidInput) {
+		errors.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list farmers:"+err.Error())
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to search farmers")
 		return
 	}
 
-	json.NewEncoder(w).Encode(farmers)
+	writeList(w, farmers, nextCursor)
 }
 
 // DeleteFarmer - Delete a farmer by ID
@@ -220,12 +263,11 @@ func (h *FarmerHandler) DeleteFarmer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	farmerID := vars["id"]
 
-	_, err := dbClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(FarmerTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: farmerID},
-		},
-	})
+	err := h.farmerService.DeleteFarmer(r.Context(), farmerID, auth.UserID(r.Context()))
+	if err == errors.ErrNotFound {
+		http.Error(w, "Farmer not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to delete farmer", http.StatusInternalServerError)
 		return
@@ -233,3 +275,54 @@ func (h *FarmerHandler) DeleteFarmer(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Fprintf(w, "Farmer deleted successfully")
 }
+
+// GetDeletedFarmers - Retrieve one page of deleted farmers
+func (h *FarmerHandler) GetDeletedFarmers(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	farmers, nextCursor, err := h.farmerService.ListDeletedFarmers(r.Context(), page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list deleted farmers")
+		return
+	}
+
+	writeList(w, farmers, nextCursor)
+}
+
+// RestoreFarmer - Take a deleted farmer out of the trash
+func (h *FarmerHandler) RestoreFarmer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	farmerID := vars["id"]
+
+	err := h.farmerService.RestoreFarmer(r.Context(), farmerID)
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Deleted farmer not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to restore farmer")
+		return
+	}
+
+	fmt.Fprintf(w, "Farmer restored successfully")
+}
+
+// writeContactTaken answers 409 with the farmer that already has the contact,
+// so the client can open that record instead of creating a duplicate.
+func writeContactTaken(w http.ResponseWriter, taken *store.ContactTakenError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/farmers/"+taken.FarmerID)
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":    "Contact number already belongs to another farmer",
+		"farmerId": taken.FarmerID,
+	})
+}
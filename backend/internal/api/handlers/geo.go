@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"backend/internal/geo"
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Defaults of the geo queries.
+const (
+	defaultNearbyLimit  = 100
+	defaultNearestLimit = 1
+	maxNearestLimit     = 20
+)
+
+type GeoHandler struct {
+	farmerService  *service.FarmerService
+	partnerService *service.PartnerService
+}
+
+func NewGeoHandler(farmerService *service.FarmerService, partnerService *service.PartnerService) *GeoHandler {
+	return &GeoHandler{farmerService: farmerService, partnerService: partnerService}
+}
+
+// GetNearbyFarmers - Retrieve the farmers within a radius of a point
+//
+// ?lat= and ?lng= give the point in decimal degrees and ?radiusKm= the radius,
+// up to service.MaxRadiusKm. Farmers come nearest first with their distance,
+// at most ?limit= of them.
+func (h *GeoHandler) GetNearbyFarmers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lat, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(query.Get("lng"), 64)
+	radius, radiusErr := strconv.ParseFloat(query.Get("radiusKm"), 64)
+	if latErr != nil || lngErr != nil || radiusErr != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "lat, lng and radiusKm are required numbers")
+		return
+	}
+	limit, ok := parseLimit(query.Get("limit"), defaultNearbyLimit, maxPageLimit)
+	if !ok {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	farmers, err := h.farmerService.FarmersNear(r.Context(), geo.Point{Lat: lat, Lng: lng}, radius, limit)
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid point or radius")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to find nearby farmers")
+		return
+	}
+
+	writeList(w, farmers, "")
+}
+
+// GetTehsilClusters - Count and place the farmers of each tehsil
+//
+// ?state= and ?district= narrow the clusters to one state or district.
+func (h *GeoHandler) GetTehsilClusters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	clusters, err := h.farmerService.ClusterByTehsil(r.Context(), query.Get("state"), query.Get("district"))
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to cluster farmers")
+		return
+	}
+
+	writeList(w, clusters, "")
+}
+
+// GetNearestPartners - Find the dealers or agronomists nearest to a farmer
+//
+// ?kind= is dealer or agronomist, both when left out, and ?limit= how many to
+// return, the nearest one by default.
+func (h *GeoHandler) GetNearestPartners(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, ok := parseLimit(query.Get("limit"), defaultNearestLimit, maxNearestLimit)
+	if !ok {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	partners, err := h.partnerService.NearestPartners(r.Context(), mux.Vars(r)["id"], query.Get("kind"), limit)
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid kind")
+		return
+	}
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Farmer has no coordinates")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to find nearest partners")
+		return
+	}
+
+	writeList(w, partners, "")
+}
+
+// parseLimit reads a positive limit, capped at max, or returns def for an
+// empty one.
+func parseLimit(s string, def, max int) (int, bool) {
+	if s == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return min(n, max), true
+}
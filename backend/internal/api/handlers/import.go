@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"backend/internal/imports"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxImportSize bounds the size of an uploaded spreadsheet.
+const maxImportSize = 10 << 20
+
+type ImportHandler struct {
+	importer *imports.Importer
+}
+
+func NewImportHandler(importer *imports.Importer) *ImportHandler {
+	return &ImportHandler{importer: importer}
+}
+
+// ImportFarmers - Create and update farmers from an uploaded CSV or XLSX file
+//
+// The spreadsheet is sent as the multipart field "file". The response is the
+// per-row result file as CSV, or the report as JSON when the client accepts
+// application/json.
+func (h *ImportHandler) ImportFarmers(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "A CSV or XLSX file is required in the file field")
+		return
+	}
+	defer file.Close()
+
+	format := imports.Format(r.URL.Query().Get("format"))
+	if format == "" {
+		format = imports.FormatOf(header.Filename)
+	}
+	if format != imports.FormatCSV && format != imports.FormatXLSX {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Only CSV and XLSX files can be imported")
+		return
+	}
+
+	rows, err := imports.ReadSheet(file, format)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Could not read the file: "+err.Error())
+		return
+	}
+
+	report, err := h.importer.Import(r.Context(), rows)
+	if errors.Is(err, errors.ErrInvalidInput) {
+		errors.WriteJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to import farmers")
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		utils.RespondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	name := fmt.Sprintf("farmer-import-%s.csv", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	report.WriteCSV(w)
+}
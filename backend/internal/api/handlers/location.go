@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"backend/internal/location"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultSuggestLimit = 20
+	maxSuggestLimit     = 100
+)
+
+type LocationHandler struct {
+	locations *location.Directory
+}
+
+func NewLocationHandler(locations *location.Directory) *LocationHandler {
+	return &LocationHandler{locations: locations}
+}
+
+// GetStates - Suggest states matching ?q=
+func (h *LocationHandler) GetStates(w http.ResponseWriter, r *http.Request) {
+	limit, ok := suggestLimit(w, r)
+	if !ok {
+		return
+	}
+	writeSuggestions(w, h.locations.SuggestStates(r.URL.Query().Get("q"), limit))
+}
+
+// GetDistricts - Suggest districts of ?state= matching ?q=
+func (h *LocationHandler) GetDistricts(w http.ResponseWriter, r *http.Request) {
+	limit, ok := suggestLimit(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	names, found := h.locations.SuggestDistricts(query.Get("state"), query.Get("q"), limit)
+	if !found {
+		errors.WriteJSONError(w, http.StatusNotFound, "Unknown state")
+		return
+	}
+	writeSuggestions(w, names)
+}
+
+// GetTehsils - Suggest tehsils of ?state= and ?district= matching ?q=
+func (h *LocationHandler) GetTehsils(w http.ResponseWriter, r *http.Request) {
+	limit, ok := suggestLimit(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	names, found := h.locations.SuggestTehsils(query.Get("state"), query.Get("district"), query.Get("q"), limit)
+	if !found {
+		errors.WriteJSONError(w, http.StatusNotFound, "Unknown state or district")
+		return
+	}
+	writeSuggestions(w, names)
+}
+
+// GetVillages - Suggest villages of ?state=, ?district= and ?tehsil=
+// matching ?q=
+func (h *LocationHandler) GetVillages(w http.ResponseWriter, r *http.Request) {
+	limit, ok := suggestLimit(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	names, found := h.locations.SuggestVillages(query.Get("state"), query.Get("district"), query.Get("tehsil"), query.Get("q"), limit)
+	if !found {
+		errors.WriteJSONError(w, http.StatusNotFound, "Unknown state, district or tehsil")
+		return
+	}
+	writeSuggestions(w, names)
+}
+
+// GetPincodes - Suggest pincodes starting with ?q=
+func (h *LocationHandler) GetPincodes(w http.ResponseWriter, r *http.Request) {
+	limit, ok := suggestLimit(w, r)
+	if !ok {
+		return
+	}
+	writeSuggestions(w, h.locations.SuggestPincodes(r.URL.Query().Get("q"), limit))
+}
+
+// GetPincode - The places a pincode serves, with the state and district to
+// fill in when all of them agree
+func (h *LocationHandler) GetPincode(w http.ResponseWriter, r *http.Request) {
+	pincode := mux.Vars(r)["pincode"]
+	places := h.locations.Pincode(pincode)
+	if len(places) == 0 {
+		errors.WriteJSONError(w, http.StatusNotFound, "Unknown pincode")
+		return
+	}
+
+	// Resolve fills in the state and district only when they are the same
+	// for every place.
+	filled, _ := h.locations.Resolve(location.Place{Pincode: pincode})
+	utils.RespondWithJSON(w, http.StatusOK, struct {
+		Pincode  string           `json:"pincode"`
+		State    string           `json:"state,omitempty"`
+		District string           `json:"district,omitempty"`
+		Places   []location.Place `json:"places"`
+	}{
+		Pincode:  filled.Pincode,
+		State:    filled.State,
+		District: filled.District,
+		Places:   places,
+	})
+}
+
+// suggestLimit reads the limit query parameter of an autocomplete request.
+func suggestLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limit := r.URL.Query().Get("limit")
+	if limit == "" {
+		return defaultSuggestLimit, true
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return 0, false
+	}
+	return min(n, maxSuggestLimit), true
+}
+
+func writeSuggestions(w http.ResponseWriter, names []string) {
+	if names == nil {
+		names = []string{}
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ListResponse[string]{Items: names})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultOverviewTickets = 5
+	defaultOverviewShoots  = 10
+	maxOverviewItems       = 50
+)
+
+type OverviewHandler struct {
+	overviewService *service.OverviewService
+}
+
+func NewOverviewHandler(overviewService *service.OverviewService) *OverviewHandler {
+	return &OverviewHandler{overviewService: overviewService}
+}
+
+// GetFarmerOverview - Retrieve the overview of a farmer by ID
+//
+// ?tickets= and ?shoots= set how many recent tickets and shoots are listed.
+func (h *OverviewHandler) GetFarmerOverview(w http.ResponseWriter, r *http.Request) {
+	opts, ok := parseOverviewOptions(w, r)
+	if !ok {
+		return
+	}
+	overview, err := h.overviewService.GetOverview(r.Context(), mux.Vars(r)["id"], opts)
+	writeOverview(w, overview, err)
+}
+
+// GetFarmerOverviewByContact - Retrieve the overview of the farmer holding a
+// contact number, for the screen pop of an incoming call
+func (h *OverviewHandler) GetFarmerOverviewByContact(w http.ResponseWriter, r *http.Request) {
+	opts, ok := parseOverviewOptions(w, r)
+	if !ok {
+		return
+	}
+	overview, err := h.overviewService.GetOverviewByContact(r.Context(), mux.Vars(r)["contact"], opts)
+	writeOverview(w, overview, err)
+}
+
+func parseOverviewOptions(w http.ResponseWriter, r *http.Request) (service.OverviewOptions, bool) {
+	opts := service.OverviewOptions{Tickets: defaultOverviewTickets, Shoots: defaultOverviewShoots}
+	for param, n := range map[string]*int{"tickets": &opts.Tickets, "shoots": &opts.Shoots} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			errors.WriteJSONError(w, http.StatusBadRequest, "Invalid "+param)
+			return opts, false
+		}
+		*n = min(parsed, maxOverviewItems)
+	}
+	return opts, true
+}
+
+func writeOverview(w http.ResponseWriter, overview *service.FarmerOverview, err error) {
+	switch {
+	case err == errors.ErrInvalidInput:
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid contact number")
+	case err == errors.ErrNotFound:
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+	case errors.Is(err, context.DeadlineExceeded):
+		errors.WriteJSONError(w, http.StatusGatewayTimeout, "Timed out reading the farmer")
+	case err != nil:
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get farmer overview")
+	default:
+		utils.RespondWithJSON(w, http.StatusOK, overview)
+	}
+}
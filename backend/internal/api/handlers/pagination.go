@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"backend/internal/store"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// parsePage reads the limit and cursor query parameters of a list request.
+// Limits above maxPageLimit are capped rather than rejected.
+func parsePage(r *http.Request) (store.Page, error) {
+	page := store.Page{
+		Limit:  defaultPageLimit,
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return store.Page{}, errors.ErrInvalidInput
+		}
+		page.Limit = int32(min(n, maxPageLimit))
+	}
+
+	return page, nil
+}
+
+// writeList responds with one page of items in the list envelope.
+func writeList[T any](w http.ResponseWriter, items []T, nextCursor string) {
+	if items == nil {
+		items = []T{}
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ListResponse[T]{
+		Items:      items,
+		NextCursor: nextCursor,
+	})
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"backend/pkg/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type PartnerHandler struct {
+	partnerService *service.PartnerService
+}
+
+func NewPartnerHandler(partnerService *service.PartnerService) *PartnerHandler {
+	return &PartnerHandler{partnerService: partnerService}
+}
+
+// CreatePartner - Register a dealer or agronomist
+func (h *PartnerHandler) CreatePartner(w http.ResponseWriter, r *http.Request) {
+	var partner models.Partner
+	if err := json.NewDecoder(r.Body).Decode(&partner); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	err := h.partnerService.CreatePartner(r.Context(), &partner)
+	if writePartnerError(w, err, "Failed to add partner") {
+		return
+	}
+
+	setETag(w, partner.Version)
+	utils.RespondWithJSON(w, http.StatusCreated, partner)
+}
+
+// GetPartners - Retrieve one page of partners, of one ?kind= if given
+func (h *PartnerHandler) GetPartners(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	partners, nextCursor, err := h.partnerService.ListPartners(r.Context(), r.URL.Query().Get("kind"), page)
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid kind")
+		return
+	}
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list partners")
+		return
+	}
+
+	writeList(w, partners, nextCursor)
+}
+
+// GetPartner - Retrieve partner by ID
+func (h *PartnerHandler) GetPartner(w http.ResponseWriter, r *http.Request) {
+	partner, err := h.partnerService.GetPartner(r.Context(), mux.Vars(r)["id"])
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Partner not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get partner")
+		return
+	}
+
+	setETag(w, partner.Version)
+	utils.RespondWithJSON(w, http.StatusOK, partner)
+}
+
+// UpdatePartner - Replace a partner
+//
+// The body is the whole partner; the kind, name and location are checked as
+// on creation. Coordinates with source "pincode", or none, are moved to the
+// centroid of the pincode.
+func (h *PartnerHandler) UpdatePartner(w http.ResponseWriter, r *http.Request) {
+	var partner models.Partner
+	if err := json.NewDecoder(r.Body).Decode(&partner); err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	existing, err := h.partnerService.GetPartner(r.Context(), mux.Vars(r)["id"])
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Partner not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to update partner")
+		return
+	}
+	if !checkIfMatch(w, r, existing.Version) {
+		return
+	}
+
+	partner.ID = existing.ID
+	partner.Version = existing.Version
+	err = h.partnerService.UpdatePartner(r.Context(), &partner)
+	if err == errors.ErrVersionConflict {
+		errors.WriteJSONError(w, http.StatusPreconditionFailed, "Resource has been modified")
+		return
+	}
+	if writePartnerError(w, err, "Failed to update partner") {
+		return
+	}
+
+	setETag(w, partner.Version)
+	utils.RespondWithJSON(w, http.StatusOK, partner)
+}
+
+// writePartnerError answers a failed partner write and reports whether there
+// was an error to answer.
+func writePartnerError(w http.ResponseWriter, err error, failure string) bool {
+	var invalidPlace *location.InvalidPlaceError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &invalidPlace):
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidPlace.Error())
+	case err == errors.ErrInvalidInput:
+		errors.WriteJSONError(w, http.StatusBadRequest, "Name, kind (dealer or agronomist) and a valid contact are required")
+	case err == errors.ErrNotFound:
+		errors.WriteJSONError(w, http.StatusNotFound, "Partner not found")
+	default:
+		errors.WriteJSONError(w, http.StatusInternalServerError, failure)
+	}
+	return true
+}
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"backend/internal/contactpolicy"
+	"backend/internal/models"
 	"backend/internal/service"
 	"backend/pkg/errors"
+	"backend/pkg/utils"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
-)
 
-const ShootTableName = "Shoots"
+	"github.com/gorilla/mux"
+)
 
 type ShootHandler struct {
 	shootService *service.ShootService
@@ -18,23 +22,136 @@ func NewShootHandler(shootService *service.ShootService) *ShootHandler {
 	return &ShootHandler{shootService: shootService}
 }
 
+// CreateShoot - Record a call or WhatsApp message sent to a farmer
+//
+// Refused with 403 unless the farmer has consented to the channel, and with
+// 429 and a Retry-After header while the contact policy asks to wait. The
+// timestamp may be in the past, for a shoot recorded after it was made, but
+// not more than a few minutes in the future (400); the policy is applied at
+// the time of the request either way.
+func (h *ShootHandler) CreateShoot(w http.ResponseWriter, r *http.Request) {
+	var shoot models.Shoot
+	err := json.NewDecoder(r.Body).Decode(&shoot)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if shoot.Type != "whatsapp" && shoot.Type != "call" {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid shoot type")
+		return
+	}
+
+	err = h.shootService.CreateShoot(r.Context(), &shoot)
+	var noConsent *service.ConsentError
+	if errors.As(err, &noConsent) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":    noConsent.Error(),
+			"farmerId": noConsent.FarmerID,
+			"channel":  noConsent.Channel,
+			"consent":  noConsent.Status,
+		})
+		return
+	}
+	var deferred *service.OutreachDeferredError
+	if errors.As(err, &deferred) {
+		retryAfter := time.Until(*deferred.Decision.Until).Seconds()
+		w.Header().Set("Retry-After", strconv.Itoa(int(max(retryAfter, 1))))
+		utils.RespondWithJSON(w, http.StatusTooManyRequests, deferred.Decision)
+		return
+	}
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Farmer ID is required")
+		return
+	}
+	if err == service.ErrShootTimestamp {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Shoot timestamp cannot be in the future")
+		return
+	}
+	if err == errors.ErrConflict {
+		errors.WriteJSONError(w, http.StatusConflict, "Other shoots of the farmer are being recorded, try again")
+		return
+	}
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to create shoot")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shoot)
+}
+
+// CheckOutreach - Ask whether a farmer may be contacted on a channel
+//
+// ?channel= is call or whatsapp, ?at= an RFC3339 time (now by default). The
+// answer is allowed, deferred with the time to try again, or blocked.
+func (h *ShootHandler) CheckOutreach(w http.ResponseWriter, r *http.Request) {
+	channel := r.URL.Query().Get("channel")
+	if channel != "whatsapp" && channel != "call" {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid channel")
+		return
+	}
+	at := time.Now().UTC()
+	if s := r.URL.Query().Get("at"); s != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, s); err != nil {
+			errors.WriteJSONError(w, http.StatusBadRequest, "Invalid time format")
+			return
+		}
+	}
+
+	_, decision, err := h.shootService.CheckOutreach(r.Context(), mux.Vars(r)["id"], channel, at)
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
+	if err != nil && decision.Outcome != contactpolicy.Blocked {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to check outreach")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, decision)
+}
+
 func (h *ShootHandler) GetAllShoots(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
 	shootType := r.URL.Query().Get("type")
 	if shootType != "" && shootType != "whatsapp" && shootType != "call" {
 		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid shoot type")
 		return
 	}
 
-	shoots, err := h.shootService.GetAllShoots(r.Context(), shootType)
+	shoots, nextCursor, err := h.shootService.GetAllShoots(r.Context(), shootType, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get shoots")
 		return
 	}
 
-	json.NewEncoder(w).Encode(shoots)
+	writeList(w, shoots, nextCursor)
 }
 
 func (h *ShootHandler) GetShootsWithDateFilter(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
 	startDate := r.URL.Query().Get("startDate")
 	endDate := r.URL.Query().Get("endDate")
 
@@ -55,21 +172,35 @@ func (h *ShootHandler) GetShootsWithDateFilter(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	shoots, err := h.shootService.GetShootsWithDateFilter(r.Context(), start, end)
+	shoots, nextCursor, err := h.shootService.GetShootsWithDateFilter(r.Context(), start, end, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get shoots")
 		return
 	}
 
-	json.NewEncoder(w).Encode(shoots)
+	writeList(w, shoots, nextCursor)
 }
 
 func (h *ShootHandler) GetMissedShoots(w http.ResponseWriter, r *http.Request) {
-	shoots, err := h.shootService.GetMissedShoots(r.Context())
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	shoots, nextCursor, err := h.shootService.GetMissedShoots(r.Context(), page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get missed shoots")
 		return
 	}
 
-	json.NewEncoder(w).Encode(shoots)
+	writeList(w, shoots, nextCursor)
 }
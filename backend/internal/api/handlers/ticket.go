@@ -3,21 +3,16 @@ package handlers
 import (
 	"backend/internal/models"
 	"backend/internal/service"
+	"backend/internal/store"
+	"backend/pkg/auth"
 	"backend/pkg/errors"
-	"context"
 	"encoding/json"
 	"net/http"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gorilla/mux"
 )
 
-const TicketTableName = "Tickets"
-
 type TicketHandler struct {
 	ticketService *service.TicketService
 	farmerService *service.FarmerService
@@ -35,86 +30,105 @@ func (h *TicketHandler) GetTicket(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ticketID := vars["id"]
 
-	result, err := dbClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(TicketTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: ticketID},
-		},
-	})
-	if err != nil || result.Item == nil {
-		http.Error(w, "Ticket not found", http.StatusNotFound)
-		return
-	}
-
-	var ticket models.Ticket
-	err = attributevalue.UnmarshalMap(result.Item, &ticket)
+	ticket, err := h.ticketService.GetTicket(r.Context(), ticketID)
 	if err != nil {
-		http.Error(w, "Failed to unmarshal ticket data", http.StatusInternalServerError)
+		http.Error(w, "Ticket not found", http.StatusNotFound)
 		return
 	}
 
+	setETag(w, ticket.Version)
 	json.NewEncoder(w).Encode(ticket)
 }
 
-// GetTickets - Retrieve all tickets
+// GetTickets - Retrieve one page of tickets
 func (h *TicketHandler) GetTickets(w http.ResponseWriter, r *http.Request) {
-	result, err := dbClient.Scan(context.TODO(), &dynamodb.ScanInput{
-		TableName: aws.String(TicketTableName),
-	})
+	page, err := parsePage(r)
 	if err != nil {
-		http.Error(w, "Failed to fetch tickets", http.StatusInternalServerError)
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
 		return
 	}
 
-	var tickets []models.Ticket
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &tickets)
+	tickets, nextCursor, err := h.ticketService.ListTickets(r.Context(), page)
+	if err == errors.ErrInvalidCursor {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to unmarshal tickets data", http.StatusInternalServerError)
+		http.Error(w, "Failed to fetch tickets", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(tickets)
+	writeList(w, tickets, nextCursor)
 }
 
 // GetTicketsByFarmerContact - Retrieve all tickets by a farmer's contact
 func (h *TicketHandler) GetTicketsByFarmer(w http.ResponseWriter, r *http.Request) {
-	contact := r.URL.Query().Get("contact")
+	vars := mux.Vars(r)
+	contact := vars["contact"]
 	if contact == "" {
 		errors.WriteJSONError(w, http.StatusBadRequest, "Contact is required")
 		return
 	}
 
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
 	farmer, err := h.farmerService.GetFarmerByContact(r.Context(), contact)
+	if err == errors.ErrInvalidInput {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid contact number")
+		return
+	}
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Farmer not found")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get farmer")
 		return
 	}
 
-	tickets, err := h.ticketService.GetTicketsByFarmerContact(r.Context(), farmer)
+	tickets, nextCursor, err := h.ticketService.GetTicketsByFarmerContact(r.Context(), farmer, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get tickets")
 		return
 	}
 
-	json.NewEncoder(w).Encode(tickets)
+	writeList(w, tickets, nextCursor)
 }
 
 // GetTicketsByCCE - Retrieve all tickets by a CCE's contact
 func (h *TicketHandler) GetTicketsByCCE(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	cceID := vars["cceId"]
+	cceID := vars["id"]
 	if cceID == "" {
 		errors.WriteJSONError(w, http.StatusBadRequest, "CCE ID is required")
 		return
 	}
 
-	tickets, err := h.ticketService.GetTicketsByCCE(r.Context(), cceID)
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	tickets, nextCursor, err := h.ticketService.GetTicketsByCCE(r.Context(), cceID, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get tickets")
 		return
 	}
 
-	json.NewEncoder(w).Encode(tickets)
+	writeList(w, tickets, nextCursor)
 }
 
 func (h *TicketHandler) GetTicketsByCCEWithDateFilter(w http.ResponseWriter, r *http.Request) {
@@ -127,6 +141,12 @@ func (h *TicketHandler) GetTicketsByCCEWithDateFilter(w http.ResponseWriter, r *
 		return
 	}
 
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
 	start, err := time.Parse(time.RFC3339, startDate)
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid start date format")
@@ -139,31 +159,46 @@ func (h *TicketHandler) GetTicketsByCCEWithDateFilter(w http.ResponseWriter, r *
 		return
 	}
 
-	tickets, err := h.ticketService.GetTicketsByCCEWithDateFilter(r.Context(), cceID, start, end)
+	tickets, nextCursor, err := h.ticketService.GetTicketsByCCEWithDateFilter(r.Context(), cceID, start, end, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get tickets")
 		return
 	}
 
-	json.NewEncoder(w).Encode(tickets)
+	writeList(w, tickets, nextCursor)
 }
 
 func (h *TicketHandler) GetTicketsByCCEAndStatus(w http.ResponseWriter, r *http.Request) {
-	cceID := r.URL.Query().Get("cceId")
-	status := r.URL.Query().Get("status")
+	vars := mux.Vars(r)
+	cceID := vars["id"]
+	status := vars["status"]
 
 	if cceID == "" || status == "" {
 		errors.WriteJSONError(w, http.StatusBadRequest, "CCE ID and status are required")
 		return
 	}
 
-	tickets, err := h.ticketService.GetTicketsByCCEAndStatus(r.Context(), cceID, status)
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	tickets, nextCursor, err := h.ticketService.GetTicketsByCCEAndStatus(r.Context(), cceID, status, page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to get tickets")
 		return
 	}
 
-	json.NewEncoder(w).Encode(tickets)
+	writeList(w, tickets, nextCursor)
 }
 
 func (h *TicketHandler) GetTicketsWithStatusAndSort(w http.ResponseWriter, r *http.Request) {
@@ -194,15 +229,17 @@ func (h *TicketHandler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = dbClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(TicketTableName),
-		Item: map[string]types.AttributeValue{
-			"ID":       &types.AttributeValueMemberS{Value: ticket.ID},
-			"FarmerID": &types.AttributeValueMemberS{Value: ticket.FarmerID},
-			"CCEID":    &types.AttributeValueMemberS{Value: ticket.CCEID},
-			"Status":   &types.AttributeValueMemberS{Value: ticket.Status},
-		},
-	})
+	err = h.ticketService.CreateTicket(r.Context(), &ticket)
+	var invalidProduct *service.CatalogError
+	if errors.As(err, &invalidProduct) {
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidProduct.Error())
+		return
+	}
+	var txErr *store.TransactionError
+	if errors.As(err, &txErr) {
+		errors.WriteJSONError(w, http.StatusConflict, txErr.Error())
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to add ticket", http.StatusInternalServerError)
 		return
@@ -224,21 +261,13 @@ func (h *TicketHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := dbClient.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String(TicketTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: ticketID},
-		},
-	})
-	if err != nil || result.Item == nil {
+	existingTicket, err := h.ticketService.GetTicket(r.Context(), ticketID)
+	if err != nil {
 		http.Error(w, "Ticket not found", http.StatusNotFound)
 		return
 	}
 
-	var existingTicket models.Ticket
-	err = attributevalue.UnmarshalMap(result.Item, &existingTicket)
-	if err != nil {
-		http.Error(w, "Failed to unmarshal ticket data", http.StatusInternalServerError)
+	if !checkIfMatch(w, r, existingTicket.Version) {
 		return
 	}
 
@@ -252,21 +281,44 @@ func (h *TicketHandler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
 	if newTicket.Status != "" {
 		existingTicket.Status = newTicket.Status
 	}
+	if newTicket.VarietyID != "" && newTicket.VarietyID != existingTicket.VarietyID {
+		// The crop and SKU of the old variety go with it.
+		existingTicket.VarietyID = newTicket.VarietyID
+		existingTicket.CropID = ""
+		existingTicket.SKU = ""
+	}
+	if newTicket.CropID != "" {
+		existingTicket.CropID = newTicket.CropID
+	}
+	if newTicket.SKU != "" {
+		existingTicket.SKU = newTicket.SKU
+	}
 
-	_, err = dbClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String(TicketTableName),
-		Item: map[string]types.AttributeValue{
-			"ID":       &types.AttributeValueMemberS{Value: existingTicket.ID},
-			"FarmerID": &types.AttributeValueMemberS{Value: existingTicket.FarmerID},
-			"CceID":    &types.AttributeValueMemberS{Value: existingTicket.CCEID},
-			"Status":   &types.AttributeValueMemberS{Value: existingTicket.Status},
-		},
-	})
+	err = h.ticketService.UpdateTicket(r.Context(), existingTicket)
+	var invalidProduct *service.CatalogError
+	if errors.As(err, &invalidProduct) {
+		errors.WriteJSONError(w, http.StatusBadRequest, invalidProduct.Error())
+		return
+	}
+	var txErr *store.TransactionError
+	if errors.Is(err, errors.ErrVersionConflict) {
+		errors.WriteJSONError(w, http.StatusPreconditionFailed, "Resource has been modified")
+		return
+	}
+	if errors.As(err, &txErr) {
+		errors.WriteJSONError(w, http.StatusConflict, txErr.Error())
+		return
+	}
+	if err == errors.ErrNotFound {
+		http.Error(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update ticket", http.StatusInternalServerError)
 		return
 	}
 
+	setETag(w, existingTicket.Version)
 	w.Write([]byte("Ticket updated successfully"))
 }
 
@@ -275,12 +327,16 @@ func (h *TicketHandler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ticketID := vars["id"]
 
-	_, err := dbClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(TicketTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: ticketID},
-		},
-	})
+	err := h.ticketService.DeleteTicket(r.Context(), ticketID, auth.UserID(r.Context()))
+	if err == errors.ErrNotFound {
+		http.Error(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	var txErr *store.TransactionError
+	if errors.As(err, &txErr) {
+		errors.WriteJSONError(w, http.StatusConflict, txErr.Error())
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to delete ticket", http.StatusInternalServerError)
 		return
@@ -288,3 +344,47 @@ func (h *TicketHandler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 
 	w.Write([]byte("Ticket deleted successfully"))
 }
+
+// GetDeletedTickets - Retrieve one page of deleted tickets
+func (h *TicketHandler) GetDeletedTickets(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	tickets, nextCursor, err := h.ticketService.ListDeletedTickets(r.Context(), page)
+	if err == errors.ErrInvalidCursor {
+		errors.WriteJSONError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to list deleted tickets")
+		return
+	}
+
+	writeList(w, tickets, nextCursor)
+}
+
+// RestoreTicket - Take a deleted ticket out of the trash
+func (h *TicketHandler) RestoreTicket(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ticketID := vars["id"]
+
+	err := h.ticketService.RestoreTicket(r.Context(), ticketID)
+	if err == errors.ErrNotFound {
+		errors.WriteJSONError(w, http.StatusNotFound, "Deleted ticket not found")
+		return
+	}
+	var txErr *store.TransactionError
+	if errors.As(err, &txErr) {
+		errors.WriteJSONError(w, http.StatusConflict, txErr.Error())
+		return
+	}
+	if err != nil {
+		errors.WriteJSONError(w, http.StatusInternalServerError, "Failed to restore ticket")
+		return
+	}
+
+	w.Write([]byte("Ticket restored successfully"))
+}
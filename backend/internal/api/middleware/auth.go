@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
 	"strings"
 
@@ -9,33 +8,51 @@ import (
 	"backend/pkg/errors"
 )
 
-var userId = "user_id"
-
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			errors.WriteJSONError(w, http.StatusUnauthorized, "Missing authorization header")
+		claims, problem := verifiedClaims(r)
+		if claims == nil {
+			errors.WriteJSONError(w, http.StatusUnauthorized, problem)
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			errors.WriteJSONError(w, http.StatusUnauthorized, "Invalid authorization header format")
-			return
-		}
+		ctx := auth.NewContext(r.Context(), claims)
+		r = r.WithContext(ctx)
 
-		token := parts[1]
+		next.ServeHTTP(w, r)
+	}
+}
 
-		claims, err := auth.VerifyToken(token)
-		if err != nil {
-			errors.WriteJSONError(w, http.StatusUnauthorized, "Invalid token")
+// RequireRole authenticates the request like AuthMiddleware and only lets it
+// through when the token carries role, or is an admin's.
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.FromContext(r.Context())
+		if !ok || (claims.Role != role && claims.Role != auth.RoleAdmin) {
+			errors.WriteJSONError(w, http.StatusForbidden, "Insufficient permissions")
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userId, claims.UserID)
-		r = r.WithContext(ctx)
-
 		next.ServeHTTP(w, r)
+	})
+}
+
+// verifiedClaims returns the claims of the request's bearer token, or nil and
+// what is wrong with the token.
+func verifiedClaims(r *http.Request) (*auth.Claims, string) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, "Missing authorization header"
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, "Invalid authorization header format"
+	}
+
+	claims, err := auth.VerifyToken(parts[1])
+	if err != nil {
+		return nil, "Invalid token"
 	}
+	return claims, ""
 }
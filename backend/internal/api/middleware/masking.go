@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/masking"
+	"backend/pkg/requestid"
+
+	"github.com/gorilla/mux"
+)
+
+// anonymousActor is logged for unmasked reads without a token.
+const anonymousActor = "anonymous"
+
+// MaskPII shapes JSON and CSV responses, such as the consent and import
+// exports, by the role in the caller's token. Callers whose role masker
+// masks, and callers without a valid token, get the configured fields
+// masked; every other response carrying them is written to accessLog, when
+// there is one. Masked values in the JSON bodies masked callers send are
+// dropped, so a record they read and send back keeps its real values.
+func MaskPII(masker *masking.Masker, accessLog *masking.AccessLog) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, actor := "", anonymousActor
+			if claims, _ := verifiedClaims(r); claims != nil {
+				role, actor = claims.Role, claims.UserID
+			}
+			masked := role == "" || masker.Masks(role)
+
+			if masked && r.Body != nil {
+				r.Body = stripMasked(masker, r.Body)
+			}
+
+			buffered := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(buffered, r)
+			body := buffered.body.Bytes()
+
+			mask, inspect := shapersOf(masker, w.Header().Get("Content-Type"), body)
+			if mask != nil {
+				if masked {
+					if shaped, err := mask(body); err == nil {
+						body = shaped
+					}
+				} else if accessLog != nil {
+					logAccess(masker, accessLog, inspect, r, buffered.status, role, actor, body)
+				}
+			}
+
+			w.WriteHeader(buffered.status)
+			w.Write(body)
+		})
+	}
+}
+
+// shapersOf returns how masker masks and inspects a body with the content
+// type, or nils for a body it cannot see fields in.
+func shapersOf(masker *masking.Masker, contentType string, body []byte) (mask func([]byte) ([]byte, error), inspect func([]byte) (*masking.Exposure, error)) {
+	switch {
+	case isJSON(contentType, body):
+		return masker.MaskJSON, masker.Inspect
+	case strings.HasPrefix(contentType, "text/csv"):
+		return masker.MaskCSV, masker.InspectCSV
+	}
+	return nil, nil
+}
+
+// isJSON reports whether a body with the content type holds a JSON object or
+// array. Many handlers encode JSON without setting a content type.
+func isJSON(contentType string, body []byte) bool {
+	if contentType != "" && !strings.HasPrefix(contentType, "application/json") {
+		return false
+	}
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
+// stripMasked returns body with the masked values taken out of it. Bodies
+// that are not JSON, such as uploaded spreadsheets, pass through as they are.
+func stripMasked(masker *masking.Masker, body io.ReadCloser) io.ReadCloser {
+	reader := bufio.NewReader(body)
+	start, _ := reader.Peek(64)
+	if !isJSON("", start) {
+		return struct {
+			io.Reader
+			io.Closer
+		}{reader, body}
+	}
+
+	data, err := io.ReadAll(reader)
+	body.Close()
+	if err == nil {
+		if stripped, err := masker.StripMasked(data); err == nil {
+			data = stripped
+		}
+	}
+	return io.NopCloser(bytes.NewReader(data))
+}
+
+func logAccess(masker *masking.Masker, accessLog *masking.AccessLog, inspect func([]byte) (*masking.Exposure, error), r *http.Request, status int, role, actor string, body []byte) {
+	exposure, err := inspect(body)
+	if err != nil || exposure.Empty() {
+		return
+	}
+	err = accessLog.Record(masking.AccessEntry{
+		Time:      time.Now().UTC(),
+		Actor:     actor,
+		Role:      role,
+		RequestID: requestid.FromContext(r.Context()),
+		Method:    r.Method,
+		Path:      loggedPath(masker, r),
+		Status:    status,
+		Fields:    exposure.Fields,
+		IDs:       exposure.IDs,
+	})
+	if err != nil {
+		log.Printf("Failed to write PII access log: %v", err)
+	}
+}
+
+// loggedPath is the path and query of the request with the values of the
+// path variables and query parameters named like a masked field masked, so
+// the access log does not keep the number in /farmer/contact/{contact}.
+func loggedPath(masker *masking.Masker, r *http.Request) string {
+	path := r.URL.Path
+	for name, value := range mux.Vars(r) {
+		if style, ok := masker.Style(name); ok && value != "" {
+			path = strings.Replace(path, value, masking.Mask(style, value), 1)
+		}
+	}
+
+	query := r.URL.Query()
+	for name, values := range query {
+		if style, ok := masker.Style(name); ok {
+			for i, value := range values {
+				values[i] = masking.Mask(style, value)
+			}
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// bufferedResponse holds a response back so it can be reshaped before it is
+// sent. Headers go straight to the underlying writer.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"backend/internal/masking"
+	"backend/pkg/auth"
+
+	"github.com/gorilla/mux"
+)
+
+// newMaskedRouter serves a farmer by contact as JSON and a consent export as
+// CSV behind MaskPII, logging unmasked reads to the returned buffer.
+func newMaskedRouter(t *testing.T) (*mux.Router, *bytes.Buffer) {
+	t.Helper()
+	masker, err := masking.New(map[string]string{"contact": masking.StylePhone}, []string{auth.RoleTempCCE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+
+	r := mux.NewRouter()
+	r.Use(MaskPII(masker, masking.NewAccessLog(&logged)))
+	r.HandleFunc("/farmer/contact/{contact}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"f1","name":"Ramesh","contact":"` + mux.Vars(r)["contact"] + `"}`))
+	})
+	r.HandleFunc("/consent/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("farmer_id,contact,channel\nf1,+919876543221,call\n"))
+	})
+	return r, &logged
+}
+
+func serve(t *testing.T, r http.Handler, path, role string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if role != "" {
+		token, err := auth.GenerateToken("u1", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMaskPII(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		role   string
+		want   string
+		logged bool
+	}{
+		{name: "anonymous caller", path: "/farmer/contact/+919876543221", want: "98xxxxxx21"},
+		{name: "masked role", path: "/farmer/contact/+919876543221", role: auth.RoleTempCCE, want: "98xxxxxx21"},
+		{name: "unmasked role", path: "/farmer/contact/+919876543221", role: auth.RoleCCE, want: "+919876543221", logged: true},
+		{name: "anonymous export", path: "/consent/export", want: "f1,98xxxxxx21,call"},
+		{name: "unmasked export", path: "/consent/export", role: auth.RoleSupervisor, want: "f1,+919876543221,call", logged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, logged := newMaskedRouter(t)
+			rec := serve(t, r, tt.path, tt.role)
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body = %q, want it to hold %q", rec.Body.String(), tt.want)
+			}
+			if got := logged.Len() > 0; got != tt.logged {
+				t.Errorf("logged = %v (%q), want %v", got, logged.String(), tt.logged)
+			}
+		})
+	}
+}
+
+func TestMaskPIILogsAccess(t *testing.T) {
+	r, logged := newMaskedRouter(t)
+	serve(t, r, "/farmer/contact/+919876543221?contact=9876543221&page=2", auth.RoleCCE)
+
+	var entry masking.AccessEntry
+	if err := json.Unmarshal(logged.Bytes(), &entry); err != nil {
+		t.Fatalf("access log %q: %v", logged.String(), err)
+	}
+	if entry.Actor != "u1" || entry.Role != auth.RoleCCE || entry.Status != http.StatusOK {
+		t.Errorf("entry = %+v, want u1 as cce with status 200", entry)
+	}
+	if !slices.Equal(entry.Fields, []string{"contact"}) || !slices.Equal(entry.IDs, []string{"f1"}) {
+		t.Errorf("entry exposes %v of %v, want the contact of f1", entry.Fields, entry.IDs)
+	}
+	if want := "/farmer/contact/98xxxxxx21?contact=98xxxxxx21&page=2"; entry.Path != want {
+		t.Errorf("logged path %q, want %q", entry.Path, want)
+	}
+}
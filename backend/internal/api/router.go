@@ -3,26 +3,60 @@ package api
 import (
 	"backend/internal/api/handlers"
 	"backend/internal/api/middleware"
+	"backend/internal/imports"
+	"backend/internal/masking"
 	"backend/internal/service"
+	"backend/pkg/auth"
 	"fmt"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupRouter(services *service.Services) *mux.Router {
+// SetupRouter routes the API to services. Responses are masked by masker for
+// the callers it masks and logged to accessLog, if any, for the others; see
+// middleware.MaskPII.
+func SetupRouter(services *service.Services, masker *masking.Masker, accessLog *masking.AccessLog) *mux.Router {
 	r := mux.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.MaskPII(masker, accessLog))
 
 	farmerHandler := handlers.NewFarmerHandler(services.Farmer)
 	cceHandler := handlers.NewCCEHandler(services.CCE)
 	ticketHandler := handlers.NewTicketHandler(services.Ticket, services.Farmer)
+	shootHandler := handlers.NewShootHandler(services.Shoot)
+	auditHandler := handlers.NewAuditHandler(services.Audit)
+	overviewHandler := handlers.NewOverviewHandler(services.Overview)
+	locationHandler := handlers.NewLocationHandler(services.Locations)
+	duplicateHandler := handlers.NewDuplicateHandler(services.Duplicate)
+	consentHandler := handlers.NewConsentHandler(services.Consent, services.Farmer)
+	contactHandler := handlers.NewContactHandler(services.Farmer)
+	geoHandler := handlers.NewGeoHandler(services.Farmer, services.Partner)
+	partnerHandler := handlers.NewPartnerHandler(services.Partner)
+	erasureHandler := handlers.NewErasureHandler(services.Erasure)
+	catalogHandler := handlers.NewCatalogHandler(services.Catalog)
+	importHandler := handlers.NewImportHandler(imports.NewImporter(services.Farmer, services.Locations))
 
 	fmt.Println("Inside setuprouter")
 
 	// GET
 	// Farmer routes
+	r.HandleFunc("/farmers/search", farmerHandler.SearchFarmers).Methods("GET")
+	r.HandleFunc("/farmers/duplicates", middleware.RequireRole(auth.RoleSupervisor, duplicateHandler.GetDuplicates)).Methods("GET")
+	r.HandleFunc("/farmers/nearby", middleware.AuthMiddleware(geoHandler.GetNearbyFarmers)).Methods("GET")
+	r.HandleFunc("/farmers/clusters", middleware.AuthMiddleware(geoHandler.GetTehsilClusters)).Methods("GET")
 	r.HandleFunc("/farmers/{id}", farmerHandler.GetFarmer).Methods("GET")
-	r.HandleFunc("/farmers", farmerHandler.GetFarmers).Methods("GET")
+	r.HandleFunc("/farmers", farmerHandler.SearchFarmers).Methods("GET")
 	r.HandleFunc("/farmer/contact/{contact}", farmerHandler.GetFarmerByContact).Methods("GET")
+	r.HandleFunc("/farmers/{id}/overview", middleware.AuthMiddleware(overviewHandler.GetFarmerOverview)).Methods("GET")
+	r.HandleFunc("/farmer/contact/{contact}/overview", middleware.AuthMiddleware(overviewHandler.GetFarmerOverviewByContact)).Methods("GET")
+	r.HandleFunc("/farmers/{id}/consent", middleware.AuthMiddleware(consentHandler.GetConsent)).Methods("GET")
+	r.HandleFunc("/farmers/{id}/contacts", middleware.AuthMiddleware(contactHandler.GetContacts)).Methods("GET")
+	r.HandleFunc("/farmers/{id}/nearest", middleware.AuthMiddleware(geoHandler.GetNearestPartners)).Methods("GET")
+	r.HandleFunc("/farmers/{id}/outreach", middleware.AuthMiddleware(shootHandler.CheckOutreach)).Methods("GET")
+	r.HandleFunc("/farmers/{id}/consent/history", middleware.AuthMiddleware(consentHandler.GetConsentHistory)).Methods("GET")
+
+	// Consent routes
+	r.HandleFunc("/consent/export", middleware.RequireRole(auth.RoleSupervisor, consentHandler.ExportConsentHistory)).Methods("GET")
 
 	// CCE routes
 	r.HandleFunc("/cces/{id}", cceHandler.GetCCE).Methods("GET")
@@ -35,29 +69,96 @@ func SetupRouter(services *service.Services) *mux.Router {
 	r.HandleFunc("/tickets/cce/{id}", ticketHandler.GetTicketsByCCE).Methods("GET")
 	r.HandleFunc("/tickets/cce/{id}/status/{status}", ticketHandler.GetTicketsByCCEAndStatus).Methods("GET")
 
+	// Shoot routes
+	r.HandleFunc("/shoots", shootHandler.GetAllShoots).Methods("GET")
+	r.HandleFunc("/shoots/date", shootHandler.GetShootsWithDateFilter).Methods("GET")
+	r.HandleFunc("/shoots/missed", shootHandler.GetMissedShoots).Methods("GET")
+
+	// Location routes
+	r.HandleFunc("/locations/states", locationHandler.GetStates).Methods("GET")
+	r.HandleFunc("/locations/districts", locationHandler.GetDistricts).Methods("GET")
+	r.HandleFunc("/locations/tehsils", locationHandler.GetTehsils).Methods("GET")
+	r.HandleFunc("/locations/villages", locationHandler.GetVillages).Methods("GET")
+	r.HandleFunc("/locations/pincodes", locationHandler.GetPincodes).Methods("GET")
+	r.HandleFunc("/locations/pincodes/{pincode}", locationHandler.GetPincode).Methods("GET")
+
+	// Partner routes
+	r.HandleFunc("/partners", middleware.AuthMiddleware(partnerHandler.GetPartners)).Methods("GET")
+	r.HandleFunc("/partners/{id}", middleware.AuthMiddleware(partnerHandler.GetPartner)).Methods("GET")
+
+	// Catalog routes
+	r.HandleFunc("/catalog/crops", middleware.AuthMiddleware(catalogHandler.GetCrops)).Methods("GET")
+	r.HandleFunc("/catalog/crops/{id}", middleware.AuthMiddleware(catalogHandler.GetCrop)).Methods("GET")
+	r.HandleFunc("/catalog/crops/{id}/recommended", middleware.AuthMiddleware(catalogHandler.GetRecommendedVarieties)).Methods("GET")
+	r.HandleFunc("/catalog/varieties", middleware.AuthMiddleware(catalogHandler.GetVarieties)).Methods("GET")
+	r.HandleFunc("/catalog/varieties/{id}", middleware.AuthMiddleware(catalogHandler.GetVariety)).Methods("GET")
+
+	// Erasure routes
+	r.HandleFunc("/erasures/public-key", erasureHandler.GetPublicKey).Methods("GET")
+
+	// Audit routes
+	r.HandleFunc("/audit", middleware.RequireRole(auth.RoleSupervisor, auditHandler.GetAudit)).Methods("GET")
+
 	// POST
 	// Farmer routes
 	r.HandleFunc("/farmers", middleware.AuthMiddleware(farmerHandler.CreateFarmer)).Methods("POST")
+	r.HandleFunc("/farmers/import", middleware.RequireRole(auth.RoleSupervisor, importHandler.ImportFarmers)).Methods("POST")
+	r.HandleFunc("/farmers/{id}/merge", middleware.RequireRole(auth.RoleSupervisor, duplicateHandler.MergeFarmer)).Methods("POST")
+	r.HandleFunc("/farmers/{id}/contacts", middleware.AuthMiddleware(contactHandler.AddContact)).Methods("POST")
+	r.HandleFunc("/farmers/duplicates/{id}/dismiss", middleware.RequireRole(auth.RoleSupervisor, duplicateHandler.DismissDuplicate)).Methods("POST")
 	// CCE routes
 	r.HandleFunc("/cces", middleware.AuthMiddleware(cceHandler.CreateCCE)).Methods("POST")
 	// Ticket routes
 	r.HandleFunc("/tickets", middleware.AuthMiddleware(ticketHandler.CreateTicket)).Methods("POST")
+	// Shoot routes
+	r.HandleFunc("/shoots", middleware.AuthMiddleware(shootHandler.CreateShoot)).Methods("POST")
+	// Consent routes
+	r.HandleFunc("/consent/opt-out", middleware.AuthMiddleware(consentHandler.OptOut)).Methods("POST")
+	// Partner routes
+	r.HandleFunc("/partners", middleware.RequireRole(auth.RoleSupervisor, partnerHandler.CreatePartner)).Methods("POST")
+	// Catalog routes
+	r.HandleFunc("/catalog/crops", middleware.RequireRole(auth.RoleSupervisor, catalogHandler.CreateCrop)).Methods("POST")
+	r.HandleFunc("/catalog/varieties", middleware.RequireRole(auth.RoleSupervisor, catalogHandler.CreateVariety)).Methods("POST")
+	// Erasure routes
+	r.HandleFunc("/erasures/verify", erasureHandler.VerifyCertificate).Methods("POST")
 
 	// PUT
 	// Farmer routes
 	r.HandleFunc("/farmers/{id}", middleware.AuthMiddleware(farmerHandler.UpdateFarmer)).Methods("PUT")
+	r.HandleFunc("/farmers/{id}/consent/{channel}", middleware.AuthMiddleware(consentHandler.SetConsent)).Methods("PUT")
+	r.HandleFunc("/farmers/{id}/contacts/{number}", middleware.AuthMiddleware(contactHandler.UpdateContact)).Methods("PUT")
 	// CCE routes
 	r.HandleFunc("/cces/{id}", middleware.AuthMiddleware(cceHandler.UpdateCCE)).Methods("PUT")
 	// Ticket routes
 	r.HandleFunc("/tickets/{id}", middleware.AuthMiddleware(ticketHandler.UpdateTicket)).Methods("PUT")
+	// Partner routes
+	r.HandleFunc("/partners/{id}", middleware.RequireRole(auth.RoleSupervisor, partnerHandler.UpdatePartner)).Methods("PUT")
+	// Catalog routes
+	r.HandleFunc("/catalog/crops/{id}", middleware.RequireRole(auth.RoleSupervisor, catalogHandler.UpdateCrop)).Methods("PUT")
+	r.HandleFunc("/catalog/varieties/{id}", middleware.RequireRole(auth.RoleSupervisor, catalogHandler.UpdateVariety)).Methods("PUT")
 
 	// DELETE
 	// Farmer routes
 	r.HandleFunc("/farmers/{id}", middleware.AuthMiddleware(farmerHandler.DeleteFarmer)).Methods("DELETE")
+	r.HandleFunc("/farmers/{id}/contacts/{number}", middleware.AuthMiddleware(contactHandler.RemoveContact)).Methods("DELETE")
 	// CCE routes
 	r.HandleFunc("/cces/{id}", middleware.AuthMiddleware(cceHandler.DeleteCCE)).Methods("DELETE")
 	// Ticket routes
 	r.HandleFunc("/tickets/{id}", middleware.AuthMiddleware(ticketHandler.DeleteTicket)).Methods("DELETE")
+	// Catalog routes
+	r.HandleFunc("/catalog/crops/{id}", middleware.RequireRole(auth.RoleSupervisor, catalogHandler.DeleteCrop)).Methods("DELETE")
+	r.HandleFunc("/catalog/varieties/{id}", middleware.RequireRole(auth.RoleSupervisor, catalogHandler.DeleteVariety)).Methods("DELETE")
+
+	// Admin
+	// Trash routes
+	r.HandleFunc("/admin/trash/farmers", middleware.RequireRole(auth.RoleAdmin, farmerHandler.GetDeletedFarmers)).Methods("GET")
+	r.HandleFunc("/admin/trash/cces", middleware.RequireRole(auth.RoleAdmin, cceHandler.GetDeletedCCEs)).Methods("GET")
+	r.HandleFunc("/admin/trash/tickets", middleware.RequireRole(auth.RoleAdmin, ticketHandler.GetDeletedTickets)).Methods("GET")
+	r.HandleFunc("/admin/trash/farmers/{id}/restore", middleware.RequireRole(auth.RoleAdmin, farmerHandler.RestoreFarmer)).Methods("POST")
+	r.HandleFunc("/admin/trash/cces/{id}/restore", middleware.RequireRole(auth.RoleAdmin, cceHandler.RestoreCCE)).Methods("POST")
+	r.HandleFunc("/admin/trash/tickets/{id}/restore", middleware.RequireRole(auth.RoleAdmin, ticketHandler.RestoreTicket)).Methods("POST")
+	// Erasure routes
+	r.HandleFunc("/admin/farmers/{id}/erasure", middleware.RequireRole(auth.RoleAdmin, erasureHandler.EraseFarmer)).Methods("POST")
 
 	return r
 }
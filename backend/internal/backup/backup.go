@@ -0,0 +1,204 @@
+// Package backup copies the DynamoDB tables of one environment to a directory
+// of gzipped JSON lines and back, to build staging from production or to
+// recover from a bad bulk edit.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"backend/internal/db"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DefaultTables are the tables a snapshot holds unless told otherwise. The
+// Migrations table is left out: a restore brings the target schema to the
+// snapshot's version with the migrator instead.
+var DefaultTables = []string{
+	db.FarmersTable,
+	db.FarmerContactsTable,
+	db.CCEsTable,
+	db.TicketsTable,
+	db.ShootsTable,
+	db.ReportsTable,
+	db.AuditTable,
+	db.DuplicateCandidatesTable,
+	db.ConsentEventsTable,
+	db.PartnersTable,
+	db.CropsTable,
+	db.VarietiesTable,
+	db.OutreachMarksTable,
+}
+
+// DefaultSegments is how many parallel scan segments read each table.
+const DefaultSegments = 4
+
+type BackupOptions struct {
+	// Tables are base table names; empty means DefaultTables.
+	Tables []string
+	// Segments is the number of parallel scan segments per table.
+	Segments int
+}
+
+// Backup writes every table to dir as <Table>.jsonl.gz and finishes with the
+// manifest. Tables are read one after another, each with a parallel segmented
+// scan, so the snapshot is not a point in time: writes made while it runs
+// may or may not be in it.
+func Backup(ctx context.Context, client *dynamodb.Client, tables db.Tables, dir string, opts BackupOptions) (*Manifest, error) {
+	if len(opts.Tables) == 0 {
+		opts.Tables = DefaultTables
+	}
+	if opts.Segments < 1 {
+		opts.Segments = DefaultSegments
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	version, _, err := db.NewMigrator(client, tables).Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+
+	manifest := &Manifest{
+		CreatedAt:     time.Now().UTC(),
+		SourcePrefix:  tables.Prefix,
+		SchemaVersion: version,
+	}
+	for _, table := range opts.Tables {
+		file, err := backupTable(ctx, client, tables.Name(table), filepath.Join(dir, table+".jsonl.gz"), opts.Segments)
+		if err != nil {
+			return nil, fmt.Errorf("back up %s: %w", table, err)
+		}
+		file.Table = table
+		manifest.Tables = append(manifest.Tables, *file)
+	}
+
+	if err := writeManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// backupTable scans table with one goroutine per segment and writes the items
+// to path from a single writer.
+func backupTable(ctx context.Context, client *dynamodb.Client, table, path string, segments int) (*TableFile, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	buffered := bufio.NewWriter(io.MultiWriter(out, hash, counter))
+	compressed := gzip.NewWriter(buffered)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	items := make(chan map[string]types.AttributeValue, 1000)
+	scanErrs := make(chan error, segments)
+	var scanners sync.WaitGroup
+	for segment := 0; segment < segments; segment++ {
+		scanners.Add(1)
+		go func() {
+			defer scanners.Done()
+			if err := scanSegment(ctx, client, table, int32(segment), int32(segments), items); err != nil {
+				scanErrs <- err
+				cancel()
+			}
+		}()
+	}
+	go func() {
+		scanners.Wait()
+		close(items)
+	}()
+
+	var count int64
+	var writeErr error
+	for item := range items {
+		if writeErr != nil {
+			continue // drain so the scanners can stop
+		}
+		encoded, err := encodeItem(item)
+		if err == nil {
+			_, err = compressed.Write(append(encoded, '\n'))
+		}
+		if err != nil {
+			writeErr = err
+			cancel()
+			continue
+		}
+		count++
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+	select {
+	case err := <-scanErrs:
+		return nil, err
+	default:
+	}
+
+	if err := compressed.Close(); err != nil {
+		return nil, err
+	}
+	if err := buffered.Flush(); err != nil {
+		return nil, err
+	}
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+
+	return &TableFile{
+		File:   filepath.Base(path),
+		Items:  count,
+		Bytes:  counter.n,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func scanSegment(ctx context.Context, client *dynamodb.Client, table string, segment, total int32, items chan<- map[string]types.AttributeValue) error {
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:      aws.String(table),
+		Segment:        aws.Int32(segment),
+		TotalSegments:  aws.Int32(total),
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Items {
+			select {
+			case items <- item:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Items are stored in DynamoDB JSON, the format of DynamoDB's own exports to
+// S3: every value is an object whose single key names its type, such as
+// {"S": "text"} or {"N": "42"}. Unlike plain JSON it keeps numbers exact and
+// tells sets from lists, so a restored item is identical to the one backed up.
+
+// line is one line of a table file.
+type line struct {
+	Item map[string]json.RawMessage `json:"Item"`
+}
+
+func encodeItem(item map[string]types.AttributeValue) ([]byte, error) {
+	encoded, err := encodeMap(item)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(line{Item: encoded})
+}
+
+func decodeItem(data []byte) (map[string]types.AttributeValue, error) {
+	var l line
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, err
+	}
+	if l.Item == nil {
+		return nil, fmt.Errorf("line has no Item")
+	}
+	return decodeMap(l.Item)
+}
+
+func encodeMap(m map[string]types.AttributeValue) (map[string]json.RawMessage, error) {
+	out := make(map[string]json.RawMessage, len(m))
+	for name, av := range m {
+		encoded, err := encodeValue(av)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = encoded
+	}
+	return out, nil
+}
+
+func encodeValue(av types.AttributeValue) (json.RawMessage, error) {
+	var typ string
+	var v interface{}
+	switch av := av.(type) {
+	case *types.AttributeValueMemberS:
+		typ, v = "S", av.Value
+	case *types.AttributeValueMemberN:
+		typ, v = "N", av.Value
+	case *types.AttributeValueMemberB:
+		typ, v = "B", av.Value
+	case *types.AttributeValueMemberBOOL:
+		typ, v = "BOOL", av.Value
+	case *types.AttributeValueMemberNULL:
+		typ, v = "NULL", av.Value
+	case *types.AttributeValueMemberM:
+		m, err := encodeMap(av.Value)
+		if err != nil {
+			return nil, err
+		}
+		typ, v = "M", m
+	case *types.AttributeValueMemberL:
+		l := make([]json.RawMessage, len(av.Value))
+		for i, element := range av.Value {
+			encoded, err := encodeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = encoded
+		}
+		typ, v = "L", l
+	case *types.AttributeValueMemberSS:
+		typ, v = "SS", av.Value
+	case *types.AttributeValueMemberNS:
+		typ, v = "NS", av.Value
+	case *types.AttributeValueMemberBS:
+		typ, v = "BS", av.Value
+	default:
+		return nil, fmt.Errorf("unsupported attribute value %T", av)
+	}
+	return json.Marshal(map[string]interface{}{typ: v})
+}
+
+func decodeMap(m map[string]json.RawMessage) (map[string]types.AttributeValue, error) {
+	out := make(map[string]types.AttributeValue, len(m))
+	for name, raw := range m {
+		av, err := decodeValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		out[name] = av
+	}
+	return out, nil
+}
+
+func decodeValue(raw json.RawMessage) (types.AttributeValue, error) {
+	var typed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return nil, err
+	}
+	if len(typed) != 1 {
+		return nil, fmt.Errorf("value %s must have exactly one type", raw)
+	}
+
+	for typ, data := range typed {
+		switch typ {
+		case "S":
+			av := &types.AttributeValueMemberS{}
+			return av, json.Unmarshal(data, &av.Value)
+		case "N":
+			av := &types.AttributeValueMemberN{}
+			return av, json.Unmarshal(data, &av.Value)
+		case "B":
+			av := &types.AttributeValueMemberB{}
+			return av, json.Unmarshal(data, &av.Value)
+		case "BOOL":
+			av := &types.AttributeValueMemberBOOL{}
+			return av, json.Unmarshal(data, &av.Value)
+		case "NULL":
+			av := &types.AttributeValueMemberNULL{}
+			return av, json.Unmarshal(data, &av.Value)
+		case "M":
+			var m map[string]json.RawMessage
+			if err := json.Unmarshal(data, &m); err != nil {
+				return nil, err
+			}
+			decoded, err := decodeMap(m)
+			if err != nil {
+				return nil, err
+			}
+			return &types.AttributeValueMemberM{Value: decoded}, nil
+		case "L":
+			var l []json.RawMessage
+			if err := json.Unmarshal(data, &l); err != nil {
+				return nil, err
+			}
+			av := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, len(l))}
+			for i, element := range l {
+				decoded, err := decodeValue(element)
+				if err != nil {
+					return nil, err
+				}
+				av.Value[i] = decoded
+			}
+			return av, nil
+		case "SS":
+			av := &types.AttributeValueMemberSS{}
+			return av, json.Unmarshal(data, &av.Value)
+		case "NS":
+			av := &types.AttributeValueMemberNS{}
+			return av, json.Unmarshal(data, &av.Value)
+		case "BS":
+			av := &types.AttributeValueMemberBS{}
+			return av, json.Unmarshal(data, &av.Value)
+		}
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	return nil, nil
+}
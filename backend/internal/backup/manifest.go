@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestFile is the name of the manifest inside a snapshot directory.
+const ManifestFile = "manifest.json"
+
+// Manifest describes a snapshot: which tables it holds, how many items each
+// file has and the SHA-256 of each file, so a restore can tell a complete
+// snapshot from a truncated or edited one before it writes anything.
+type Manifest struct {
+	CreatedAt     time.Time   `json:"createdAt"`
+	SourcePrefix  string      `json:"sourcePrefix"`
+	SchemaVersion int         `json:"schemaVersion"`
+	Tables        []TableFile `json:"tables"`
+}
+
+// TableFile is one table of a snapshot, stored as gzipped JSON lines.
+type TableFile struct {
+	Table  string `json:"table"`
+	File   string `json:"file"`
+	Items  int64  `json:"items"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// writeManifest is the last step of a backup; a directory without a manifest
+// is an unfinished snapshot.
+func writeManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644)
+}
@@ -0,0 +1,268 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/db"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchWriteItems is the most writes DynamoDB accepts in one BatchWriteItem.
+const maxBatchWriteItems = 25
+
+// batchWriteAttempts bounds how often unprocessed items are sent again before
+// the restore gives up.
+const batchWriteAttempts = 8
+
+// DefaultWorkers is how many batches are written to a table at once.
+const DefaultWorkers = 4
+
+type RestoreOptions struct {
+	// Tables are base table names to restore; empty means every table in
+	// the snapshot.
+	Tables []string
+	// Workers is the number of concurrent BatchWriteItem calls per table.
+	Workers int
+}
+
+// Restore loads the snapshot in dir into the tables of one environment.
+//
+// Nothing is written until every file matches its checksum in the manifest.
+// The target schema is then migrated up to the snapshot's version, which
+// creates the tables of a new prefix, and is refused if it is already newer.
+// Every target table must be empty, so a restore never mixes two datasets.
+func Restore(ctx context.Context, client *dynamodb.Client, tables db.Tables, dir string, opts RestoreOptions) (*Manifest, error) {
+	if opts.Workers < 1 {
+		opts.Workers = DefaultWorkers
+	}
+
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	files, err := selectTables(manifest, opts.Tables)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := verifyFile(dir, file); err != nil {
+			return nil, err
+		}
+	}
+
+	migrator := db.NewMigrator(client, tables)
+	version, _, err := migrator.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+	if version > manifest.SchemaVersion {
+		return nil, fmt.Errorf("target schema is at version %d, newer than the snapshot's version %d", version, manifest.SchemaVersion)
+	}
+	if version < manifest.SchemaVersion {
+		if err := migrator.Migrate(ctx, manifest.SchemaVersion); err != nil {
+			return nil, fmt.Errorf("migrate target to version %d: %w", manifest.SchemaVersion, err)
+		}
+	}
+
+	for _, file := range files {
+		empty, err := tableEmpty(ctx, client, tables.Name(file.Table))
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", file.Table, err)
+		}
+		if !empty {
+			return nil, fmt.Errorf("table %s is not empty", tables.Name(file.Table))
+		}
+	}
+
+	restored := &Manifest{
+		CreatedAt:     manifest.CreatedAt,
+		SourcePrefix:  manifest.SourcePrefix,
+		SchemaVersion: manifest.SchemaVersion,
+	}
+	for _, file := range files {
+		count, err := restoreTable(ctx, client, tables.Name(file.Table), filepath.Join(dir, file.File), opts.Workers)
+		if err != nil {
+			return nil, fmt.Errorf("restore %s: %w", file.Table, err)
+		}
+		if count != file.Items {
+			return nil, fmt.Errorf("restore %s: wrote %d items, manifest lists %d", file.Table, count, file.Items)
+		}
+		restored.Tables = append(restored.Tables, file)
+	}
+	return restored, nil
+}
+
+func selectTables(manifest *Manifest, names []string) ([]TableFile, error) {
+	if len(names) == 0 {
+		return manifest.Tables, nil
+	}
+	var files []TableFile
+	for _, name := range names {
+		i := slices.IndexFunc(manifest.Tables, func(f TableFile) bool { return f.Table == name })
+		if i < 0 {
+			return nil, fmt.Errorf("snapshot has no table %s", name)
+		}
+		files = append(files, manifest.Tables[i])
+	}
+	return files, nil
+}
+
+func verifyFile(dir string, file TableFile) error {
+	f, err := os.Open(filepath.Join(dir, file.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	if n != file.Bytes || hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("%s does not match the manifest", file.File)
+	}
+	return nil
+}
+
+func tableEmpty(ctx context.Context, client *dynamodb.Client, table string) (bool, error) {
+	result, err := client.Scan(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(table),
+		Limit:     aws.Int32(1),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(result.Items) == 0, nil
+}
+
+// restoreTable reads the items of path in batches and hands them to workers
+// that write them to table. It returns how many items were written.
+func restoreTable(ctx context.Context, client *dynamodb.Client, table, path string, workers int) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	decompressed, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []types.WriteRequest, workers)
+	writeErrs := make(chan error, workers)
+	var written atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if err := batchWrite(ctx, client, table, batch); err != nil {
+					writeErrs <- err
+					cancel()
+					return
+				}
+				written.Add(int64(len(batch)))
+			}
+		}()
+	}
+
+	readErr := readBatches(ctx, decompressed, batches)
+	close(batches)
+	wg.Wait()
+
+	select {
+	case err := <-writeErrs:
+		return 0, err
+	default:
+	}
+	if readErr != nil {
+		return 0, readErr
+	}
+	return written.Load(), nil
+}
+
+func readBatches(ctx context.Context, r io.Reader, batches chan<- []types.WriteRequest) error {
+	scanner := bufio.NewScanner(r)
+	// Items can be up to 400 KB, more once written as JSON.
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+
+	var batch []types.WriteRequest
+	send := func() error {
+		select {
+		case batches <- batch:
+			batch = nil
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		item, err := decodeItem(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		batch = append(batch, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		if len(batch) == maxBatchWriteItems {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return send()
+	}
+	return nil
+}
+
+// batchWrite sends requests until DynamoDB has processed all of them, backing
+// off while it returns unprocessed items.
+func batchWrite(ctx context.Context, client *dynamodb.Client, table string, requests []types.WriteRequest) error {
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		result, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{table: requests},
+		})
+		if err != nil {
+			return err
+		}
+
+		requests = result.UnprocessedItems[table]
+		if len(requests) == 0 {
+			return nil
+		}
+		if attempt == batchWriteAttempts {
+			return fmt.Errorf("%d items still unprocessed after %d attempts", len(requests), attempt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -2,26 +2,49 @@ package config
 
 import (
 	"fmt"
+	"regexp"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all the configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	AWS      AWSConfig
-	SMTP     SMTPConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	AWS           AWSConfig
+	SMTP          SMTPConfig
+	Trash         TrashConfig
+	Location      LocationConfig
+	Search        SearchConfig
+	Duplicates    DuplicatesConfig
+	ContactPolicy ContactPolicyConfig
+	PII           PIIConfig
+	Masking       MaskingConfig
+	Erasure       ErasureConfig
 }
 
 // ServerConfig holds the configuration for the server
 type ServerConfig struct {
 	Port int
 	Host string
+	// CursorSecret signs the pagination cursors handed to clients. Every
+	// instance behind the same load balancer needs the same secret.
+	CursorSecret string
 }
 
+// Database drivers selectable through DatabaseConfig.Driver
+const (
+	DriverDynamoDB = "dynamodb"
+	DriverMemory   = "memory"
+)
+
+// tablePrefixPattern matches the characters DynamoDB allows in table names
+var tablePrefixPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]*$`)
+
 // DatabaseConfig holds the configuration for the database
 type DatabaseConfig struct {
+	Driver      string
 	TablePrefix string
 }
 
@@ -38,6 +61,89 @@ type SMTPConfig struct {
 	Password string
 }
 
+// TrashConfig holds the configuration for purging soft-deleted records
+type TrashConfig struct {
+	// Retention is how long deleted records stay restorable
+	Retention time.Duration
+	// PurgeSchedule is the cron spec of the purge job
+	PurgeSchedule string
+}
+
+// LocationConfig holds the configuration for the location master
+type LocationConfig struct {
+	// DirectoryFiles are government pincode or LGD directory files in CSV
+	// that add tehsils, villages and pincodes to the built in states and
+	// districts
+	DirectoryFiles []string
+}
+
+// SearchConfig holds the configuration for the farmer search index
+type SearchConfig struct {
+	// RefreshSchedule is the cron spec of the rebuild that picks up farmers
+	// written by other instances
+	RefreshSchedule string
+}
+
+// DuplicatesConfig holds the configuration for duplicate farmer detection
+type DuplicatesConfig struct {
+	// Schedule is the cron spec of the job that queues likely duplicates
+	// for review
+	Schedule string
+}
+
+// ContactPolicyConfig holds the rules outbound shoots are checked against.
+// Quiet hours are written as "HH:MM-HH:MM" in IST; zero caps and gaps are off.
+type ContactPolicyConfig struct {
+	QuietHours string
+	// RegionQuietHours overrides QuietHours, keyed by "state" or
+	// "state/district"
+	RegionQuietHours map[string]string
+	MaxPerDay        int
+	MaxPerWeek       int
+	MinGapAfterCall  time.Duration
+}
+
+// Key providers selectable through PIIConfig.KeyProvider
+const (
+	KeyProviderKeyfile = "keyfile"
+	KeyProviderKMS     = "kms"
+)
+
+// PIIConfig holds the configuration for the encryption of farmer PII. An
+// empty KeyProvider stores PII in plaintext.
+type PIIConfig struct {
+	KeyProvider string
+	// Keyfile holds the master keys of the keyfile provider; it is created
+	// on first use
+	Keyfile string
+	// KMSKeyID is the key ID, ARN or alias of the KMS master key
+	KMSKeyID string
+	// KMSEndpoint overrides the AWS KMS endpoint, for KMS compatible services
+	KMSEndpoint string
+	// BlindIndexKey is the secret contact numbers are hashed with for
+	// lookups. Changing it orphans every stored index, so it is not rotated
+	// with the keys.
+	BlindIndexKey string
+}
+
+// MaskingConfig holds the configuration for masking PII in API responses
+type MaskingConfig struct {
+	// Roles see the fields masked; callers without a token do too
+	Roles []string
+	// Fields maps JSON field names to a masking style: phone, text or phones
+	Fields map[string]string
+	// AccessLog is the file unmasked reads of the fields are logged to, or
+	// empty for none
+	AccessLog string
+}
+
+// ErasureConfig holds the configuration for erasing farmer PII on request
+type ErasureConfig struct {
+	// SigningSecret derives the key erasure certificates are signed with.
+	// Changing it leaves certificates issued before unverifiable.
+	SigningSecret string
+}
+
 // Load reads the configuration from a file and environment variables
 func Load() (*Config, error) {
 	viper.SetConfigName("config")   // name of config file (without extension)
@@ -47,6 +153,24 @@ func Load() (*Config, error) {
 
 	viper.AutomaticEnv() // read in environment variables that match
 
+	viper.SetDefault("database.driver", DriverDynamoDB)
+	viper.SetDefault("trash.retention", "720h")
+	viper.SetDefault("trash.purgeSchedule", "30 0 * * *")
+	viper.SetDefault("search.refreshSchedule", "*/10 * * * *")
+	viper.SetDefault("duplicates.schedule", "0 2 * * *")
+	viper.SetDefault("contactPolicy.quietHours", "21:00-08:00")
+	viper.SetDefault("contactPolicy.maxPerDay", 2)
+	viper.SetDefault("contactPolicy.maxPerWeek", 5)
+	viper.SetDefault("contactPolicy.minGapAfterCall", "4h")
+	viper.SetDefault("masking.roles", []string{"temp_cce", "report"})
+	viper.SetDefault("masking.fields", map[string]string{
+		"contact":     "phone",
+		"number":      "phone",
+		"address":     "text",
+		"description": "phones",
+	})
+	viper.SetDefault("masking.accessLog", "pii-access.log")
+
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -63,8 +187,10 @@ func Load() (*Config, error) {
 	// Server configuration
 	config.Server.Port = viper.GetInt("server.port")
 	config.Server.Host = viper.GetString("server.host")
+	config.Server.CursorSecret = viper.GetString("server.cursorSecret")
 
 	// Database configuration
+	config.Database.Driver = viper.GetString("database.driver")
 	config.Database.TablePrefix = viper.GetString("database.tablePrefix")
 
 	// AWS configuration
@@ -76,6 +202,41 @@ func Load() (*Config, error) {
 	config.SMTP.Username = viper.GetString(("smtp.username"))
 	config.SMTP.Password = viper.GetString(("smtp.password"))
 
+	// Trash configuration
+	config.Trash.Retention = viper.GetDuration("trash.retention")
+	config.Trash.PurgeSchedule = viper.GetString("trash.purgeSchedule")
+
+	// Location configuration
+	config.Location.DirectoryFiles = viper.GetStringSlice("location.directoryFiles")
+
+	// Search configuration
+	config.Search.RefreshSchedule = viper.GetString("search.refreshSchedule")
+
+	// Duplicates configuration
+	config.Duplicates.Schedule = viper.GetString("duplicates.schedule")
+
+	// Contact policy configuration
+	config.ContactPolicy.QuietHours = viper.GetString("contactPolicy.quietHours")
+	config.ContactPolicy.RegionQuietHours = viper.GetStringMapString("contactPolicy.regionQuietHours")
+	config.ContactPolicy.MaxPerDay = viper.GetInt("contactPolicy.maxPerDay")
+	config.ContactPolicy.MaxPerWeek = viper.GetInt("contactPolicy.maxPerWeek")
+	config.ContactPolicy.MinGapAfterCall = viper.GetDuration("contactPolicy.minGapAfterCall")
+
+	// PII configuration
+	config.PII.KeyProvider = viper.GetString("pii.keyProvider")
+	config.PII.Keyfile = viper.GetString("pii.keyfile")
+	config.PII.KMSKeyID = viper.GetString("pii.kmsKeyId")
+	config.PII.KMSEndpoint = viper.GetString("pii.kmsEndpoint")
+	config.PII.BlindIndexKey = viper.GetString("pii.blindIndexKey")
+
+	// Masking configuration
+	config.Masking.Roles = viper.GetStringSlice("masking.roles")
+	config.Masking.Fields = viper.GetStringMapString("masking.fields")
+	config.Masking.AccessLog = viper.GetString("masking.accessLog")
+
+	// Erasure configuration
+	config.Erasure.SigningSecret = viper.GetString("erasure.signingSecret")
+
 	// Validate the configuration
 	if err := validateConfig(&config); err != nil {
 		return nil, err
@@ -92,8 +253,42 @@ func validateConfig(config *Config) error {
 	if config.Server.Host == "" {
 		return fmt.Errorf("server host is required")
 	}
-	if config.AWS.Region == "" {
-		return fmt.Errorf("AWS region is required")
+	if config.Server.CursorSecret == "" {
+		return fmt.Errorf("server cursor secret is required")
+	}
+	switch config.Database.Driver {
+	case DriverDynamoDB:
+		if config.AWS.Region == "" {
+			return fmt.Errorf("AWS region is required")
+		}
+	case DriverMemory:
+	default:
+		return fmt.Errorf("unknown database driver %q", config.Database.Driver)
+	}
+	if !tablePrefixPattern.MatchString(config.Database.TablePrefix) {
+		return fmt.Errorf("table prefix %q may only contain letters, digits, '_', '-' and '.'", config.Database.TablePrefix)
+	}
+	if config.Trash.Retention <= 0 {
+		return fmt.Errorf("trash retention must be positive")
+	}
+	switch config.PII.KeyProvider {
+	case "":
+	case KeyProviderKeyfile:
+		if config.PII.Keyfile == "" {
+			return fmt.Errorf("PII keyfile is required")
+		}
+	case KeyProviderKMS:
+		if config.PII.KMSKeyID == "" {
+			return fmt.Errorf("PII KMS key ID is required")
+		}
+	default:
+		return fmt.Errorf("unknown PII key provider %q", config.PII.KeyProvider)
+	}
+	if config.PII.KeyProvider != "" && config.PII.BlindIndexKey == "" {
+		return fmt.Errorf("PII blind index key is required")
+	}
+	if config.Erasure.SigningSecret == "" {
+		return fmt.Errorf("erasure signing secret is required")
 	}
 	if config.SMTP.Host == "" {
 		return fmt.Errorf("SMTP host is required")
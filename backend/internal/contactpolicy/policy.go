@@ -0,0 +1,243 @@
+// Package contactpolicy decides when a farmer may be contacted again. It
+// works from the shoots already made to the farmer, so it needs no state of
+// its own: quiet hours by region, caps on contacts per day and per week
+// across channels, and a pause after a completed call.
+package contactpolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// IST is the time zone quiet hours and calendar days are counted in.
+var IST = time.FixedZone("IST", 5*60*60+30*60)
+
+// Week is the rolling window MaxPerWeek counts over.
+const Week = 7 * 24 * time.Hour
+
+// maxRounds bounds how often the rules are re-applied to a deferred time.
+// Every round moves the time later, and in practice two or three settle it.
+const maxRounds = 16
+
+// Outcomes of a Decision.
+const (
+	Allowed  = "allowed"
+	Deferred = "deferred"
+	Blocked  = "blocked"
+)
+
+// Decision is the answer to "may this farmer be contacted now".
+type Decision struct {
+	Outcome string `json:"outcome"`
+	// Until is the earliest time the farmer may be contacted, for deferred
+	// decisions.
+	Until   *time.Time `json:"until,omitempty"`
+	Reasons []string   `json:"reasons,omitempty"`
+}
+
+// Policy holds the contact rules. Zero values switch a rule off.
+type Policy struct {
+	// QuietHours applies to farmers whose region has no quiet hours of its own.
+	QuietHours Window
+	// RegionQuietHours is keyed by lower case "state" or "state/district";
+	// a district entry wins over its state.
+	RegionQuietHours map[string]Window
+	MaxPerDay        int
+	MaxPerWeek       int
+	// MinGapAfterCall is how long to wait after a completed call.
+	MinGapAfterCall time.Duration
+}
+
+// Load builds a Policy, reading quiet hours as ParseWindow does. Region keys
+// are lower cased.
+func Load(quietHours string, regionQuietHours map[string]string, maxPerDay, maxPerWeek int, minGapAfterCall time.Duration) (*Policy, error) {
+	if maxPerDay < 0 || maxPerWeek < 0 || minGapAfterCall < 0 {
+		return nil, fmt.Errorf("contact caps and gaps cannot be negative")
+	}
+	p := &Policy{
+		RegionQuietHours: make(map[string]Window, len(regionQuietHours)),
+		MaxPerDay:        maxPerDay,
+		MaxPerWeek:       maxPerWeek,
+		MinGapAfterCall:  minGapAfterCall,
+	}
+	var err error
+	if p.QuietHours, err = ParseWindow(quietHours); err != nil {
+		return nil, err
+	}
+	for region, hours := range regionQuietHours {
+		w, err := ParseWindow(hours)
+		if err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		p.RegionQuietHours[strings.ToLower(region)] = w
+	}
+	return p, nil
+}
+
+// Window is a daily stretch of time in IST. It may run past midnight, as
+// 21:00-08:00 does. The zero Window is empty.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow reads a window written as "HH:MM-HH:MM". An empty string is
+// the empty window.
+func ParseWindow(s string) (Window, error) {
+	if s == "" {
+		return Window{}, nil
+	}
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("quiet hours %q are not HH:MM-HH:MM", s)
+	}
+	var w Window
+	var err error
+	if w.Start, err = parseClock(start); err != nil {
+		return Window{}, err
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return Window{}, err
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// end returns the end of the window t falls in, and false if t is outside
+// the window.
+func (w Window) end(t time.Time) (time.Time, bool) {
+	if w.Start == w.End {
+		return time.Time{}, false
+	}
+	day := dayStart(t)
+	offset := t.Sub(day)
+	switch {
+	case w.Start < w.End && offset >= w.Start && offset < w.End:
+		return day.Add(w.End), true
+	case w.Start > w.End && offset >= w.Start:
+		return day.AddDate(0, 0, 1).Add(w.End), true
+	case w.Start > w.End && offset < w.End:
+		return day.Add(w.End), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// QuietHoursFor returns the quiet hours that apply to the farmer.
+func (p *Policy) QuietHoursFor(farmer *models.Farmer) Window {
+	state := strings.ToLower(farmer.State)
+	if w, ok := p.RegionQuietHours[state+"/"+strings.ToLower(farmer.District)]; ok {
+		return w
+	}
+	if w, ok := p.RegionQuietHours[state]; ok {
+		return w
+	}
+	return p.QuietHours
+}
+
+// Lookback is how far back the shoot history passed to Evaluate must reach.
+func (p *Policy) Lookback() time.Duration {
+	return max(Week, p.MinGapAfterCall)
+}
+
+// Evaluate decides whether the farmer may be contacted at, given the shoots
+// made to them so far on any channel. It never blocks: that is left to the
+// caller, for reasons such as missing consent that are not about timing.
+func (p *Policy) Evaluate(farmer *models.Farmer, history []models.Shoot, at time.Time) Decision {
+	shoots := make([]time.Time, 0, len(history))
+	var lastCall time.Time
+	for _, shoot := range history {
+		if shoot.Timestamp.After(at) {
+			continue
+		}
+		shoots = append(shoots, shoot.Timestamp)
+		if shoot.Type == models.ChannelCall && shoot.Status == "completed" && shoot.Timestamp.After(lastCall) {
+			lastCall = shoot.Timestamp
+		}
+	}
+	sort.Slice(shoots, func(i, j int) bool { return shoots[i].Before(shoots[j]) })
+	quiet := p.QuietHoursFor(farmer)
+
+	t := at
+	var reasons []string
+	for round := 0; round < maxRounds; round++ {
+		next, reason := p.nextAllowed(quiet, shoots, lastCall, t)
+		if !next.After(t) {
+			break
+		}
+		t = next
+		if len(reasons) == 0 || reasons[len(reasons)-1] != reason {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	if t.Equal(at) {
+		return Decision{Outcome: Allowed}
+	}
+	until := t.In(IST)
+	return Decision{Outcome: Deferred, Until: &until, Reasons: reasons}
+}
+
+// nextAllowed applies the rules in turn to t and returns the first later time
+// one of them asks for, with the reason. It returns t if every rule allows it.
+func (p *Policy) nextAllowed(quiet Window, shoots []time.Time, lastCall, t time.Time) (time.Time, string) {
+	if end, ok := quiet.end(t); ok {
+		return end, "quiet hours " + quiet.String() + " IST"
+	}
+
+	if p.MinGapAfterCall > 0 && !lastCall.IsZero() {
+		if resume := lastCall.Add(p.MinGapAfterCall); resume.After(t) {
+			return resume, fmt.Sprintf("%s gap after a completed call", p.MinGapAfterCall)
+		}
+	}
+
+	if p.MaxPerDay > 0 {
+		day := dayStart(t)
+		next := day.AddDate(0, 0, 1)
+		if countBetween(shoots, day, next) >= p.MaxPerDay {
+			return next, fmt.Sprintf("%d contacts per day", p.MaxPerDay)
+		}
+	}
+
+	if p.MaxPerWeek > 0 {
+		// Rolling window (t-Week, t]: wait for enough of it to age out.
+		from := sort.Search(len(shoots), func(i int) bool { return shoots[i].After(t.Add(-Week)) })
+		to := sort.Search(len(shoots), func(i int) bool { return shoots[i].After(t) })
+		if n := to - from; n >= p.MaxPerWeek {
+			return shoots[from+n-p.MaxPerWeek].Add(Week), fmt.Sprintf("%d contacts per week", p.MaxPerWeek)
+		}
+	}
+
+	return t, ""
+}
+
+// countBetween counts the sorted times in [from, to).
+func countBetween(times []time.Time, from, to time.Time) int {
+	i := sort.Search(len(times), func(i int) bool { return !times[i].Before(from) })
+	j := sort.Search(len(times), func(i int) bool { return !times[i].Before(to) })
+	return j - i
+}
+
+// dayStart returns midnight IST of the day t falls on.
+func dayStart(t time.Time) time.Time {
+	y, m, d := t.In(IST).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, IST)
+}
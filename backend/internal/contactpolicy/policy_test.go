@@ -0,0 +1,193 @@
+package contactpolicy
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// ist returns 10 March 2026 at hh:mm IST, plus days.
+func ist(days, hh, mm int) time.Time {
+	return time.Date(2026, 3, 10+days, hh, mm, 0, 0, IST)
+}
+
+func shoot(channel, status string, at time.Time) models.Shoot {
+	return models.Shoot{Type: channel, Status: status, Timestamp: at}
+}
+
+func TestEvaluate(t *testing.T) {
+	policy, err := Load("21:00-08:00", map[string]string{
+		"Kerala":             "20:00-09:00",
+		"Maharashtra/Nashik": "22:00-07:00",
+	}, 2, 5, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maharashtra := &models.Farmer{State: "Maharashtra", District: "Pune"}
+	tests := []struct {
+		name    string
+		farmer  *models.Farmer
+		history []models.Shoot
+		at      time.Time
+		// want is the deferral time, or zero for an allowed contact.
+		want    time.Time
+		reasons []string
+	}{
+		{
+			name:   "no history in the day",
+			farmer: maharashtra,
+			at:     ist(0, 11, 0),
+		},
+		{
+			name:    "quiet hours after midnight",
+			farmer:  maharashtra,
+			at:      ist(0, 2, 30),
+			want:    ist(0, 8, 0),
+			reasons: []string{"quiet hours 21:00-08:00 IST"},
+		},
+		{
+			name:    "quiet hours before midnight",
+			farmer:  maharashtra,
+			at:      ist(0, 22, 0),
+			want:    ist(1, 8, 0),
+			reasons: []string{"quiet hours 21:00-08:00 IST"},
+		},
+		{
+			name:    "state quiet hours",
+			farmer:  &models.Farmer{State: "kerala", District: "Idukki"},
+			at:      ist(0, 20, 30),
+			want:    ist(1, 9, 0),
+			reasons: []string{"quiet hours 20:00-09:00 IST"},
+		},
+		{
+			name:   "district quiet hours win",
+			farmer: &models.Farmer{State: "Maharashtra", District: "Nashik"},
+			at:     ist(0, 21, 30),
+		},
+		{
+			name:   "evaluated in IST whatever the zone of at",
+			farmer: maharashtra,
+			at:     ist(0, 7, 59).UTC(),
+			want:   ist(0, 8, 0),
+			reasons: []string{
+				"quiet hours 21:00-08:00 IST",
+			},
+		},
+		{
+			name:   "gap after a completed call",
+			farmer: maharashtra,
+			history: []models.Shoot{
+				shoot(models.ChannelCall, "completed", ist(-1, 10, 0)),
+			},
+			at:      ist(0, 10, 0),
+			want:    ist(1, 10, 0),
+			reasons: []string{"48h0m0s gap after a completed call"},
+		},
+		{
+			name:   "missed call leaves no gap",
+			farmer: maharashtra,
+			history: []models.Shoot{
+				shoot(models.ChannelCall, "missed", ist(-1, 10, 0)),
+			},
+			at: ist(0, 10, 0),
+		},
+		{
+			name:   "daily cap across channels",
+			farmer: maharashtra,
+			history: []models.Shoot{
+				shoot(models.ChannelWhatsApp, "completed", ist(0, 9, 0)),
+				shoot(models.ChannelCall, "missed", ist(0, 10, 0)),
+			},
+			at: ist(0, 15, 0),
+			// Midnight is quiet, so the deferral rolls on to 08:00.
+			want:    ist(1, 8, 0),
+			reasons: []string{"2 contacts per day", "quiet hours 21:00-08:00 IST"},
+		},
+		{
+			name:   "daily cap counts the IST day",
+			farmer: maharashtra,
+			history: []models.Shoot{
+				shoot(models.ChannelWhatsApp, "completed", ist(-1, 19, 0)),
+				shoot(models.ChannelWhatsApp, "completed", ist(0, 8, 30)),
+			},
+			at: ist(0, 15, 0),
+		},
+		{
+			name:   "weekly cap waits for the oldest to age out",
+			farmer: maharashtra,
+			history: []models.Shoot{
+				shoot(models.ChannelWhatsApp, "completed", ist(-6, 12, 0)),
+				shoot(models.ChannelWhatsApp, "completed", ist(-5, 12, 0)),
+				shoot(models.ChannelWhatsApp, "completed", ist(-4, 12, 0)),
+				shoot(models.ChannelWhatsApp, "completed", ist(-3, 12, 0)),
+				shoot(models.ChannelWhatsApp, "completed", ist(-2, 12, 0)),
+			},
+			at:      ist(0, 10, 0),
+			want:    ist(1, 12, 0),
+			reasons: []string{"5 contacts per week"},
+		},
+		{
+			name:   "shoots after at are ignored",
+			farmer: maharashtra,
+			history: []models.Shoot{
+				shoot(models.ChannelCall, "completed", ist(0, 12, 0)),
+				shoot(models.ChannelWhatsApp, "completed", ist(0, 13, 0)),
+			},
+			at: ist(0, 11, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Evaluate(tt.farmer, tt.history, tt.at)
+			if tt.want.IsZero() {
+				if got.Outcome != Allowed {
+					t.Fatalf("Evaluate = %+v, want allowed", got)
+				}
+				return
+			}
+			if got.Outcome != Deferred || got.Until == nil {
+				t.Fatalf("Evaluate = %+v, want deferred until %v", got, tt.want)
+			}
+			if !got.Until.Equal(tt.want) {
+				t.Fatalf("deferred until %v, want %v", got.Until, tt.want)
+			}
+			if got.Until.Location() != IST {
+				t.Fatalf("until is in %v, want IST", got.Until.Location())
+			}
+			if !slices.Equal(got.Reasons, tt.reasons) {
+				t.Fatalf("reasons = %q, want %q", got.Reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Window
+		wantErr bool
+	}{
+		{in: "", want: Window{}},
+		{in: "21:00-08:00", want: Window{Start: 21 * time.Hour, End: 8 * time.Hour}},
+		{in: " 13:30 - 14:15 ", want: Window{Start: 13*time.Hour + 30*time.Minute, End: 14*time.Hour + 15*time.Minute}},
+		{in: "21:00", wantErr: true},
+		{in: "25:00-08:00", wantErr: true},
+		{in: "21:00-8pm", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseWindow(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindow(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseWindow(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
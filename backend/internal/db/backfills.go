@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/phone"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// normaliseFarmerContact rewrites Contact in E.164 form. Numbers that cannot
+// be normalised are logged and left as they are for someone to fix by hand.
+func normaliseFarmerContact(item map[string]types.AttributeValue) (*ItemUpdate, error) {
+	contact, ok := item["Contact"].(*types.AttributeValueMemberS)
+	if !ok || contact.Value == "" {
+		return nil, nil
+	}
+
+	normalised, err := phone.Normalize(contact.Value)
+	if err != nil {
+		log.Printf("Farmer %v has an invalid contact number %q, leaving it unchanged", item["ID"], contact.Value)
+		return nil, nil
+	}
+	if normalised == contact.Value {
+		return nil, nil
+	}
+
+	return &ItemUpdate{
+		Set: map[string]types.AttributeValue{
+			"Contact": &types.AttributeValueMemberS{Value: normalised},
+		},
+	}, nil
+}
+
+// backfillFarmerCreatedAt sets CreatedAt on farmers that have none, or only
+// the zero time written by an update made before the backfill ran. The real
+// creation time is unknown, so the start time of the migrating process is used.
+func backfillFarmerCreatedAt(now time.Time) func(map[string]types.AttributeValue) (*ItemUpdate, error) {
+	return func(item map[string]types.AttributeValue) (*ItemUpdate, error) {
+		var createdAt time.Time
+		if value, ok := item["CreatedAt"].(*types.AttributeValueMemberS); ok {
+			createdAt, _ = time.Parse(time.RFC3339Nano, value.Value)
+		}
+		if !createdAt.IsZero() {
+			return nil, nil
+		}
+
+		return &ItemUpdate{
+			Set: map[string]types.AttributeValue{
+				"CreatedAt": &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+			},
+		}, nil
+	}
+}
+
+// legacyConsentSource is the Source of the consent grantLegacyConsent grants.
+const legacyConsentSource = "legacy"
+
+// grantLegacyConsent grants consent on every channel to the farmers created
+// before now who have none on record. They were reached before consent was
+// recorded at all and would otherwise all be blocked from outreach the moment
+// RequireConsent is enforced. Erased farmers and the aliases merges leave
+// behind are skipped, and so is a farmer whose consent is recorded between
+// the scan and the write. The grant is marked with Source "legacy" and has no
+// consent event.
+func grantLegacyConsent(now time.Time) func(map[string]types.AttributeValue) (*ItemUpdate, error) {
+	return func(item map[string]types.AttributeValue) (*ItemUpdate, error) {
+		if _, ok := item["Consent"]; ok {
+			return nil, nil
+		}
+		if _, ok := item["ErasedAt"]; ok {
+			return nil, nil
+		}
+		if _, ok := item["MergedInto"]; ok {
+			return nil, nil
+		}
+		if value, ok := item["CreatedAt"].(*types.AttributeValueMemberS); ok {
+			createdAt, err := time.Parse(time.RFC3339Nano, value.Value)
+			if err == nil && !createdAt.Before(now) {
+				return nil, nil
+			}
+		}
+
+		consent := make(map[string]types.AttributeValue, len(models.Channels))
+		for _, channel := range models.Channels {
+			consent[channel] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"Status":    &types.AttributeValueMemberS{Value: models.ConsentGranted},
+				"Source":    &types.AttributeValueMemberS{Value: legacyConsentSource},
+				"UpdatedAt": &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+				"UpdatedBy": &types.AttributeValueMemberS{Value: "migration"},
+			}}
+		}
+		return &ItemUpdate{
+			Set:    map[string]types.AttributeValue{"Consent": &types.AttributeValueMemberM{Value: consent}},
+			Absent: []string{"Consent"},
+		}, nil
+	}
+}
+
+// renameTicketCceID fixes tickets written by the old UpdateTicket handler,
+// which stored the CCE under CceID instead of CCEID and so dropped out of the
+// CCE indexes. A CCEID that is already present wins.
+func renameTicketCceID(item map[string]types.AttributeValue) (*ItemUpdate, error) {
+	cceID, ok := item["CceID"]
+	if !ok {
+		return nil, nil
+	}
+
+	update := &ItemUpdate{Remove: []string{"CceID"}}
+	if _, ok := item["CCEID"]; !ok {
+		update.Set = map[string]types.AttributeValue{"CCEID": cceID}
+	}
+	return update, nil
+}
+
+// claimFarmerContacts writes a FarmerContacts item for every farmer contact.
+// When several farmers share a contact the first one scanned keeps it and the
+// others are logged, to be merged by hand. Contacts that are already claimed
+// are left alone, so the migration can be run again after a failure.
+func claimFarmerContacts(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+	contactsTable := tables.Name(FarmerContactsTable)
+
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:            aws.String(tables.Name(FarmersTable)),
+		ProjectionExpression: aws.String("ID, Contact"),
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to scan farmers: %w", err)
+		}
+
+		for _, item := range result.Items {
+			contact, ok := item["Contact"].(*types.AttributeValueMemberS)
+			if !ok || contact.Value == "" {
+				continue
+			}
+
+			_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName: aws.String(contactsTable),
+				Item: map[string]types.AttributeValue{
+					"ID":       contact,
+					"FarmerID": item["ID"],
+				},
+				ConditionExpression: aws.String("attribute_not_exists(ID) OR FarmerID = :farmerId"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":farmerId": item["ID"],
+				},
+			})
+			var conditionErr *types.ConditionalCheckFailedException
+			if errors.As(err, &conditionErr) {
+				log.Printf("Farmer %v shares contact %q with another farmer, not claiming it", item["ID"], contact.Value)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to claim contact of farmer %v: %w", item["ID"], err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func s(value string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: value}
+}
+
+func TestBackfillTransforms(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)
+	backfillCreatedAt := backfillFarmerCreatedAt(now)
+	grantConsent := grantLegacyConsent(now)
+	granted := &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"Status":    s("granted"),
+		"Source":    s("legacy"),
+		"UpdatedAt": s(now.Format(time.RFC3339Nano)),
+		"UpdatedBy": s("migration"),
+	}}
+
+	tests := []struct {
+		name      string
+		transform func(map[string]types.AttributeValue) (*ItemUpdate, error)
+		item      map[string]types.AttributeValue
+		want      *ItemUpdate
+	}{
+		{
+			name:      "contact normalised",
+			transform: normaliseFarmerContact,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "Contact": s("98765 43210")},
+			want:      &ItemUpdate{Set: map[string]types.AttributeValue{"Contact": s("+919876543210")}},
+		},
+		{
+			name:      "contact already normalised",
+			transform: normaliseFarmerContact,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "Contact": s("+919876543210")},
+		},
+		{
+			name:      "invalid contact left alone",
+			transform: normaliseFarmerContact,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "Contact": s("12345")},
+		},
+		{
+			name:      "no contact",
+			transform: normaliseFarmerContact,
+			item:      map[string]types.AttributeValue{"ID": s("f1")},
+		},
+		{
+			name:      "created at missing",
+			transform: backfillCreatedAt,
+			item:      map[string]types.AttributeValue{"ID": s("f1")},
+			want:      &ItemUpdate{Set: map[string]types.AttributeValue{"CreatedAt": s(now.Format(time.RFC3339Nano))}},
+		},
+		{
+			name:      "created at zero",
+			transform: backfillCreatedAt,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "CreatedAt": s(time.Time{}.Format(time.RFC3339Nano))},
+			want:      &ItemUpdate{Set: map[string]types.AttributeValue{"CreatedAt": s(now.Format(time.RFC3339Nano))}},
+		},
+		{
+			name:      "created at kept",
+			transform: backfillCreatedAt,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "CreatedAt": s("2025-11-02T08:00:00Z")},
+		},
+		{
+			name:      "legacy farmer granted consent",
+			transform: grantConsent,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "CreatedAt": s("2025-11-02T08:00:00Z")},
+			want: &ItemUpdate{
+				Set: map[string]types.AttributeValue{"Consent": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+					"call":     granted,
+					"whatsapp": granted,
+				}}},
+				Absent: []string{"Consent"},
+			},
+		},
+		{
+			name:      "recorded consent kept",
+			transform: grantConsent,
+			item: map[string]types.AttributeValue{"ID": s("f1"), "Consent": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"call": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"Status": s("revoked")}},
+			}}},
+		},
+		{
+			name:      "farmer created after the migration started",
+			transform: grantConsent,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "CreatedAt": s(now.Format(time.RFC3339Nano))},
+		},
+		{
+			name:      "erased farmer",
+			transform: grantConsent,
+			item:      map[string]types.AttributeValue{"ID": s("f1"), "ErasedAt": s("2026-01-05T10:00:00Z")},
+		},
+		{
+			name:      "merged alias",
+			transform: grantConsent,
+			item:      map[string]types.AttributeValue{"ID": s("f2"), "MergedInto": s("f1")},
+		},
+		{
+			name:      "cce id renamed",
+			transform: renameTicketCceID,
+			item:      map[string]types.AttributeValue{"ID": s("t1"), "CceID": s("c1")},
+			want:      &ItemUpdate{Set: map[string]types.AttributeValue{"CCEID": s("c1")}, Remove: []string{"CceID"}},
+		},
+		{
+			name:      "existing cce id wins",
+			transform: renameTicketCceID,
+			item:      map[string]types.AttributeValue{"ID": s("t1"), "CceID": s("c1"), "CCEID": s("c2")},
+			want:      &ItemUpdate{Remove: []string{"CceID"}},
+		},
+		{
+			name:      "cce id already correct",
+			transform: renameTicketCceID,
+			item:      map[string]types.AttributeValue{"ID": s("t1"), "CCEID": s("c2")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.transform(tt.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("transform = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
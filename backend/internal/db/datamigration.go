@@ -0,0 +1,247 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// defaultDataBatchSize is how many items a data migration scans per batch when
+// it does not set BatchSize.
+const defaultDataBatchSize = 100
+
+// DataMigration rewrites the items of one table instead of changing its
+// structure. The table is scanned in batches and the position is saved to the
+// Migrations table after every batch, so an interrupted run picks up where it
+// stopped.
+type DataMigration struct {
+	// Table is the base table name, it is resolved through Tables when the
+	// migration runs.
+	Table     string
+	BatchSize int32
+
+	// Transform inspects one item and returns the changes to make to it, or
+	// nil when the item is already fine. It must be idempotent: after a crash
+	// the last batch is processed again.
+	Transform func(item map[string]types.AttributeValue) (*ItemUpdate, error)
+}
+
+// ItemUpdate describes the attributes to set and remove on one item. It is
+// applied with UpdateItem so attributes written concurrently by the
+// application are left alone.
+type ItemUpdate struct {
+	Set    map[string]types.AttributeValue
+	Remove []string
+	// Absent lists attributes that must still be missing when the update is
+	// applied. The item is skipped if the application wrote one of them
+	// since it was scanned.
+	Absent []string
+}
+
+// DataProgress is the checkpoint of a data migration.
+type DataProgress struct {
+	Key       string `dynamodbav:"Key"`
+	Processed int    `dynamodbav:"Processed"`
+	Updated   int    `dynamodbav:"Updated"`
+	Completed bool   `dynamodbav:"Completed"`
+
+	// LastEvaluatedKey is stored as a raw map attribute, the attributevalue
+	// codec cannot round-trip AttributeValue members.
+	LastEvaluatedKey map[string]types.AttributeValue `dynamodbav:"-"`
+}
+
+func dataProgressKey(version int) string {
+	return "Data#" + strconv.Itoa(version)
+}
+
+// runDataMigration scans the migration's table from its last checkpoint and
+// applies Transform to every item. renew is called after every batch to keep
+// the migration lock alive.
+func runDataMigration(ctx context.Context, client *dynamodb.Client, tables Tables, version int, dm *DataMigration, renew func(context.Context) error) error {
+	migrationsTable := tables.Name(MigrationsTable)
+	table := tables.Name(dm.Table)
+
+	progress, err := getDataProgress(ctx, client, migrationsTable, version)
+	if err != nil {
+		return err
+	}
+	if progress.Completed {
+		log.Printf("Data migration %d already completed: %d items processed, %d updated", version, progress.Processed, progress.Updated)
+		return nil
+	}
+	if progress.LastEvaluatedKey != nil {
+		log.Printf("Resuming data migration %d after %d items", version, progress.Processed)
+	}
+
+	batchSize := dm.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultDataBatchSize
+	}
+
+	for {
+		result, err := client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(table),
+			Limit:             aws.Int32(batchSize),
+			ExclusiveStartKey: progress.LastEvaluatedKey,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+
+		for _, item := range result.Items {
+			update, err := dm.Transform(item)
+			if err != nil {
+				return fmt.Errorf("failed to transform item %v: %w", item["ID"], err)
+			}
+			if update != nil {
+				if err := applyItemUpdate(ctx, client, table, item, update); err != nil {
+					return err
+				}
+				progress.Updated++
+			}
+			progress.Processed++
+		}
+
+		progress.LastEvaluatedKey = result.LastEvaluatedKey
+		progress.Completed = result.LastEvaluatedKey == nil
+		if err := putDataProgress(ctx, client, migrationsTable, progress); err != nil {
+			return err
+		}
+		log.Printf("Data migration %d: %d items processed, %d updated", version, progress.Processed, progress.Updated)
+
+		if progress.Completed {
+			return nil
+		}
+		if err := renew(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// applyItemUpdate writes update to the item. Items deleted since they were
+// scanned, or given one of update.Absent, are skipped rather than recreated
+// or overwritten.
+func applyItemUpdate(ctx context.Context, client *dynamodb.Client, table string, item map[string]types.AttributeValue, update *ItemUpdate) error {
+	names := map[string]string{"#key": "ID"}
+	values := make(map[string]types.AttributeValue)
+
+	var expression string
+	i := 0
+	for attribute, value := range update.Set {
+		if expression == "" {
+			expression = "SET "
+		} else {
+			expression += ", "
+		}
+		name, placeholder := fmt.Sprintf("#a%d", i), fmt.Sprintf(":v%d", i)
+		names[name] = attribute
+		values[placeholder] = value
+		expression += name + " = " + placeholder
+		i++
+	}
+	for j, attribute := range update.Remove {
+		if j == 0 {
+			expression += " REMOVE "
+		} else {
+			expression += ", "
+		}
+		name := fmt.Sprintf("#a%d", i)
+		names[name] = attribute
+		expression += name
+		i++
+	}
+	condition := "attribute_exists(#key)"
+	for _, attribute := range update.Absent {
+		name := fmt.Sprintf("#a%d", i)
+		names[name] = attribute
+		condition += " AND attribute_not_exists(" + name + ")"
+		i++
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key: map[string]types.AttributeValue{
+			"ID": item["ID"],
+		},
+		UpdateExpression:         aws.String(expression),
+		ConditionExpression:      aws.String(condition),
+		ExpressionAttributeNames: names,
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+
+	_, err := client.UpdateItem(ctx, input)
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to update item %v in %s: %w", item["ID"], table, err)
+	}
+	return nil
+}
+
+func getDataProgress(ctx context.Context, client *dynamodb.Client, migrationsTable string, version int) (*DataProgress, error) {
+	key := dataProgressKey(version)
+	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(migrationsTable),
+		Key: map[string]types.AttributeValue{
+			"Key": &types.AttributeValueMemberS{Value: key},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data migration checkpoint: %w", err)
+	}
+
+	progress := &DataProgress{Key: key}
+	if result.Item == nil {
+		return progress, nil
+	}
+	if err := attributevalue.UnmarshalMap(result.Item, progress); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data migration checkpoint: %w", err)
+	}
+	if lastKey, ok := result.Item["LastEvaluatedKey"].(*types.AttributeValueMemberM); ok {
+		progress.LastEvaluatedKey = lastKey.Value
+	}
+	return progress, nil
+}
+
+func putDataProgress(ctx context.Context, client *dynamodb.Client, migrationsTable string, progress *DataProgress) error {
+	item, err := attributevalue.MarshalMap(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data migration checkpoint: %w", err)
+	}
+	if progress.LastEvaluatedKey != nil {
+		item["LastEvaluatedKey"] = &types.AttributeValueMemberM{Value: progress.LastEvaluatedKey}
+	}
+
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(migrationsTable),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save data migration checkpoint: %w", err)
+	}
+	return nil
+}
+
+func deleteDataProgress(ctx context.Context, client *dynamodb.Client, migrationsTable string, version int) error {
+	_, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(migrationsTable),
+		Key: map[string]types.AttributeValue{
+			"Key": &types.AttributeValueMemberS{Value: dataProgressKey(version)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete data migration checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fakeDynamo answers the GetItem, PutItem, DeleteItem, Scan and UpdateItem
+// calls made by data migrations. Items are kept as raw JSON keyed by their
+// Key or ID attribute and scanned in key order; updates are only recorded.
+type fakeDynamo struct {
+	mu      sync.Mutex
+	tables  map[string]map[string]map[string]json.RawMessage
+	updates []string
+}
+
+func newFakeDynamo(t *testing.T) (*fakeDynamo, *dynamodb.Client) {
+	t.Helper()
+	fake := &fakeDynamo{tables: make(map[string]map[string]map[string]json.RawMessage)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := dynamodb.New(dynamodb.Options{
+		Region:           "local",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		HTTPClient:       server.Client(),
+		RetryMaxAttempts: 1,
+	})
+	return fake, client
+}
+
+func itemKey(item map[string]json.RawMessage) string {
+	if key, ok := item["Key"]; ok {
+		return string(key)
+	}
+	return string(item["ID"])
+}
+
+func (f *fakeDynamo) put(table string, item map[string]json.RawMessage) {
+	if f.tables[table] == nil {
+		f.tables[table] = make(map[string]map[string]json.RawMessage)
+	}
+	f.tables[table][itemKey(item)] = item
+}
+
+func (f *fakeDynamo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var in struct {
+		TableName         string
+		Key               map[string]json.RawMessage
+		Item              map[string]json.RawMessage
+		Limit             int
+		ExclusiveStartKey map[string]json.RawMessage
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	out := map[string]interface{}{}
+	switch operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810."); operation {
+	case "GetItem":
+		if item, ok := f.tables[in.TableName][itemKey(in.Key)]; ok {
+			out["Item"] = item
+		}
+	case "PutItem":
+		f.put(in.TableName, in.Item)
+	case "DeleteItem":
+		delete(f.tables[in.TableName], itemKey(in.Key))
+	case "UpdateItem":
+		f.updates = append(f.updates, string(in.Key["ID"]))
+	case "Scan":
+		keys := make([]string, 0, len(f.tables[in.TableName]))
+		for key := range f.tables[in.TableName] {
+			if in.ExclusiveStartKey == nil || key > itemKey(in.ExclusiveStartKey) {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		items := []map[string]json.RawMessage{}
+		for _, key := range keys {
+			if in.Limit > 0 && len(items) == in.Limit {
+				break
+			}
+			items = append(items, f.tables[in.TableName][key])
+		}
+		out["Items"], out["Count"] = items, len(items)
+		// Like DynamoDB, a full page returns a key even when nothing is left.
+		if in.Limit > 0 && len(items) == in.Limit {
+			out["LastEvaluatedKey"] = map[string]json.RawMessage{"ID": items[len(items)-1]["ID"]}
+		}
+	default:
+		http.Error(w, "unsupported operation "+operation, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(out)
+}
+
+func TestRunDataMigrationCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	fake, client := newFakeDynamo(t)
+	tables := NewTables("test-")
+	migrationsTable := tables.Name(MigrationsTable)
+	for _, id := range []string{"f1", "f2", "f3", "f4", "f5"} {
+		fake.put(tables.Name(FarmersTable), map[string]json.RawMessage{"ID": json.RawMessage(`{"S":"` + id + `"}`)})
+	}
+
+	var transformed []string
+	dm := &DataMigration{
+		Table:     FarmersTable,
+		BatchSize: 2,
+		Transform: func(item map[string]types.AttributeValue) (*ItemUpdate, error) {
+			id := item["ID"].(*types.AttributeValueMemberS).Value
+			transformed = append(transformed, id)
+			if id == "f2" || id == "f4" {
+				return &ItemUpdate{Remove: []string{"Stale"}}, nil
+			}
+			return nil, nil
+		},
+	}
+	errInterrupted := errors.New("lock lost")
+
+	steps := []struct {
+		name            string
+		renew           func(context.Context) error
+		wantErr         error
+		wantTransformed []string
+		want            DataProgress
+		wantLastKey     string
+	}{
+		{
+			name:            "interrupted after the first batch",
+			renew:           func(context.Context) error { return errInterrupted },
+			wantErr:         errInterrupted,
+			wantTransformed: []string{"f1", "f2"},
+			want:            DataProgress{Processed: 2, Updated: 1},
+			wantLastKey:     "f2",
+		},
+		{
+			name:            "resumed from the checkpoint",
+			renew:           func(context.Context) error { return nil },
+			wantTransformed: []string{"f3", "f4", "f5"},
+			want:            DataProgress{Processed: 5, Updated: 2, Completed: true},
+		},
+		{
+			name:  "completed runs are skipped",
+			renew: func(context.Context) error { return nil },
+			want:  DataProgress{Processed: 5, Updated: 2, Completed: true},
+		},
+	}
+	for _, step := range steps {
+		transformed = nil
+		err := runDataMigration(ctx, client, tables, 4, dm, step.renew)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if !slices.Equal(transformed, step.wantTransformed) {
+			t.Fatalf("%s: transformed %v, want %v", step.name, transformed, step.wantTransformed)
+		}
+
+		progress, err := getDataProgress(ctx, client, migrationsTable, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lastKey string
+		if id, ok := progress.LastEvaluatedKey["ID"].(*types.AttributeValueMemberS); ok {
+			lastKey = id.Value
+		}
+		if lastKey != step.wantLastKey {
+			t.Fatalf("%s: checkpoint key = %q, want %q", step.name, lastKey, step.wantLastKey)
+		}
+		step.want.Key = dataProgressKey(4)
+		progress.LastEvaluatedKey = nil
+		if !reflect.DeepEqual(*progress, step.want) {
+			t.Fatalf("%s: checkpoint = %+v, want %+v", step.name, *progress, step.want)
+		}
+	}
+	if want := []string{`{"S":"f2"}`, `{"S":"f4"}`}; !slices.Equal(fake.updates, want) {
+		t.Fatalf("updated %v, want %v", fake.updates, want)
+	}
+
+	migrator := &Migrator{client: client, tables: tables, table: migrationsTable}
+	if err := migrator.rollback(ctx, Migration{Version: 4, Data: dm}); err != nil {
+		t.Fatal(err)
+	}
+	progress, err := getDataProgress(ctx, client, migrationsTable, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Processed != 0 || progress.Completed || progress.LastEvaluatedKey != nil {
+		t.Fatalf("checkpoint survived the rollback: %+v", progress)
+	}
+}
+
+func TestRollbackKeepsOtherCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	_, client := newFakeDynamo(t)
+	tables := NewTables("test-")
+	migrator := &Migrator{client: client, tables: tables, table: tables.Name(MigrationsTable)}
+
+	for _, version := range []int{4, 6} {
+		if err := putDataProgress(ctx, client, migrator.table, &DataProgress{Key: dataProgressKey(version), Processed: 3, Completed: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var downs []int
+	down := func(version int) func(context.Context, *dynamodb.Client, Tables) error {
+		return func(context.Context, *dynamodb.Client, Tables) error {
+			downs = append(downs, version)
+			return nil
+		}
+	}
+	tests := []struct {
+		name      string
+		migration Migration
+		wantDowns []int
+		// wantKept are the versions whose checkpoints must still exist.
+		wantKept []int
+	}{
+		{name: "schema migration", migration: Migration{Version: 5, Down: down(5)}, wantDowns: []int{5}, wantKept: []int{4, 6}},
+		{name: "data migration with a down step", migration: Migration{Version: 6, Down: down(6), Data: &DataMigration{}}, wantDowns: []int{6}, wantKept: []int{4}},
+		{name: "data migration only", migration: Migration{Version: 4, Data: &DataMigration{}}, wantKept: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downs = nil
+			if err := migrator.rollback(ctx, tt.migration); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(downs, tt.wantDowns) {
+				t.Fatalf("ran down steps %v, want %v", downs, tt.wantDowns)
+			}
+			var kept []int
+			for _, version := range []int{4, 6} {
+				progress, err := getDataProgress(ctx, client, migrator.table, version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if progress.Completed {
+					kept = append(kept, version)
+				}
+			}
+			if !slices.Equal(kept, tt.wantKept) {
+				t.Fatalf("checkpoints kept for %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
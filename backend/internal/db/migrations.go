@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -12,78 +13,330 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// Migration is one versioned change. Schema migrations change table structure
+// through Up and Down; data migrations set Data instead and rewrite items.
 type Migration struct {
 	Version     int
 	Description string
-	Up          func(context.Context, *dynamodb.Client) error
-	Down        func(context.Context, *dynamodb.Client) error
+	Up          func(context.Context, *dynamodb.Client, Tables) error
+	Down        func(context.Context, *dynamodb.Client, Tables) error
+	Data        *DataMigration
+}
+
+// Kind is "data" for data migrations and "schema" for everything else.
+func (m Migration) Kind() string {
+	if m.Data != nil {
+		return "data"
+	}
+	return "schema"
 }
 
 var migrations = []Migration{
 	{
 		Version:     1,
 		Description: "Create initial tables",
-		Up: func(ctx context.Context, client *dynamodb.Client) error {
-			if err := createTable(ctx, client, "Farmers"); err != nil {
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(FarmersTable)); err != nil {
 				return err
 			}
-			if err := createTable(ctx, client, "CCEs"); err != nil {
+			if err := createTable(ctx, client, tables.Name(CCEsTable)); err != nil {
 				return err
 			}
-			if err := createTable(ctx, client, "Tickets"); err != nil {
+			if err := createTable(ctx, client, tables.Name(TicketsTable)); err != nil {
 				return err
 			}
 			return nil
 		},
-		Down: func(ctx context.Context, client *dynamodb.Client) error {
-			if err := deleteTable(ctx, client, "Farmers"); err != nil {
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := deleteTable(ctx, client, tables.Name(FarmersTable)); err != nil {
 				return err
 			}
-			if err := deleteTable(ctx, client, "CCEs"); err != nil {
+			if err := deleteTable(ctx, client, tables.Name(CCEsTable)); err != nil {
 				return err
 			}
-			if err := deleteTable(ctx, client, "Tickets"); err != nil {
+			if err := deleteTable(ctx, client, tables.Name(TicketsTable)); err != nil {
 				return err
 			}
 			return nil
 		},
 	},
+	{
+		Version:     2,
+		Description: "Create Shoots and Reports tables",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(ShootsTable)); err != nil {
+				return err
+			}
+			if err := createTable(ctx, client, tables.Name(ReportsTable)); err != nil {
+				return err
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := deleteTable(ctx, client, tables.Name(ShootsTable)); err != nil {
+				return err
+			}
+			if err := deleteTable(ctx, client, tables.Name(ReportsTable)); err != nil {
+				return err
+			}
+			return nil
+		},
+	},
+	{
+		Version:     3,
+		Description: "Add ContactIndex to Farmers",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return createIndex(ctx, client, tables.Name(FarmersTable), index{Name: "ContactIndex", HashKey: "Contact"})
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteIndex(ctx, client, tables.Name(FarmersTable), "ContactIndex")
+		},
+	},
+	{
+		Version:     4,
+		Description: "Add farmer and CCE indexes to Tickets",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			for _, idx := range ticketIndexes {
+				if err := createIndex(ctx, client, tables.Name(TicketsTable), idx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			for i := len(ticketIndexes) - 1; i >= 0; i-- {
+				if err := deleteIndex(ctx, client, tables.Name(TicketsTable), ticketIndexes[i].Name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     5,
+		Description: "Normalise farmer contact numbers to E.164",
+		Data: &DataMigration{
+			Table:     FarmersTable,
+			Transform: normaliseFarmerContact,
+		},
+	},
+	{
+		Version:     6,
+		Description: "Add CreatedAt to farmers created before it was recorded",
+		Data: &DataMigration{
+			Table:     FarmersTable,
+			Transform: backfillFarmerCreatedAt(time.Now().UTC()),
+		},
+	},
+	{
+		Version:     7,
+		Description: "Move ticket CceID attributes to CCEID",
+		Data: &DataMigration{
+			Table:     TicketsTable,
+			Transform: renameTicketCceID,
+		},
+	},
+	{
+		Version:     8,
+		Description: "Create Audit table",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(AuditTable)); err != nil {
+				return err
+			}
+			return createIndex(ctx, client, tables.Name(AuditTable), auditEntityIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteTable(ctx, client, tables.Name(AuditTable))
+		},
+	},
+	{
+		Version:     9,
+		Description: "Create FarmerContacts table and claim existing contacts",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(FarmerContactsTable)); err != nil {
+				return err
+			}
+			return claimFarmerContacts(ctx, client, tables)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteTable(ctx, client, tables.Name(FarmerContactsTable))
+		},
+	},
+	{
+		Version:     10,
+		Description: "Add FarmerIDTimestampIndex to Shoots",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return createIndex(ctx, client, tables.Name(ShootsTable), shootFarmerIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteIndex(ctx, client, tables.Name(ShootsTable), shootFarmerIndex.Name)
+		},
+	},
+	{
+		Version:     11,
+		Description: "Create DuplicateCandidates table",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(DuplicateCandidatesTable)); err != nil {
+				return err
+			}
+			return createIndex(ctx, client, tables.Name(DuplicateCandidatesTable), duplicateStatusIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteTable(ctx, client, tables.Name(DuplicateCandidatesTable))
+		},
+	},
+	{
+		Version:     12,
+		Description: "Create ConsentEvents table",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(ConsentEventsTable)); err != nil {
+				return err
+			}
+			return createIndex(ctx, client, tables.Name(ConsentEventsTable), consentFarmerIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteTable(ctx, client, tables.Name(ConsentEventsTable))
+		},
+	},
+	{
+		Version:     13,
+		Description: "Add GeoCellIndex to Farmers",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return createIndex(ctx, client, tables.Name(FarmersTable), farmerGeoIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteIndex(ctx, client, tables.Name(FarmersTable), farmerGeoIndex.Name)
+		},
+	},
+	{
+		Version:     14,
+		Description: "Create Partners table",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(PartnersTable)); err != nil {
+				return err
+			}
+			return createIndex(ctx, client, tables.Name(PartnersTable), partnerKindIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteTable(ctx, client, tables.Name(PartnersTable))
+		},
+	},
+	{
+		Version:     15,
+		Description: "Create Crops and Varieties tables",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := createTable(ctx, client, tables.Name(CropsTable)); err != nil {
+				return err
+			}
+			if err := createTable(ctx, client, tables.Name(VarietiesTable)); err != nil {
+				return err
+			}
+			return createIndex(ctx, client, tables.Name(VarietiesTable), varietyCropIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			if err := deleteTable(ctx, client, tables.Name(VarietiesTable)); err != nil {
+				return err
+			}
+			return deleteTable(ctx, client, tables.Name(CropsTable))
+		},
+	},
+	{
+		Version:     16,
+		Description: "Create OutreachMarks table",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return createTable(ctx, client, tables.Name(OutreachMarksTable))
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteTable(ctx, client, tables.Name(OutreachMarksTable))
+		},
+	},
+	{
+		Version:     17,
+		Description: "Grant consent to farmers created before consent was recorded",
+		Data: &DataMigration{
+			Table:     FarmersTable,
+			Transform: grantLegacyConsent(time.Now().UTC()),
+		},
+	},
+	{
+		Version:     18,
+		Description: "Add CropIDIndex to Tickets",
+		Up: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return createIndex(ctx, client, tables.Name(TicketsTable), ticketCropIndex)
+		},
+		Down: func(ctx context.Context, client *dynamodb.Client, tables Tables) error {
+			return deleteIndex(ctx, client, tables.Name(TicketsTable), ticketCropIndex.Name)
+		},
+	},
 	// Add more migrations here as your schema evolves
 }
 
-func RunMigrations(client *dynamodb.Client) error {
-	ctx := context.Background()
+// ticketIndexes are the global secondary indexes TicketService queries.
+var ticketIndexes = []index{
+	{Name: "FarmerIDIndex", HashKey: "FarmerID"},
+	{Name: "CCEIDIndex", HashKey: "CCEID"},
+	{Name: "CCEIDCreatedAtIndex", HashKey: "CCEID", RangeKey: "CreatedAt"},
+	{Name: "CCEIDStatusIndex", HashKey: "CCEID", RangeKey: "Status"},
+}
 
-	// Create migrations table if it doesn't exist
-	if err := createMigrationsTable(ctx, client); err != nil {
-		return err
-	}
+// auditEntityIndex returns the history of one entity. Audit record IDs start
+// with their timestamp, so the range key keeps the history in order.
+var auditEntityIndex = index{Name: "EntityKeyIndex", HashKey: "EntityKey", RangeKey: "ID"}
 
-	// Get current migration version
-	currentVersion, err := getCurrentMigrationVersion(ctx, client)
-	if err != nil {
-		return err
-	}
+// shootFarmerIndex returns the shoots of one farmer in the order they were
+// made.
+var shootFarmerIndex = index{Name: "FarmerIDTimestampIndex", HashKey: "FarmerID", RangeKey: "Timestamp"}
 
-	// Run pending migrations
-	for _, migration := range migrations {
-		if migration.Version > currentVersion {
-			log.Printf("Running migration %d: %s", migration.Version, migration.Description)
-			if err := migration.Up(ctx, client); err != nil {
-				return fmt.Errorf("failed to run migration %d: %w", migration.Version, err)
-			}
-			if err := updateMigrationVersion(ctx, client, migration.Version); err != nil {
-				return fmt.Errorf("failed to update migration version: %w", err)
-			}
-		}
-	}
+// duplicateStatusIndex serves the review queue: the candidates in one status,
+// oldest first.
+var duplicateStatusIndex = index{Name: "StatusCreatedAtIndex", HashKey: "Status", RangeKey: "CreatedAt"}
 
-	return nil
+// consentFarmerIndex returns the consent history of one farmer. Event IDs
+// start with their timestamp, like audit record IDs.
+var consentFarmerIndex = index{Name: "FarmerIDIndex", HashKey: "FarmerID", RangeKey: "ID"}
+
+// farmerGeoIndex finds the farmers in a geohash cell, see
+// FarmerStore.ListByGeohash. Only farmers with coordinates are in it.
+var farmerGeoIndex = index{Name: "GeoCellIndex", HashKey: "GeoCell", RangeKey: "GeoHash"}
+
+// partnerKindIndex lists the dealers or the agronomists by name.
+var partnerKindIndex = index{Name: "KindNameIndex", HashKey: "Kind", RangeKey: "Name"}
+
+// varietyCropIndex lists the varieties of a crop by name.
+var varietyCropIndex = index{Name: "CropIDNameIndex", HashKey: "CropID", RangeKey: "Name"}
+
+// ticketCropIndex finds the tickets about a crop, which keep it from being
+// deleted from the catalog.
+var ticketCropIndex = index{Name: "CropIDIndex", HashKey: "CropID"}
+
+// schemaWaitTimeout bounds how long a migration waits for a table or index to
+// become ACTIVE. Index creation includes the backfill of existing items, which
+// can take a while on large tables.
+const schemaWaitTimeout = 30 * time.Minute
+
+// schemaPollInterval is how often DescribeTable is polled while waiting.
+const schemaPollInterval = 10 * time.Second
+
+// index describes a global secondary index whose key attributes are strings.
+type index struct {
+	Name     string
+	HashKey  string
+	RangeKey string
+}
+
+// RunMigrations applies every pending migration. It is run at server start, so
+// it waits for another instance that is already migrating instead of failing.
+func RunMigrations(client *dynamodb.Client, tables Tables) error {
+	ctx := context.Background()
+
+	migrator := NewMigrator(client, tables)
+	migrator.WaitForLock = schemaWaitTimeout
+	return migrator.Up(ctx)
 }
 
-func createMigrationsTable(ctx context.Context, client *dynamodb.Client) error {
+func createMigrationsTable(ctx context.Context, client *dynamodb.Client, table string) error {
 	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName: aws.String("Migrations"),
+		TableName: aws.String(table),
 		AttributeDefinitions: []types.AttributeDefinition{
 			{
 				AttributeName: aws.String("Key"),
@@ -112,9 +365,9 @@ func createMigrationsTable(ctx context.Context, client *dynamodb.Client) error {
 	return nil
 }
 
-func getCurrentMigrationVersion(ctx context.Context, client *dynamodb.Client) (int, error) {
+func getCurrentMigrationVersion(ctx context.Context, client *dynamodb.Client, table string) (int, error) {
 	result, err := client.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String("Migrations"),
+		TableName: aws.String(table),
 		Key: map[string]types.AttributeValue{
 			"Key": &types.AttributeValueMemberS{Value: "Version"},
 		},
@@ -137,7 +390,7 @@ func getCurrentMigrationVersion(ctx context.Context, client *dynamodb.Client) (i
 	return version.Version, nil
 }
 
-func updateMigrationVersion(ctx context.Context, client *dynamodb.Client, version int) error {
+func updateMigrationVersion(ctx context.Context, client *dynamodb.Client, table string, version int) error {
 	item, err := attributevalue.MarshalMap(struct {
 		Key     string `dynamodbav:"Key"`
 		Version int    `dynamodbav:"Version"`
@@ -150,7 +403,7 @@ func updateMigrationVersion(ctx context.Context, client *dynamodb.Client, versio
 	}
 
 	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String("Migrations"),
+		TableName: aws.String(table),
 		Item:      item,
 	})
 	if err != nil {
@@ -183,6 +436,12 @@ func createTable(ctx context.Context, client *dynamodb.Client, tableName string)
 	if err != nil {
 		return fmt.Errorf("failed to create %s table: %w", tableName, err)
 	}
+
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	err = waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, schemaWaitTimeout)
+	if err != nil {
+		return fmt.Errorf("failed waiting for %s table to become active: %w", tableName, err)
+	}
 	log.Printf("Table %s created successfully", tableName)
 	return nil
 }
@@ -194,6 +453,184 @@ func deleteTable(ctx context.Context, client *dynamodb.Client, tableName string)
 	if err != nil {
 		return fmt.Errorf("failed to delete table %s: %w", tableName, err)
 	}
+
+	waiter := dynamodb.NewTableNotExistsWaiter(client)
+	err = waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, schemaWaitTimeout)
+	if err != nil {
+		return fmt.Errorf("failed waiting for table %s to be deleted: %w", tableName, err)
+	}
 	log.Printf("Table %s deleted successfully", tableName)
 	return nil
 }
+
+// createIndex adds a global secondary index to tableName and waits until it is
+// ACTIVE. DynamoDB fills a new index from the items already in the table before
+// marking it ACTIVE, so once this returns the index can be queried for existing
+// data as well as new writes. An index that already exists is left alone.
+func createIndex(ctx context.Context, client *dynamodb.Client, tableName string, idx index) error {
+	existing, err := describeIndex(ctx, client, tableName, idx.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		log.Printf("Index %s on %s already exists", idx.Name, tableName)
+		return waitForIndex(ctx, client, tableName, idx.Name)
+	}
+
+	attributeDefinitions := []types.AttributeDefinition{
+		{
+			AttributeName: aws.String(idx.HashKey),
+			AttributeType: types.ScalarAttributeTypeS,
+		},
+	}
+	keySchema := []types.KeySchemaElement{
+		{
+			AttributeName: aws.String(idx.HashKey),
+			KeyType:       types.KeyTypeHash,
+		},
+	}
+	if idx.RangeKey != "" {
+		attributeDefinitions = append(attributeDefinitions, types.AttributeDefinition{
+			AttributeName: aws.String(idx.RangeKey),
+			AttributeType: types.ScalarAttributeTypeS,
+		})
+		keySchema = append(keySchema, types.KeySchemaElement{
+			AttributeName: aws.String(idx.RangeKey),
+			KeyType:       types.KeyTypeRange,
+		})
+	}
+
+	_, err = client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName:            aws.String(tableName),
+		AttributeDefinitions: attributeDefinitions,
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{
+				Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName: aws.String(idx.Name),
+					KeySchema: keySchema,
+					Projection: &types.Projection{
+						ProjectionType: types.ProjectionTypeAll,
+					},
+					ProvisionedThroughput: &types.ProvisionedThroughput{
+						ReadCapacityUnits:  aws.Int64(5),
+						WriteCapacityUnits: aws.Int64(5),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index %s on %s: %w", idx.Name, tableName, err)
+	}
+
+	log.Printf("Creating index %s on %s, waiting for backfill", idx.Name, tableName)
+	if err := waitForIndex(ctx, client, tableName, idx.Name); err != nil {
+		return err
+	}
+	log.Printf("Index %s on %s created successfully", idx.Name, tableName)
+	return nil
+}
+
+// deleteIndex removes a global secondary index from tableName and waits until
+// it is gone. A missing index is not an error.
+func deleteIndex(ctx context.Context, client *dynamodb.Client, tableName, indexName string) error {
+	existing, err := describeIndex(ctx, client, tableName, indexName)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+
+	_, err = client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName: aws.String(tableName),
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{
+				Delete: &types.DeleteGlobalSecondaryIndexAction{
+					IndexName: aws.String(indexName),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete index %s on %s: %w", indexName, tableName, err)
+	}
+
+	err = pollSchema(ctx, func() (bool, error) {
+		existing, err := describeIndex(ctx, client, tableName, indexName)
+		return existing == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed waiting for index %s on %s to be deleted: %w", indexName, tableName, err)
+	}
+	log.Printf("Index %s on %s deleted successfully", indexName, tableName)
+	return nil
+}
+
+// waitForIndex blocks until the index is ACTIVE and no longer backfilling, and
+// the table itself has finished updating.
+func waitForIndex(ctx context.Context, client *dynamodb.Client, tableName, indexName string) error {
+	err := pollSchema(ctx, func() (bool, error) {
+		output, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if err != nil {
+			return false, err
+		}
+		if output.Table.TableStatus != types.TableStatusActive {
+			return false, nil
+		}
+		for _, gsi := range output.Table.GlobalSecondaryIndexes {
+			if aws.ToString(gsi.IndexName) != indexName {
+				continue
+			}
+			backfilling := gsi.Backfilling != nil && *gsi.Backfilling
+			return gsi.IndexStatus == types.IndexStatusActive && !backfilling, nil
+		}
+		return false, fmt.Errorf("index %s not found on %s", indexName, tableName)
+	})
+	if err != nil {
+		return fmt.Errorf("failed waiting for index %s on %s to become active: %w", indexName, tableName, err)
+	}
+	return nil
+}
+
+func describeIndex(ctx context.Context, client *dynamodb.Client, tableName, indexName string) (*types.GlobalSecondaryIndexDescription, error) {
+	output, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+	}
+	for _, gsi := range output.Table.GlobalSecondaryIndexes {
+		if aws.ToString(gsi.IndexName) == indexName {
+			return &gsi, nil
+		}
+	}
+	return nil, nil
+}
+
+// pollSchema calls done every schemaPollInterval until it reports true, returns
+// an error, or schemaWaitTimeout elapses.
+func pollSchema(ctx context.Context, done func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, schemaWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(schemaPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
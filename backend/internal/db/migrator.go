@@ -0,0 +1,403 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// lockKey is the Migrations table item that guards against two instances
+// migrating at the same time.
+const lockKey = "Lock"
+
+// lockLease is how long a lock is honoured without being renewed. The lease is
+// renewed after every migration, so it only needs to outlast the slowest one;
+// a lock left behind by a crashed process expires after it.
+const lockLease = time.Hour
+
+// lockPollInterval is how often a waiting migrator retries the lock.
+const lockPollInterval = 5 * time.Second
+
+// ErrMigrationLocked is returned when another process holds the migration
+// lock and the migrator is not willing to wait for it.
+var ErrMigrationLocked = errors.New("migrations are locked by another process")
+
+// Direction says whether a Step applies or rolls back its migration.
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Step is one migration run in one direction.
+type Step struct {
+	Direction Direction
+	Migration Migration
+}
+
+// MigrationState reports whether a known migration has been applied. Progress
+// is set for data migrations that have started.
+type MigrationState struct {
+	Migration Migration
+	Applied   bool
+	Progress  *DataProgress
+}
+
+// Migrator applies and rolls back migrations against DynamoDB.
+type Migrator struct {
+	client *dynamodb.Client
+	tables Tables
+	table  string
+	owner  string
+
+	// WaitForLock is how long Migrate waits for another process to release
+	// the migration lock. Zero fails immediately with ErrMigrationLocked.
+	WaitForLock time.Duration
+}
+
+func NewMigrator(client *dynamodb.Client, tables Tables) *Migrator {
+	host, _ := os.Hostname()
+	return &Migrator{
+		client: client,
+		tables: tables,
+		table:  tables.Name(MigrationsTable),
+		owner:  fmt.Sprintf("%s/%d/%s", host, os.Getpid(), uuid.New().String()),
+	}
+}
+
+// LatestVersion is the version of the newest known migration.
+func LatestVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Status returns the current schema version and every known migration with
+// whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) (int, []MigrationState, error) {
+	if err := m.ensureMigrationsTable(ctx); err != nil {
+		return 0, nil, err
+	}
+
+	currentVersion, err := getCurrentMigrationVersion(ctx, m.client, m.table)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{
+			Migration: migration,
+			Applied:   migration.Version <= currentVersion,
+		}
+		if migration.Data != nil {
+			progress, err := getDataProgress(ctx, m.client, m.table, migration.Version)
+			if err != nil {
+				return 0, nil, err
+			}
+			if progress.Processed > 0 || progress.Completed {
+				state.Progress = progress
+			}
+		}
+		states = append(states, state)
+	}
+	return currentVersion, states, nil
+}
+
+// Plan returns the steps that would move the schema from its current version
+// to target, without running them.
+func (m *Migrator) Plan(ctx context.Context, target int) ([]Step, error) {
+	if err := m.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	currentVersion, err := getCurrentMigrationVersion(ctx, m.client, m.table)
+	if err != nil {
+		return nil, err
+	}
+	return plan(currentVersion, target)
+}
+
+// Up applies every pending migration. It never rolls anything back, so an
+// older build starting against a newer schema leaves the schema alone.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.migrate(ctx, LatestVersion(), false)
+}
+
+// Migrate moves the schema to target, applying pending migrations with Up or
+// rolling applied ones back with Down. It holds the migration lock while it
+// runs and records the version after every step, so a failed run can be
+// resumed.
+func (m *Migrator) Migrate(ctx context.Context, target int) error {
+	return m.migrate(ctx, target, true)
+}
+
+func (m *Migrator) migrate(ctx context.Context, target int, allowDown bool) error {
+	if err := m.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+
+	if err := m.acquireLock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err := m.releaseLock(context.Background()); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	// Read the version only once the lock is held, another process may have
+	// migrated while we waited.
+	currentVersion, err := getCurrentMigrationVersion(ctx, m.client, m.table)
+	if err != nil {
+		return err
+	}
+	if !allowDown && currentVersion > target {
+		log.Printf("Schema version %d is newer than the latest known migration %d, skipping", currentVersion, target)
+		return nil
+	}
+
+	steps, err := plan(currentVersion, target)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		migration := step.Migration
+		if step.Direction == DirectionUp {
+			log.Printf("Running %s migration %d: %s", migration.Kind(), migration.Version, migration.Description)
+			if err := m.apply(ctx, migration); err != nil {
+				return fmt.Errorf("failed to run migration %d: %w", migration.Version, err)
+			}
+			if err := updateMigrationVersion(ctx, m.client, m.table, migration.Version); err != nil {
+				return fmt.Errorf("failed to update migration version: %w", err)
+			}
+		} else {
+			log.Printf("Rolling back %s migration %d: %s", migration.Kind(), migration.Version, migration.Description)
+			if err := m.rollback(ctx, migration); err != nil {
+				return fmt.Errorf("failed to roll back migration %d: %w", migration.Version, err)
+			}
+			if err := updateMigrationVersion(ctx, m.client, m.table, previousVersion(migration.Version)); err != nil {
+				return fmt.Errorf("failed to update migration version: %w", err)
+			}
+		}
+
+		if err := m.renewLock(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	if migration.Data != nil {
+		return runDataMigration(ctx, m.client, m.tables, migration.Version, migration.Data, m.renewLock)
+	}
+	return migration.Up(ctx, m.client, m.tables)
+}
+
+// rollback runs Down. Data migrations without a Down leave the rewritten items
+// as they are; only their checkpoint is cleared so a later Up runs them again.
+func (m *Migrator) rollback(ctx context.Context, migration Migration) error {
+	if migration.Down != nil {
+		if err := migration.Down(ctx, m.client, m.tables); err != nil {
+			return err
+		}
+	}
+	if migration.Data != nil {
+		return deleteDataProgress(ctx, m.client, m.table, migration.Version)
+	}
+	return nil
+}
+
+// plan works out the steps between two versions. Moving up applies every
+// migration above current up to target in ascending order; moving down rolls
+// back every migration above target in descending order.
+func plan(current, target int) ([]Step, error) {
+	if target != 0 && findMigration(target) == nil {
+		return nil, fmt.Errorf("unknown migration version %d", target)
+	}
+
+	var steps []Step
+	if target >= current {
+		for _, migration := range migrations {
+			if migration.Version > current && migration.Version <= target {
+				steps = append(steps, Step{Direction: DirectionUp, Migration: migration})
+			}
+		}
+		return steps, nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version <= target || migration.Version > current {
+			continue
+		}
+		if migration.Down == nil && migration.Data == nil {
+			return nil, fmt.Errorf("migration %d cannot be rolled back", migration.Version)
+		}
+		steps = append(steps, Step{Direction: DirectionDown, Migration: migration})
+	}
+	return steps, nil
+}
+
+func findMigration(version int) *Migration {
+	for i := range migrations {
+		if migrations[i].Version == version {
+			return &migrations[i]
+		}
+	}
+	return nil
+}
+
+// previousVersion is the version the schema is at once version has been
+// rolled back.
+func previousVersion(version int) int {
+	previous := 0
+	for _, migration := range migrations {
+		if migration.Version >= version {
+			break
+		}
+		previous = migration.Version
+	}
+	return previous
+}
+
+func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
+	if err := createMigrationsTable(ctx, m.client, m.table); err != nil {
+		return err
+	}
+
+	waiter := dynamodb.NewTableExistsWaiter(m.client)
+	err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(m.table)}, schemaWaitTimeout)
+	if err != nil {
+		return fmt.Errorf("failed waiting for migrations table to become active: %w", err)
+	}
+	return nil
+}
+
+type migrationLock struct {
+	Key       string `dynamodbav:"Key"`
+	Owner     string `dynamodbav:"Owner"`
+	ExpiresAt int64  `dynamodbav:"ExpiresAt"`
+}
+
+// acquireLock takes the migration lock, waiting up to WaitForLock for another
+// owner to release it or for its lease to run out.
+func (m *Migrator) acquireLock(ctx context.Context) error {
+	deadline := time.Now().Add(m.WaitForLock)
+	for {
+		holder, err := m.tryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if holder == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: held by %s until %s", ErrMigrationLocked, holder.Owner,
+				time.Unix(holder.ExpiresAt, 0).UTC().Format(time.RFC3339))
+		}
+		log.Printf("Waiting for migration lock held by %s", holder.Owner)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// tryLock makes a single attempt at the lock. It returns the current holder
+// when somebody else has it.
+func (m *Migrator) tryLock(ctx context.Context) (*migrationLock, error) {
+	now := time.Now()
+	item, err := attributevalue.MarshalMap(migrationLock{
+		Key:       lockKey,
+		Owner:     m.owner,
+		ExpiresAt: now.Add(lockLease).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migration lock: %w", err)
+	}
+
+	_, err = m.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(m.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR ExpiresAt < :now OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":   "Key",
+			"#owner": "Owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":owner": &types.AttributeValueMemberS{Value: m.owner},
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err == nil {
+		return nil, nil
+	}
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionErr) {
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	var holder migrationLock
+	if err := attributevalue.UnmarshalMap(conditionErr.Item, &holder); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal migration lock: %w", err)
+	}
+	return &holder, nil
+}
+
+// renewLock extends the lease on a lock this migrator already holds.
+func (m *Migrator) renewLock(ctx context.Context) error {
+	holder, err := m.tryLock(ctx)
+	if err != nil {
+		return err
+	}
+	if holder != nil {
+		return fmt.Errorf("%w: lost to %s", ErrMigrationLocked, holder.Owner)
+	}
+	return nil
+}
+
+func (m *Migrator) releaseLock(ctx context.Context) error {
+	_, err := m.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(m.table),
+		Key: map[string]types.AttributeValue{
+			"Key": &types.AttributeValueMemberS{Value: lockKey},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "Owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: m.owner},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			// The lease ran out and somebody else took over.
+			return nil
+		}
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// useMigrations swaps the registered migrations for the length of a test.
+func useMigrations(t *testing.T, fixture []Migration) {
+	t.Helper()
+	registered := migrations
+	migrations = fixture
+	t.Cleanup(func() { migrations = registered })
+}
+
+func noop(context.Context, *dynamodb.Client, Tables) error { return nil }
+
+func TestPlan(t *testing.T) {
+	useMigrations(t, []Migration{
+		{Version: 1, Up: noop, Down: noop},
+		{Version: 2, Data: &DataMigration{Table: FarmersTable}},
+		{Version: 3, Up: noop},
+		{Version: 5, Up: noop, Down: noop},
+	})
+
+	tests := []struct {
+		name            string
+		current, target int
+		wantDirection   Direction
+		wantVersions    []int
+		wantErr         bool
+	}{
+		{name: "up from scratch", current: 0, target: 5, wantDirection: DirectionUp, wantVersions: []int{1, 2, 3, 5}},
+		{name: "up part of the way", current: 2, target: 3, wantDirection: DirectionUp, wantVersions: []int{3}},
+		{name: "already there", current: 5, target: 5},
+		{name: "down one", current: 5, target: 3, wantDirection: DirectionDown, wantVersions: []int{5}},
+		{name: "down through a data migration", current: 2, target: 0, wantDirection: DirectionDown, wantVersions: []int{2, 1}},
+		{name: "down past a one-way migration", current: 5, target: 2, wantErr: true},
+		{name: "unknown target", current: 0, target: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := plan(tt.current, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("plan(%d, %d) error = %v, want error %v", tt.current, tt.target, err, tt.wantErr)
+			}
+			if err != nil {
+				if steps != nil {
+					t.Fatalf("plan(%d, %d) returned steps with its error: %+v", tt.current, tt.target, steps)
+				}
+				return
+			}
+
+			var versions []int
+			for _, step := range steps {
+				if step.Direction != tt.wantDirection {
+					t.Fatalf("step %d goes %s, want %s", step.Migration.Version, step.Direction, tt.wantDirection)
+				}
+				versions = append(versions, step.Migration.Version)
+			}
+			if !slices.Equal(versions, tt.wantVersions) {
+				t.Fatalf("plan(%d, %d) = %v, want %v", tt.current, tt.target, versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestPreviousVersion(t *testing.T) {
+	useMigrations(t, []Migration{{Version: 1}, {Version: 2}, {Version: 5}})
+
+	tests := []struct {
+		version, want int
+	}{
+		{version: 1, want: 0},
+		{version: 2, want: 1},
+		{version: 5, want: 2},
+		{version: 4, want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := previousVersion(tt.version); got != tt.want {
+			t.Errorf("previousVersion(%d) = %d, want %d", tt.version, got, tt.want)
+		}
+	}
+}
+
+// TestRegisteredMigrations checks the real list stays ordered and that the
+// latest migration can always be rolled back one step.
+func TestRegisteredMigrations(t *testing.T) {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Fatalf("migration %d is listed after %d", migrations[i].Version, migrations[i-1].Version)
+		}
+	}
+
+	latest := LatestVersion()
+	steps, err := plan(latest, previousVersion(latest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].Direction != DirectionDown || steps[0].Migration.Version != latest {
+		t.Fatalf("rolling back the latest migration plans %+v", steps)
+	}
+}
@@ -0,0 +1,35 @@
+package db
+
+// Base table names. Every table is reached through Tables.Name, which adds the
+// environment's prefix, so never pass these to DynamoDB directly.
+const (
+	FarmersTable             = "Farmers"
+	CCEsTable                = "CCEs"
+	TicketsTable             = "Tickets"
+	ShootsTable              = "Shoots"
+	ReportsTable             = "Reports"
+	AuditTable               = "Audit"
+	FarmerContactsTable      = "FarmerContacts"
+	DuplicateCandidatesTable = "DuplicateCandidates"
+	ConsentEventsTable       = "ConsentEvents"
+	PartnersTable            = "Partners"
+	CropsTable               = "Crops"
+	VarietiesTable           = "Varieties"
+	OutreachMarksTable       = "OutreachMarks"
+	MigrationsTable          = "Migrations"
+)
+
+// Tables resolves base table names to the names used by one environment. The
+// prefix lets dev, staging, prod and personal sandboxes share an AWS account.
+type Tables struct {
+	Prefix string
+}
+
+func NewTables(prefix string) Tables {
+	return Tables{Prefix: prefix}
+}
+
+// Name returns the name of the base table in this environment.
+func (t Tables) Name(base string) string {
+	return t.Prefix + base
+}
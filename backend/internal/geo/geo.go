@@ -0,0 +1,138 @@
+// Package geo measures distances between points on the earth and indexes
+// points by geohash. A geohash names a cell of a grid laid over the earth,
+// and every extra character splits the cell into 32, so points close to each
+// other tend to share a prefix and a radius can be looked up as a handful of
+// prefixes, see Cover.
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// earthRadiusKm is the mean radius of the earth.
+const earthRadiusKm = 6371.0088
+
+// kmPerDegree is the length of one degree of latitude.
+const kmPerDegree = 111.32
+
+// MaxPrecision is the length of the geohashes Encode is usually asked for:
+// cells of a few metres.
+const MaxPrecision = 9
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Point is a position in decimal degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Validate reports a point outside the range of latitudes and longitudes.
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude %v is out of range", p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("longitude %v is out of range", p.Lng)
+	}
+	return nil
+}
+
+// Distance returns the great-circle distance between a and b in kilometres.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLng := lat2-lat1, radians(b.Lng-a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Encode returns the geohash of p with precision characters.
+func Encode(p Point, precision int) string {
+	latMin, latMax := -90.0, 90.0
+	lngMin, lngMax := -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	bits, ch := 0, 0
+	for even := true; len(hash) < precision; even = !even {
+		ch <<= 1
+		if even {
+			if mid := (lngMin + lngMax) / 2; p.Lng >= mid {
+				ch |= 1
+				lngMin = mid
+			} else {
+				lngMax = mid
+			}
+		} else {
+			if mid := (latMin + latMax) / 2; p.Lat >= mid {
+				ch |= 1
+				latMin = mid
+			} else {
+				latMax = mid
+			}
+		}
+		if bits++; bits == 5 {
+			hash = append(hash, base32[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// cellSize returns the height and width in degrees of the cells of geohashes
+// with precision characters.
+func cellSize(precision int) (float64, float64) {
+	lngBits := (5*precision + 1) / 2
+	latBits := 5 * precision / 2
+	return 180 / math.Exp2(float64(latBits)), 360 / math.Exp2(float64(lngBits))
+}
+
+// Cover returns geohash prefixes whose cells together hold every point within
+// radiusKm of centre, picking the longest prefixes that need at most maxCells
+// of them, but none shorter than minPrecision. Points in the cells may still
+// lie outside the radius; check them with Distance.
+func Cover(centre Point, radiusKm float64, minPrecision, maxCells int) []string {
+	dLat := radiusKm / kmPerDegree
+	dLng := 180.0
+	if cos := math.Cos(radians(centre.Lat)); cos > 1e-6 {
+		dLng = math.Min(180, radiusKm/(kmPerDegree*cos))
+	}
+	south, north := math.Max(-90, centre.Lat-dLat), math.Min(90, centre.Lat+dLat)
+	west, east := math.Max(-180, centre.Lng-dLng), math.Min(180, centre.Lng+dLng)
+
+	precision := MaxPrecision
+	for ; precision > minPrecision; precision-- {
+		h, w := cellSize(precision)
+		rows := math.Floor(north/h) - math.Floor(south/h) + 1
+		cols := math.Floor(east/w) - math.Floor(west/w) + 1
+		if rows*cols <= float64(maxCells) {
+			break
+		}
+	}
+
+	// Stepping by the cell size from one edge and adding the other edge
+	// visits every cell of the box at least once.
+	h, w := cellSize(precision)
+	seen := make(map[string]bool)
+	var cells []string
+	for lat := south; ; lat += h {
+		lat = math.Min(lat, north)
+		for lng := west; ; lng += w {
+			lng = math.Min(lng, east)
+			if cell := Encode(Point{Lat: lat, Lng: lng}, precision); !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+			if lng == east {
+				break
+			}
+		}
+		if lat == north {
+			break
+		}
+	}
+	return cells
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,146 @@
+package imports
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"backend/internal/models"
+)
+
+// field is a Farmer field a column can be mapped onto.
+type field string
+
+const (
+	fieldName     field = "name"
+	fieldContact  field = "contact"
+	fieldState    field = "state"
+	fieldDistrict field = "district"
+	fieldTehsil   field = "tehsil"
+	fieldVillage  field = "village"
+	fieldPincode  field = "pincode"
+	fieldAddress  field = "address"
+	fieldTag      field = "tag"
+	fieldCrop     field = "crop"
+)
+
+// headers maps the column headings found in field team spreadsheets, folded by
+// headerKey, to the field they hold.
+var headers = map[string]field{
+	"name":          fieldName,
+	"farmername":    fieldName,
+	"fullname":      fieldName,
+	"contact":       fieldContact,
+	"contactno":     fieldContact,
+	"contactnumber": fieldContact,
+	"phone":         fieldContact,
+	"phoneno":       fieldContact,
+	"phonenumber":   fieldContact,
+	"mobile":        fieldContact,
+	"mobileno":      fieldContact,
+	"mobilenumber":  fieldContact,
+	"state":         fieldState,
+	"district":      fieldDistrict,
+	"tehsil":        fieldTehsil,
+	"taluka":        fieldTehsil,
+	"taluk":         fieldTehsil,
+	"block":         fieldTehsil,
+	"mandal":        fieldTehsil,
+	"village":       fieldVillage,
+	"pincode":       fieldPincode,
+	"pin":           fieldPincode,
+	"postalcode":    fieldPincode,
+	"address":       fieldAddress,
+	"tag":           fieldTag,
+	"crop":          fieldCrop,
+	"crops":         fieldCrop,
+}
+
+// requiredFields must have a column. Contact is what rows are matched to
+// existing farmers by.
+var requiredFields = []field{fieldName, fieldContact}
+
+// columns holds the index of the column of each mapped field.
+type columns map[field]int
+
+// mapColumns maps a header row onto Farmer fields. Columns with headings it
+// does not know are ignored.
+func mapColumns(header []string) (columns, error) {
+	cols := make(columns)
+	for i, heading := range header {
+		f, ok := headers[headerKey(heading)]
+		if !ok {
+			continue
+		}
+		if _, dup := cols[f]; dup {
+			return nil, fmt.Errorf("more than one column holds %s", f)
+		}
+		cols[f] = i
+	}
+
+	for _, f := range requiredFields {
+		if _, ok := cols[f]; !ok {
+			return nil, fmt.Errorf("no column holds %s", f)
+		}
+	}
+	return cols, nil
+}
+
+// farmer builds a farmer from the cells of one row. Values are only trimmed,
+// validation happens afterwards.
+func (c columns) farmer(row []string) *models.Farmer {
+	cell := func(f field) string {
+		i, ok := c[f]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	return &models.Farmer{
+		Name:     cell(fieldName),
+		Contact:  cell(fieldContact),
+		State:    cell(fieldState),
+		District: cell(fieldDistrict),
+		Tehsil:   cell(fieldTehsil),
+		Village:  cell(fieldVillage),
+		Pincode:  cell(fieldPincode),
+		Address:  cell(fieldAddress),
+		Tag:      cell(fieldTag),
+		Crop:     splitCrops(cell(fieldCrop)),
+	}
+}
+
+// splitCrops reads a list of crops separated by commas, semicolons or pipes.
+func splitCrops(value string) []string {
+	var crops []string
+	for _, crop := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|'
+	}) {
+		if crop = strings.TrimSpace(crop); crop != "" {
+			crops = append(crops, crop)
+		}
+	}
+	return crops
+}
+
+// headerKey folds a heading to lowercase letters and digits, so "Mobile No."
+// and "mobile_no" both become "mobileno".
+func headerKey(heading string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, heading)
+}
+
+// blank reports whether every cell of row is empty.
+func blank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
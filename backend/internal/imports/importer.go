@@ -0,0 +1,201 @@
+package imports
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/internal/service"
+	"backend/pkg/errors"
+	"backend/pkg/phone"
+)
+
+// MaxRows is the most data rows one import may contain.
+const MaxRows = 10000
+
+// Importer creates and updates farmers from spreadsheet rows.
+type Importer struct {
+	farmers   *service.FarmerService
+	locations *location.Directory
+}
+
+func NewImporter(farmers *service.FarmerService, locations *location.Directory) *Importer {
+	return &Importer{
+		farmers:   farmers,
+		locations: locations,
+	}
+}
+
+// pending is a valid row waiting to be written.
+type pending struct {
+	result *RowResult
+	farmer *models.Farmer
+}
+
+// Import processes rows, header row first, and reports the outcome of every
+// data row. A row whose contact belongs to an existing farmer updates that
+// farmer; a row that repeats the contact of an earlier row is skipped. Blank
+// rows are left out of the report.
+//
+// The error is only set when the sheet as a whole cannot be imported, such as
+// when a required column is missing. It wraps errors.ErrInvalidInput.
+func (im *Importer) Import(ctx context.Context, rows [][]string) (*Report, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the sheet is empty", errors.ErrInvalidInput)
+	}
+	if len(rows)-1 > MaxRows {
+		return nil, fmt.Errorf("%w: the sheet has more than %d rows", errors.ErrInvalidInput, MaxRows)
+	}
+	cols, err := mapColumns(rows[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+	}
+
+	report := &Report{}
+	seen := make(map[string]int)
+	var creates []pending
+
+	for i, row := range rows[1:] {
+		if blank(row) {
+			continue
+		}
+		// Row numbers count from 1 and include the header, as spreadsheets do.
+		result := report.add(i + 2)
+		farmer := cols.farmer(row)
+		result.Name = farmer.Name
+		result.Contact = farmer.Contact
+
+		if problems := im.validate(farmer); len(problems) > 0 {
+			result.fail(strings.Join(problems, "; "))
+			continue
+		}
+		result.Contact = farmer.Contact
+
+		if first, ok := seen[farmer.Contact]; ok {
+			result.skip(fmt.Sprintf("same contact as row %d", first))
+			continue
+		}
+		seen[farmer.Contact] = result.Row
+
+		existing, err := im.farmers.GetFarmerByContact(ctx, farmer.Contact)
+		switch {
+		case err == nil:
+			im.update(ctx, result, existing, farmer)
+		case errors.Is(err, errors.ErrNotFound):
+			creates = append(creates, pending{result: result, farmer: farmer})
+		default:
+			result.fail("could not look up contact: " + err.Error())
+		}
+	}
+
+	im.create(ctx, creates)
+	report.count()
+	return report, nil
+}
+
+// validate normalises the contact and location of farmer, and returns what is
+// wrong with the row.
+func (im *Importer) validate(farmer *models.Farmer) []string {
+	var problems []string
+
+	if farmer.Name == "" {
+		problems = append(problems, "name is missing")
+	}
+
+	if farmer.Contact == "" {
+		problems = append(problems, "contact is missing")
+	} else if normalised, err := phone.Normalize(farmer.Contact); err != nil {
+		problems = append(problems, fmt.Sprintf("contact %q is not a valid phone number", farmer.Contact))
+	} else {
+		farmer.Contact = normalised
+	}
+
+	place, err := im.locations.Resolve(location.Place{
+		State:    farmer.State,
+		District: farmer.District,
+		Tehsil:   farmer.Tehsil,
+		Village:  farmer.Village,
+		Pincode:  farmer.Pincode,
+	})
+	var invalid *location.InvalidPlaceError
+	if errors.As(err, &invalid) {
+		problems = append(problems, invalid.Problems...)
+	} else {
+		farmer.State, farmer.District, farmer.Tehsil, farmer.Village, farmer.Pincode =
+			place.State, place.District, place.Tehsil, place.Village, place.Pincode
+	}
+
+	return problems
+}
+
+// update copies the fields the row fills in onto the existing farmer, the way
+// PUT /farmers/{id} does, and saves it if anything changed.
+func (im *Importer) update(ctx context.Context, result *RowResult, existing, row *models.Farmer) {
+	result.FarmerID = existing.ID
+
+	updated := *existing
+	merge := func(dst *string, value string) {
+		if value != "" {
+			*dst = value
+		}
+	}
+	merge(&updated.Name, row.Name)
+	merge(&updated.State, row.State)
+	merge(&updated.District, row.District)
+	merge(&updated.Tehsil, row.Tehsil)
+	merge(&updated.Village, row.Village)
+	merge(&updated.Pincode, row.Pincode)
+	merge(&updated.Address, row.Address)
+	merge(&updated.Tag, row.Tag)
+	if len(row.Crop) > 0 {
+		updated.Crop = row.Crop
+	}
+
+	if sameFarmer(existing, &updated) {
+		result.skip("farmer already up to date")
+		return
+	}
+	if err := im.farmers.UpdateFarmer(ctx, &updated); err != nil {
+		result.fail("could not update farmer: " + err.Error())
+		return
+	}
+	result.Status = StatusUpdated
+}
+
+// create writes the new farmers in one batch.
+func (im *Importer) create(ctx context.Context, creates []pending) {
+	if len(creates) == 0 {
+		return
+	}
+
+	farmers := make([]*models.Farmer, len(creates))
+	for i, c := range creates {
+		farmers[i] = c.farmer
+	}
+
+	for i, err := range im.farmers.CreateFarmers(ctx, farmers) {
+		result := creates[i].result
+		if err != nil {
+			result.fail("could not create farmer: " + err.Error())
+			continue
+		}
+		result.Status = StatusCreated
+		result.FarmerID = farmers[i].ID
+	}
+}
+
+// sameFarmer compares the fields an import can change.
+func sameFarmer(a, b *models.Farmer) bool {
+	return a.Name == b.Name &&
+		a.State == b.State &&
+		a.District == b.District &&
+		a.Tehsil == b.Tehsil &&
+		a.Village == b.Village &&
+		a.Pincode == b.Pincode &&
+		a.Address == b.Address &&
+		a.Tag == b.Tag &&
+		slices.Equal(a.Crop, b.Crop)
+}
@@ -0,0 +1,79 @@
+package imports
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// Status is the outcome of one row.
+type Status string
+
+const (
+	StatusCreated Status = "created"
+	StatusUpdated Status = "updated"
+	StatusSkipped Status = "skipped"
+	StatusError   Status = "error"
+)
+
+// RowResult is the outcome of one data row. Contact is normalised once the
+// row passed validation.
+type RowResult struct {
+	Row      int    `json:"row"`
+	Status   Status `json:"status"`
+	FarmerID string `json:"farmerId,omitempty"`
+	Name     string `json:"name"`
+	Contact  string `json:"contact"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (r *RowResult) fail(reason string) {
+	r.Status = StatusError
+	r.Reason = reason
+}
+
+func (r *RowResult) skip(reason string) {
+	r.Status = StatusSkipped
+	r.Reason = reason
+}
+
+// Report is the outcome of an import, row by row.
+type Report struct {
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Skipped int          `json:"skipped"`
+	Errored int          `json:"errored"`
+	Rows    []*RowResult `json:"rows"`
+}
+
+func (r *Report) add(row int) *RowResult {
+	result := &RowResult{Row: row}
+	r.Rows = append(r.Rows, result)
+	return result
+}
+
+func (r *Report) count() {
+	for _, row := range r.Rows {
+		switch row.Status {
+		case StatusCreated:
+			r.Created++
+		case StatusUpdated:
+			r.Updated++
+		case StatusSkipped:
+			r.Skipped++
+		case StatusError:
+			r.Errored++
+		}
+	}
+}
+
+// WriteCSV writes the result file: one line per row with its outcome.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"row", "status", "farmer_id", "name", "contact", "reason"})
+	for _, row := range r.Rows {
+		writer.Write([]string{strconv.Itoa(row.Row), string(row.Status), row.FarmerID, row.Name, row.Contact, row.Reason})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,83 @@
+// Package imports loads farmers in bulk from the spreadsheets field teams keep.
+package imports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is the file format of a spreadsheet.
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// FormatOf picks the format from a file name, or returns "" if the extension
+// is not one ReadSheet understands.
+func FormatOf(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	default:
+		return ""
+	}
+}
+
+// ReadSheet returns the rows of a spreadsheet as text, header row first. Only
+// the first sheet of a workbook is read.
+func ReadSheet(r io.Reader, format Format) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		var rows [][]string
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			// The reader skips blank lines. Put them back so row numbers
+			// match the lines of the file.
+			line, _ := reader.FieldPos(0)
+			for len(rows) < line-1 {
+				rows = append(rows, nil)
+			}
+			rows = append(rows, record)
+		}
+		// Excel writes a byte order mark at the start of UTF-8 CSV files.
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+		}
+		return rows, nil
+
+	case FormatXLSX:
+		workbook, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer workbook.Close()
+
+		sheets := workbook.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("workbook has no sheets")
+		}
+		return workbook.GetRows(sheets[0])
+
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
@@ -0,0 +1,216 @@
+// Package location is the location master: the states, districts, tehsils,
+// villages and pincodes of India, and the matching of the names people type
+// against them. The states and districts are built into the binary; tehsils,
+// villages and pincodes come from government directory files, see Merge.
+package location
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//go:embed data/districts.csv
+var districtsCSV []byte
+
+// stateAliases are older or informal state names still found in spreadsheets.
+var stateAliases = map[string]string{
+	"Orissa":                 "Odisha",
+	"Pondicherry":            "Puducherry",
+	"Uttaranchal":            "Uttarakhand",
+	"NCT of Delhi":           "Delhi",
+	"J&K":                    "Jammu and Kashmir",
+	"Andaman and Nicobar":    "Andaman and Nicobar Islands",
+	"Dadra and Nagar Haveli": "Dadra and Nagar Haveli and Daman and Diu",
+	"Daman and Diu":          "Dadra and Nagar Haveli and Daman and Diu",
+	"Chattisgarh":            "Chhattisgarh",
+	"Tamilnadu":              "Tamil Nadu",
+	"UP":                     "Uttar Pradesh",
+	"MP":                     "Madhya Pradesh",
+	"AP":                     "Andhra Pradesh",
+	"HP":                     "Himachal Pradesh",
+	"WB":                     "West Bengal",
+}
+
+// Directory resolves place names to their official spelling. Each level maps
+// the key of every name it knows, aliases included, to the place.
+type Directory struct {
+	states map[string]*state
+	// pincodes lists the places each pincode serves.
+	pincodes map[string][]Place
+	// centroids sums the points given for each pincode.
+	centroids map[string]pointSum
+}
+
+type pointSum struct {
+	lat, lng float64
+	n        int
+}
+
+type state struct {
+	name      string
+	districts map[string]*district
+}
+
+type district struct {
+	name    string
+	tehsils map[string]*tehsil
+}
+
+type tehsil struct {
+	name     string
+	villages map[string]*village
+}
+
+type village struct {
+	name     string
+	pincodes []string
+}
+
+// Load reads a directory from CSV with the columns state, district and
+// aliases, where aliases is a semicolon separated list of other names of the
+// district. The first row is a header.
+func Load(r io.Reader) (*Directory, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	d := &Directory{
+		states:    make(map[string]*state),
+		pincodes:  make(map[string][]Place),
+		centroids: make(map[string]pointSum),
+	}
+	header := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header {
+			header = false
+			continue
+		}
+		if len(record) < 2 || record[0] == "" || record[1] == "" {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: state and district are required", line)
+		}
+
+		dist := d.addState(record[0]).addDistrict(record[1])
+		if len(record) > 2 && record[2] != "" {
+			for _, alias := range strings.Split(record[2], ";") {
+				d.states[key(record[0])].districts[key(alias)] = dist
+			}
+		}
+	}
+
+	for alias, name := range stateAliases {
+		if s, ok := d.states[key(name)]; ok {
+			if _, taken := d.states[key(alias)]; !taken {
+				d.states[key(alias)] = s
+			}
+		}
+	}
+	return d, nil
+}
+
+// State returns the official name of the state called name.
+func (d *Directory) State(name string) (string, bool) {
+	s, ok := d.states[key(name)]
+	if !ok {
+		return "", false
+	}
+	return s.name, true
+}
+
+// District returns the official names of the state and of its district called
+// district.
+func (d *Directory) District(stateName, district string) (string, string, bool) {
+	s, dist := d.district(stateName, district)
+	if dist == nil {
+		return "", "", false
+	}
+	return s.name, dist.name, true
+}
+
+// FindDistrict looks a district up by name alone. It succeeds only if one
+// state has a district of that name.
+func (d *Directory) FindDistrict(district string) (string, string, bool) {
+	var found *state
+	var name string
+	for _, s := range d.states {
+		dist, ok := s.districts[key(district)]
+		if !ok || s == found {
+			continue
+		}
+		if found != nil {
+			return "", "", false
+		}
+		found, name = s, dist.name
+	}
+	if found == nil {
+		return "", "", false
+	}
+	return found.name, name, true
+}
+
+func (d *Directory) district(stateName, district string) (*state, *district) {
+	s, ok := d.states[key(stateName)]
+	if !ok {
+		return nil, nil
+	}
+	return s, s.districts[key(district)]
+}
+
+func (d *Directory) addState(name string) *state {
+	s, ok := d.states[key(name)]
+	if !ok {
+		s = &state{name: name, districts: make(map[string]*district)}
+		d.states[key(name)] = s
+	}
+	return s
+}
+
+func (s *state) addDistrict(name string) *district {
+	dist, ok := s.districts[key(name)]
+	if !ok {
+		dist = &district{name: name, tehsils: make(map[string]*tehsil)}
+		s.districts[key(name)] = dist
+	}
+	return dist
+}
+
+func (dist *district) addTehsil(name string) *tehsil {
+	t, ok := dist.tehsils[key(name)]
+	if !ok {
+		t = &tehsil{name: name, villages: make(map[string]*village)}
+		dist.tehsils[key(name)] = t
+	}
+	return t
+}
+
+func (t *tehsil) addVillage(name string) *village {
+	v, ok := t.villages[key(name)]
+	if !ok {
+		v = &village{name: name}
+		t.villages[key(name)] = v
+	}
+	return v
+}
+
+// key folds the differences in case, spacing and punctuation that do not make
+// two names different places.
+func key(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "&", " and ")
+	name = strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == ',' {
+			return ' '
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
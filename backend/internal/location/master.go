@@ -0,0 +1,223 @@
+package location
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"backend/internal/geo"
+)
+
+// Columns of a directory file, matched against the header after folding case,
+// spacing and the "(In English)" and "Name" decorations of the published
+// files. "office" is the post office name of the India Post directory, which
+// names a village or locality.
+var masterColumns = map[string]string{
+	"state":       "state",
+	"district":    "district",
+	"tehsil":      "tehsil",
+	"taluk":       "tehsil",
+	"taluka":      "tehsil",
+	"subdistrict": "tehsil",
+	"mandal":      "tehsil",
+	"village":     "village",
+	"office":      "office",
+	"locality":    "village",
+	"pincode":     "pincode",
+	"pin":         "pincode",
+	"latitude":    "latitude",
+	"lat":         "latitude",
+	"longitude":   "longitude",
+	"long":        "longitude",
+	"lng":         "longitude",
+	"lon":         "longitude",
+}
+
+// officeSuffix is the office type India Post appends to office names.
+var officeSuffix = regexp.MustCompile(`(?i)\s+(b\.?o|s\.?o|h\.?o|g\.?p\.?o)\.?$`)
+
+var pincodePattern = regexp.MustCompile(`^[1-9][0-9]{5}$`)
+
+// LoadFiles returns the built in directory extended with the directory files
+// at paths, see Merge.
+func LoadFiles(paths []string) (*Directory, error) {
+	d, err := Load(bytes.NewReader(districtsCSV))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = d.Merge(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return d, nil
+}
+
+// Merge adds the places of a government directory file in CSV: the All India
+// Pincode Directory of India Post or an LGD village list. The header must name
+// the state and district columns and at least one of tehsil, village and
+// pincode. Rows whose state or district are blank are skipped, and so are
+// villages without a tehsil, though their pincode is still recorded.
+//
+// Latitude and longitude columns, as in the India Post directory, place the
+// pincode at the average of its rows, see Centroid. A file of pincodes and
+// coordinates alone needs no state or district column.
+//
+// Merge is meant for building a directory at startup; a Directory is not safe
+// to change while it is being read.
+func (d *Directory) Merge(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	cols := make(map[string]int)
+	for i, name := range header {
+		if column, ok := masterColumns[headerKey(name)]; ok {
+			if _, seen := cols[column]; !seen {
+				cols[column] = i
+			}
+		}
+	}
+	_, hasPincode := cols["pincode"]
+	_, hasLat := cols["latitude"]
+	_, hasLng := cols["longitude"]
+	coordinates := hasPincode && hasLat && hasLng
+	if _, ok := cols["state"]; !ok && !coordinates {
+		return fmt.Errorf("no state column")
+	}
+	if _, ok := cols["district"]; !ok && !coordinates {
+		return fmt.Errorf("no district column")
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := cols[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		value := strings.TrimSpace(record[i])
+		if strings.EqualFold(value, "NA") || strings.EqualFold(value, "N.A.") {
+			return ""
+		}
+		return value
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if coordinates {
+			d.addCoordinates(field(record, "pincode"), field(record, "latitude"), field(record, "longitude"))
+		}
+
+		stateName, districtName := field(record, "state"), field(record, "district")
+		if stateName == "" || districtName == "" {
+			continue
+		}
+		s := d.addState(displayName(stateName))
+		dist := s.addDistrict(displayName(districtName))
+		place := Place{State: s.name, District: dist.name}
+
+		if name := field(record, "tehsil"); name != "" {
+			t := dist.addTehsil(displayName(name))
+			place.Tehsil = t.name
+
+			name := field(record, "village")
+			if name == "" {
+				name = officeSuffix.ReplaceAllString(field(record, "office"), "")
+			}
+			if name != "" {
+				v := t.addVillage(displayName(name))
+				place.Village = v.name
+				if pin := field(record, "pincode"); pincodePattern.MatchString(pin) && !slices.Contains(v.pincodes, pin) {
+					v.pincodes = append(v.pincodes, pin)
+				}
+			}
+		}
+
+		if pin := field(record, "pincode"); pincodePattern.MatchString(pin) && !slices.Contains(d.pincodes[pin], place) {
+			d.pincodes[pin] = append(d.pincodes[pin], place)
+		}
+	}
+}
+
+// addCoordinates counts a point towards the centroid of pin. Rows without a
+// usable point are common in the published files and are skipped, as are
+// points at 0,0, which stand for a missing value.
+func (d *Directory) addCoordinates(pin, lat, lng string) {
+	if !pincodePattern.MatchString(pin) {
+		return
+	}
+	var p geo.Point
+	var err error
+	if p.Lat, err = strconv.ParseFloat(lat, 64); err != nil {
+		return
+	}
+	if p.Lng, err = strconv.ParseFloat(lng, 64); err != nil {
+		return
+	}
+	if p.Validate() != nil || (p.Lat == 0 && p.Lng == 0) {
+		return
+	}
+
+	sum := d.centroids[pin]
+	sum.lat += p.Lat
+	sum.lng += p.Lng
+	sum.n++
+	d.centroids[pin] = sum
+}
+
+func headerKey(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, "(in english)", "")
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, name)
+	if name != "name" {
+		name = strings.TrimSuffix(name, "name")
+	}
+	return name
+}
+
+// displayName turns the upper case names of government files into title case.
+// Names that already mix cases are kept as they are.
+func displayName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if strings.ToUpper(name) != name {
+		return name
+	}
+	words := strings.Fields(strings.ToLower(name))
+	for i, word := range words {
+		if i > 0 && (word == "and" || word == "of") {
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
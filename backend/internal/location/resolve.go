@@ -0,0 +1,178 @@
+package location
+
+import (
+	"fmt"
+	"strings"
+
+	"backend/internal/geo"
+	"backend/pkg/errors"
+)
+
+// Place is a location at any depth of the hierarchy, from a state alone down
+// to a village and its pincode.
+type Place struct {
+	State    string `json:"state"`
+	District string `json:"district"`
+	Tehsil   string `json:"tehsil,omitempty"`
+	Village  string `json:"village,omitempty"`
+	Pincode  string `json:"pincode,omitempty"`
+}
+
+// InvalidPlaceError lists what is wrong with a place given to Resolve.
+// errors.Is matches ErrInvalidInput.
+type InvalidPlaceError struct {
+	Problems []string
+}
+
+func (e *InvalidPlaceError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+func (e *InvalidPlaceError) Is(target error) bool {
+	return target == errors.ErrInvalidInput
+}
+
+// Resolve checks p against the directory and returns it with every name in
+// its official spelling. A blank state or district is filled in from the
+// pincode when all places of the pincode agree on it, and a blank tehsil from
+// the village when only one tehsil of the district has a village of that name.
+//
+// Levels the directory has no data for are taken as given: a tehsil is only
+// checked once the district's tehsils are known, a village once the tehsil's
+// villages are, and a pincode once any pincodes are loaded.
+func (d *Directory) Resolve(p Place) (Place, error) {
+	var problems []string
+	p.Pincode = strings.TrimSpace(p.Pincode)
+
+	var served []Place
+	if p.Pincode != "" {
+		switch {
+		case !pincodePattern.MatchString(p.Pincode):
+			problems = append(problems, fmt.Sprintf("pincode %q is not a 6 digit pincode", p.Pincode))
+		case len(d.pincodes) > 0:
+			served = d.pincodes[p.Pincode]
+			if len(served) == 0 {
+				problems = append(problems, fmt.Sprintf("pincode %s is not a known pincode", p.Pincode))
+			}
+		}
+	}
+	if p.State == "" && len(served) > 0 {
+		p.State = common(served, func(place Place) string { return place.State })
+	}
+	if p.District == "" && len(served) > 0 && common(served, func(place Place) string { return place.State }) != "" {
+		p.District = common(served, func(place Place) string { return place.District })
+	}
+
+	if p.State == "" {
+		if p.District != "" || p.Tehsil != "" || p.Village != "" {
+			problems = append(problems, "state is missing")
+		}
+		return p, invalid(problems)
+	}
+	s, ok := d.states[key(p.State)]
+	if !ok {
+		problems = append(problems, fmt.Sprintf("state %q is not a known state", p.State))
+		return p, invalid(problems)
+	}
+	p.State = s.name
+
+	if p.District == "" {
+		if p.Tehsil != "" || p.Village != "" {
+			problems = append(problems, "district is missing")
+		}
+		return p, invalid(problems)
+	}
+	dist, ok := s.districts[key(p.District)]
+	if !ok {
+		problems = append(problems, fmt.Sprintf("district %q is not a known district of %s", p.District, s.name))
+		return p, invalid(problems)
+	}
+	p.District = dist.name
+
+	if len(served) > 0 && !servesDistrict(served, p) {
+		problems = append(problems, fmt.Sprintf("pincode %s is not in %s, %s", p.Pincode, p.District, p.State))
+	}
+
+	if p.Tehsil == "" && p.Village != "" {
+		p.Tehsil = dist.tehsilOf(p.Village)
+	}
+	if p.Tehsil == "" || len(dist.tehsils) == 0 {
+		return p, invalid(problems)
+	}
+	t, ok := dist.tehsils[key(p.Tehsil)]
+	if !ok {
+		problems = append(problems, fmt.Sprintf("tehsil %q is not a known tehsil of %s", p.Tehsil, dist.name))
+		return p, invalid(problems)
+	}
+	p.Tehsil = t.name
+
+	if p.Village == "" || len(t.villages) == 0 {
+		return p, invalid(problems)
+	}
+	v, ok := t.villages[key(p.Village)]
+	if !ok {
+		problems = append(problems, fmt.Sprintf("village %q is not a known village of %s", p.Village, t.name))
+		return p, invalid(problems)
+	}
+	p.Village = v.name
+
+	return p, invalid(problems)
+}
+
+// Pincode returns the places a pincode serves.
+func (d *Directory) Pincode(pincode string) []Place {
+	return d.pincodes[strings.TrimSpace(pincode)]
+}
+
+// Centroid returns the centre of the area a pincode serves, and false when no
+// directory file gave coordinates for it.
+func (d *Directory) Centroid(pincode string) (geo.Point, bool) {
+	sum, ok := d.centroids[strings.TrimSpace(pincode)]
+	if !ok {
+		return geo.Point{}, false
+	}
+	return geo.Point{Lat: sum.lat / float64(sum.n), Lng: sum.lng / float64(sum.n)}, true
+}
+
+// tehsilOf returns the tehsil of the district's village called name, or ""
+// if no tehsil or more than one has such a village.
+func (dist *district) tehsilOf(name string) string {
+	found := ""
+	for _, t := range dist.tehsils {
+		if _, ok := t.villages[key(name)]; !ok || t.name == found {
+			continue
+		}
+		if found != "" {
+			return ""
+		}
+		found = t.name
+	}
+	return found
+}
+
+func servesDistrict(served []Place, p Place) bool {
+	for _, place := range served {
+		if place.State == p.State && place.District == p.District {
+			return true
+		}
+	}
+	return false
+}
+
+// common returns the value every place has for a field, or "".
+func common(places []Place, field func(Place) string) string {
+	value := field(places[0])
+	for _, place := range places[1:] {
+		if field(place) != value {
+			return ""
+		}
+	}
+	return value
+}
+
+func invalid(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &InvalidPlaceError{Problems: problems}
+}
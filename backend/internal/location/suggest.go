@@ -0,0 +1,97 @@
+package location
+
+import (
+	"slices"
+	"strings"
+)
+
+// SuggestStates returns up to limit state names matching query, see suggest.
+func (d *Directory) SuggestStates(query string, limit int) []string {
+	return suggest(d.states, func(s *state) string { return s.name }, query, limit)
+}
+
+// SuggestDistricts returns up to limit districts of a state matching query.
+// It returns false if the state is unknown.
+func (d *Directory) SuggestDistricts(stateName, query string, limit int) ([]string, bool) {
+	s, ok := d.states[key(stateName)]
+	if !ok {
+		return nil, false
+	}
+	return suggest(s.districts, func(dist *district) string { return dist.name }, query, limit), true
+}
+
+// SuggestTehsils returns up to limit tehsils of a district matching query. It
+// returns false if the district is unknown.
+func (d *Directory) SuggestTehsils(stateName, districtName, query string, limit int) ([]string, bool) {
+	_, dist := d.district(stateName, districtName)
+	if dist == nil {
+		return nil, false
+	}
+	return suggest(dist.tehsils, func(t *tehsil) string { return t.name }, query, limit), true
+}
+
+// SuggestVillages returns up to limit villages of a tehsil matching query. It
+// returns false if the tehsil is unknown.
+func (d *Directory) SuggestVillages(stateName, districtName, tehsilName, query string, limit int) ([]string, bool) {
+	_, dist := d.district(stateName, districtName)
+	if dist == nil {
+		return nil, false
+	}
+	t, ok := dist.tehsils[key(tehsilName)]
+	if !ok {
+		return nil, false
+	}
+	return suggest(t.villages, func(v *village) string { return v.name }, query, limit), true
+}
+
+// SuggestPincodes returns up to limit known pincodes starting with prefix.
+func (d *Directory) SuggestPincodes(prefix string, limit int) []string {
+	prefix = strings.TrimSpace(prefix)
+	var pincodes []string
+	for pincode := range d.pincodes {
+		if strings.HasPrefix(pincode, prefix) {
+			pincodes = append(pincodes, pincode)
+		}
+	}
+	slices.Sort(pincodes)
+	return pincodes[:min(limit, len(pincodes))]
+}
+
+// suggest matches query against the keys of places, aliases included, and
+// returns the official names of the matches: names starting with the query
+// first, then names with a later word starting with it, each group in
+// alphabetical order.
+func suggest[T any](places map[string]T, name func(T) string, query string, limit int) []string {
+	q := key(query)
+	// rank is 0 for names starting with the query and 1 for names with a
+	// later word starting with it; a name matched through several keys
+	// keeps its best rank.
+	rank := make(map[string]int)
+	for k, place := range places {
+		r := -1
+		switch {
+		case strings.HasPrefix(k, q):
+			r = 0
+		case strings.Contains(k, " "+q):
+			r = 1
+		}
+		if r < 0 {
+			continue
+		}
+		if best, ok := rank[name(place)]; !ok || r < best {
+			rank[name(place)] = r
+		}
+	}
+
+	names := make([]string, 0, len(rank))
+	for n := range rank {
+		names = append(names, n)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if rank[a] != rank[b] {
+			return rank[a] - rank[b]
+		}
+		return strings.Compare(a, b)
+	})
+	return names[:min(limit, len(names))]
+}
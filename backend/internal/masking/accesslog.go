@@ -0,0 +1,49 @@
+package masking
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AccessEntry is one unmasked read of personal data.
+type AccessEntry struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Role      string    `json:"role"`
+	RequestID string    `json:"requestId,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Fields    []string  `json:"fields"`
+	IDs       []string  `json:"ids,omitempty"`
+}
+
+// AccessLog writes AccessEntries as JSON lines, apart from the application
+// log so it can be kept and shipped under its own retention.
+type AccessLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// OpenAccessLog appends to the access log at path, creating it if needed.
+func OpenAccessLog(path string) (*AccessLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewAccessLog(file), nil
+}
+
+func NewAccessLog(out io.Writer) *AccessLog {
+	return &AccessLog{enc: json.NewEncoder(out)}
+}
+
+// Record writes one entry.
+func (l *AccessLog) Record(entry AccessEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(entry)
+}
@@ -0,0 +1,328 @@
+// Package masking hides personal data in JSON payloads and CSV exports from
+// callers whose role may not see it, and logs who saw it unmasked. Fields are
+// picked by their JSON name or CSV column wherever they appear, so a farmer's
+// contact is masked alike in a farmer, in a CCE's list of farmers, in an
+// overview or in an export.
+package masking
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Styles a field can be masked in.
+const (
+	// StylePhone masks a phone number down to its first and last two digits,
+	// "+919876543221" becoming "98xxxxxx21".
+	StylePhone = "phone"
+	// StyleText keeps the first letter of every word, "Near the temple"
+	// becoming "N*** t** t*****".
+	StyleText = "text"
+	// StylePhones masks the phone numbers inside free text, such as a ticket
+	// description, and leaves the rest of the text alone.
+	StylePhones = "phones"
+)
+
+// phonePattern finds phone numbers in free text: ten digits or more, possibly
+// grouped by spaces or dashes.
+var phonePattern = regexp.MustCompile(`\+?\d[\d -]{8,}\d`)
+
+// maskedPattern finds the phone numbers StylePhone and StylePhones leave.
+var maskedPattern = regexp.MustCompile(`\d{0,2}xxxx+\d{0,2}`)
+
+// Masker masks the configured fields for the configured roles.
+type Masker struct {
+	// fields maps lower case JSON field names to their style.
+	fields map[string]string
+	roles  map[string]bool
+}
+
+// New returns a Masker masking fields, a map of JSON field name to style, for
+// callers holding one of roles. Field names match case-insensitively.
+func New(fields map[string]string, roles []string) (*Masker, error) {
+	m := &Masker{fields: make(map[string]string), roles: make(map[string]bool)}
+	for field, style := range fields {
+		switch style {
+		case StylePhone, StyleText, StylePhones:
+		default:
+			return nil, fmt.Errorf("field %s: unknown masking style %q", field, style)
+		}
+		m.fields[strings.ToLower(field)] = style
+	}
+	for _, role := range roles {
+		m.roles[role] = true
+	}
+	return m, nil
+}
+
+// Masks reports whether callers with role see masked payloads.
+func (m *Masker) Masks(role string) bool {
+	return m.roles[role]
+}
+
+// Style returns the style field is masked in, and whether it is masked at all.
+func (m *Masker) Style(field string) (string, bool) {
+	style, ok := m.fields[strings.ToLower(field)]
+	return style, ok
+}
+
+// Exposure lists the personal data a payload carried unmasked.
+type Exposure struct {
+	// Fields are the masked fields present, sorted.
+	Fields []string
+	// IDs are the "id" of the objects holding them, in payload order.
+	IDs []string
+}
+
+// Empty reports whether the payload carried no personal data.
+func (e *Exposure) Empty() bool {
+	return len(e.Fields) == 0
+}
+
+// MaskJSON returns payload with the configured fields masked. Payloads that
+// hold none of them come back unchanged.
+func (m *Masker) MaskJSON(payload []byte) ([]byte, error) {
+	value, err := decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	m.walk(value, func(object map[string]interface{}, key, style, s string) {
+		object[key] = Mask(style, s)
+		changed = true
+	})
+	if !changed {
+		return payload, nil
+	}
+	return json.Marshal(value)
+}
+
+// Inspect returns the personal data payload carries.
+func (m *Masker) Inspect(payload []byte) (*Exposure, error) {
+	value, err := decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	exposure := &Exposure{}
+	fields := make(map[string]bool)
+	ids := make(map[string]bool)
+	m.walk(value, func(object map[string]interface{}, key, style, s string) {
+		fields[strings.ToLower(key)] = true
+		if id, ok := object["id"].(string); ok && id != "" && !ids[id] {
+			ids[id] = true
+			exposure.IDs = append(exposure.IDs, id)
+		}
+	})
+	for field := range fields {
+		exposure.Fields = append(exposure.Fields, field)
+	}
+	sort.Strings(exposure.Fields)
+	return exposure, nil
+}
+
+// StripMasked drops the configured fields that hold masked values from
+// payload, so a record read masked and sent back does not overwrite the real
+// values with masked ones.
+func (m *Masker) StripMasked(payload []byte) ([]byte, error) {
+	value, err := decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	m.walk(value, func(object map[string]interface{}, key, style, s string) {
+		if masked(style, s) {
+			delete(object, key)
+			changed = true
+		}
+	})
+	if !changed {
+		return payload, nil
+	}
+	return json.Marshal(value)
+}
+
+// MaskCSV returns a CSV payload with the columns named like the configured
+// fields masked. The first record is taken as the header.
+func (m *Masker) MaskCSV(payload []byte) ([]byte, error) {
+	records, styles, err := m.readCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(styles) == 0 {
+		return payload, nil
+	}
+	for _, record := range records[1:] {
+		for column, style := range styles {
+			if column < len(record) && record[column] != "" {
+				record[column] = Mask(style, record[column])
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	writer.WriteAll(records)
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// InspectCSV is Inspect for a CSV payload. The ID of a row is its "id"
+// column, or its "farmer_id" column when there is none.
+func (m *Masker) InspectCSV(payload []byte) (*Exposure, error) {
+	records, styles, err := m.readCSV(payload)
+	if err != nil {
+		return nil, err
+	}
+	exposure := &Exposure{}
+	if len(records) == 0 {
+		return exposure, nil
+	}
+	header := records[0]
+	idColumn := slices.IndexFunc(header, func(name string) bool { return strings.EqualFold(name, "id") })
+	if idColumn < 0 {
+		idColumn = slices.IndexFunc(header, func(name string) bool { return strings.EqualFold(name, "farmer_id") })
+	}
+
+	fields := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, record := range records[1:] {
+		exposed := false
+		for column := range styles {
+			if column < len(record) && record[column] != "" {
+				fields[strings.ToLower(header[column])] = true
+				exposed = true
+			}
+		}
+		if exposed && idColumn >= 0 && idColumn < len(record) {
+			if id := record[idColumn]; id != "" && !ids[id] {
+				ids[id] = true
+				exposure.IDs = append(exposure.IDs, id)
+			}
+		}
+	}
+	for field := range fields {
+		exposure.Fields = append(exposure.Fields, field)
+	}
+	sort.Strings(exposure.Fields)
+	return exposure, nil
+}
+
+// readCSV reads a CSV payload and returns its records with the styles of the
+// masked columns, by column.
+func (m *Masker) readCSV(payload []byte) ([][]string, map[int]string, error) {
+	reader := csv.NewReader(bytes.NewReader(payload))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	styles := make(map[int]string)
+	if len(records) > 0 {
+		for column, name := range records[0] {
+			if style, ok := m.Style(name); ok {
+				styles[column] = style
+			}
+		}
+	}
+	return records, styles, nil
+}
+
+// walk calls visit for every non-empty string held by a configured field.
+func (m *Masker) walk(value interface{}, visit func(object map[string]interface{}, key, style, s string)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if s, ok := child.(string); ok && s != "" {
+				if style, ok := m.fields[strings.ToLower(key)]; ok {
+					visit(v, key, style, s)
+				}
+				continue
+			}
+			m.walk(child, visit)
+		}
+	case []interface{}:
+		for _, child := range v {
+			m.walk(child, visit)
+		}
+	}
+}
+
+// Mask masks s in style.
+func Mask(style, s string) string {
+	switch style {
+	case StylePhone:
+		return maskPhone(s)
+	case StyleText:
+		return maskText(s)
+	case StylePhones:
+		return phonePattern.ReplaceAllStringFunc(s, maskPhone)
+	}
+	return s
+}
+
+// maskPhone keeps the first and last two digits of the national number.
+func maskPhone(s string) string {
+	var digits []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			digits = append(digits, s[i])
+		}
+	}
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	if len(digits) < 6 {
+		return strings.Repeat("x", len(digits))
+	}
+	for i := 2; i < len(digits)-2; i++ {
+		digits[i] = 'x'
+	}
+	return string(digits)
+}
+
+func maskText(s string) string {
+	var b strings.Builder
+	first := true
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r) || unicode.IsPunct(r):
+			b.WriteRune(r)
+			first = true
+		case first:
+			b.WriteRune(r)
+			first = false
+		default:
+			b.WriteRune('*')
+		}
+	}
+	return b.String()
+}
+
+// masked reports whether s looks like the output of Mask in style.
+func masked(style, s string) bool {
+	switch style {
+	case StyleText:
+		return strings.Contains(s, "*")
+	default:
+		return maskedPattern.MatchString(s)
+	}
+}
+
+func decode(payload []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
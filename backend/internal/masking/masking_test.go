@@ -0,0 +1,164 @@
+package masking
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMasker(t *testing.T) *Masker {
+	t.Helper()
+	masker, err := New(map[string]string{
+		"contact":     StylePhone,
+		"Address":     StyleText,
+		"description": StylePhones,
+	}, []string{"temp_cce", "report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return masker
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		style, in, want string
+	}{
+		{style: StylePhone, in: "+919876543221", want: "98xxxxxx21"},
+		{style: StylePhone, in: "98765", want: "xxxxx"},
+		{style: StyleText, in: "Near the temple", want: "N*** t** t*****"},
+		{style: StylePhones, in: "Call 98765 43221 after 5", want: "Call 98xxxxxx21 after 5"},
+		{style: StylePhones, in: "no number here", want: "no number here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style+"/"+tt.in, func(t *testing.T) {
+			if got := Mask(tt.style, tt.in); got != tt.want {
+				t.Fatalf("Mask(%q, %q) = %q, want %q", tt.style, tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRefusesUnknownStyle(t *testing.T) {
+	if _, err := New(map[string]string{"contact": "hash"}, nil); err == nil {
+		t.Fatal("New accepted an unknown style")
+	}
+}
+
+func TestMasks(t *testing.T) {
+	masker := newTestMasker(t)
+	for role, want := range map[string]bool{"temp_cce": true, "report": true, "cce": false, "admin": false} {
+		if got := masker.Masks(role); got != want {
+			t.Errorf("Masks(%q) = %v, want %v", role, got, want)
+		}
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	masker := newTestMasker(t)
+	payload := []byte(`{"id":"f1","name":"Ramesh","contact":"+919876543221","nested":[{"id":"t1","description":"call 9876543221"}],"address":""}`)
+
+	got, err := masker.MaskJSON(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shaped map[string]interface{}
+	if err := json.Unmarshal(got, &shaped); err != nil {
+		t.Fatal(err)
+	}
+	if shaped["contact"] != "98xxxxxx21" || shaped["name"] != "Ramesh" || shaped["address"] != "" {
+		t.Errorf("masked farmer = %s", got)
+	}
+	if description := shaped["nested"].([]interface{})[0].(map[string]interface{})["description"]; description != "call 98xxxxxx21" {
+		t.Errorf("masked description = %v", description)
+	}
+
+	plain := []byte(`{"id":"c1","name":"Asha"}`)
+	if got, err := masker.MaskJSON(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("MaskJSON of a payload without PII = %s, %v; want it unchanged", got, err)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	masker := newTestMasker(t)
+	exposure, err := masker.Inspect([]byte(`[{"id":"f1","contact":"+919876543221"},{"id":"f2","Address":"Ozar"},{"id":"f3","contact":""}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(exposure.Fields, []string{"address", "contact"}) || !slices.Equal(exposure.IDs, []string{"f1", "f2"}) {
+		t.Errorf("exposure = %+v, want address and contact of f1 and f2", exposure)
+	}
+}
+
+func TestStripMasked(t *testing.T) {
+	masker := newTestMasker(t)
+	got, err := masker.StripMasked([]byte(`{"id":"f1","contact":"98xxxxxx21","address":"Near the temple"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stripped map[string]interface{}
+	if err := json.Unmarshal(got, &stripped); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stripped["contact"]; ok || stripped["address"] != "Near the temple" {
+		t.Errorf("stripped = %s, want the masked contact dropped and the address kept", got)
+	}
+}
+
+func TestMaskCSV(t *testing.T) {
+	masker := newTestMasker(t)
+	payload := []byte("timestamp,farmer_id,contact,channel\n2026-03-10T05:30:00Z,f1,+919876543221,call\n2026-03-10T06:00:00Z,f2,,sms\n")
+
+	got, err := masker.MaskCSV(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "timestamp,farmer_id,contact,channel\n2026-03-10T05:30:00Z,f1,98xxxxxx21,call\n2026-03-10T06:00:00Z,f2,,sms\n"
+	if string(got) != want {
+		t.Errorf("MaskCSV = %q, want %q", got, want)
+	}
+
+	exposure, err := masker.InspectCSV(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(exposure.Fields, []string{"contact"}) || !slices.Equal(exposure.IDs, []string{"f1"}) {
+		t.Errorf("exposure = %+v, want the contact of f1", exposure)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	log := NewAccessLog(&out)
+	entry := AccessEntry{
+		Time:   time.Date(2026, 3, 10, 5, 30, 0, 0, time.UTC),
+		Actor:  "u1",
+		Role:   "cce",
+		Method: "GET",
+		Path:   "/farmers/f1",
+		Status: 200,
+		Fields: []string{"contact"},
+		IDs:    []string{"f1"},
+	}
+	if err := log.Record(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Record(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("access log holds %d lines, want 2: %q", len(lines), out.String())
+	}
+	var got AccessEntry
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(entry.Time) || got.Actor != "u1" || got.Path != "/farmers/f1" || !slices.Equal(got.IDs, []string{"f1"}) {
+		t.Errorf("logged entry = %+v, want %+v", got, entry)
+	}
+}
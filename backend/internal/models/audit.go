@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AuditRecord is one change made to a farmer, ticket or CCE.
+type AuditRecord struct {
+	ID string `json:"id" dynamodbav:"ID"`
+	// EntityKey is Entity and EntityID joined by '#', the hash key of the
+	// index the history of an entity is read from.
+	EntityKey string        `json:"-" dynamodbav:"EntityKey"`
+	Entity    string        `json:"entity" dynamodbav:"Entity"`
+	EntityID  string        `json:"entityId" dynamodbav:"EntityID"`
+	Action    string        `json:"action" dynamodbav:"Action"`
+	Actor     string        `json:"actor" dynamodbav:"Actor"`
+	RequestID string        `json:"requestId,omitempty" dynamodbav:"RequestID,omitempty"`
+	Timestamp time.Time     `json:"timestamp" dynamodbav:"Timestamp"`
+	Changes   []FieldChange `json:"changes,omitempty" dynamodbav:"Changes,omitempty"`
+}
+
+// FieldChange is the old and new value of one field. Old is nil for fields
+// that were set for the first time and New is nil for fields that were cleared.
+type FieldChange struct {
+	Field string      `json:"field" dynamodbav:"Field"`
+	Old   interface{} `json:"old" dynamodbav:"Old"`
+	New   interface{} `json:"new" dynamodbav:"New"`
+}
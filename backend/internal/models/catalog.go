@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Seasons a variety can be recommended for.
+const (
+	SeasonKharif = "kharif"
+	SeasonRabi   = "rabi"
+	SeasonZaid   = "zaid"
+)
+
+// Kinds of variety.
+const (
+	VarietyOpenPollinated = "variety"
+	VarietyHybrid         = "hybrid"
+)
+
+// Crop is a crop of the product catalog. Its ID is a short name such as
+// "onion" or "bitter-gourd", which farmers' crop lists and tickets hold.
+type Crop struct {
+	ID   string `json:"id" dynamodbav:"ID"`
+	Name string `json:"name" dynamodbav:"Name"`
+	// Aliases are other names the crop goes by, such as "kanda" or "paddy",
+	// which resolve to it like its name does.
+	Aliases   []string  `json:"aliases,omitempty" dynamodbav:"Aliases,omitempty"`
+	CreatedAt time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	Version   int64     `json:"version" dynamodbav:"Version"`
+}
+
+// Variety is a seed variety or hybrid of a crop, sold in the packs of its
+// SKUs.
+type Variety struct {
+	ID     string `json:"id" dynamodbav:"ID"`
+	CropID string `json:"cropId" dynamodbav:"CropID"`
+	Name   string `json:"name" dynamodbav:"Name"`
+	Kind   string `json:"kind" dynamodbav:"Kind"`
+	// Seasons are the seasons the variety is recommended for.
+	Seasons []string `json:"seasons" dynamodbav:"Seasons"`
+	// Regions are the states, or "state/district", the variety is
+	// recommended for; none means everywhere.
+	Regions   []string  `json:"regions,omitempty" dynamodbav:"Regions,omitempty"`
+	SKUs      []SKU     `json:"skus,omitempty" dynamodbav:"SKUs,omitempty"`
+	CreatedAt time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	Version   int64     `json:"version" dynamodbav:"Version"`
+}
+
+// SKU is one pack of a variety as it is sold, such as a 450 g packet.
+type SKU struct {
+	Code string `json:"code" dynamodbav:"Code"`
+	// PackSize is counted in PackUnit: "g", "kg" or "seeds".
+	PackSize     float64 `json:"packSize" dynamodbav:"PackSize"`
+	PackUnit     string  `json:"packUnit" dynamodbav:"PackUnit"`
+	Discontinued bool    `json:"discontinued,omitempty" dynamodbav:"Discontinued,omitempty"`
+}
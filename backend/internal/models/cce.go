@@ -1,9 +1,21 @@
 package models
 
+import "time"
+
 type CCE struct {
-	ID      string   `json:"id" dynamodbav:"id"`
-	Name    string   `json:"name" dynamodbav:"name"`
-	Farmers []Farmer `json:"farmers" dynamodbav:"farmers"`
-	Tickets []Ticket `json:"tickets" dynamodbav:"tickets"`
-	AvgTime float64  `json:"avgTime" dynamodbav:"avgTime"`
+	ID      string   `json:"id" dynamodbav:"ID"`
+	Name    string   `json:"name" dynamodbav:"Name"`
+	Farmers []Farmer `json:"farmers" dynamodbav:"Farmers"`
+	Tickets []Ticket `json:"tickets" dynamodbav:"Tickets"`
+	// AvgTime is the average time in seconds the CCE took to close a ticket.
+	AvgTime float64 `json:"avgTime" dynamodbav:"AvgTime"`
+	// TicketIDs, OpenTickets, ClosedTickets and ResolvedSeconds are kept in
+	// step with the tickets assigned to the CCE whenever a ticket is written.
+	TicketIDs       []string   `json:"ticketIds" dynamodbav:"TicketIDs,stringset,omitempty"`
+	OpenTickets     int64      `json:"openTickets" dynamodbav:"OpenTickets"`
+	ClosedTickets   int64      `json:"closedTickets" dynamodbav:"ClosedTickets"`
+	ResolvedSeconds int64      `json:"resolvedSeconds" dynamodbav:"ResolvedSeconds"`
+	Version         int64      `json:"version" dynamodbav:"Version"`
+	DeletedAt       *time.Time `json:"deletedAt,omitempty" dynamodbav:"DeletedAt,omitempty"`
+	DeletedBy       string     `json:"deletedBy,omitempty" dynamodbav:"DeletedBy,omitempty"`
 }
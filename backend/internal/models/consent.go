@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Outreach channels. They match the Type of the shoots made on them.
+const (
+	ChannelCall     = "call"
+	ChannelWhatsApp = "whatsapp"
+)
+
+// Channels lists every outreach channel.
+var Channels = []string{ChannelCall, ChannelWhatsApp}
+
+// Consent statuses. A channel without consent on record counts as not
+// granted.
+const (
+	ConsentGranted = "granted"
+	ConsentRevoked = "revoked"
+)
+
+// ChannelConsent is whether a farmer currently agrees to be reached on one
+// channel, and how that was recorded.
+type ChannelConsent struct {
+	Status string `json:"status" dynamodbav:"Status"`
+	// Source is where the consent was given or withdrawn, such as
+	// "registration-form", "ivr" or "whatsapp-stop".
+	Source    string    `json:"source" dynamodbav:"Source"`
+	Reason    string    `json:"reason,omitempty" dynamodbav:"Reason,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	UpdatedBy string    `json:"updatedBy" dynamodbav:"UpdatedBy"`
+}
+
+// ConsentEvent is one change of a farmer's consent. Events are kept after
+// the consent changes again, as the record compliance reports are built from.
+type ConsentEvent struct {
+	// ID starts with the timestamp so IDs sort in the order events happened.
+	ID       string `json:"id" dynamodbav:"ID"`
+	FarmerID string `json:"farmerId" dynamodbav:"FarmerID"`
+	// Contact is the farmer's contact number when the event happened.
+	Contact   string    `json:"contact" dynamodbav:"Contact"`
+	Channel   string    `json:"channel" dynamodbav:"Channel"`
+	Status    string    `json:"status" dynamodbav:"Status"`
+	Source    string    `json:"source" dynamodbav:"Source"`
+	Reason    string    `json:"reason,omitempty" dynamodbav:"Reason,omitempty"`
+	Actor     string    `json:"actor" dynamodbav:"Actor"`
+	Timestamp time.Time `json:"timestamp" dynamodbav:"Timestamp"`
+	// PII holds Contact once it is encrypted for storage, in which case the
+	// stored Contact is its blind index.
+	PII *SealedPII `json:"-" dynamodbav:"PII,omitempty"`
+}
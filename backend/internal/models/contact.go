@@ -0,0 +1,54 @@
+package models
+
+// Relationships of a contact to the farmer. RelationshipSelf is the farmer's
+// own number.
+const (
+	RelationshipSelf     = "self"
+	RelationshipSpouse   = "spouse"
+	RelationshipSon      = "son"
+	RelationshipDaughter = "daughter"
+	RelationshipParent   = "parent"
+	RelationshipSibling  = "sibling"
+	RelationshipRelative = "relative"
+	RelationshipOther    = "other"
+)
+
+// Relationships lists every relationship a contact may have.
+var Relationships = []string{
+	RelationshipSelf, RelationshipSpouse, RelationshipSon, RelationshipDaughter,
+	RelationshipParent, RelationshipSibling, RelationshipRelative, RelationshipOther,
+}
+
+// FarmerContact is a number a farmer is reached on, their own or that of a
+// household member who answers for them. Every number is claimed in the
+// contact index, so it resolves to its farmer like Farmer.Contact does.
+type FarmerContact struct {
+	// Number is in E.164 form.
+	Number string `json:"number" dynamodbav:"Number"`
+	// Name is who answers on the number, when it is not the farmer.
+	Name         string `json:"name,omitempty" dynamodbav:"Name,omitempty"`
+	Relationship string `json:"relationship" dynamodbav:"Relationship"`
+	// Language is the language to speak on the number, such as "mr" or "hi".
+	Language string `json:"language,omitempty" dynamodbav:"Language,omitempty"`
+	// Primary marks the number mirrored in Farmer.Contact. Exactly one
+	// contact of a farmer is primary.
+	Primary bool `json:"primary" dynamodbav:"Primary"`
+}
+
+// ContactNumbers returns every number the farmer holds: Contact and the
+// numbers of Contacts, without repeats.
+func (f *Farmer) ContactNumbers() []string {
+	var numbers []string
+	seen := make(map[string]bool, len(f.Contacts)+1)
+	add := func(number string) {
+		if number != "" && !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+	add(f.Contact)
+	for _, contact := range f.Contacts {
+		add(contact.Number)
+	}
+	return numbers
+}
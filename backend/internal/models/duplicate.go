@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Duplicate candidate statuses.
+const (
+	DuplicateStatusPending   = "pending"
+	DuplicateStatusMerged    = "merged"
+	DuplicateStatusDismissed = "dismissed"
+)
+
+// DuplicateCandidate is a pair of farmers that are likely the same person,
+// waiting for a supervisor to merge or dismiss them.
+type DuplicateCandidate struct {
+	// ID is the two farmer IDs in ascending order joined by '|', so a pair is
+	// queued only once whichever way round it was found.
+	ID            string     `json:"id" dynamodbav:"ID"`
+	FarmerID      string     `json:"farmerId" dynamodbav:"FarmerID"`
+	OtherFarmerID string     `json:"otherFarmerId" dynamodbav:"OtherFarmerID"`
+	Score         float64    `json:"score" dynamodbav:"Score"`
+	Reasons       []string   `json:"reasons" dynamodbav:"Reasons"`
+	Status        string     `json:"status" dynamodbav:"Status"`
+	CreatedAt     time.Time  `json:"createdAt" dynamodbav:"CreatedAt"`
+	ReviewedBy    string     `json:"reviewedBy,omitempty" dynamodbav:"ReviewedBy,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewedAt,omitempty" dynamodbav:"ReviewedAt,omitempty"`
+}
+
+// DuplicatePairID returns the ID of the candidate for the farmers a and b.
+func DuplicatePairID(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Erased replaces personal data wiped by an erasure, wherever a value has to
+// stay behind.
+const Erased = "[erased]"
+
+// ErasureCertificate attests that a farmer's personal data was erased. It is
+// signed with Ed25519 over its JSON form with Signature left empty, so it can
+// be checked with the public key alone.
+type ErasureCertificate struct {
+	ID          string    `json:"id"`
+	FarmerID    string    `json:"farmerId"`
+	Reason      string    `json:"reason,omitempty"`
+	RequestedBy string    `json:"requestedBy"`
+	ErasedAt    time.Time `json:"erasedAt"`
+	// Fields are the farmer fields that were wiped.
+	Fields []string `json:"fields"`
+	// Tickets, ConsentEvents and AuditRecords count the records of the
+	// farmer that had personal data wiped.
+	Tickets       int `json:"tickets"`
+	ConsentEvents int `json:"consentEvents"`
+	AuditRecords  int `json:"auditRecords"`
+	// KeyID names the key that signed the certificate.
+	KeyID     string `json:"keyId"`
+	Signature []byte `json:"signature,omitempty"`
+}
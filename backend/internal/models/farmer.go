@@ -1,15 +1,46 @@
 package models
 
+import "time"
+
 type Farmer struct {
-    ID       string   `json:"id" dynamodbav:"id"`
-    Name     string   `json:"name" dynamodbav:"name"`
-    Contact  string   `json:"contact" dynamodbav:"contact"`
-    State    string   `json:"state" dynamodbav:"state"`
-    District string   `json:"district" dynamodbav:"district"`
-    Tehsil   string   `json:"tehsil" dynamodbav:"tehsil"`
-    Village  string   `json:"village" dynamodbav:"village"`
-    Pincode  string   `json:"pincode" dynamodbav:"pincode"`
-    Address  string   `json:"address" dynamodbav:"address"`
-    Tag      string   `json:"tag" dynamodbav:"tag"`
-    Crop     []string `json:"crop" dynamodbav:"crop"`
+    ID        string     `json:"id" dynamodbav:"ID"`
+    Name      string     `json:"name" dynamodbav:"Name"`
+    // Contact is the primary number. Contacts, when set, holds it too along
+    // with the numbers of household members.
+    Contact   string     `json:"contact" dynamodbav:"Contact"`
+    Contacts  []FarmerContact `json:"contacts,omitempty" dynamodbav:"Contacts,omitempty"`
+    State     string     `json:"state" dynamodbav:"State"`
+    District  string     `json:"district" dynamodbav:"District"`
+    Tehsil    string     `json:"tehsil" dynamodbav:"Tehsil"`
+    Village   string     `json:"village" dynamodbav:"Village"`
+    Pincode   string     `json:"pincode" dynamodbav:"Pincode"`
+    Address   string     `json:"address" dynamodbav:"Address"`
+    Tag       string     `json:"tag" dynamodbav:"Tag"`
+    // Coordinates default to the centroid of the pincode. GeoHash and its
+    // first GeoCellLength characters, GeoCell, key the geo index and follow
+    // the coordinates.
+    Coordinates *GeoPoint `json:"coordinates,omitempty" dynamodbav:"Coordinates,omitempty"`
+    GeoHash   string     `json:"geohash,omitempty" dynamodbav:"GeoHash,omitempty"`
+    GeoCell   string     `json:"-" dynamodbav:"GeoCell,omitempty"`
+    // Crop holds the IDs of catalog crops. Farmers saved before the catalog
+    // may still hold free text, until their crops are changed or linked.
+    Crop      []string   `json:"crop" dynamodbav:"Crop,stringset,omitempty"`
+    // Consent is keyed by channel. It is only changed through ConsentService,
+    // which keeps the history alongside.
+    Consent   map[string]ChannelConsent `json:"consent,omitempty" dynamodbav:"Consent,omitempty"`
+    CreatedAt time.Time  `json:"createdAt" dynamodbav:"CreatedAt"`
+    UpdatedAt time.Time  `json:"updatedAt" dynamodbav:"UpdatedAt"`
+    Version   int64      `json:"version" dynamodbav:"Version"`
+    DeletedAt *time.Time `json:"deletedAt,omitempty" dynamodbav:"DeletedAt,omitempty"`
+    DeletedBy string     `json:"deletedBy,omitempty" dynamodbav:"DeletedBy,omitempty"`
+    // MergedInto is set once the farmer was merged into another as a
+    // duplicate. The record is kept so its ID still resolves to the survivor.
+    MergedInto string    `json:"mergedInto,omitempty" dynamodbav:"MergedInto,omitempty"`
+    // ErasedAt is set once the farmer's personal data was erased. The record
+    // stays, anonymised, so tickets and shoots still point at a farmer.
+    ErasedAt  *time.Time `json:"erasedAt,omitempty" dynamodbav:"ErasedAt,omitempty"`
+    // PII holds Name, Address and the contact numbers once they are
+    // encrypted for storage. A stored farmer has them blanked, and its
+    // numbers replaced by their blind index, see pii.Cipher.
+    PII       *SealedPII `json:"-" dynamodbav:"PII,omitempty"`
 }
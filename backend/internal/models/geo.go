@@ -0,0 +1,21 @@
+package models
+
+// Sources of a GeoPoint.
+const (
+	// GeoSourcePincode is the centroid of the pincode, from the location
+	// master. It is moved along when the pincode changes.
+	GeoSourcePincode = "pincode"
+	// GeoSourceManual is a point given by hand or taken on a field visit.
+	GeoSourceManual = "manual"
+)
+
+// GeoCellLength is the length of the geohash prefix that partitions the geo
+// index, cells of about 40 by 20 km.
+const GeoCellLength = 4
+
+// GeoPoint places a farmer or a partner on the map, in decimal degrees.
+type GeoPoint struct {
+	Lat    float64 `json:"lat" dynamodbav:"Lat"`
+	Lng    float64 `json:"lng" dynamodbav:"Lng"`
+	Source string  `json:"source" dynamodbav:"Source"`
+}
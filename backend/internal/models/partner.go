@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Kinds of partner.
+const (
+	PartnerDealer     = "dealer"
+	PartnerAgronomist = "agronomist"
+)
+
+// Partner is a dealer or agronomist farmers can be sent to.
+type Partner struct {
+	ID       string `json:"id" dynamodbav:"ID"`
+	Kind     string `json:"kind" dynamodbav:"Kind"`
+	Name     string `json:"name" dynamodbav:"Name"`
+	Contact  string `json:"contact" dynamodbav:"Contact"`
+	State    string `json:"state" dynamodbav:"State"`
+	District string `json:"district" dynamodbav:"District"`
+	Tehsil   string `json:"tehsil" dynamodbav:"Tehsil"`
+	Village  string `json:"village" dynamodbav:"Village"`
+	Pincode  string `json:"pincode" dynamodbav:"Pincode"`
+	Address  string `json:"address" dynamodbav:"Address"`
+	// Coordinates are needed to be found as the nearest partner.
+	Coordinates *GeoPoint `json:"coordinates,omitempty" dynamodbav:"Coordinates,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt   time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	Version     int64     `json:"version" dynamodbav:"Version"`
+}
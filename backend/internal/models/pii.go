@@ -0,0 +1,11 @@
+package models
+
+// SealedPII is personal data under envelope encryption: the data is encrypted
+// with a data key, and the data key is stored alongside, encrypted with a
+// master key of the key provider that only the provider can decrypt.
+type SealedPII struct {
+	// KeyID names the master key DataKey is encrypted with.
+	KeyID      string `dynamodbav:"KeyID"`
+	DataKey    []byte `dynamodbav:"DataKey"`
+	Ciphertext []byte `dynamodbav:"Ciphertext"`
+}
@@ -2,12 +2,27 @@ package models
 
 import "time"
 
+// Ticket statuses. Any status other than closed counts as open.
+const (
+	TicketStatusOpen   = "open"
+	TicketStatusClosed = "closed"
+)
+
 type Ticket struct {
-	ID          string    `json:"id" dynamodbav:"ID"`
-	FarmerID    string    `json:"farmerId" dynamodbav:"FarmerID"`
-	CCEID       string    `json:"cceId" dynamodbav:"CCEID"`
-	Description string    `json:"description" dynamodbav:"Description"`
-	Status      string    `json:"status" dynamodbav:"Status"`
-	CreatedAt   time.Time `json:"createdAt" dynamodbav:"CreatedAt"`
-	UpdatedAt   time.Time `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	ID          string `json:"id" dynamodbav:"ID"`
+	FarmerID    string `json:"farmerId" dynamodbav:"FarmerID"`
+	CCEID       string `json:"cceId" dynamodbav:"CCEID"`
+	Description string `json:"description" dynamodbav:"Description"`
+	// CropID, VarietyID and SKU name the catalog product the ticket is
+	// about, as far as it is known.
+	CropID    string     `json:"cropId,omitempty" dynamodbav:"CropID,omitempty"`
+	VarietyID string     `json:"varietyId,omitempty" dynamodbav:"VarietyID,omitempty"`
+	SKU       string     `json:"sku,omitempty" dynamodbav:"SKU,omitempty"`
+	Status    string     `json:"status" dynamodbav:"Status"`
+	CreatedAt time.Time  `json:"createdAt" dynamodbav:"CreatedAt"`
+	UpdatedAt time.Time  `json:"updatedAt" dynamodbav:"UpdatedAt"`
+	ClosedAt  *time.Time `json:"closedAt,omitempty" dynamodbav:"ClosedAt,omitempty"`
+	Version   int64      `json:"version" dynamodbav:"Version"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" dynamodbav:"DeletedAt,omitempty"`
+	DeletedBy string     `json:"deletedBy,omitempty" dynamodbav:"DeletedBy,omitempty"`
 }
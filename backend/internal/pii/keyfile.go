@@ -0,0 +1,142 @@
+package pii
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Keyfile is a KeyProvider keeping its master keys in a local file, for
+// development. Each line holds a key ID and a base64 256 bit key; the last
+// key is the current one and the earlier ones stay to decrypt older data
+// keys. Blank lines and lines starting with '#' are skipped.
+type Keyfile struct {
+	path string
+
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	current string
+}
+
+// LoadKeyfile reads the master keys at path. A missing file is created with
+// one new key.
+func LoadKeyfile(path string) (*Keyfile, error) {
+	k := &Keyfile{path: path, keys: make(map[string][]byte)}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		if _, err := k.AddKey(); err != nil {
+			return nil, err
+		}
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want a key ID and a key", path, line)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s:%d: key is not 32 bytes of base64", path, line)
+		}
+		k.keys[fields[0]] = key
+		k.current = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if k.current == "" {
+		return nil, fmt.Errorf("%s holds no keys", path)
+	}
+	return k, nil
+}
+
+// AddKey appends a new master key to the file and makes it the current one.
+// It returns the ID of the key.
+func (k *Keyfile) AddKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	id := "k" + time.Now().UTC().Format("20060102T150405")
+	if _, taken := k.keys[id]; taken {
+		return "", fmt.Errorf("key %s already exists", id)
+	}
+
+	file, err := os.OpenFile(k.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintf(file, "%s %s\n", id, base64.StdEncoding.EncodeToString(key)); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	k.keys[id] = key
+	k.current = id
+	return id, nil
+}
+
+func (k *Keyfile) GenerateDataKey(ctx context.Context) (DataKey, error) {
+	k.mu.RLock()
+	id, master := k.current, k.keys[k.current]
+	k.mu.RUnlock()
+
+	plaintext := make([]byte, 32)
+	if _, err := rand.Read(plaintext); err != nil {
+		return DataKey{}, err
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return DataKey{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return DataKey{}, err
+	}
+	return DataKey{
+		KeyID:     id,
+		Plaintext: plaintext,
+		Encrypted: aead.Seal(nonce, nonce, plaintext, []byte(id)),
+	}, nil
+}
+
+func (k *Keyfile) Decrypt(ctx context.Context, keyID string, encrypted []byte) ([]byte, error) {
+	k.mu.RLock()
+	master, ok := k.keys[keyID]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("master key %s is not in %s", keyID, k.path)
+	}
+
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted data key is truncated")
+	}
+	return aead.Open(nil, encrypted[:aead.NonceSize()], encrypted[aead.NonceSize():], []byte(keyID))
+}
@@ -0,0 +1,93 @@
+package pii
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadKeyfileCreatesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	keys, err := LoadKeyfile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyfile: %v", err)
+	}
+
+	again, err := LoadKeyfile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyfile of the created file: %v", err)
+	}
+	if again.current != keys.current || string(again.keys[again.current]) != string(keys.keys[keys.current]) {
+		t.Errorf("reloaded key %s differs from created key %s", again.current, keys.current)
+	}
+}
+
+func TestLoadKeyfileRefusesBadFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{name: "no keys", contents: "# keys\n\n"},
+		{name: "missing key", contents: "k1\n"},
+		{name: "not base64", contents: "k1 not-base64!\n"},
+		{name: "short key", contents: "k1 c2hvcnQ=\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys")
+			if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadKeyfile(path); err == nil {
+				t.Fatal("LoadKeyfile succeeded")
+			}
+		})
+	}
+}
+
+func TestAddKey(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeys(t, path, "k1")
+	keys, err := LoadKeyfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := keys.GenerateDataKey(ctx)
+	if err != nil {
+		t.Fatalf("GenerateDataKey: %v", err)
+	}
+
+	id, err := keys.AddKey()
+	if err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if id == "k1" || !strings.HasPrefix(id, "k") {
+		t.Errorf("AddKey = %q", id)
+	}
+
+	reloaded, err := LoadKeyfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := reloaded.GenerateDataKey(ctx)
+	if err != nil {
+		t.Fatalf("GenerateDataKey: %v", err)
+	}
+	if generated.KeyID != id {
+		t.Errorf("data key generated under %s, want the added key %s", generated.KeyID, id)
+	}
+	plaintext, err := reloaded.Decrypt(ctx, old.KeyID, old.Encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt data key under the old master key: %v", err)
+	}
+	if string(plaintext) != string(old.Plaintext) {
+		t.Errorf("Decrypt returned another data key")
+	}
+	if _, err := reloaded.Decrypt(ctx, id, old.Encrypted); err == nil {
+		t.Errorf("Decrypt under the wrong master key succeeded")
+	}
+}
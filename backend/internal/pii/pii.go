@@ -0,0 +1,205 @@
+// Package pii encrypts personal data for storage. Data is sealed with envelope
+// encryption: a data key encrypts the data and a KeyProvider, a keyfile in
+// development or a KMS in production, encrypts the data key with a master key
+// it never hands out. Values that must still be looked up once encrypted, such
+// as contact numbers, are stored as a blind index: a keyed hash that matches
+// equal values without revealing them.
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"backend/internal/models"
+)
+
+// MinBlindIndexKeyLength is the shortest blind index key NewCipher accepts.
+const MinBlindIndexKeyLength = 16
+
+// KeyProvider issues data keys and decrypts them again, following the
+// GenerateDataKey and Decrypt calls of a KMS.
+type KeyProvider interface {
+	// GenerateDataKey returns a new 256 bit data key, in plaintext and
+	// encrypted with the provider's current master key.
+	GenerateDataKey(ctx context.Context) (DataKey, error)
+	// Decrypt returns the plaintext of a data key GenerateDataKey encrypted
+	// with the master key keyID.
+	Decrypt(ctx context.Context, keyID string, encrypted []byte) ([]byte, error)
+}
+
+// DataKey is a data key as GenerateDataKey returns it.
+type DataKey struct {
+	KeyID     string
+	Plaintext []byte
+	Encrypted []byte
+}
+
+// Cipher seals and opens personal data. Everything sealed by one Cipher uses
+// the same data key until Rotate, so the key provider is asked for a key once
+// rather than for every record; data keys met when opening are kept in memory
+// for the same reason.
+type Cipher struct {
+	keys     KeyProvider
+	blindKey []byte
+
+	mu      sync.Mutex
+	current *dataKey
+	// opened holds the decrypted data keys, by their encrypted form.
+	opened map[string]cipher.AEAD
+}
+
+type dataKey struct {
+	keyID     string
+	encrypted []byte
+	aead      cipher.AEAD
+}
+
+func NewCipher(keys KeyProvider, blindIndexKey []byte) (*Cipher, error) {
+	if len(blindIndexKey) < MinBlindIndexKeyLength {
+		return nil, fmt.Errorf("blind index key must be at least %d bytes", MinBlindIndexKeyLength)
+	}
+	return &Cipher{
+		keys:     keys,
+		blindKey: blindIndexKey,
+		opened:   make(map[string]cipher.AEAD),
+	}, nil
+}
+
+// Seal encrypts plaintext with the current data key. binding is tied to the
+// ciphertext, typically the ID of the record, so Open fails if the sealed data
+// is copied onto another record.
+func (c *Cipher) Seal(ctx context.Context, plaintext, binding []byte) (*models.SealedPII, error) {
+	key, err := c.dataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, key.aead.NonceSize(), key.aead.NonceSize()+len(plaintext)+key.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &models.SealedPII{
+		KeyID:      key.keyID,
+		DataKey:    key.encrypted,
+		Ciphertext: key.aead.Seal(nonce, nonce, plaintext, binding),
+	}, nil
+}
+
+// Open decrypts what Seal sealed with the same binding.
+func (c *Cipher) Open(ctx context.Context, sealed *models.SealedPII, binding []byte) ([]byte, error) {
+	aead, err := c.openDataKey(ctx, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed data is truncated")
+	}
+	nonce, ciphertext := sealed.Ciphertext[:aead.NonceSize()], sealed.Ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, binding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sealed data: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Rotate switches Seal to a new data key, encrypted with the provider's
+// current master key. Data sealed before stays readable.
+func (c *Cipher) Rotate(ctx context.Context) error {
+	key, err := c.generate(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.current = key
+	c.mu.Unlock()
+	return nil
+}
+
+// BlindIndex returns the blind index of value. Equal values have equal
+// indexes; the empty value stays empty.
+func (c *Cipher) BlindIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, c.blindKey)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Cipher) dataKey(ctx context.Context) (*dataKey, error) {
+	c.mu.Lock()
+	key := c.current
+	c.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+
+	key, err := c.generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Another Seal may have generated one meanwhile; keep the first.
+	if c.current == nil {
+		c.current = key
+	}
+	return c.current, nil
+}
+
+func (c *Cipher) generate(ctx context.Context) (*dataKey, error) {
+	generated, err := c.keys.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	aead, err := newAEAD(generated.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.opened[string(generated.Encrypted)] = aead
+	c.mu.Unlock()
+	return &dataKey{keyID: generated.KeyID, encrypted: generated.Encrypted, aead: aead}, nil
+}
+
+func (c *Cipher) openDataKey(ctx context.Context, sealed *models.SealedPII) (cipher.AEAD, error) {
+	c.mu.Lock()
+	aead, ok := c.opened[string(sealed.DataKey)]
+	c.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	plaintext, err := c.keys.Decrypt(ctx, sealed.KeyID, sealed.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	aead, err = newAEAD(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.opened[string(sealed.DataKey)] = aead
+	c.mu.Unlock()
+	return aead, nil
+}
+
+// newAEAD returns AES-256-GCM with key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("data key is %d bytes, want 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
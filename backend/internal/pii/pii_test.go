@@ -0,0 +1,221 @@
+package pii
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/models"
+)
+
+// testKeyfile writes a keyfile holding master keys with the given IDs and
+// loads it. The last key is the current one.
+func testKeyfile(t *testing.T, keys ...string) *Keyfile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeys(t, path, keys...)
+	keyfile, err := LoadKeyfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyfile
+}
+
+// writeKeys appends master keys named keys to the keyfile at path.
+func writeKeys(t *testing.T, path string, keys ...string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, id := range keys {
+		key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte(id), 32)[:32])
+		if _, err := file.WriteString(id + " " + key + "\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testCipher(t *testing.T, keys KeyProvider) *Cipher {
+	t.Helper()
+	cipher, err := NewCipher(keys, []byte("blind-index-test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	cipher := testCipher(t, testKeyfile(t, "k1"))
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{name: "empty", plaintext: ""},
+		{name: "json", plaintext: `{"name":"Ramesh Patil","contact":"+919876543210"}`},
+		{name: "devanagari", plaintext: "रमेश पाटील"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := cipher.Seal(ctx, []byte(tt.plaintext), []byte("f1"))
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+			if sealed.KeyID != "k1" {
+				t.Errorf("KeyID = %q, want k1", sealed.KeyID)
+			}
+			if tt.plaintext != "" && bytes.Contains(sealed.Ciphertext, []byte(tt.plaintext)) {
+				t.Errorf("ciphertext holds the plaintext")
+			}
+
+			opened, err := cipher.Open(ctx, sealed, []byte("f1"))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if string(opened) != tt.plaintext {
+				t.Errorf("Open = %q, want %q", opened, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestOpenRefusesAlteredData(t *testing.T) {
+	ctx := context.Background()
+	cipher := testCipher(t, testKeyfile(t, "k1"))
+	sealed, err := cipher.Seal(ctx, []byte("Ramesh Patil"), []byte("f1"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	tampered := *sealed
+	tampered.Ciphertext = bytes.Clone(sealed.Ciphertext)
+	tampered.Ciphertext[len(tampered.Ciphertext)-1] ^= 1
+	truncated := *sealed
+	truncated.Ciphertext = sealed.Ciphertext[:4]
+	wrongKey := *sealed
+	wrongKey.DataKey = []byte("not a data key")
+
+	tests := []struct {
+		name    string
+		sealed  *models.SealedPII
+		binding string
+	}{
+		{name: "other record", sealed: sealed, binding: "f2"},
+		{name: "tampered ciphertext", sealed: &tampered, binding: "f1"},
+		{name: "truncated ciphertext", sealed: &truncated, binding: "f1"},
+		{name: "unknown data key", sealed: &wrongKey, binding: "f1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if opened, err := cipher.Open(ctx, tt.sealed, []byte(tt.binding)); err == nil {
+				t.Fatalf("Open = %q, want an error", opened)
+			}
+		})
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	cipher := testCipher(t, testKeyfile(t, "k1"))
+	same := testCipher(t, testKeyfile(t, "k2"))
+	other, err := NewCipher(testKeyfile(t, "k1"), []byte("another-blind-index-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := cipher.BlindIndex("+919876543210")
+	if index == "" || index == "+919876543210" {
+		t.Fatalf("BlindIndex = %q", index)
+	}
+	if again := cipher.BlindIndex("+919876543210"); again != index {
+		t.Errorf("BlindIndex changed from %q to %q", index, again)
+	}
+	if got := same.BlindIndex("+919876543210"); got != index {
+		t.Errorf("BlindIndex under another master key = %q, want %q", got, index)
+	}
+	if got := cipher.BlindIndex("+919876543211"); got == index {
+		t.Errorf("another number has the same blind index")
+	}
+	if got := other.BlindIndex("+919876543210"); got == index {
+		t.Errorf("another blind index key gives the same blind index")
+	}
+	if got := cipher.BlindIndex(""); got != "" {
+		t.Errorf("BlindIndex(\"\") = %q, want empty", got)
+	}
+}
+
+func TestNewCipherRefusesShortBlindIndexKey(t *testing.T) {
+	if _, err := NewCipher(testKeyfile(t, "k1"), []byte("short")); err == nil {
+		t.Fatal("NewCipher with a 5 byte blind index key succeeded")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys")
+	writeKeys(t, path, "k1")
+	keys, err := LoadKeyfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher := testCipher(t, keys)
+
+	first, err := cipher.Seal(ctx, []byte("Ramesh Patil"), []byte("f1"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	second, err := cipher.Seal(ctx, []byte("Ramesh Patil"), []byte("f1"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !bytes.Equal(second.DataKey, first.DataKey) {
+		t.Errorf("Seal switched data keys without Rotate")
+	}
+	if err := cipher.Rotate(ctx); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	rotated, err := cipher.Seal(ctx, []byte("Ramesh Patil"), []byte("f1"))
+	if err != nil {
+		t.Fatalf("Seal after Rotate: %v", err)
+	}
+	if bytes.Equal(rotated.DataKey, first.DataKey) {
+		t.Errorf("Seal kept the data key after Rotate")
+	}
+
+	// What rotate-keys -new-master does: a new master key in the keyfile,
+	// then a process sealing under it.
+	writeKeys(t, path, "k2")
+	if keys, err = LoadKeyfile(path); err != nil {
+		t.Fatal(err)
+	}
+	cipher = testCipher(t, keys)
+	if err := cipher.Rotate(ctx); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	resealed, err := cipher.Seal(ctx, []byte("Ramesh Patil"), []byte("f1"))
+	if err != nil {
+		t.Fatalf("Seal under the new master key: %v", err)
+	}
+	if resealed.KeyID != "k2" {
+		t.Errorf("sealed under %s, want k2", resealed.KeyID)
+	}
+
+	// A cipher that never saw these data keys, as on a restarted server.
+	fresh := testCipher(t, keys)
+	for _, sealed := range []*models.SealedPII{first, rotated, resealed} {
+		opened, err := fresh.Open(ctx, sealed, []byte("f1"))
+		if err != nil {
+			t.Fatalf("Open data sealed under %s: %v", sealed.KeyID, err)
+		}
+		if string(opened) != "Ramesh Patil" {
+			t.Errorf("Open data sealed under %s = %q", sealed.KeyID, opened)
+		}
+	}
+}
@@ -5,38 +5,35 @@ package reports
 import (
 	"backend/internal/models"
 	"backend/internal/service"
+	"backend/internal/store"
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 )
 
 type ReportGenerator struct {
 	shootService *service.ShootService
 	cceService   *service.CCEService
-	dbClient     *dynamodb.Client
+	reportStore  store.ReportStore
 }
 
-func NewReportGenerator(shootService *service.ShootService, cceService *service.CCEService, dbClient *dynamodb.Client) *ReportGenerator {
+func NewReportGenerator(shootService *service.ShootService, cceService *service.CCEService, reportStore store.ReportStore) *ReportGenerator {
 	return &ReportGenerator{
 		shootService: shootService,
 		cceService:   cceService,
-		dbClient:     dbClient,
+		reportStore:  reportStore,
 	}
 }
 
 func (rg *ReportGenerator) GenerateReport(ctx context.Context, reportType string, startDate, endDate time.Time) (*models.Report, error) {
-	shoots, err := rg.shootService.GetShootsWithDateFilter(ctx, startDate, endDate)
+	// A zero page reads every page, so the report covers the whole period.
+	shoots, _, err := rg.shootService.GetShootsWithDateFilter(ctx, startDate, endDate, store.Page{})
 	if err != nil {
 		return nil, err
 	}
 
-	cces, _, err := rg.cceService.ListCCEs(ctx, "")
+	cces, _, err := rg.cceService.ListCCEs(ctx, store.Page{})
 	if err != nil {
 		return nil, err
 	}
@@ -84,19 +81,5 @@ func (rg *ReportGenerator) GenerateReport(ctx context.Context, reportType string
 }
 
 func (rg *ReportGenerator) SaveReport(ctx context.Context, report *models.Report) error {
-	// Assuming you're using DynamoDB to store reports
-	item, err := attributevalue.MarshalMap(report)
-	if err != nil {
-		return fmt.Errorf("failed to marshal report: %w", err)
-	}
-
-	_, err = rg.dbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String("Reports"),
-		Item:      item,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to save report: %w", err)
-	}
-
-	return nil
+	return rg.reportStore.Put(ctx, report)
 }
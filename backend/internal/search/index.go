@@ -0,0 +1,226 @@
+// Package search keeps an in-process index of farmers for name search and
+// filtering, so listing farmers does not scan the Farmers table.
+//
+// Each instance holds its own index. It follows the writes the instance makes
+// itself as they happen and is rebuilt from the store on a schedule to pick up
+// writes made by other instances, so a farmer written elsewhere can take up
+// to one refresh interval to show up.
+package search
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"backend/internal/models"
+)
+
+// Fields are the filters a Query can carry. Values of a field are matched
+// ignoring case, accents and punctuation.
+var Fields = []string{"state", "district", "tehsil", "village", "pincode", "tag", "crop"}
+
+// FarmerIndex is safe for concurrent use.
+type FarmerIndex struct {
+	mu   sync.RWMutex
+	data *indexData
+
+	// rebuildMu lets one Rebuild run at a time. While it runs, journal
+	// records the writes it may have missed so they can be applied on top.
+	rebuildMu sync.Mutex
+	journal   map[string]*models.Farmer
+}
+
+type indexData struct {
+	docs map[string]*doc
+	// fields maps field, folded value and farmer ID to presence.
+	fields map[string]map[string]set
+	// words maps each folded name word to the farmers using it, and sounds
+	// each phonetic key to the words having it.
+	words  map[string]set
+	sounds map[string]set
+}
+
+type doc struct {
+	farmer models.Farmer
+	name   string
+	words  []string
+}
+
+type set map[string]struct{}
+
+func NewFarmerIndex() *FarmerIndex {
+	return &FarmerIndex{data: newIndexData()}
+}
+
+func newIndexData() *indexData {
+	data := &indexData{
+		docs:   make(map[string]*doc),
+		fields: make(map[string]map[string]set),
+		words:  make(map[string]set),
+		sounds: make(map[string]set),
+	}
+	for _, field := range Fields {
+		data.fields[field] = make(map[string]set)
+	}
+	return data
+}
+
+// Put adds or replaces a farmer. A deleted or merged farmer is removed, and a
+// farmer older than the indexed one, by Version, is ignored.
+func (ix *FarmerIndex) Put(farmer models.Farmer) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.journal != nil {
+		f := farmer
+		ix.journal[farmer.ID] = &f
+	}
+	ix.data.put(farmer)
+}
+
+// Remove drops a farmer from the index.
+func (ix *FarmerIndex) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.journal != nil {
+		ix.journal[id] = nil
+	}
+	ix.data.remove(id)
+}
+
+// Len returns the number of indexed farmers.
+func (ix *FarmerIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.data.docs)
+}
+
+// Rebuild replaces the contents of the index with the farmers load yields.
+// Searches keep using the old contents until the new ones are complete, and
+// the Put and Remove calls made while load runs are applied again afterwards.
+func (ix *FarmerIndex) Rebuild(ctx context.Context, load func(ctx context.Context, yield func(models.Farmer)) error) error {
+	ix.rebuildMu.Lock()
+	defer ix.rebuildMu.Unlock()
+
+	ix.mu.Lock()
+	ix.journal = make(map[string]*models.Farmer)
+	ix.mu.Unlock()
+
+	data := newIndexData()
+	err := load(ctx, data.put)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	journal := ix.journal
+	ix.journal = nil
+	if err != nil {
+		return err
+	}
+
+	for id, farmer := range journal {
+		if farmer == nil {
+			data.remove(id)
+		} else {
+			data.put(*farmer)
+		}
+	}
+	ix.data = data
+	return nil
+}
+
+func (data *indexData) put(farmer models.Farmer) {
+	if existing, ok := data.docs[farmer.ID]; ok {
+		if existing.farmer.Version > farmer.Version {
+			return
+		}
+		data.remove(farmer.ID)
+	}
+	if farmer.DeletedAt != nil || farmer.MergedInto != "" {
+		return
+	}
+
+	farmer.Crop = slices.Clone(farmer.Crop)
+	farmer.Consent = maps.Clone(farmer.Consent)
+	farmer.Contacts = slices.Clone(farmer.Contacts)
+	if farmer.Coordinates != nil {
+		coordinates := *farmer.Coordinates
+		farmer.Coordinates = &coordinates
+	}
+	d := &doc{farmer: farmer, name: fold(farmer.Name)}
+	d.words = words(d.name)
+	data.docs[farmer.ID] = d
+
+	for _, word := range d.words {
+		add(data.words, word, farmer.ID)
+		if key := phonetic(word); key != "" {
+			add(data.sounds, key, word)
+		}
+	}
+	for field, value := range fieldValues(&farmer) {
+		for _, v := range value {
+			if v = fold(v); v != "" {
+				add(data.fields[field], v, farmer.ID)
+			}
+		}
+	}
+}
+
+func (data *indexData) remove(id string) {
+	d, ok := data.docs[id]
+	if !ok {
+		return
+	}
+	delete(data.docs, id)
+
+	for _, word := range d.words {
+		if drop(data.words, word, id) {
+			if key := phonetic(word); key != "" {
+				drop(data.sounds, key, word)
+			}
+		}
+	}
+	for field, value := range fieldValues(&d.farmer) {
+		for _, v := range value {
+			drop(data.fields[field], fold(v), id)
+		}
+	}
+}
+
+func fieldValues(farmer *models.Farmer) map[string][]string {
+	return map[string][]string{
+		"state":    {farmer.State},
+		"district": {farmer.District},
+		"tehsil":   {farmer.Tehsil},
+		"village":  {farmer.Village},
+		"pincode":  {farmer.Pincode},
+		"tag":      strings.Split(farmer.Tag, ","),
+		"crop":     farmer.Crop,
+	}
+}
+
+func add(m map[string]set, key, member string) {
+	s, ok := m[key]
+	if !ok {
+		s = make(set)
+		m[key] = s
+	}
+	s[member] = struct{}{}
+}
+
+// drop removes member from the set at key and reports whether the set is now
+// gone.
+func drop(m map[string]set, key, member string) bool {
+	s, ok := m[key]
+	if !ok {
+		return false
+	}
+	delete(s, member)
+	if len(s) == 0 {
+		delete(m, key)
+		return true
+	}
+	return false
+}
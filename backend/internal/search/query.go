@@ -0,0 +1,300 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"backend/internal/models"
+	"backend/pkg/errors"
+)
+
+// Sort orders of a Query. A leading "-" reverses an order.
+const (
+	SortRelevance = "relevance"
+	SortName      = "name"
+	SortCreatedAt = "createdAt"
+	SortUpdatedAt = "updatedAt"
+)
+
+// Query selects farmers. Every field filter must match, and a field matches
+// when the farmer has any of its values. For crop, AllCrops asks for farmers
+// growing every listed crop instead.
+type Query struct {
+	// Name is matched word by word: each word of the query must match a word
+	// of the farmer's name exactly, as a prefix, by sound or with a typo.
+	Name     string
+	Filters  map[string][]string
+	AllCrops bool
+	// Sort defaults to relevance when Name is set and to name otherwise.
+	Sort string
+}
+
+// Result is one page of matches.
+type Result struct {
+	Farmers []models.Farmer
+	// Total counts every match, not just the page.
+	Total int
+}
+
+// Scores of the ways a query word can match a name word.
+const (
+	scoreExact    = 1.0
+	scorePrefix   = 0.8
+	scorePhonetic = 0.6
+	scoreTypo     = 0.5
+)
+
+// Validate checks the filter fields and sort order. The error wraps
+// errors.ErrInvalidInput.
+func (q *Query) Validate() error {
+	for field := range q.Filters {
+		if !slices.Contains(Fields, field) {
+			return fmt.Errorf("%w: unknown filter %q", errors.ErrInvalidInput, field)
+		}
+	}
+	switch strings.TrimPrefix(q.Sort, "-") {
+	case "", SortRelevance, SortName, SortCreatedAt, SortUpdatedAt:
+	default:
+		return fmt.Errorf("%w: unknown sort %q", errors.ErrInvalidInput, q.Sort)
+	}
+	if strings.TrimPrefix(q.Sort, "-") == SortRelevance && fold(q.Name) == "" {
+		return fmt.Errorf("%w: sorting by relevance needs a name", errors.ErrInvalidInput)
+	}
+	return nil
+}
+
+// Key identifies the query, for telling whether a cursor was issued for it.
+func (q *Query) Key() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\x00%t\x00%s", fold(q.Name), q.AllCrops, q.Sort)
+	for _, field := range Fields {
+		values := make([]string, 0, len(q.Filters[field]))
+		for _, v := range q.Filters[field] {
+			values = append(values, fold(v))
+		}
+		slices.Sort(values)
+		fmt.Fprintf(&b, "\x00%s=%s", field, strings.Join(values, "\x01"))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Search returns limit matches of q starting at offset, or every match from
+// offset on when limit is 0. q must be valid.
+func (ix *FarmerIndex) Search(q Query, offset, limit int) Result {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	data := ix.data
+
+	ids, filtered := data.filter(&q)
+	var scores map[string]float64
+	if name := fold(q.Name); name != "" {
+		scores = data.match(words(name), ids, filtered)
+		ids = make(set, len(scores))
+		for id := range scores {
+			ids[id] = struct{}{}
+		}
+	} else if !filtered {
+		ids = make(set, len(data.docs))
+		for id := range data.docs {
+			ids[id] = struct{}{}
+		}
+	}
+
+	docs := make([]*doc, 0, len(ids))
+	for id := range ids {
+		docs = append(docs, data.docs[id])
+	}
+	sortDocs(docs, q.Sort, scores)
+
+	result := Result{Total: len(docs)}
+	if offset >= len(docs) {
+		return result
+	}
+	docs = docs[offset:]
+	if limit > 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+	result.Farmers = make([]models.Farmer, len(docs))
+	for i, d := range docs {
+		result.Farmers[i] = d.farmer
+		result.Farmers[i].Crop = slices.Clone(d.farmer.Crop)
+	}
+	return result
+}
+
+// filter returns the farmers matching every field filter, and false if the
+// query has no field filters at all.
+func (data *indexData) filter(q *Query) (set, bool) {
+	var ids set
+	filtered := false
+	for _, field := range Fields {
+		values := q.Filters[field]
+		if len(values) == 0 {
+			continue
+		}
+		filtered = true
+
+		var matches set
+		if field == "crop" && q.AllCrops {
+			for i, value := range values {
+				s := data.fields[field][fold(value)]
+				if i == 0 {
+					matches = union(nil, s)
+				} else {
+					matches = intersect(matches, s)
+				}
+			}
+		} else {
+			for _, value := range values {
+				matches = union(matches, data.fields[field][fold(value)])
+			}
+		}
+
+		if ids == nil {
+			ids = matches
+		} else {
+			ids = intersect(ids, matches)
+		}
+		if len(ids) == 0 {
+			return set{}, true
+		}
+	}
+	return ids, filtered
+}
+
+// match scores the farmers whose names match every query word, restricted to
+// within when filtered is set. A farmer scores the sum of its best match for
+// each query word.
+func (data *indexData) match(query []string, within set, filtered bool) map[string]float64 {
+	var scores map[string]float64
+	for _, q := range query {
+		// best is the score of each name word matching q.
+		best := make(map[string]float64)
+		edits := maxEdits(len([]rune(q)))
+		for word := range data.words {
+			switch {
+			case word == q:
+				best[word] = scoreExact
+			case strings.HasPrefix(word, q):
+				best[word] = scorePrefix
+			case edits > 0 && editDistance(q, word, edits) <= edits:
+				best[word] = scoreTypo
+			}
+		}
+		if key := phonetic(q); key != "" {
+			for word := range data.sounds[key] {
+				best[word] = max(best[word], scorePhonetic)
+			}
+		}
+
+		next := make(map[string]float64)
+		for word, score := range best {
+			for id := range data.words[word] {
+				if filtered {
+					if _, ok := within[id]; !ok {
+						continue
+					}
+				}
+				if scores != nil {
+					if _, ok := scores[id]; !ok {
+						continue
+					}
+				}
+				next[id] = max(next[id], score)
+			}
+		}
+		for id := range next {
+			next[id] += scores[id]
+		}
+		scores = next
+		if len(scores) == 0 {
+			break
+		}
+	}
+	return scores
+}
+
+func sortDocs(docs []*doc, order string, scores map[string]float64) {
+	desc := strings.HasPrefix(order, "-")
+	order = strings.TrimPrefix(order, "-")
+	if order == "" {
+		order = SortName
+		if scores != nil {
+			order = SortRelevance
+		}
+	}
+
+	byName := func(a, b *doc) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.farmer.ID, b.farmer.ID)
+	}
+	var cmp func(a, b *doc) int
+	switch order {
+	case SortRelevance:
+		// Best first, so the order is reversed unless asked otherwise.
+		desc = !desc
+		cmp = func(a, b *doc) int {
+			switch sa, sb := scores[a.farmer.ID], scores[b.farmer.ID]; {
+			case sa < sb:
+				return -1
+			case sa > sb:
+				return 1
+			}
+			return -byName(a, b)
+		}
+	case SortCreatedAt:
+		cmp = func(a, b *doc) int {
+			if c := a.farmer.CreatedAt.Compare(b.farmer.CreatedAt); c != 0 {
+				return c
+			}
+			return byName(a, b)
+		}
+	case SortUpdatedAt:
+		cmp = func(a, b *doc) int {
+			if c := a.farmer.UpdatedAt.Compare(b.farmer.UpdatedAt); c != 0 {
+				return c
+			}
+			return byName(a, b)
+		}
+	default:
+		cmp = byName
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		if desc {
+			return cmp(docs[i], docs[j]) > 0
+		}
+		return cmp(docs[i], docs[j]) < 0
+	})
+}
+
+func union(a, b set) set {
+	out := make(set, len(a)+len(b))
+	for id := range a {
+		out[id] = struct{}{}
+	}
+	for id := range b {
+		out[id] = struct{}{}
+	}
+	return out
+}
+
+func intersect(a, b set) set {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	out := make(set)
+	for id := range a {
+		if _, ok := b[id]; ok {
+			out[id] = struct{}{}
+		}
+	}
+	return out
+}
@@ -0,0 +1,275 @@
+package search
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/pkg/errors"
+)
+
+// day returns midnight UTC on the given day of March 2026.
+func day(d int) time.Time {
+	return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testIndex() *FarmerIndex {
+	ix := NewFarmerIndex()
+	for _, farmer := range []models.Farmer{
+		{ID: "f1", Name: "Lakshmi Patil", State: "Maharashtra", Crop: []string{"bajra", "jowar"}, CreatedAt: day(3), UpdatedAt: day(10)},
+		{ID: "f2", Name: "Laxmi Pawar", State: "Maharashtra", Crop: []string{"bajra"}, CreatedAt: day(1), UpdatedAt: day(10)},
+		{ID: "f3", Name: "Mohammad Shaikh", State: "Karnataka", Crop: []string{"jowar", "cotton"}, CreatedAt: day(5), UpdatedAt: day(10)},
+		{ID: "f4", Name: "Muhammed Ansari", State: "Karnataka", Crop: []string{"cotton"}, CreatedAt: day(2), UpdatedAt: day(10)},
+		{ID: "f5", Name: "Ramesh Patel", State: "Maharashtra", Crop: []string{"bajra", "jowar", "cotton"}, CreatedAt: day(4), UpdatedAt: day(10)},
+		{ID: "f6", Name: "रमेश पाटील", State: "Maharashtra", CreatedAt: day(6), UpdatedAt: day(10)},
+	} {
+		ix.Put(farmer)
+	}
+	return ix
+}
+
+func ids(farmers []models.Farmer) []string {
+	out := make([]string, len(farmers))
+	for i, farmer := range farmers {
+		out[i] = farmer.ID
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	ix := testIndex()
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{
+			name:  "prefix",
+			query: Query{Name: "laks"},
+			want:  []string{"f1"},
+		},
+		{
+			name:  "exact spelling ranks above the phonetic one",
+			query: Query{Name: "Laxmi"},
+			want:  []string{"f2", "f1"},
+		},
+		{
+			name:  "phonetic spelling",
+			query: Query{Name: "lakshmi"},
+			want:  []string{"f1", "f2"},
+		},
+		{
+			name:  "typo in a phonetic spelling",
+			query: Query{Name: "Muhammad"},
+			want:  []string{"f3", "f4"},
+		},
+		{
+			name:  "typo",
+			query: Query{Name: "ramsh"},
+			want:  []string{"f5"},
+		},
+		{
+			name:  "exact surname ranks above a variant",
+			query: Query{Name: "patil"},
+			want:  []string{"f1", "f5"},
+		},
+		{
+			name:  "every word must match",
+			query: Query{Name: "Lakshmi Patil"},
+			want:  []string{"f1"},
+		},
+		{
+			name:  "accents and case",
+			query: Query{Name: "LAKSHMĪ"},
+			want:  []string{"f1", "f2"},
+		},
+		{
+			name:  "devanagari",
+			query: Query{Name: "पाटील"},
+			want:  []string{"f6"},
+		},
+		{
+			name:  "no match",
+			query: Query{Name: "Sunita"},
+			want:  []string{},
+		},
+		{
+			name:  "any crop",
+			query: Query{Filters: map[string][]string{"crop": {"bajra", "JOWAR"}}},
+			want:  []string{"f1", "f2", "f3", "f5"},
+		},
+		{
+			name:  "all crops",
+			query: Query{Filters: map[string][]string{"crop": {"bajra", "jowar"}}, AllCrops: true},
+			want:  []string{"f1", "f5"},
+		},
+		{
+			name:  "all crops, none grows every one",
+			query: Query{Filters: map[string][]string{"crop": {"bajra", "cotton", "tur"}}, AllCrops: true},
+			want:  []string{},
+		},
+		{
+			name:  "all crops with another filter",
+			query: Query{Filters: map[string][]string{"crop": {"jowar", "cotton"}, "state": {"karnataka"}}, AllCrops: true},
+			want:  []string{"f3"},
+		},
+		{
+			name:  "name within filters",
+			query: Query{Name: "laxmi", Filters: map[string][]string{"crop": {"jowar"}}},
+			want:  []string{"f1"},
+		},
+		{
+			name:  "unknown filter value",
+			query: Query{Filters: map[string][]string{"state": {"Kerala"}}},
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ix.Search(tt.query, 0, 0)
+			if got := ids(result.Farmers); !slices.Equal(got, tt.want) {
+				t.Fatalf("Search = %v, want %v", got, tt.want)
+			}
+			if result.Total != len(tt.want) {
+				t.Fatalf("Total = %d, want %d", result.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSearchSort(t *testing.T) {
+	ix := testIndex()
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{
+			name:  "name by default",
+			query: Query{},
+			want:  []string{"f1", "f2", "f3", "f4", "f5", "f6"},
+		},
+		{
+			name:  "name descending",
+			query: Query{Sort: "-name"},
+			want:  []string{"f6", "f5", "f4", "f3", "f2", "f1"},
+		},
+		{
+			name:  "createdAt",
+			query: Query{Sort: SortCreatedAt},
+			want:  []string{"f2", "f4", "f1", "f5", "f3", "f6"},
+		},
+		{
+			name:  "createdAt descending",
+			query: Query{Sort: "-createdAt"},
+			want:  []string{"f6", "f3", "f5", "f1", "f4", "f2"},
+		},
+		{
+			name:  "updatedAt ties broken by name",
+			query: Query{Sort: SortUpdatedAt},
+			want:  []string{"f1", "f2", "f3", "f4", "f5", "f6"},
+		},
+		{
+			name:  "relevance by default with a name",
+			query: Query{Name: "laxmi"},
+			want:  []string{"f2", "f1"},
+		},
+		{
+			name:  "relevance ascending",
+			query: Query{Name: "laxmi", Sort: "-relevance"},
+			want:  []string{"f1", "f2"},
+		},
+		{
+			name:  "name with a name query",
+			query: Query{Name: "laxmi", Sort: SortName},
+			want:  []string{"f1", "f2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.query.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if got := ids(ix.Search(tt.query, 0, 0).Farmers); !slices.Equal(got, tt.want) {
+				t.Fatalf("Search = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	ix := testIndex()
+
+	result := ix.Search(Query{}, 1, 2)
+	if got := ids(result.Farmers); !slices.Equal(got, []string{"f2", "f3"}) || result.Total != 6 {
+		t.Fatalf("Search = %v of %d, want [f2 f3] of 6", got, result.Total)
+	}
+	result = ix.Search(Query{}, 6, 2)
+	if len(result.Farmers) != 0 || result.Total != 6 {
+		t.Fatalf("Search past the end = %v of %d, want nothing of 6", ids(result.Farmers), result.Total)
+	}
+}
+
+func TestQueryValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		valid bool
+	}{
+		{name: "empty", query: Query{}, valid: true},
+		{name: "every field", query: Query{Filters: map[string][]string{"state": {"x"}, "crop": {"y"}, "tag": {"z"}}}, valid: true},
+		{name: "unknown filter", query: Query{Filters: map[string][]string{"contact": {"98"}}}},
+		{name: "unknown sort", query: Query{Sort: "contact"}},
+		{name: "relevance without a name", query: Query{Sort: SortRelevance}},
+		{name: "relevance with punctuation only", query: Query{Name: "..", Sort: "-relevance"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.valid && err != nil {
+				t.Fatalf("Validate = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, errors.ErrInvalidInput) {
+				t.Fatalf("Validate = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestQueryKey(t *testing.T) {
+	a := Query{Name: "Lakshmi", Filters: map[string][]string{"crop": {"Bajra", "jowar"}}}
+	b := Query{Name: "lakshmi ", Filters: map[string][]string{"crop": {"jowar", "bajra"}}}
+	if a.Key() != b.Key() {
+		t.Errorf("queries differing in case, spacing and value order have keys %s and %s", a.Key(), b.Key())
+	}
+	b.AllCrops = true
+	if a.Key() == b.Key() {
+		t.Errorf("AllCrops does not change the key")
+	}
+}
+
+func TestPut(t *testing.T) {
+	ix := testIndex()
+	now := day(20)
+
+	ix.Put(models.Farmer{ID: "f1", Name: "Lakshmi Patil", Version: 2})
+	ix.Put(models.Farmer{ID: "f1", Name: "Sunita Patil", Version: 1})
+	if got := ids(ix.Search(Query{Name: "lakshmi"}, 0, 0).Farmers); !slices.Equal(got, []string{"f1", "f2"}) {
+		t.Errorf("after an older version, Search = %v, want [f1 f2]", got)
+	}
+
+	ix.Put(models.Farmer{ID: "f1", Name: "Lakshmi Patil", Version: 3, DeletedAt: &now})
+	ix.Put(models.Farmer{ID: "f2", Name: "Laxmi Pawar", Version: 1, MergedInto: "f5"})
+	if got := ids(ix.Search(Query{Name: "lakshmi"}, 0, 0).Farmers); len(got) != 0 {
+		t.Errorf("after deleting and merging, Search = %v, want nothing", got)
+	}
+	if ix.Len() != 4 {
+		t.Errorf("Len = %d, want 4", ix.Len())
+	}
+}
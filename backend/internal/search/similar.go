@@ -0,0 +1,57 @@
+package search
+
+// NameSimilarity scores how likely two farmer names belong to the same
+// person, from 0 for unrelated names to 1 for the same name. Every word is
+// paired with its closest word in the other name: an exact match counts 1, a
+// phonetic match 0.9, a word within typing distance less for every edit, and
+// an initial that matches the first letter of the other word 0.7. Words that
+// find no partner count 0, so "Ramesh" against "Ramesh Patil" scores 2/3.
+func NameSimilarity(a, b string) float64 {
+	wa, wb := words(fold(a)), words(fold(b))
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, w := range wa {
+		total += bestWordMatch(w, wb)
+	}
+	for _, w := range wb {
+		total += bestWordMatch(w, wa)
+	}
+	return total / float64(len(wa)+len(wb))
+}
+
+// bestWordMatch returns the score of the word in others closest to word.
+func bestWordMatch(word string, others []string) float64 {
+	best := 0.0
+	for _, other := range others {
+		best = max(best, wordSimilarity(word, other))
+		if best == 1 {
+			break
+		}
+	}
+	return best
+}
+
+func wordSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	if key := phonetic(a); key != "" && key == phonetic(b) {
+		return 0.9
+	}
+	if len(a) == 1 || len(b) == 1 {
+		if a[0] == b[0] {
+			return 0.7
+		}
+		return 0
+	}
+
+	longest := max(len([]rune(a)), len([]rune(b)))
+	limit := maxEdits(longest)
+	if d := editDistance(a, b, limit); d <= limit {
+		return 0.85 - 0.15*float64(d-1)
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{name: "same name", a: "Ramesh Patil", b: "ramesh  PATIL", want: 1},
+		{name: "accents", a: "Rāmesh Pātil", b: "Ramesh Patil", want: 1},
+		{name: "phonetic spelling", a: "Lakshmi Patil", b: "Laxmi Patil", want: 0.95},
+		{name: "phonetic spelling of every word", a: "Mohammad Shaikh", b: "Muhammed Sheikh", want: 0.9},
+		{name: "typo", a: "Ganesh", b: "Ganeshh", want: 0.85},
+		{name: "initial", a: "R. Patil", b: "Ramesh Patil", want: 0.85},
+		{name: "missing word", a: "Ramesh", b: "Ramesh Patil", want: 2.0 / 3},
+		{name: "word order", a: "Patil Ramesh", b: "Ramesh Patil", want: 1},
+		{name: "unrelated", a: "Ramesh Patil", b: "Sunita Deshmukh", want: 0},
+		{name: "empty", a: "", b: "Ramesh Patil", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NameSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("NameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if back := NameSimilarity(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+				t.Fatalf("NameSimilarity(%q, %q) = %v, but %v the other way round", tt.b, tt.a, back, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// accents are the combining marks fold strips. The vowel signs of Indic
+// scripts are marks too but spell the name, so they stay.
+var accents = &unicode.RangeTable{
+	R16: []unicode.Range16{{Lo: 0x0300, Hi: 0x036f, Stride: 1}},
+}
+
+// fold lowercases s, strips accents such as the macrons of transliterated
+// names and collapses runs of spaces and punctuation into one space.
+func fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(accents)), norm.NFC)
+	if stripped, _, err := transform.String(t, s); err == nil {
+		s = stripped
+	}
+	return strings.Join(words(strings.ToLower(s)), " ")
+}
+
+// words splits s into runs of letters, marks and digits.
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r)
+	})
+}
+
+// phoneticReplacer merges the spellings Latin transliterations of Indian
+// names use for one sound. Longer patterns come first so "ksh" is not read
+// as "k" followed by "sh".
+var phoneticReplacer = strings.NewReplacer(
+	"ksh", "x", "ks", "x",
+	"chh", "c", "ch", "c",
+	"sh", "s", "th", "t", "dh", "d", "bh", "b", "ph", "f",
+	"gh", "g", "jh", "j", "kh", "k", "ck", "k",
+	"w", "v", "q", "k", "z", "j", "y", "i",
+)
+
+// phonetic returns a key shared by the usual spellings of a romanised Indian
+// name: Lakshmi and Laxmi, Mohammad and Muhammed, Devendra and Devender. It
+// keeps the consonant skeleton of the word after merging aspirated and
+// alternative spellings, which is where these variants agree; a leading vowel
+// is kept as "a" so Ishwar and Eshwar still meet.
+func phonetic(word string) string {
+	word = phoneticReplacer.Replace(word)
+
+	var key []byte
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		vowel := strings.IndexByte("aeiou", c) >= 0
+		switch {
+		case c < 'a' || c > 'z':
+			// Only Latin script has spelling variants worth folding.
+			return ""
+		case i == 0 && vowel:
+			key = append(key, 'a')
+		case vowel:
+		case len(key) > 0 && key[len(key)-1] == c:
+		default:
+			key = append(key, c)
+		}
+	}
+	if len(key) < 2 {
+		return ""
+	}
+	return string(key)
+}
+
+// editDistance returns the Levenshtein distance between a and b, or max+1 once
+// it is known to exceed max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		best := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			best = min(best, curr[j])
+		}
+		if best > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// maxEdits is how many typing mistakes a query word of length n may contain.
+func maxEdits(n int) int {
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,83 @@
+package search
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "Ramesh Patil", want: "ramesh patil"},
+		{in: "  Rāmesh   PATIL. ", want: "ramesh patil"},
+		{in: "D'Souza-Pinto", want: "d souza pinto"},
+		{in: "रमेश पाटील", want: "रमेश पाटील"},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := fold(tt.in); got != tt.want {
+				t.Fatalf("fold(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhonetic(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{a: "lakshmi", b: "laxmi", same: true},
+		{a: "mohammad", b: "muhammed", same: true},
+		{a: "devendra", b: "devender", same: true},
+		{a: "ishwar", b: "eshwar", same: true},
+		{a: "shaikh", b: "sheikh", same: true},
+		{a: "patil", b: "patel", same: true},
+		{a: "ramesh", b: "suresh", same: false},
+		{a: "lakshmi", b: "lalita", same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			ka, kb := phonetic(tt.a), phonetic(tt.b)
+			if ka == "" || kb == "" {
+				t.Fatalf("phonetic(%q) = %q, phonetic(%q) = %q; want keys", tt.a, ka, tt.b, kb)
+			}
+			if (ka == kb) != tt.same {
+				t.Fatalf("phonetic(%q) = %q, phonetic(%q) = %q; same = %v, want %v", tt.a, ka, tt.b, kb, ka == kb, tt.same)
+			}
+		})
+	}
+}
+
+func TestPhoneticWithoutKey(t *testing.T) {
+	for _, word := range []string{"a", "ai", "रमेश", "ram3sh"} {
+		if key := phonetic(word); key != "" {
+			t.Errorf("phonetic(%q) = %q, want no key", word, key)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{a: "ramesh", b: "ramesh", max: 1, want: 0},
+		{a: "ramesh", b: "ramsh", max: 1, want: 1},
+		{a: "ramesh", b: "rajesh", max: 1, want: 1},
+		{a: "ganesh", b: "ganseh", max: 2, want: 2},
+		{a: "ganesh", b: "ganseh", max: 1, want: 2},
+		{a: "ram", b: "ramesh", max: 2, want: 3},
+		{a: "रमेश", b: "रमेस", max: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b, tt.max); got != tt.want {
+				t.Fatalf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/auth"
+	"backend/pkg/requestid"
+
+	"github.com/google/uuid"
+)
+
+// Audited entities
+const (
+	EntityFarmer = "farmer"
+	EntityCCE    = "cce"
+	EntityTicket = "ticket"
+	// EntityPartner is a dealer or agronomist.
+	EntityPartner = "partner"
+	// EntityCrop and EntityVariety are entries of the product catalog.
+	EntityCrop    = "crop"
+	EntityVariety = "variety"
+)
+
+// Audited actions
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionMerge   = "merge"
+)
+
+// systemActor is recorded for changes made outside an authenticated request.
+const systemActor = "system"
+
+// auditIgnoredFields change on every write and would drown out the fields
+// that were actually edited.
+var auditIgnoredFields = map[string]bool{
+	"updatedAt": true,
+	"version":   true,
+}
+
+type AuditService struct {
+	store store.AuditStore
+}
+
+func NewAuditService(auditStore store.AuditStore) *AuditService {
+	return &AuditService{
+		store: auditStore,
+	}
+}
+
+// Record writes the audit record of one change, taking the actor and request
+// ID from ctx. The diff is taken between the JSON fields of before and after;
+// pass nil for whichever side does not exist. A failed write is logged rather
+// than returned, since the change it describes has already been saved.
+func (s *AuditService) Record(ctx context.Context, entity, id, action string, before, after interface{}) {
+	changes, err := diffFields(before, after)
+	if err != nil {
+		log.Printf("Failed to diff %s %s for audit: %v", entity, id, err)
+	}
+
+	actor := auth.UserID(ctx)
+	if actor == "" {
+		actor = systemActor
+	}
+
+	now := time.Now().UTC()
+	record := &models.AuditRecord{
+		ID:        now.Format("20060102T150405.000000000Z") + "-" + uuid.New().String(),
+		EntityKey: entity + "#" + id,
+		Entity:    entity,
+		EntityID:  id,
+		Action:    action,
+		Actor:     actor,
+		RequestID: requestid.FromContext(ctx),
+		Timestamp: now,
+		Changes:   changes,
+	}
+	if err := s.store.Put(ctx, record); err != nil {
+		log.Printf("Failed to write audit record for %s %s %s: %v", action, entity, id, err)
+	}
+}
+
+// History returns the changes made to one entity, oldest first.
+func (s *AuditService) History(ctx context.Context, entity, id string, page store.Page) ([]models.AuditRecord, string, error) {
+	return s.store.ListByEntity(ctx, entity, id, page)
+}
+
+// Redact wipes fields from the recorded changes of one entity, see
+// AuditStore.Redact, and returns how many records it changed.
+func (s *AuditService) Redact(ctx context.Context, entity, id string, fields []string) (int, error) {
+	return s.store.Redact(ctx, entity, id, fields)
+}
+
+// diffFields returns the fields whose JSON value differs between before and
+// after, sorted by field name.
+func diffFields(before, after interface{}) ([]models.FieldChange, error) {
+	oldFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+
+	var changes []models.FieldChange
+	for name := range names {
+		if auditIgnoredFields[name] || reflect.DeepEqual(oldFields[name], newFields[name]) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{
+			Field: name,
+			Old:   oldFields[name],
+			New:   newFields[name],
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes, nil
+}
+
+// jsonFields returns the fields of v as they appear in the API, dropping the
+// empty ones so that a create only lists what was set.
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if isEmptyJSON(value) {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+func isEmptyJSON(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,527 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// Seasons lists the seasons varieties can be recommended for.
+var Seasons = []string{models.SeasonKharif, models.SeasonRabi, models.SeasonZaid}
+
+// VarietyKinds lists the kinds of variety.
+var VarietyKinds = []string{models.VarietyOpenPollinated, models.VarietyHybrid}
+
+// PackUnits lists the units pack sizes are counted in.
+var PackUnits = []string{"g", "kg", "seeds"}
+
+// cropIDPattern is the form of crop IDs: lower case words joined by dashes.
+var cropIDPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CatalogError lists what is wrong with the catalog references of a farmer or
+// ticket, or with a catalog entry. errors.Is matches ErrInvalidInput.
+type CatalogError struct {
+	Problems []string
+}
+
+func (e *CatalogError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+func (e *CatalogError) Is(target error) bool {
+	return target == errors.ErrInvalidInput
+}
+
+// catalogError returns the problems as a *CatalogError, or nil if there are
+// none.
+func catalogError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &CatalogError{Problems: problems}
+}
+
+type CatalogService struct {
+	store     store.CatalogStore
+	farmers   store.FarmerStore
+	tickets   store.TicketStore
+	locations *location.Directory
+	audit     *AuditService
+}
+
+func NewCatalogService(catalogStore store.CatalogStore, farmerStore store.FarmerStore, ticketStore store.TicketStore, locations *location.Directory, audit *AuditService) *CatalogService {
+	return &CatalogService{
+		store:     catalogStore,
+		farmers:   farmerStore,
+		tickets:   ticketStore,
+		locations: locations,
+		audit:     audit,
+	}
+}
+
+// CreateCrop adds a crop to the catalog. Without an ID, the crop gets its
+// name in lower case with dashes between the words. A crop whose ID, name or
+// aliases are taken by another crop fails with errors.ErrConflict.
+func (s *CatalogService) CreateCrop(ctx context.Context, crop *models.Crop) error {
+	if crop.ID == "" {
+		crop.ID = cropKey(crop.Name)
+	}
+	if err := s.prepareCrop(ctx, crop); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	crop.CreatedAt = now
+	crop.UpdatedAt = now
+	crop.Version = 1
+	if err := s.store.PutCrop(ctx, crop); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityCrop, crop.ID, ActionCreate, nil, crop)
+	return nil
+}
+
+func (s *CatalogService) GetCrop(ctx context.Context, id string) (*models.Crop, error) {
+	return s.store.GetCrop(ctx, id)
+}
+
+func (s *CatalogService) ListCrops(ctx context.Context, page store.Page) ([]models.Crop, string, error) {
+	return s.store.ListCrops(ctx, page)
+}
+
+// UpdateCrop saves crop if it is still at crop.Version and bumps the version.
+// The ID stays; farmers hold it.
+func (s *CatalogService) UpdateCrop(ctx context.Context, crop *models.Crop) error {
+	before, err := s.store.GetCrop(ctx, crop.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareCrop(ctx, crop); err != nil {
+		return err
+	}
+	crop.CreatedAt = before.CreatedAt
+	crop.UpdatedAt = time.Now().UTC()
+	if err := s.store.UpdateCrop(ctx, crop); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityCrop, crop.ID, ActionUpdate, before, crop)
+	return nil
+}
+
+// DeleteCrop removes a crop from the catalog. A crop that still has
+// varieties, that farmers grow or that tickets are about fails with
+// errors.ErrConflict. Farmers and tickets in the trash count too, as they
+// may be restored.
+func (s *CatalogService) DeleteCrop(ctx context.Context, id string) error {
+	if _, err := s.store.GetCrop(ctx, id); err != nil {
+		return err
+	}
+	varieties, _, err := s.store.ListVarieties(ctx, id, store.Page{Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(varieties) > 0 {
+		return errors.ErrConflict
+	}
+	for _, uses := range []func(context.Context, string) (bool, error){s.farmers.UsesCrop, s.tickets.UsesCrop} {
+		used, err := uses(ctx, id)
+		if err != nil {
+			return err
+		}
+		if used {
+			return errors.ErrConflict
+		}
+	}
+	if err := s.store.DeleteCrop(ctx, id); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityCrop, id, ActionDelete, nil, nil)
+	return nil
+}
+
+// CreateVariety adds a variety to the catalog, see prepareVariety.
+func (s *CatalogService) CreateVariety(ctx context.Context, variety *models.Variety) error {
+	if err := s.prepareVariety(ctx, variety); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	variety.ID = uuid.New().String()
+	variety.CreatedAt = now
+	variety.UpdatedAt = now
+	variety.Version = 1
+	if err := s.store.PutVariety(ctx, variety); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityVariety, variety.ID, ActionCreate, nil, variety)
+	return nil
+}
+
+func (s *CatalogService) GetVariety(ctx context.Context, id string) (*models.Variety, error) {
+	return s.store.GetVariety(ctx, id)
+}
+
+// ListVarieties returns one page of the varieties of the crop, or of every
+// crop when cropID is empty.
+func (s *CatalogService) ListVarieties(ctx context.Context, cropID string, page store.Page) ([]models.Variety, string, error) {
+	return s.store.ListVarieties(ctx, cropID, page)
+}
+
+// UpdateVariety saves variety if it is still at variety.Version and bumps the
+// version.
+func (s *CatalogService) UpdateVariety(ctx context.Context, variety *models.Variety) error {
+	before, err := s.store.GetVariety(ctx, variety.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareVariety(ctx, variety); err != nil {
+		return err
+	}
+	variety.CreatedAt = before.CreatedAt
+	variety.UpdatedAt = time.Now().UTC()
+	if err := s.store.UpdateVariety(ctx, variety); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityVariety, variety.ID, ActionUpdate, before, variety)
+	return nil
+}
+
+// DeleteVariety removes a variety from the catalog. Tickets about it keep its
+// ID; mark its SKUs discontinued instead to keep it on record.
+func (s *CatalogService) DeleteVariety(ctx context.Context, id string) error {
+	if err := s.store.DeleteVariety(ctx, id); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityVariety, id, ActionDelete, nil, nil)
+	return nil
+}
+
+// RecommendVarieties returns the varieties of the crop recommended for the
+// season in the state and district, by name. An empty season, state or
+// district is not filtered on. Varieties recommended for no region in
+// particular are recommended everywhere.
+func (s *CatalogService) RecommendVarieties(ctx context.Context, cropID, season, state, district string) ([]models.Variety, error) {
+	if season != "" && !slices.Contains(Seasons, strings.ToLower(season)) {
+		return nil, errors.ErrInvalidInput
+	}
+	if _, err := s.store.GetCrop(ctx, cropID); err != nil {
+		return nil, err
+	}
+	if state != "" {
+		place, err := s.locations.Resolve(location.Place{State: state, District: district})
+		if err != nil {
+			return nil, err
+		}
+		state, district = place.State, place.District
+	}
+
+	recommended := []models.Variety{}
+	page := store.Page{Limit: 500}
+	for {
+		varieties, next, err := s.store.ListVarieties(ctx, cropID, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, variety := range varieties {
+			if season != "" && !slices.Contains(variety.Seasons, strings.ToLower(season)) {
+				continue
+			}
+			if state != "" && !recommendedIn(variety.Regions, state, district) {
+				continue
+			}
+			recommended = append(recommended, variety)
+		}
+		if next == "" {
+			return recommended, nil
+		}
+		page.Cursor = next
+	}
+}
+
+// recommendedIn reports whether regions cover the state and district. A
+// district of "" is covered by any region of the state.
+func recommendedIn(regions []string, state, district string) bool {
+	if len(regions) == 0 {
+		return true
+	}
+	for _, region := range regions {
+		regionState, regionDistrict, _ := strings.Cut(region, "/")
+		if regionState == state && (regionDistrict == "" || district == "" || regionDistrict == district) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareCrop checks a crop about to be saved and tidies its aliases.
+func (s *CatalogService) prepareCrop(ctx context.Context, crop *models.Crop) error {
+	crop.Name = strings.TrimSpace(crop.Name)
+	if crop.Name == "" || !cropIDPattern.MatchString(crop.ID) {
+		return errors.ErrInvalidInput
+	}
+	var aliases []string
+	for _, alias := range crop.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias != "" && !slices.ContainsFunc(aliases, func(a string) bool { return strings.EqualFold(a, alias) }) {
+			aliases = append(aliases, alias)
+		}
+	}
+	crop.Aliases = aliases
+
+	names, err := cropNames(ctx, s.store)
+	if err != nil {
+		return err
+	}
+	for _, name := range append([]string{crop.ID, crop.Name}, crop.Aliases...) {
+		if id, ok := names[cropKey(name)]; ok && id != crop.ID {
+			return errors.ErrConflict
+		}
+	}
+	return nil
+}
+
+// prepareVariety checks a variety about to be saved. Its crop may be given by
+// name and is stored by ID, and its regions are spelled the way the location
+// master does.
+func (s *CatalogService) prepareVariety(ctx context.Context, variety *models.Variety) error {
+	var problems []string
+	variety.Name = strings.TrimSpace(variety.Name)
+	if variety.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if !slices.Contains(VarietyKinds, variety.Kind) {
+		problems = append(problems, fmt.Sprintf("kind must be one of %s", strings.Join(VarietyKinds, ", ")))
+	}
+
+	if variety.CropID == "" {
+		problems = append(problems, "crop is required")
+	} else {
+		crops, err := resolveCrops(ctx, s.store, []string{variety.CropID})
+		var unknown *CatalogError
+		switch {
+		case errors.As(err, &unknown):
+			problems = append(problems, unknown.Problems...)
+		case err != nil:
+			return err
+		default:
+			variety.CropID = crops[0]
+		}
+	}
+
+	if len(variety.Seasons) == 0 {
+		problems = append(problems, "at least one season is required")
+	}
+	for i, season := range variety.Seasons {
+		variety.Seasons[i] = strings.ToLower(season)
+		if !slices.Contains(Seasons, variety.Seasons[i]) {
+			problems = append(problems, fmt.Sprintf("unknown season %q", season))
+		}
+	}
+
+	for i, region := range variety.Regions {
+		state, district, _ := strings.Cut(region, "/")
+		place, err := s.locations.Resolve(location.Place{State: state, District: district})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("unknown region %q", region))
+			continue
+		}
+		variety.Regions[i] = place.State
+		if place.District != "" {
+			variety.Regions[i] += "/" + place.District
+		}
+	}
+
+	codes := make(map[string]bool)
+	for i := range variety.SKUs {
+		sku := &variety.SKUs[i]
+		sku.Code = strings.TrimSpace(sku.Code)
+		switch {
+		case sku.Code == "":
+			problems = append(problems, "SKU code is required")
+			continue
+		case codes[sku.Code]:
+			problems = append(problems, fmt.Sprintf("SKU %s is listed twice", sku.Code))
+		}
+		codes[sku.Code] = true
+		if sku.PackSize <= 0 || !slices.Contains(PackUnits, sku.PackUnit) {
+			problems = append(problems, fmt.Sprintf("SKU %s needs a pack size in %s", sku.Code, strings.Join(PackUnits, ", ")))
+		}
+	}
+	return catalogError(problems)
+}
+
+// cropKey is the form crop names and aliases are compared in, which is also
+// the form of crop IDs: "Bitter Gourd" becomes "bitter-gourd".
+func cropKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(name, "-", " "))), "-")
+}
+
+// cropNames maps the IDs, names and aliases of every crop, by cropKey, to the
+// crop ID.
+func cropNames(ctx context.Context, catalog store.CatalogStore) (map[string]string, error) {
+	names := make(map[string]string)
+	page := store.Page{Limit: 500}
+	for {
+		crops, next, err := catalog.ListCrops(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, crop := range crops {
+			names[crop.ID] = crop.ID
+			names[cropKey(crop.Name)] = crop.ID
+			for _, alias := range crop.Aliases {
+				names[cropKey(alias)] = crop.ID
+			}
+		}
+		if next == "" {
+			return names, nil
+		}
+		page.Cursor = next
+	}
+}
+
+// resolveCrops returns the catalog IDs of crops, which may be given by ID,
+// name or alias, once each and in the order given. Crops missing from the
+// catalog fail as a *CatalogError.
+//
+// Crops are mostly given by ID, or by a name that is their ID, so each is
+// looked up by ID first; the whole catalog is only read for the others.
+func resolveCrops(ctx context.Context, catalog store.CatalogStore, crops []string) ([]string, error) {
+	var ids, problems []string
+	var names map[string]string
+	for _, crop := range crops {
+		key := cropKey(crop)
+		id := ""
+		if _, err := catalog.GetCrop(ctx, key); err == nil {
+			id = key
+		} else if err != errors.ErrNotFound {
+			return nil, err
+		} else {
+			if names == nil {
+				if names, err = cropNames(ctx, catalog); err != nil {
+					return nil, err
+				}
+			}
+			id = names[key]
+		}
+		switch {
+		case id == "":
+			problems = append(problems, fmt.Sprintf("unknown crop %q", crop))
+		case !slices.Contains(ids, id):
+			ids = append(ids, id)
+		}
+	}
+	if err := catalogError(problems); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// resolveProduct checks the catalog references of a ticket and fills in the
+// crop of its variety.
+func resolveProduct(ctx context.Context, catalog store.CatalogStore, ticket *models.Ticket) error {
+	var problems []string
+	if ticket.CropID != "" {
+		crops, err := resolveCrops(ctx, catalog, []string{ticket.CropID})
+		if err != nil {
+			return err
+		}
+		ticket.CropID = crops[0]
+	}
+
+	if ticket.VarietyID == "" {
+		if ticket.SKU != "" {
+			problems = append(problems, "an SKU needs its variety")
+		}
+		return catalogError(problems)
+	}
+	variety, err := catalog.GetVariety(ctx, ticket.VarietyID)
+	if err == errors.ErrNotFound {
+		return catalogError([]string{fmt.Sprintf("unknown variety %q", ticket.VarietyID)})
+	}
+	if err != nil {
+		return err
+	}
+	switch ticket.CropID {
+	case "":
+		ticket.CropID = variety.CropID
+	case variety.CropID:
+	default:
+		problems = append(problems, fmt.Sprintf("variety %s is not a %s variety", variety.Name, ticket.CropID))
+	}
+	if ticket.SKU != "" && !slices.ContainsFunc(variety.SKUs, func(sku models.SKU) bool { return sku.Code == ticket.SKU }) {
+		problems = append(problems, fmt.Sprintf("variety %s has no SKU %s", variety.Name, ticket.SKU))
+	}
+	return catalogError(problems)
+}
+
+// LinkCrops replaces the free text crops of farmers saved before the catalog
+// with the IDs of the catalog crops they name. It returns how many farmers it
+// linked and, by how many farmers grow them, the crops it found nowhere in
+// the catalog. Farmers growing one of those are left as they are; add the
+// crop, or an alias, to the catalog and run it again.
+func (s *FarmerService) LinkCrops(ctx context.Context) (int, map[string]int, error) {
+	names, err := cropNames(ctx, s.catalog)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	linked := 0
+	unknown := make(map[string]int)
+	page := store.Page{Limit: 500}
+	for {
+		farmers, next, err := s.store.List(ctx, page)
+		if err != nil {
+			return linked, unknown, err
+		}
+		for i := range farmers {
+			farmer := &farmers[i]
+			var crops []string
+			complete := true
+			for _, crop := range farmer.Crop {
+				id, ok := names[cropKey(crop)]
+				if !ok {
+					unknown[crop]++
+					complete = false
+					continue
+				}
+				if !slices.Contains(crops, id) {
+					crops = append(crops, id)
+				}
+			}
+			if !complete || slices.Equal(crops, farmer.Crop) {
+				continue
+			}
+			farmer.Crop = crops
+			err := s.update(ctx, farmer, false)
+			if err == errors.ErrVersionConflict || err == ErrFarmerErased {
+				// Edited meanwhile, which linked its crops already.
+				continue
+			}
+			if err != nil {
+				return linked, unknown, err
+			}
+			linked++
+		}
+		if next == "" {
+			return linked, unknown, nil
+		}
+		page.Cursor = next
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/pkg/errors"
+)
+
+func TestDeleteCropRefusesCropInUse(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// use refers to the crop "bajra" somewhere.
+		use  func(t *testing.T, farmers *FarmerService, tickets *TicketService, catalog *CatalogService)
+		want error
+	}{
+		{
+			name: "unused",
+			use:  func(*testing.T, *FarmerService, *TicketService, *CatalogService) {},
+		},
+		{
+			name: "variety",
+			use: func(t *testing.T, _ *FarmerService, _ *TicketService, catalog *CatalogService) {
+				variety := &models.Variety{Name: "HHB 67", CropID: "bajra", Kind: models.VarietyHybrid, Seasons: []string{models.SeasonKharif}}
+				if err := catalog.CreateVariety(ctx, variety); err != nil {
+					t.Fatalf("CreateVariety: %v", err)
+				}
+			},
+			want: errors.ErrConflict,
+		},
+		{
+			name: "farmer",
+			use: func(t *testing.T, farmers *FarmerService, _ *TicketService, _ *CatalogService) {
+				if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210", Crop: []string{"Bajra"}}); err != nil {
+					t.Fatalf("CreateFarmer: %v", err)
+				}
+			},
+			want: errors.ErrConflict,
+		},
+		{
+			name: "farmer in the trash",
+			use: func(t *testing.T, farmers *FarmerService, _ *TicketService, _ *CatalogService) {
+				if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210", Crop: []string{"bajra"}}); err != nil {
+					t.Fatalf("CreateFarmer: %v", err)
+				}
+				if err := farmers.DeleteFarmer(ctx, "f1", "admin"); err != nil {
+					t.Fatalf("DeleteFarmer: %v", err)
+				}
+			},
+			want: errors.ErrConflict,
+		},
+		{
+			name: "ticket",
+			use: func(t *testing.T, _ *FarmerService, tickets *TicketService, _ *CatalogService) {
+				if err := tickets.CreateTicket(ctx, &models.Ticket{ID: "t1", CropID: "bajra"}); err != nil {
+					t.Fatalf("CreateTicket: %v", err)
+				}
+			},
+			want: errors.ErrConflict,
+		},
+		{
+			name: "ticket in the trash",
+			use: func(t *testing.T, _ *FarmerService, tickets *TicketService, _ *CatalogService) {
+				if err := tickets.CreateTicket(ctx, &models.Ticket{ID: "t1", CropID: "bajra"}); err != nil {
+					t.Fatalf("CreateTicket: %v", err)
+				}
+				if err := tickets.DeleteTicket(ctx, "t1", "admin"); err != nil {
+					t.Fatalf("DeleteTicket: %v", err)
+				}
+			},
+			want: errors.ErrConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stores := newTestStores(t)
+			audit := NewAuditService(stores.Audit)
+			locations, err := location.Load(strings.NewReader("state,district\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			farmers := newTestFarmerService(t, stores, nil)
+			tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+			catalog := NewCatalogService(stores.Catalog, stores.Farmer, stores.Ticket, locations, audit)
+
+			if err := catalog.CreateCrop(ctx, &models.Crop{Name: "Bajra"}); err != nil {
+				t.Fatalf("CreateCrop: %v", err)
+			}
+			tt.use(t, farmers, tickets, catalog)
+
+			if err := catalog.DeleteCrop(ctx, "bajra"); err != tt.want {
+				t.Fatalf("DeleteCrop = %v, want %v", err, tt.want)
+			}
+			_, err = catalog.GetCrop(ctx, "bajra")
+			if tt.want == nil && err != errors.ErrNotFound {
+				t.Errorf("GetCrop after delete = %v, want ErrNotFound", err)
+			}
+			if tt.want != nil && err != nil {
+				t.Errorf("GetCrop after refused delete = %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteCropIgnoresMergedAliases(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	audit := NewAuditService(stores.Audit)
+	locations, err := location.Load(strings.NewReader("state,district\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := NewCatalogService(stores.Catalog, stores.Farmer, stores.Ticket, locations, audit)
+	if err := catalog.CreateCrop(ctx, &models.Crop{Name: "Bajra"}); err != nil {
+		t.Fatalf("CreateCrop: %v", err)
+	}
+
+	// An alias keeps the crops it had; its survivor took them over and
+	// dropped them since.
+	alias := &models.Farmer{ID: "f2", Name: "Ramesh Patil", Crop: []string{"bajra"}, MergedInto: "f1"}
+	if err := stores.Farmer.Put(ctx, alias); err != nil {
+		t.Fatalf("Put alias: %v", err)
+	}
+	if err := catalog.DeleteCrop(ctx, "bajra"); err != nil {
+		t.Fatalf("DeleteCrop: %v", err)
+	}
+}
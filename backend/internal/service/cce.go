@@ -2,125 +2,112 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"backend/internal/models"
-	"backend/pkg/errors"
+	"backend/internal/store"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 )
 
-const CCETableName = "CCEs"
-
 type CCEService struct {
-	dbClient *dynamodb.Client
+	store store.CCEStore
+	audit *AuditService
 }
 
-func NewCCEService(dbClient *dynamodb.Client) *CCEService {
+func NewCCEService(cceStore store.CCEStore, audit *AuditService) *CCEService {
 	return &CCEService{
-		dbClient: dbClient,
+		store: cceStore,
+		audit: audit,
 	}
 }
 
+// CreateCCE saves a new CCE, never deleted. Its ticket counters start at zero
+// and are only changed by ticket writes from then on. A CCE without an ID is
+// given one; an ID that is taken fails with ErrConflict.
 func (s *CCEService) CreateCCE(ctx context.Context, cce *models.CCE) error {
-	item, err := attributevalue.MarshalMap(cce)
-	if err != nil {
-		return errors.ErrInternal
+	if cce.ID == "" {
+		cce.ID = uuid.New().String()
 	}
-
-	_, err = s.dbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(CCETableName),
-		Item:      item,
-	})
-	if err != nil {
-		return errors.ErrInternal
+	cce.TicketIDs = nil
+	cce.OpenTickets = 0
+	cce.ClosedTickets = 0
+	cce.ResolvedSeconds = 0
+	cce.AvgTime = 0
+	cce.Version = 1
+	cce.DeletedAt = nil
+	cce.DeletedBy = ""
+	if err := s.store.Put(ctx, cce); err != nil {
+		return err
 	}
 
+	s.audit.Record(ctx, EntityCCE, cce.ID, ActionCreate, nil, cce)
 	return nil
 }
 
 func (s *CCEService) GetCCE(ctx context.Context, id string) (*models.CCE, error) {
-	result, err := s.dbClient.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(CCETableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: id},
-		},
-	})
-	if err != nil {
-		return nil, errors.ErrInternal
-	}
-	if result.Item == nil {
-		return nil, errors.ErrNotFound
-	}
-
-	var cce models.CCE
-	err = attributevalue.UnmarshalMap(result.Item, &cce)
+	cce, err := s.store.Get(ctx, id)
 	if err != nil {
-		return nil, errors.ErrInternal
+		return nil, err
 	}
-
-	return &cce, nil
+	setAvgTime(cce)
+	return cce, nil
 }
 
+// UpdateCCE saves cce if it is still at cce.Version and bumps the version.
 func (s *CCEService) UpdateCCE(ctx context.Context, cce *models.CCE) error {
-	item, err := attributevalue.MarshalMap(cce)
+	before, err := s.store.Get(ctx, cce.ID)
 	if err != nil {
-		return errors.ErrInternal
+		return err
 	}
-
-	_, err = s.dbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(CCETableName),
-		Item:      item,
-	})
-	if err != nil {
-		return errors.ErrInternal
+	if err := s.store.Update(ctx, cce); err != nil {
+		return err
 	}
 
+	s.audit.Record(ctx, EntityCCE, cce.ID, ActionUpdate, before, cce)
 	return nil
 }
 
-func (s *CCEService) DeleteCCE(ctx context.Context, id string) error {
-	_, err := s.dbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(CCETableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: id},
-		},
-	})
-	if err != nil {
-		return errors.ErrInternal
+func (s *CCEService) DeleteCCE(ctx context.Context, id, deletedBy string) error {
+	if err := s.store.Delete(ctx, id, deletedBy); err != nil {
+		return err
 	}
 
+	s.audit.Record(ctx, EntityCCE, id, ActionDelete, nil, nil)
 	return nil
 }
 
-func (s *CCEService) ListCCEs(ctx context.Context, nextToken string) ([]models.CCE, string, error) {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(CCETableName),
-	}
+func (s *CCEService) ListDeletedCCEs(ctx context.Context, page store.Page) ([]models.CCE, string, error) {
+	return s.store.ListDeleted(ctx, page)
+}
 
-	if nextToken != "" {
-		input.ExclusiveStartKey = map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: nextToken},
-		}
+func (s *CCEService) RestoreCCE(ctx context.Context, id string) error {
+	if err := s.store.Restore(ctx, id); err != nil {
+		return err
 	}
 
-	result, err := s.dbClient.Scan(ctx, input)
-	if err != nil {
-		return nil, "", errors.ErrInternal
-	}
+	s.audit.Record(ctx, EntityCCE, id, ActionRestore, nil, nil)
+	return nil
+}
 
-	var cces []models.CCE
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &cces)
+func (s *CCEService) PurgeCCEs(ctx context.Context, before time.Time) (int, error) {
+	return s.store.Purge(ctx, before)
+}
+
+func (s *CCEService) ListCCEs(ctx context.Context, page store.Page) ([]models.CCE, string, error) {
+	cces, nextCursor, err := s.store.List(ctx, page)
 	if err != nil {
-		return nil, "", errors.ErrInternal
+		return nil, "", err
 	}
-
-	var newNextToken string
-	if result.LastEvaluatedKey != nil {
-		newNextToken = result.LastEvaluatedKey["ID"].(*types.AttributeValueMemberS).Value
+	for i := range cces {
+		setAvgTime(&cces[i])
 	}
+	return cces, nextCursor, nil
+}
 
-	return cces, newNextToken, nil
+// setAvgTime derives AvgTime from the counters kept by ticket writes.
+func setAvgTime(cce *models.CCE) {
+	if cce.ClosedTickets > 0 {
+		cce.AvgTime = float64(cce.ResolvedSeconds) / float64(cce.ClosedTickets)
+	}
 }
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/auth"
+	"backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// consentAttempts bounds how often a consent change is retried when the
+// farmer is edited at the same time.
+const consentAttempts = 3
+
+// ConsentError is returned for outreach to a farmer who has not agreed to
+// the channel, or has withdrawn. It matches errors.ErrConflict.
+type ConsentError struct {
+	FarmerID string
+	Channel  string
+	// Status is the consent on record, empty if there is none.
+	Status string
+	Reason string
+}
+
+func (e *ConsentError) Error() string {
+	switch e.Status {
+	case models.ConsentRevoked:
+		msg := fmt.Sprintf("farmer %s opted out of %s", e.FarmerID, e.Channel)
+		if e.Reason != "" {
+			msg += ": " + e.Reason
+		}
+		return msg
+	default:
+		return fmt.Sprintf("farmer %s has not consented to %s", e.FarmerID, e.Channel)
+	}
+}
+
+func (e *ConsentError) Is(target error) bool {
+	return target == errors.ErrConflict
+}
+
+// ConsentChange grants or withdraws consent on some channels.
+type ConsentChange struct {
+	Channels []string
+	Status   string
+	Source   string
+	Reason   string
+}
+
+type ConsentService struct {
+	store   store.ConsentStore
+	farmers *FarmerService
+}
+
+// NewConsentService returns a ConsentService on consentStore. The contact
+// numbers of consent events are encrypted with the cipher of farmers, if it
+// has one.
+func NewConsentService(consentStore store.ConsentStore, farmers *FarmerService) *ConsentService {
+	if farmers.cipher != nil {
+		consentStore = &sealedConsent{ConsentStore: consentStore, cipher: farmers.cipher}
+	}
+	return &ConsentService{
+		store:   consentStore,
+		farmers: farmers,
+	}
+}
+
+// SetConsent applies change to the farmer and records one event per channel
+// in the consent history. Channels already in the requested status are left
+// alone and get no event. A failed event write is logged rather than
+// returned, like an audit record.
+func (s *ConsentService) SetConsent(ctx context.Context, farmerID string, change ConsentChange) (*models.Farmer, error) {
+	if err := change.validate(); err != nil {
+		return nil, err
+	}
+
+	actor := auth.UserID(ctx)
+	if actor == "" {
+		actor = systemActor
+	}
+
+	for attempt := 1; ; attempt++ {
+		farmer, err := s.farmers.GetFarmer(ctx, farmerID)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		consent := make(map[string]models.ChannelConsent, len(models.Channels))
+		for channel, c := range farmer.Consent {
+			consent[channel] = c
+		}
+		var changed []string
+		for _, channel := range change.Channels {
+			if consent[channel].Status == change.Status {
+				continue
+			}
+			consent[channel] = models.ChannelConsent{
+				Status:    change.Status,
+				Source:    change.Source,
+				Reason:    change.Reason,
+				UpdatedAt: now,
+				UpdatedBy: actor,
+			}
+			changed = append(changed, channel)
+		}
+		if len(changed) == 0 {
+			return farmer, nil
+		}
+
+		farmer.Consent = consent
+		err = s.farmers.update(ctx, farmer, true)
+		if err == errors.ErrVersionConflict && attempt < consentAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, channel := range changed {
+			event := &models.ConsentEvent{
+				ID:        now.Format("20060102T150405.000000000Z") + "-" + uuid.New().String(),
+				FarmerID:  farmer.ID,
+				Contact:   farmer.Contact,
+				Channel:   channel,
+				Status:    change.Status,
+				Source:    change.Source,
+				Reason:    change.Reason,
+				Actor:     actor,
+				Timestamp: now,
+			}
+			if err := s.store.Put(ctx, event); err != nil {
+				// The consent is saved and enforced already, and the
+				// farmer's audit trail holds the change too.
+				log.Printf("Failed to record %s consent event for farmer %s: %v", channel, farmer.ID, err)
+			}
+		}
+		return farmer, nil
+	}
+}
+
+// OptOut withdraws the consent of the farmer holding contact, on every
+// channel when change lists none. It serves inbound STOP replies, which only
+// know the number they came from.
+func (s *ConsentService) OptOut(ctx context.Context, contact string, change ConsentChange) (*models.Farmer, error) {
+	farmer, err := s.farmers.GetFarmerByContact(ctx, contact)
+	if err != nil {
+		return nil, err
+	}
+	if len(change.Channels) == 0 {
+		change.Channels = models.Channels
+	}
+	change.Status = models.ConsentRevoked
+	return s.SetConsent(ctx, farmer.ID, change)
+}
+
+// RequireConsent returns the farmer if they have granted consent to be
+// reached on channel, and a *ConsentError otherwise. Farmers created before
+// consent was recorded are granted it by the data migration that ships with
+// this check, with Source "legacy".
+func (s *ConsentService) RequireConsent(ctx context.Context, farmerID, channel string) (*models.Farmer, error) {
+	farmer, err := s.farmers.GetFarmer(ctx, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	consent := farmer.Consent[channel]
+	if consent.Status != models.ConsentGranted {
+		return nil, &ConsentError{FarmerID: farmer.ID, Channel: channel, Status: consent.Status, Reason: consent.Reason}
+	}
+	return farmer, nil
+}
+
+// History returns the consent changes of one farmer, oldest first.
+func (s *ConsentService) History(ctx context.Context, farmerID string, page store.Page) ([]models.ConsentEvent, string, error) {
+	return s.store.ListByFarmer(ctx, farmerID, page)
+}
+
+// ExportHistory writes the consent changes between startDate and endDate to w
+// as CSV, oldest first, for compliance reviews. Nothing is written unless every
+// event could be read.
+func (s *ConsentService) ExportHistory(ctx context.Context, w io.Writer, startDate, endDate time.Time) error {
+	var events []models.ConsentEvent
+	page := store.Page{Limit: 500}
+	for {
+		batch, next, err := s.store.ListByTimestamp(ctx, startDate, endDate, page)
+		if err != nil {
+			return err
+		}
+		events = append(events, batch...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"timestamp", "farmer_id", "contact", "channel", "status", "source", "reason", "actor"})
+	for _, event := range events {
+		out.Write([]string{
+			event.Timestamp.Format(time.RFC3339),
+			event.FarmerID,
+			event.Contact,
+			event.Channel,
+			event.Status,
+			event.Source,
+			event.Reason,
+			event.Actor,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func (c *ConsentChange) validate() error {
+	if len(c.Channels) == 0 || c.Source == "" {
+		return errors.ErrInvalidInput
+	}
+	if c.Status != models.ConsentGranted && c.Status != models.ConsentRevoked {
+		return errors.ErrInvalidInput
+	}
+	for _, channel := range c.Channels {
+		if !slices.Contains(models.Channels, channel) {
+			return errors.ErrInvalidInput
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"backend/internal/models"
+	"backend/pkg/errors"
+	"backend/pkg/phone"
+)
+
+// AddContact, UpdateContact and RemoveContact edit a farmer's contacts one at
+// a time. They save the farmer as UpdateFarmer does, so every number claim and
+// release happens in the same write as the farmer and shows up in its audit
+// trail.
+//
+// They fail with errors.ErrConflict for a change that would leave the farmer
+// with contacts but none of them primary.
+
+// GetContacts returns the farmer's contacts, primary one included.
+func (s *FarmerService) GetContacts(ctx context.Context, farmerID string) ([]models.FarmerContact, error) {
+	farmer, err := s.GetFarmer(ctx, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	return contactsOf(farmer), nil
+}
+
+// AddContact adds a number to the farmer. A primary contact takes over
+// Farmer.Contact and the previous primary number stays on as a secondary one.
+func (s *FarmerService) AddContact(ctx context.Context, farmerID string, contact models.FarmerContact) (*models.Farmer, error) {
+	farmer, err := s.GetFarmer(ctx, farmerID)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts := contactsOf(farmer)
+	if len(contacts) == 0 {
+		contact.Primary = true
+	}
+	if contact.Primary {
+		for i := range contacts {
+			contacts[i].Primary = false
+		}
+	}
+	return s.saveContacts(ctx, farmer, append(contacts, contact))
+}
+
+// UpdateContact replaces the contact holding number, given in any form
+// phone.Normalize accepts. contact may carry a new number; without one the
+// number stays. Making a contact primary demotes the previous primary one.
+func (s *FarmerService) UpdateContact(ctx context.Context, farmerID, number string, contact models.FarmerContact) (*models.Farmer, error) {
+	farmer, contacts, i, err := s.findContact(ctx, farmerID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if contacts[i].Primary && !contact.Primary {
+		return nil, errors.ErrConflict
+	}
+	if contact.Number == "" {
+		contact.Number = contacts[i].Number
+	}
+	if contact.Primary {
+		for j := range contacts {
+			contacts[j].Primary = false
+		}
+	}
+	contacts[i] = contact
+	return s.saveContacts(ctx, farmer, contacts)
+}
+
+// RemoveContact drops the contact holding number and frees the number for
+// other farmers. The primary contact can only go once it is the last one.
+func (s *FarmerService) RemoveContact(ctx context.Context, farmerID, number string) (*models.Farmer, error) {
+	farmer, contacts, i, err := s.findContact(ctx, farmerID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if contacts[i].Primary && len(contacts) > 1 {
+		return nil, errors.ErrConflict
+	}
+	return s.saveContacts(ctx, farmer, slices.Delete(contacts, i, i+1))
+}
+
+// findContact returns the farmer, its contacts and the index of the one
+// holding number.
+func (s *FarmerService) findContact(ctx context.Context, farmerID, number string) (*models.Farmer, []models.FarmerContact, int, error) {
+	normalised, err := phone.Normalize(number)
+	if err != nil {
+		return nil, nil, 0, errors.ErrInvalidInput
+	}
+	farmer, err := s.GetFarmer(ctx, farmerID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	contacts := contactsOf(farmer)
+	i := slices.IndexFunc(contacts, func(c models.FarmerContact) bool { return c.Number == normalised })
+	if i < 0 {
+		return nil, nil, 0, errors.ErrNotFound
+	}
+	return farmer, contacts, i, nil
+}
+
+// saveContacts replaces the farmer's contacts and moves Contact to the primary
+// one.
+func (s *FarmerService) saveContacts(ctx context.Context, farmer *models.Farmer, contacts []models.FarmerContact) (*models.Farmer, error) {
+	farmer.Contacts = contacts
+	farmer.Contact = ""
+	for _, contact := range contacts {
+		if contact.Primary {
+			farmer.Contact = contact.Number
+		}
+	}
+	if farmer.Contact == "" && len(contacts) > 0 {
+		return nil, errors.ErrConflict
+	}
+
+	if err := s.update(ctx, farmer, false); err != nil {
+		return nil, err
+	}
+	return farmer, nil
+}
+
+// contactsOf returns a copy of the farmer's contacts. A farmer saved before
+// contacts existed has its Contact as its only, primary, contact.
+func contactsOf(farmer *models.Farmer) []models.FarmerContact {
+	if len(farmer.Contacts) > 0 || farmer.Contact == "" {
+		return slices.Clone(farmer.Contacts)
+	}
+	return []models.FarmerContact{{
+		Number:       farmer.Contact,
+		Relationship: models.RelationshipSelf,
+		Primary:      true,
+	}}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/search"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+// DuplicateThreshold is the score from which FindDuplicates queues a pair of
+// farmers for review.
+const DuplicateThreshold = 0.75
+
+// minNameSimilarity is the name similarity below which a pair is not scored
+// at all: a shared village or pincode does not make two names the same person.
+const minNameSimilarity = 0.6
+
+// maxDuplicateBlock bounds the farmers compared with each other in one block.
+// Comparisons grow with the square of the block, and a block this large is a
+// placeholder pincode or village rather than a real place.
+const maxDuplicateBlock = 500
+
+type DuplicateService struct {
+	store   store.DuplicateStore
+	farmers *FarmerService
+	tickets *TicketService
+	shoots  *ShootService
+}
+
+func NewDuplicateService(duplicateStore store.DuplicateStore, farmers *FarmerService, tickets *TicketService, shoots *ShootService) *DuplicateService {
+	return &DuplicateService{
+		store:   duplicateStore,
+		farmers: farmers,
+		tickets: tickets,
+		shoots:  shoots,
+	}
+}
+
+// FindDuplicates compares the farmers that share a pincode or a village and
+// queues the pairs scoring at least DuplicateThreshold for review. Pairs that
+// were queued before are not queued again, so a dismissed pair stays
+// dismissed. It returns how many pairs it queued.
+func (s *DuplicateService) FindDuplicates(ctx context.Context) (int, error) {
+	var farmers []models.Farmer
+	page := store.Page{Limit: 500}
+	for {
+		batch, next, err := s.farmers.ListFarmers(ctx, page)
+		if err != nil {
+			return 0, err
+		}
+		farmers = append(farmers, batch...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+
+	blocks := make(map[string][]int)
+	for i, farmer := range farmers {
+		if farmer.Pincode != "" {
+			key := "pincode:" + farmer.Pincode
+			blocks[key] = append(blocks[key], i)
+		}
+		if farmer.Village != "" {
+			key := "village:" + villageKey(&farmer)
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+	keys := make([]string, 0, len(blocks))
+	for key := range blocks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	now := time.Now().UTC()
+	compared := make(map[string]bool)
+	queued := 0
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return queued, err
+		}
+		members := blocks[key]
+		if len(members) > maxDuplicateBlock {
+			log.Printf("Skipping duplicate check of %s: %d farmers", key, len(members))
+			continue
+		}
+
+		for i, a := range members {
+			for _, b := range members[i+1:] {
+				id := models.DuplicatePairID(farmers[a].ID, farmers[b].ID)
+				if compared[id] {
+					continue
+				}
+				compared[id] = true
+
+				score, reasons := scoreDuplicate(&farmers[a], &farmers[b])
+				if score < DuplicateThreshold {
+					continue
+				}
+				first, second := farmers[a].ID, farmers[b].ID
+				if second < first {
+					first, second = second, first
+				}
+				err := s.store.Put(ctx, &models.DuplicateCandidate{
+					ID:            id,
+					FarmerID:      first,
+					OtherFarmerID: second,
+					Score:         score,
+					Reasons:       reasons,
+					Status:        models.DuplicateStatusPending,
+					CreatedAt:     now,
+				})
+				if err == errors.ErrConflict {
+					continue
+				}
+				if err != nil {
+					return queued, err
+				}
+				queued++
+			}
+		}
+	}
+	return queued, nil
+}
+
+// ListCandidates returns one page of the candidates in status.
+func (s *DuplicateService) ListCandidates(ctx context.Context, status string, page store.Page) ([]models.DuplicateCandidate, string, error) {
+	switch status {
+	case models.DuplicateStatusPending, models.DuplicateStatusMerged, models.DuplicateStatusDismissed:
+	default:
+		return nil, "", errors.ErrInvalidInput
+	}
+	return s.store.ListByStatus(ctx, status, page)
+}
+
+// DismissCandidate marks a pending candidate as not a duplicate.
+func (s *DuplicateService) DismissCandidate(ctx context.Context, id, dismissedBy string) error {
+	return s.store.Review(ctx, id, models.DuplicateStatusDismissed, dismissedBy, time.Now().UTC())
+}
+
+// MergeFarmers folds the farmer duplicateID into survivorID: the records are
+// combined as described at FarmerService.mergeFarmer, its ID stays behind as
+// an alias of the survivor and its tickets and shoots move to the survivor. A
+// queued candidate for the pair is marked merged.
+//
+// Erased farmers cannot be merged, either way round, and fail with
+// ErrFarmerErased: the survivor would take the loser's personal data back
+// onto an erased record.
+//
+// The farmer records change first and all at once, so a merge refused there
+// leaves everything as it was. Tickets and shoots move afterwards; if that
+// fails part way, running the merge again finds the alias in place and moves
+// the rest.
+func (s *DuplicateService) MergeFarmers(ctx context.Context, survivorID, duplicateID, mergedBy string) (*models.Farmer, error) {
+	if survivorID == duplicateID || duplicateID == "" {
+		return nil, errors.ErrInvalidInput
+	}
+	survivor, err := s.farmers.store.Get(ctx, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	duplicate, err := s.farmers.store.Get(ctx, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case survivor.ErasedAt != nil || duplicate.ErasedAt != nil:
+		return nil, ErrFarmerErased
+	case survivor.MergedInto != "":
+		return nil, errors.ErrConflict
+	case duplicate.MergedInto == survivorID:
+		// A previous merge of the pair stopped before moving everything.
+	case duplicate.MergedInto != "":
+		return nil, errors.ErrConflict
+	default:
+		if err := s.farmers.mergeFarmer(ctx, survivor, duplicate); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.tickets.MoveFarmerTickets(ctx, duplicateID, survivorID); err != nil {
+		return nil, fmt.Errorf("moving tickets: %w", err)
+	}
+	if _, err := s.shoots.MoveFarmerShoots(ctx, duplicateID, survivorID); err != nil {
+		return nil, fmt.Errorf("moving shoots: %w", err)
+	}
+
+	pairID := models.DuplicatePairID(survivorID, duplicateID)
+	err = s.store.Review(ctx, pairID, models.DuplicateStatusMerged, mergedBy, time.Now().UTC())
+	if err != nil && err != errors.ErrNotFound && err != errors.ErrConflict {
+		log.Printf("Failed to mark duplicate candidate %s merged: %v", pairID, err)
+	}
+	return survivor, nil
+}
+
+// scoreDuplicate rates how likely a and b are the same farmer and explains
+// the score. The name carries most of the weight; the place and the contact
+// numbers then add to or take from it. Two farmers never hold the same
+// number, so a near miss is the best the contacts can do.
+func scoreDuplicate(a, b *models.Farmer) (float64, []string) {
+	name := search.NameSimilarity(a.Name, b.Name)
+	if name < minNameSimilarity {
+		return 0, nil
+	}
+
+	score := 0.6 * name
+	reasons := []string{fmt.Sprintf("name similarity %.2f", name)}
+	if a.Village != "" && villageKey(a) == villageKey(b) {
+		score += 0.2
+		reasons = append(reasons, "same village")
+	}
+	if a.Pincode != "" && a.Pincode == b.Pincode {
+		score += 0.15
+		reasons = append(reasons, "same pincode")
+	}
+	switch {
+	case a.Contact == "" || b.Contact == "":
+		score += 0.05
+		reasons = append(reasons, "contact missing on one record")
+	case nearContacts(a.Contact, b.Contact):
+		score += 0.1
+		reasons = append(reasons, "contacts differ by one digit")
+	default:
+		score -= 0.1
+	}
+	return min(score, 1), reasons
+}
+
+// villageKey identifies a village by its district too, as village names
+// repeat across districts.
+func villageKey(farmer *models.Farmer) string {
+	return strings.ToLower(farmer.District) + "|" + strings.ToLower(farmer.Village)
+}
+
+// nearContacts reports whether two contact numbers differ by one mistyped
+// digit or two swapped neighbouring digits.
+func nearContacts(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var diffs []int
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			diffs = append(diffs, i)
+		}
+	}
+	switch len(diffs) {
+	case 1:
+		return true
+	case 2:
+		i, j := diffs[0], diffs[1]
+		return j == i+1 && a[i] == b[j] && a[j] == b[i]
+	default:
+		return false
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+func TestScoreDuplicate(t *testing.T) {
+	base := models.Farmer{
+		Name:     "Ramesh Patil",
+		District: "Nashik",
+		Village:  "Ozar",
+		Pincode:  "422206",
+		Contact:  "+919876543210",
+	}
+	with := func(change func(*models.Farmer)) *models.Farmer {
+		farmer := base
+		change(&farmer)
+		return &farmer
+	}
+
+	tests := []struct {
+		name    string
+		b       *models.Farmer
+		want    float64
+		reasons []string
+	}{
+		{
+			name: "same farmer registered again without a number",
+			b: with(func(f *models.Farmer) {
+				f.Contact = ""
+			}),
+			want:    1,
+			reasons: []string{"name similarity 1.00", "same village", "same pincode", "contact missing on one record"},
+		},
+		{
+			name: "village matched case-insensitively with its district",
+			b: with(func(f *models.Farmer) {
+				f.Village, f.District, f.Pincode, f.Contact = "OZAR", "nashik", "", ""
+			}),
+			want:    0.85,
+			reasons: []string{"name similarity 1.00", "same village", "contact missing on one record"},
+		},
+		{
+			name: "same village name in another district",
+			b: with(func(f *models.Farmer) {
+				f.District, f.Pincode, f.Contact = "Pune", "411001", "+918888888888"
+			}),
+			want:    0.5,
+			reasons: []string{"name similarity 1.00"},
+		},
+		{
+			name: "mistyped contact",
+			b: with(func(f *models.Farmer) {
+				f.Village, f.Pincode, f.Contact = "", "", "+919876543211"
+			}),
+			want:    0.7,
+			reasons: []string{"name similarity 1.00", "contacts differ by one digit"},
+		},
+		{
+			name: "different contact counts against",
+			b: with(func(f *models.Farmer) {
+				f.Village, f.Pincode, f.Contact = "", "", "+918888888888"
+			}),
+			want:    0.5,
+			reasons: []string{"name similarity 1.00"},
+		},
+		{
+			name: "unlike names are not scored",
+			b: with(func(f *models.Farmer) {
+				f.Name = "Sunita Deshmukh"
+			}),
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := base
+			score, reasons := scoreDuplicate(&a, tt.b)
+			if math.Abs(score-tt.want) > 1e-9 {
+				t.Fatalf("score = %v, want %v (reasons %q)", score, tt.want, reasons)
+			}
+			if !slices.Equal(reasons, tt.reasons) {
+				t.Fatalf("reasons = %q, want %q", reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestNearContacts(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "+919876543210", b: "+919876543210", want: false},
+		{a: "+919876543210", b: "+919876543219", want: true},
+		{a: "+919876543210", b: "+919876543201", want: true},
+		{a: "+919876543210", b: "+919876543012", want: false},
+		{a: "+919876543210", b: "+919876543299", want: false},
+		{a: "+919876543210", b: "+91987654321", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := nearContacts(tt.a, tt.b); got != tt.want {
+				t.Fatalf("nearContacts(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeFarmersFinishesInterruptedMerge(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	audit := NewAuditService(stores.Audit)
+	farmers := newTestFarmerService(t, stores, nil)
+	tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+	shoots := NewShootService(stores.Shoot, NewConsentService(stores.Consent, farmers), nil)
+	duplicates := NewDuplicateService(stores.Duplicate, farmers, tickets, shoots)
+
+	for _, farmer := range []*models.Farmer{
+		{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210"},
+		{ID: "f2", Name: "Ramesh Patil", Contact: "9876543211"},
+	} {
+		if err := farmers.CreateFarmer(ctx, farmer); err != nil {
+			t.Fatalf("CreateFarmer(%s): %v", farmer.ID, err)
+		}
+	}
+	if err := tickets.CreateTicket(ctx, &models.Ticket{ID: "t1", FarmerID: "f2"}); err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	if err := stores.Shoot.Put(ctx, &models.Shoot{ID: "s1", FarmerID: "f2", Type: "call", Timestamp: time.Now().UTC()}); err != nil {
+		t.Fatalf("Put shoot: %v", err)
+	}
+
+	// A merge that stopped after writing the farmers, before anything moved.
+	survivor, _ := stores.Farmer.Get(ctx, "f1")
+	duplicate, _ := stores.Farmer.Get(ctx, "f2")
+	if err := farmers.mergeFarmer(ctx, survivor, duplicate); err != nil {
+		t.Fatalf("mergeFarmer: %v", err)
+	}
+
+	merged, err := duplicates.MergeFarmers(ctx, "f1", "f2", "admin")
+	if err != nil {
+		t.Fatalf("MergeFarmers run again: %v", err)
+	}
+	if merged.ID != "f1" {
+		t.Errorf("MergeFarmers returned farmer %s, want f1", merged.ID)
+	}
+	ticket, err := tickets.GetTicket(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.FarmerID != "f1" {
+		t.Errorf("ticket belongs to %s, want f1", ticket.FarmerID)
+	}
+	moved, _, err := stores.Shoot.ListByFarmer(ctx, "f1", store.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 1 || moved[0].ID != "s1" {
+		t.Errorf("survivor has shoots %v, want s1", moved)
+	}
+
+	if _, err := duplicates.MergeFarmers(ctx, "f2", "f1", "admin"); !errors.Is(err, errors.ErrConflict) {
+		t.Errorf("merging into an alias = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/auth"
+	"backend/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// ActionErase is audited when a farmer's personal data is erased.
+const ActionErase = "erase"
+
+// ErrFarmerErased is returned for edits of a farmer whose personal data was
+// erased.
+var ErrFarmerErased = errors.New("farmer was erased")
+
+// erasedFarmerFields are the farmer fields an erasure wipes, by JSON name. The
+// ID, state, district, tehsil and crops stay, so reports and aggregates that
+// count farmers by region or crop come out the same after an erasure.
+var erasedFarmerFields = []string{
+	"address", "consent", "contact", "contacts", "coordinates",
+	"geohash", "name", "pincode", "tag", "village",
+}
+
+// erasedTicketFields are the ticket fields an erasure wipes.
+var erasedTicketFields = []string{"description"}
+
+// ErasureService erases a farmer's personal data on request, leaving their
+// records behind anonymised under the same IDs. It wipes the farmer, the
+// descriptions of their tickets, the numbers and reasons of their consent
+// history and those values in the audit trail, and hands out a signed
+// certificate of what it did.
+//
+// Shoots hold nothing personal beyond the farmer ID and are kept as they are,
+// so ReportGenerator counts the same shoots before and after.
+type ErasureService struct {
+	farmers *FarmerService
+	tickets *TicketService
+	consent store.ConsentStore
+	audit   *AuditService
+	key     ed25519.PrivateKey
+	keyID   string
+}
+
+// NewErasureService returns an ErasureService signing certificates with key.
+func NewErasureService(farmers *FarmerService, tickets *TicketService, consentStore store.ConsentStore, audit *AuditService, key ed25519.PrivateKey) *ErasureService {
+	return &ErasureService{
+		farmers: farmers,
+		tickets: tickets,
+		consent: consentStore,
+		audit:   audit,
+		key:     key,
+		keyID:   ErasureKeyID(key.Public().(ed25519.PublicKey)),
+	}
+}
+
+// ErasureKeyID names an erasure signing key by a short hash of its public
+// key, so a certificate tells which key to check it with.
+func ErasureKeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// erasureRecord is what the audit trail keeps of an erasure.
+type erasureRecord struct {
+	CertificateID string `json:"certificateId"`
+	Reason        string `json:"reason,omitempty"`
+	Tickets       int    `json:"tickets"`
+	ConsentEvents int    `json:"consentEvents"`
+}
+
+// EraseFarmer erases the personal data of the farmer with the ID, resolving
+// merged IDs as GetFarmer does, and returns the signed certificate. Erasing a
+// farmer twice is harmless: the second run finds nothing left to wipe but
+// still certifies it. A failure part way leaves what was wiped so far wiped;
+// calling it again finishes the job. Farmers in the trash are erased where
+// they are.
+func (s *ErasureService) EraseFarmer(ctx context.Context, farmerID, reason string) (*models.ErasureCertificate, error) {
+	farmer, farmerIDs, err := s.farmers.eraseFarmer(ctx, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	ticketIDs, err := s.tickets.eraseFarmerTickets(ctx, farmer.ID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.consent.Erase(ctx, farmer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := 0
+	for _, id := range farmerIDs {
+		n, err := s.audit.Redact(ctx, EntityFarmer, id, erasedFarmerFields)
+		if err != nil {
+			return nil, err
+		}
+		redacted += n
+	}
+	for _, id := range ticketIDs {
+		n, err := s.audit.Redact(ctx, EntityTicket, id, erasedTicketFields)
+		if err != nil {
+			return nil, err
+		}
+		redacted += n
+	}
+
+	requestedBy := auth.UserID(ctx)
+	if requestedBy == "" {
+		requestedBy = systemActor
+	}
+	cert := &models.ErasureCertificate{
+		ID:            uuid.New().String(),
+		FarmerID:      farmer.ID,
+		Reason:        reason,
+		RequestedBy:   requestedBy,
+		ErasedAt:      *farmer.ErasedAt,
+		Fields:        erasedFarmerFields,
+		Tickets:       len(ticketIDs),
+		ConsentEvents: events,
+		AuditRecords:  redacted,
+	}
+	if err := s.sign(cert); err != nil {
+		return nil, err
+	}
+
+	// Recorded after the redaction above, which would otherwise find
+	// nothing to redact in it anyway.
+	s.audit.Record(ctx, EntityFarmer, farmer.ID, ActionErase, nil, &erasureRecord{
+		CertificateID: cert.ID,
+		Reason:        reason,
+		Tickets:       cert.Tickets,
+		ConsentEvents: cert.ConsentEvents,
+	})
+	return cert, nil
+}
+
+// VerifyCertificate reports whether cert was signed by this service's key and
+// is unchanged since.
+func (s *ErasureService) VerifyCertificate(cert *models.ErasureCertificate) bool {
+	if cert.KeyID != s.keyID {
+		return false
+	}
+	data, err := certificatePayload(cert)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(s.key.Public().(ed25519.PublicKey), data, cert.Signature)
+}
+
+// PublicKey returns the key certificates are checked with, and its ID.
+func (s *ErasureService) PublicKey() (string, ed25519.PublicKey) {
+	return s.keyID, s.key.Public().(ed25519.PublicKey)
+}
+
+func (s *ErasureService) sign(cert *models.ErasureCertificate) error {
+	cert.KeyID = s.keyID
+	data, err := certificatePayload(cert)
+	if err != nil {
+		return err
+	}
+	cert.Signature = ed25519.Sign(s.key, data)
+	return nil
+}
+
+// certificatePayload is what a certificate's signature covers: its JSON form
+// without the signature.
+func certificatePayload(cert *models.ErasureCertificate) ([]byte, error) {
+	unsigned := *cert
+	unsigned.Signature = nil
+	return json.Marshal(&unsigned)
+}
+
+// eraseFarmer wipes the personal data of the farmer with the ID and of the
+// aliases on the way to it. It returns the farmer and the IDs of every record
+// it went through, the farmer's last. The farmer is written
+// without going through update, so no audit record carries the values wiped.
+// Farmers merged into this one by other IDs than the one given keep their
+// own records; erase them by their IDs. Farmers in the trash are erased too
+// and stay in the trash.
+func (s *FarmerService) eraseFarmer(ctx context.Context, id string) (*models.Farmer, []string, error) {
+	now := time.Now().UTC()
+	var ids []string
+	for hop := 0; ; hop++ {
+		farmer, err := s.eraseRecord(ctx, id, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.index.Remove(farmer.ID)
+		ids = append(ids, farmer.ID)
+		if farmer.MergedInto == "" {
+			return farmer, ids, nil
+		}
+		if hop == maxAliasHops {
+			return nil, nil, errors.ErrNotFound
+		}
+		id = farmer.MergedInto
+	}
+}
+
+// eraseRecord wipes the personal data of the one farmer record with the ID
+// and returns it. A farmer in the trash is taken out for the write, which
+// the store refuses on deleted records, and put back in by the same user who
+// deleted it; that starts its time in the trash over.
+func (s *FarmerService) eraseRecord(ctx context.Context, id string, now time.Time) (_ *models.Farmer, err error) {
+	farmer, err := s.store.Get(ctx, id)
+	if err == errors.ErrNotFound {
+		deleted, deletedErr := s.store.GetDeleted(ctx, id)
+		if deletedErr != nil {
+			return nil, deletedErr
+		}
+		if err := s.store.Restore(ctx, id); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if deleteErr := s.store.Delete(ctx, id, deleted.DeletedBy); err == nil {
+				err = deleteErr
+			}
+		}()
+		farmer, err = s.store.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if farmer.ErasedAt == nil {
+		farmer.Name = ""
+		farmer.Contact = ""
+		farmer.Contacts = nil
+		farmer.Village = ""
+		farmer.Pincode = ""
+		farmer.Address = ""
+		farmer.Tag = ""
+		farmer.Coordinates = nil
+		farmer.GeoHash = ""
+		farmer.GeoCell = ""
+		farmer.Consent = nil
+		farmer.ErasedAt = &now
+		farmer.UpdatedAt = now
+		if err := s.store.Update(ctx, farmer); err != nil {
+			return nil, err
+		}
+	}
+	return farmer, nil
+}
+
+// eraseFarmerTickets replaces the description of every ticket of the farmer
+// with models.Erased and returns the IDs of the tickets. Like eraseFarmer it
+// writes around UpdateTicket, whose audit record would keep the description;
+// the erasure is audited with no values instead. Tickets in the trash cannot
+// be written and are left to the purge.
+func (s *TicketService) eraseFarmerTickets(ctx context.Context, farmerID string) ([]string, error) {
+	var tickets []models.Ticket
+	page := store.Page{Limit: 100}
+	for {
+		batch, next, err := s.store.ListByFarmer(ctx, farmerID, page)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, batch...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+
+	ids := make([]string, len(tickets))
+	for i := range tickets {
+		ticket := &tickets[i]
+		ids[i] = ticket.ID
+		if ticket.Description == "" || ticket.Description == models.Erased {
+			continue
+		}
+		ticket.Description = models.Erased
+		ticket.UpdatedAt = time.Now().UTC()
+		if err := s.store.Update(ctx, ticket); err != nil {
+			return nil, err
+		}
+		s.audit.Record(ctx, EntityTicket, ticket.ID, ActionErase, nil, nil)
+	}
+	return ids, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/models"
+	"backend/internal/pii"
+	"backend/pkg/errors"
+)
+
+func TestErasedFarmerCannotBeRecreated(t *testing.T) {
+	keys, err := pii.LoadKeyfile(filepath.Join(t.TempDir(), "keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealing, err := pii.NewCipher(keys, []byte("blind-index-test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, cipher := range map[string]*pii.Cipher{"plaintext": nil, "sealed": sealing} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			stores := newTestStores(t)
+			audit := NewAuditService(stores.Audit)
+			farmers := newTestFarmerService(t, stores, cipher)
+			tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+			_, key, err := ed25519.GenerateKey(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			erasure := NewErasureService(farmers, tickets, stores.Consent, audit, key)
+
+			if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210"}); err != nil {
+				t.Fatalf("CreateFarmer: %v", err)
+			}
+			if _, err := erasure.EraseFarmer(ctx, "f1", "farmer asked"); err != nil {
+				t.Fatalf("EraseFarmer: %v", err)
+			}
+
+			again := &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210"}
+			if err := farmers.CreateFarmer(ctx, again); !errors.Is(err, errors.ErrConflict) {
+				t.Fatalf("CreateFarmer over erased farmer = %v, want ErrConflict", err)
+			}
+
+			stored, err := farmers.GetFarmer(ctx, "f1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.ErasedAt == nil || stored.Name != "" || stored.Contact != "" {
+				t.Errorf("erased farmer was overwritten: erasedAt %v, name %q, contact %q", stored.ErasedAt, stored.Name, stored.Contact)
+			}
+		})
+	}
+}
+
+func TestMergeFarmersRefusesErasedFarmer(t *testing.T) {
+	for _, erased := range []string{"f1", "f2"} {
+		t.Run("erased "+erased, func(t *testing.T) {
+			ctx := context.Background()
+			stores := newTestStores(t)
+			audit := NewAuditService(stores.Audit)
+			farmers := newTestFarmerService(t, stores, nil)
+			tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+			shoots := NewShootService(stores.Shoot, NewConsentService(stores.Consent, farmers), nil)
+			duplicates := NewDuplicateService(stores.Duplicate, farmers, tickets, shoots)
+			_, key, err := ed25519.GenerateKey(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			erasure := NewErasureService(farmers, tickets, stores.Consent, audit, key)
+
+			for _, farmer := range []*models.Farmer{
+				{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210", Address: "Near the temple"},
+				{ID: "f2", Name: "Ramesh Patil", Contact: "9876543211", Address: "Behind the school"},
+			} {
+				if err := farmers.CreateFarmer(ctx, farmer); err != nil {
+					t.Fatalf("CreateFarmer(%s): %v", farmer.ID, err)
+				}
+			}
+			if _, err := erasure.EraseFarmer(ctx, erased, "farmer asked"); err != nil {
+				t.Fatalf("EraseFarmer: %v", err)
+			}
+
+			if _, err := duplicates.MergeFarmers(ctx, "f1", "f2", "admin"); err != ErrFarmerErased {
+				t.Fatalf("MergeFarmers = %v, want ErrFarmerErased", err)
+			}
+			stored, err := farmers.GetFarmer(ctx, erased)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.MergedInto != "" || stored.Contact != "" || len(stored.Contacts) > 0 || stored.Address != "" {
+				t.Errorf("erased farmer was changed by the merge: %+v", stored)
+			}
+		})
+	}
+}
+
+func TestEraseDeletedFarmer(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	audit := NewAuditService(stores.Audit)
+	farmers := newTestFarmerService(t, stores, nil)
+	tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	erasure := NewErasureService(farmers, tickets, stores.Consent, audit, key)
+
+	if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210", Address: "Near the temple"}); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+	if err := farmers.DeleteFarmer(ctx, "f1", "admin"); err != nil {
+		t.Fatalf("DeleteFarmer: %v", err)
+	}
+
+	cert, err := erasure.EraseFarmer(ctx, "f1", "farmer asked")
+	if err != nil {
+		t.Fatalf("EraseFarmer of a deleted farmer: %v", err)
+	}
+	if cert.FarmerID != "f1" {
+		t.Errorf("certificate for farmer %s, want f1", cert.FarmerID)
+	}
+
+	if _, err := farmers.GetFarmer(ctx, "f1"); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("GetFarmer after erasure = %v, want the farmer still in the trash", err)
+	}
+	stored, err := stores.Farmer.GetDeleted(ctx, "f1")
+	if err != nil {
+		t.Fatalf("GetDeleted: %v", err)
+	}
+	if stored.ErasedAt == nil || stored.Name != "" || stored.Contact != "" || stored.Address != "" {
+		t.Errorf("deleted farmer was not erased: %+v", stored)
+	}
+	if stored.DeletedBy != "admin" {
+		t.Errorf("deleted by %q after erasure, want admin", stored.DeletedBy)
+	}
+	if _, err := farmers.GetFarmerByContact(ctx, "9876543210"); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("erased farmer's contact still resolves: %v", err)
+	}
+}
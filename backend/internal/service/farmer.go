@@ -2,190 +2,566 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"backend/internal/location"
 	"backend/internal/models"
+	"backend/internal/pii"
+	"backend/internal/search"
+	"backend/internal/store"
 	"backend/pkg/errors"
+	"backend/pkg/phone"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 )
 
-const FarmerTableName = "Farmers"
-
 type FarmerService struct {
-	dbClient *dynamodb.Client
+	store store.FarmerStore
+	// catalog holds the crops farmers grow, see resolveCrops.
+	catalog   store.CatalogStore
+	locations *location.Directory
+	// index serves farmer search. Every write below is copied into it; see
+	// RebuildSearchIndex for the writes of other instances.
+	index   *search.FarmerIndex
+	cursors *store.Cursors
+	audit   *AuditService
+	// cipher encrypts the farmers' personal data in the store, see
+	// sealedFarmers. It is nil when PII is stored in plaintext.
+	cipher *pii.Cipher
 }
 
-func NewFarmerService(dbClient *dynamodb.Client) *FarmerService {
+// NewFarmerService returns a FarmerService on farmerStore. A non-nil cipher
+// encrypts the name, address and numbers of every farmer written.
+func NewFarmerService(farmerStore store.FarmerStore, catalog store.CatalogStore, locations *location.Directory, cursors *store.Cursors, audit *AuditService, cipher *pii.Cipher) *FarmerService {
+	if cipher != nil {
+		farmerStore = &sealedFarmers{FarmerStore: farmerStore, cipher: cipher}
+	}
 	return &FarmerService{
-		dbClient: dbClient,
+		store:     farmerStore,
+		catalog:   catalog,
+		locations: locations,
+		index:     search.NewFarmerIndex(),
+		cursors:   cursors,
+		audit:     audit,
+		cipher:    cipher,
 	}
 }
 
+// CreateFarmer saves a new farmer. Its location is checked against the
+// location master, see location.Directory.Resolve; a location that does not
+// resolve is returned as a *location.InvalidPlaceError, and so are invalid
+// coordinates, see locate. Crops are given by catalog ID, name or alias and
+// stored by ID; crops missing from the catalog fail as a *CatalogError. A new
+// farmer starts without consent, which is recorded through ConsentService,
+// and is never deleted, merged or erased, see clearManagedFields. A farmer
+// without an ID is given one; an ID that is taken, even by a deleted or
+// merged farmer, fails with ErrConflict.
 func (s *FarmerService) CreateFarmer(ctx context.Context, farmer *models.Farmer) error {
-	item, err := attributevalue.MarshalMap(farmer)
-	if err != nil {
-		return errors.ErrInternal
+	if farmer.ID == "" {
+		farmer.ID = uuid.New().String()
+	}
+	clearManagedFields(farmer)
+	if err := normaliseContact(farmer); err != nil {
+		return err
+	}
+	if err := s.resolveLocation(farmer); err != nil {
+		return err
+	}
+	if err := s.resolveCrops(ctx, farmer); err != nil {
+		return err
+	}
+	if err := s.locate(farmer); err != nil {
+		return err
 	}
 
-	_, err = s.dbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(FarmerTableName),
-		Item:      item,
-	})
-	if err != nil {
-		return errors.ErrInternal
+	now := time.Now().UTC()
+	farmer.CreatedAt = now
+	farmer.UpdatedAt = now
+	farmer.Version = 1
+
+	if err := s.store.Put(ctx, farmer); err != nil {
+		return err
 	}
+	s.index.Put(*farmer)
 
+	s.audit.Record(ctx, EntityFarmer, farmer.ID, ActionCreate, nil, s.auditView(farmer))
 	return nil
 }
 
-func (s *FarmerService) GetFarmer(ctx context.Context, id string) (*models.Farmer, error) {
-	result, err := s.dbClient.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(FarmerTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: id},
-		},
-	})
-	if err != nil {
-		return nil, errors.ErrInternal
+// CreateFarmers saves many new farmers at once, see FarmerStore.PutBatch. The
+// outcome of each farmer is returned in the order of farmers. Every farmer is
+// given a new ID, replacing any it came with, as a batch cannot refuse a
+// taken one.
+func (s *FarmerService) CreateFarmers(ctx context.Context, farmers []*models.Farmer) []error {
+	errs := make([]error, len(farmers))
+	var valid []*models.Farmer
+	var positions []int
+
+	now := time.Now().UTC()
+	for i, farmer := range farmers {
+		farmer.ID = uuid.New().String()
+		clearManagedFields(farmer)
+		if err := normaliseContact(farmer); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := s.resolveLocation(farmer); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := s.resolveCrops(ctx, farmer); err != nil {
+			errs[i] = err
+			continue
+		}
+		if err := s.locate(farmer); err != nil {
+			errs[i] = err
+			continue
+		}
+		farmer.CreatedAt = now
+		farmer.UpdatedAt = now
+		farmer.Version = 1
+
+		valid = append(valid, farmer)
+		positions = append(positions, i)
 	}
-	if result.Item == nil {
-		return nil, errors.ErrNotFound
+
+	for j, err := range s.store.PutBatch(ctx, valid) {
+		errs[positions[j]] = err
+		if err == nil {
+			s.index.Put(*valid[j])
+			s.audit.Record(ctx, EntityFarmer, valid[j].ID, ActionCreate, nil, s.auditView(valid[j]))
+		}
+	}
+	return errs
+}
+
+// maxAliasHops bounds how many merges GetFarmer follows, so a cycle left by a
+// bad manual edit cannot loop forever.
+const maxAliasHops = 8
+
+// GetFarmer returns the farmer with the ID. The ID of a farmer merged into
+// another resolves to the farmer it was merged into.
+func (s *FarmerService) GetFarmer(ctx context.Context, id string) (*models.Farmer, error) {
+	for hop := 0; ; hop++ {
+		farmer, err := s.store.Get(ctx, id)
+		if err != nil || farmer.MergedInto == "" {
+			return farmer, err
+		}
+		if hop == maxAliasHops {
+			return nil, errors.ErrNotFound
+		}
+		id = farmer.MergedInto
 	}
+}
 
-	var farmer models.Farmer
-	err = attributevalue.UnmarshalMap(result.Item, &farmer)
+func (s *FarmerService) GetFarmerByContact(ctx context.Context, contact string) (*models.Farmer, error) {
+	normalised, err := phone.Normalize(contact)
 	if err != nil {
-		return nil, errors.ErrInternal
+		return nil, errors.ErrInvalidInput
 	}
 
-	return &farmer, nil
+	return s.store.GetByContact(ctx, normalised)
 }
 
-func (s *FarmerService) GetFarmerByContact(ctx context.Context, contact string) (*models.Farmer, error) {
-	result, err := s.dbClient.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String("Farmers"),
-		IndexName:              aws.String("ContactIndex"),
-		KeyConditionExpression: aws.String("Contact = :contact"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":contact": &types.AttributeValueMemberS{Value: contact},
-		},
+// SearchFarmers returns one page of the farmers matching q, see
+// search.Query. State and district filters are spelled the way the location
+// master does first, so aliases such as old district names still match. Crop
+// filters are resolved against the catalog, and crops missing from it fail as
+// a *CatalogError.
+func (s *FarmerService) SearchFarmers(ctx context.Context, q search.Query, page store.Page) ([]models.Farmer, string, error) {
+	if err := q.Validate(); err != nil {
+		return nil, "", err
+	}
+	q.Filters = s.canonicalFilters(q.Filters)
+	if crops := q.Filters["crop"]; len(crops) > 0 {
+		resolved, err := resolveCrops(ctx, s.catalog, crops)
+		if err != nil {
+			return nil, "", err
+		}
+		q.Filters["crop"] = resolved
+	}
+
+	scope := "farmers/search/" + q.Key()
+	key, err := s.cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	offset := 0
+	if key != nil {
+		n, ok := key["Offset"].(*types.AttributeValueMemberN)
+		if !ok {
+			return nil, "", errors.ErrInvalidCursor
+		}
+		if offset, err = strconv.Atoi(n.Value); err != nil || offset < 0 {
+			return nil, "", errors.ErrInvalidCursor
+		}
+	}
+
+	result := s.index.Search(q, offset, int(page.Limit))
+	next := offset + len(result.Farmers)
+	if page.Limit <= 0 || next >= result.Total {
+		return result.Farmers, "", nil
+	}
+	nextCursor, err := s.cursors.Encode(scope, map[string]types.AttributeValue{
+		"Offset": &types.AttributeValueMemberN{Value: strconv.Itoa(next)},
 	})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	return result.Farmers, nextCursor, nil
+}
 
-	if len(result.Items) == 0 {
-		return nil, errors.New("farmer not found")
+// RebuildSearchIndex reloads the search index from the store, picking up the
+// farmers other instances wrote since the last rebuild.
+func (s *FarmerService) RebuildSearchIndex(ctx context.Context) error {
+	return s.index.Rebuild(ctx, func(ctx context.Context, yield func(models.Farmer)) error {
+		page := store.Page{Limit: 500}
+		for {
+			farmers, next, err := s.store.List(ctx, page)
+			if err != nil {
+				return err
+			}
+			for _, farmer := range farmers {
+				if farmer.ErasedAt == nil {
+					yield(farmer)
+				}
+			}
+			if next == "" {
+				return nil
+			}
+			page.Cursor = next
+		}
+	})
+}
+
+// UpdateFarmer saves farmer if it is still at farmer.Version and bumps the
+// version. The location is checked as in CreateFarmer, but only if it
+// changed, so farmers saved before the location master can still be edited.
+// Crops are checked the same way. Coordinates are filled in as in
+// CreateFarmer.
+// Consent is kept as stored; it changes through ConsentService only. Erased
+// farmers cannot be edited and fail with ErrFarmerErased.
+func (s *FarmerService) UpdateFarmer(ctx context.Context, farmer *models.Farmer) error {
+	return s.update(ctx, farmer, false)
+}
+
+func (s *FarmerService) update(ctx context.Context, farmer *models.Farmer, withConsent bool) error {
+	if err := normaliseContact(farmer); err != nil {
+		return err
 	}
 
-	var farmer models.Farmer
-	err = attributevalue.UnmarshalMap(result.Items[0], &farmer)
+	farmer.UpdatedAt = time.Now().UTC()
+
+	before, err := s.store.Get(ctx, farmer.ID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if before.ErasedAt != nil {
+		return ErrFarmerErased
+	}
+	if !withConsent {
+		farmer.Consent = before.Consent
+	}
+	if placeOf(farmer) != placeOf(before) {
+		if err := s.resolveLocation(farmer); err != nil {
+			return err
+		}
+	}
+	if !slices.Equal(farmer.Crop, before.Crop) {
+		if err := s.resolveCrops(ctx, farmer); err != nil {
+			return err
+		}
+	}
+	if err := s.locate(farmer); err != nil {
+		return err
 	}
+	if err := s.store.Update(ctx, farmer); err != nil {
+		return err
+	}
+	s.index.Put(*farmer)
 
-	return &farmer, nil
+	s.audit.Record(ctx, EntityFarmer, farmer.ID, ActionUpdate, s.auditView(before), s.auditView(farmer))
+	return nil
 }
 
-func (s *FarmerService) ListFarmersWithFilters(ctx context.Context, filters map[string]string) ([]models.Farmer, error) {
-	var filterExpression string
-	expressionAttributeValues := make(map[string]types.AttributeValue)
-	expressionAttributeNames := make(map[string]string)
-
-	for key, value := range filters {
-		if value != "" {
-			if filterExpression != "" {
-				filterExpression += " AND "
+// mergeFarmer folds loser into survivor. The loser gives up its numbers and
+// is kept as an alias of the survivor. The survivor gains the loser's crops,
+// tags and numbers, the latter as secondary contacts unless it had none of
+// its own, and takes the loser's location and address where its own are
+// blank. The location moves only as a whole, so the survivor never ends up
+// with a village of one place and the pincode of another. On each channel
+// the more recently recorded consent of the two wins.
+//
+// Both farmers are written together, see FarmerStore.Merge, so a merge that
+// fails leaves both records as they were. Their tickets and shoots are not
+// touched here; see DuplicateService.MergeFarmers.
+func (s *FarmerService) mergeFarmer(ctx context.Context, survivor, loser *models.Farmer) error {
+	survivorBefore, loserBefore := *survivor, *loser
+	now := time.Now().UTC()
+
+	survivor.Crop = unionStrings(survivor.Crop, loser.Crop)
+	survivor.Tag = strings.Join(unionStrings(splitTags(survivor.Tag), splitTags(loser.Tag)), ", ")
+	contacts := contactsOf(survivor)
+	for _, contact := range contactsOf(loser) {
+		if slices.ContainsFunc(contacts, func(c models.FarmerContact) bool { return c.Number == contact.Number }) {
+			continue
+		}
+		contact.Primary = contact.Primary && survivor.Contact == ""
+		contacts = append(contacts, contact)
+	}
+	survivor.Contacts = contacts
+	if err := normaliseContact(survivor); err != nil {
+		return err
+	}
+	if placeOf(survivor) == (location.Place{}) {
+		survivor.State = loser.State
+		survivor.District = loser.District
+		survivor.Tehsil = loser.Tehsil
+		survivor.Village = loser.Village
+		survivor.Pincode = loser.Pincode
+		survivor.Coordinates = loser.Coordinates
+	}
+	if survivor.Address == "" {
+		survivor.Address = loser.Address
+	}
+	if err := s.locate(survivor); err != nil {
+		return err
+	}
+	survivor.Consent = maps.Clone(survivor.Consent)
+	for channel, consent := range loser.Consent {
+		if current, ok := survivor.Consent[channel]; !ok || consent.UpdatedAt.After(current.UpdatedAt) {
+			if survivor.Consent == nil {
+				survivor.Consent = make(map[string]models.ChannelConsent)
 			}
-			filterExpression += fmt.Sprintf("#%s = :%s", key, key)
-			expressionAttributeValues[":"+key] = &types.AttributeValueMemberS{Value: value}
-			expressionAttributeNames["#"+key] = key
+			survivor.Consent[channel] = consent
 		}
 	}
+	survivor.UpdatedAt = now
+
+	loser.MergedInto = survivor.ID
+	loser.Contact = ""
+	loser.Contacts = nil
+	loser.UpdatedAt = now
 
-	input := &dynamodb.ScanInput{
-		TableName:                 aws.String("Farmers"),
-		FilterExpression:          aws.String(filterExpression),
-		ExpressionAttributeValues: expressionAttributeValues,
-		ExpressionAttributeNames:  expressionAttributeNames,
+	if err := s.store.Merge(ctx, survivor, loser); err != nil {
+		*survivor, *loser = survivorBefore, loserBefore
+		return err
 	}
+	s.index.Remove(loser.ID)
+	s.index.Put(*survivor)
+	s.audit.Record(ctx, EntityFarmer, loser.ID, ActionMerge, s.auditView(&loserBefore), s.auditView(loser))
+	s.audit.Record(ctx, EntityFarmer, survivor.ID, ActionMerge, s.auditView(&survivorBefore), s.auditView(survivor))
+	return nil
+}
 
-	result, err := s.dbClient.Scan(ctx, input)
-	if err != nil {
-		return nil, err
+func (s *FarmerService) DeleteFarmer(ctx context.Context, id, deletedBy string) error {
+	if err := s.store.Delete(ctx, id, deletedBy); err != nil {
+		return err
 	}
+	s.index.Remove(id)
 
-	var farmers []models.Farmer
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &farmers)
-	if err != nil {
-		return nil, err
+	s.audit.Record(ctx, EntityFarmer, id, ActionDelete, nil, nil)
+	return nil
+}
+
+func (s *FarmerService) ListDeletedFarmers(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	return s.store.ListDeleted(ctx, page)
+}
+
+func (s *FarmerService) RestoreFarmer(ctx context.Context, id string) error {
+	if err := s.store.Restore(ctx, id); err != nil {
+		return err
+	}
+	if farmer, err := s.store.Get(ctx, id); err == nil {
+		s.index.Put(*farmer)
 	}
 
-	return farmers, nil
+	s.audit.Record(ctx, EntityFarmer, id, ActionRestore, nil, nil)
+	return nil
 }
 
-func (s *FarmerService) UpdateFarmer(ctx context.Context, farmer *models.Farmer) error {
-	item, err := attributevalue.MarshalMap(farmer)
-	if err != nil {
-		return errors.ErrInternal
+// PurgeFarmers removes the farmers deleted before the cutoff for good.
+func (s *FarmerService) PurgeFarmers(ctx context.Context, before time.Time) (int, error) {
+	return s.store.Purge(ctx, before)
+}
+
+func (s *FarmerService) ListFarmers(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	return s.store.List(ctx, page)
+}
+
+// clearManagedFields blanks what a client may not set on a new farmer:
+// consent, and the marks left by a delete, merge or erasure, which are only
+// set along with their audit records.
+func clearManagedFields(farmer *models.Farmer) {
+	farmer.Consent = nil
+	farmer.DeletedAt = nil
+	farmer.DeletedBy = ""
+	farmer.MergedInto = ""
+	farmer.ErasedAt = nil
+	farmer.PII = nil
+}
+
+// normaliseContact rewrites the farmer's numbers in E.164 form, which is how
+// contacts are stored and looked up, and keeps Contact and the primary entry
+// of Contacts in step. Contact wins when they differ: an entry with that
+// number becomes primary, or else the primary entry takes the number.
+func normaliseContact(farmer *models.Farmer) error {
+	if farmer.Contact != "" {
+		normalised, err := phone.Normalize(farmer.Contact)
+		if err != nil {
+			return errors.ErrInvalidInput
+		}
+		farmer.Contact = normalised
+	}
+	if len(farmer.Contacts) == 0 {
+		farmer.Contacts = nil
+		return nil
 	}
 
-	_, err = s.dbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(FarmerTableName),
-		Item:      item,
-	})
-	if err != nil {
-		return errors.ErrInternal
+	primaries := 0
+	seen := make(map[string]bool, len(farmer.Contacts))
+	for i := range farmer.Contacts {
+		contact := &farmer.Contacts[i]
+		normalised, err := phone.Normalize(contact.Number)
+		if err != nil || seen[normalised] || !slices.Contains(models.Relationships, contact.Relationship) {
+			return errors.ErrInvalidInput
+		}
+		seen[normalised] = true
+		contact.Number = normalised
+		contact.Language = strings.ToLower(strings.TrimSpace(contact.Language))
+		if contact.Primary {
+			primaries++
+		}
+	}
+	if primaries > 1 {
+		return errors.ErrInvalidInput
 	}
 
+	primary := slices.IndexFunc(farmer.Contacts, func(c models.FarmerContact) bool { return c.Primary })
+	switch {
+	case farmer.Contact == "":
+		primary = max(primary, 0)
+	case seen[farmer.Contact]:
+		primary = slices.IndexFunc(farmer.Contacts, func(c models.FarmerContact) bool { return c.Number == farmer.Contact })
+	case primary >= 0:
+		farmer.Contacts[primary].Number = farmer.Contact
+	default:
+		farmer.Contacts = slices.Insert(farmer.Contacts, 0, models.FarmerContact{
+			Number:       farmer.Contact,
+			Relationship: models.RelationshipSelf,
+		})
+		primary = 0
+	}
+	for i := range farmer.Contacts {
+		farmer.Contacts[i].Primary = i == primary
+	}
+	farmer.Contact = farmer.Contacts[primary].Number
 	return nil
 }
 
-func (s *FarmerService) DeleteFarmer(ctx context.Context, id string) error {
-	_, err := s.dbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(FarmerTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: id},
-		},
-	})
+// resolveCrops replaces the farmer's crops with their catalog IDs.
+func (s *FarmerService) resolveCrops(ctx context.Context, farmer *models.Farmer) error {
+	if len(farmer.Crop) == 0 {
+		return nil
+	}
+	crops, err := resolveCrops(ctx, s.catalog, farmer.Crop)
 	if err != nil {
-		return errors.ErrInternal
+		return err
 	}
+	farmer.Crop = crops
+	return nil
+}
 
+// resolveLocation rewrites the farmer's location as the location master spells
+// it and fills in what the pincode implies.
+func (s *FarmerService) resolveLocation(farmer *models.Farmer) error {
+	place, err := s.locations.Resolve(placeOf(farmer))
+	if err != nil {
+		return err
+	}
+	farmer.State = place.State
+	farmer.District = place.District
+	farmer.Tehsil = place.Tehsil
+	farmer.Village = place.Village
+	farmer.Pincode = place.Pincode
 	return nil
 }
 
-func (s *FarmerService) ListFarmers(ctx context.Context, limit int32, nextToken string) ([]models.Farmer, string, error) {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(FarmerTableName),
-		Limit:     aws.Int32(limit),
+// canonicalFilters returns filters with the state and district values
+// resolved as far as they can be. A value that does not resolve is left as
+// given. A district is resolved within the state filter when that names a
+// single state, and by name alone otherwise.
+func (s *FarmerService) canonicalFilters(filters map[string][]string) map[string][]string {
+	canonical := make(map[string][]string, len(filters))
+	for k, v := range filters {
+		canonical[k] = v
 	}
 
-	if nextToken != "" {
-		input.ExclusiveStartKey = map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: nextToken},
+	states := make([]string, len(filters["state"]))
+	for i, value := range filters["state"] {
+		states[i] = value
+		if state, ok := s.locations.State(value); ok {
+			states[i] = state
+		}
+	}
+	districts := make([]string, len(filters["district"]))
+	for i, value := range filters["district"] {
+		districts[i] = value
+		var district string
+		var ok bool
+		if len(states) == 1 {
+			_, district, ok = s.locations.District(states[0], value)
+		} else {
+			_, district, ok = s.locations.FindDistrict(value)
+		}
+		if ok {
+			districts[i] = district
 		}
 	}
 
-	result, err := s.dbClient.Scan(ctx, input)
-	if err != nil {
-		return nil, "", errors.ErrInternal
+	if len(states) > 0 {
+		canonical["state"] = states
+	}
+	if len(districts) > 0 {
+		canonical["district"] = districts
 	}
+	return canonical
+}
 
-	var farmers []models.Farmer
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &farmers)
-	if err != nil {
-		return nil, "", errors.ErrInternal
+// splitTags returns the comma separated tags of a farmer.
+func splitTags(tag string) []string {
+	var tags []string
+	for _, t := range strings.Split(tag, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
 	}
+	return tags
+}
 
-	var newNextToken string
-	if result.LastEvaluatedKey != nil {
-		newNextToken = result.LastEvaluatedKey["ID"].(*types.AttributeValueMemberS).Value
+// unionStrings returns a followed by the values of b that a lacks, ignoring
+// case.
+func unionStrings(a, b []string) []string {
+	union := append([]string(nil), a...)
+	for _, v := range b {
+		if !slices.ContainsFunc(union, func(u string) bool { return strings.EqualFold(u, v) }) {
+			union = append(union, v)
+		}
 	}
+	return union
+}
 
-	return farmers, newNextToken, nil
+func placeOf(farmer *models.Farmer) location.Place {
+	return location.Place{
+		State:    farmer.State,
+		District: farmer.District,
+		Tehsil:   farmer.Tehsil,
+		Village:  farmer.Village,
+		Pincode:  farmer.Pincode,
+	}
 }
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/internal/pii"
+	"backend/internal/store"
+	"backend/internal/store/memory"
+	"backend/pkg/errors"
+)
+
+// newTestStores returns empty in-memory stores.
+func newTestStores(t *testing.T) *store.Stores {
+	t.Helper()
+	return memory.NewStores(store.NewCursors([]byte("test")))
+}
+
+// newTestFarmerService returns a FarmerService on stores with an empty
+// location master, so any location is taken as given. A nil cipher keeps PII
+// in plaintext.
+func newTestFarmerService(t *testing.T, stores *store.Stores, cipher *pii.Cipher) *FarmerService {
+	t.Helper()
+	locations, err := location.Load(strings.NewReader("state,district\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFarmerService(stores.Farmer, stores.Catalog, locations, stores.Cursors, NewAuditService(stores.Audit), cipher)
+}
+
+func TestCreateFarmerRefusesTakenID(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	farmers := newTestFarmerService(t, stores, nil)
+
+	first := &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210"}
+	if err := farmers.CreateFarmer(ctx, first); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+
+	second := &models.Farmer{ID: "f1", Name: "Sunita Deshmukh", Contact: "9123456780"}
+	if err := farmers.CreateFarmer(ctx, second); !errors.Is(err, errors.ErrConflict) {
+		t.Fatalf("second CreateFarmer = %v, want ErrConflict", err)
+	}
+
+	stored, err := farmers.GetFarmer(ctx, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Ramesh Patil" || stored.Contact != "+919876543210" {
+		t.Errorf("farmer = %q %q, want the first farmer kept", stored.Name, stored.Contact)
+	}
+	if _, err := farmers.GetFarmerByContact(ctx, "9123456780"); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("refused farmer's contact was claimed: %v", err)
+	}
+}
+
+func TestCreateFarmerGeneratesID(t *testing.T) {
+	ctx := context.Background()
+	farmers := newTestFarmerService(t, newTestStores(t), nil)
+
+	farmer := &models.Farmer{Name: "Ramesh Patil"}
+	if err := farmers.CreateFarmer(ctx, farmer); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+	if farmer.ID == "" {
+		t.Fatal("CreateFarmer left the ID empty")
+	}
+	if _, err := farmers.GetFarmer(ctx, farmer.ID); err != nil {
+		t.Errorf("GetFarmer(%s): %v", farmer.ID, err)
+	}
+}
+
+func TestCreateCCERefusesTakenID(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	cces := NewCCEService(stores.CCE, NewAuditService(stores.Audit))
+
+	if err := cces.CreateCCE(ctx, &models.CCE{ID: "c1", Name: "Asha"}); err != nil {
+		t.Fatalf("CreateCCE: %v", err)
+	}
+	if err := cces.CreateCCE(ctx, &models.CCE{ID: "c1", Name: "Vikram"}); !errors.Is(err, errors.ErrConflict) {
+		t.Fatalf("second CreateCCE = %v, want ErrConflict", err)
+	}
+	cce, err := cces.GetCCE(ctx, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cce.Name != "Asha" {
+		t.Errorf("CCE name = %q, want the first CCE kept", cce.Name)
+	}
+}
+
+func TestCreateFarmerClearsManagedFields(t *testing.T) {
+	ctx := context.Background()
+	farmers := newTestFarmerService(t, newTestStores(t), nil)
+
+	now := time.Now().UTC()
+	farmer := &models.Farmer{
+		ID:         "f1",
+		Name:       "Ramesh Patil",
+		DeletedAt:  &now,
+		DeletedBy:  "someone",
+		MergedInto: "f2",
+		ErasedAt:   &now,
+		Consent:    map[string]models.ChannelConsent{"call": {}},
+	}
+	if err := farmers.CreateFarmer(ctx, farmer); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+
+	stored, err := farmers.GetFarmer(ctx, "f1")
+	if err != nil {
+		t.Fatalf("GetFarmer: %v", err)
+	}
+	if stored.ID != "f1" || stored.DeletedAt != nil || stored.DeletedBy != "" || stored.MergedInto != "" || stored.ErasedAt != nil || stored.Consent != nil {
+		t.Errorf("new farmer kept client-set fields: %+v", stored)
+	}
+}
+
+func TestCreateFarmersGeneratesIDs(t *testing.T) {
+	ctx := context.Background()
+	farmers := newTestFarmerService(t, newTestStores(t), nil)
+
+	if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210"}); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+	batch := []*models.Farmer{
+		{ID: "f1", Name: "Sunita Deshmukh", Contact: "9123456780"},
+		{Name: "Vikram Rao", Contact: "9123456781"},
+	}
+	for i, err := range farmers.CreateFarmers(ctx, batch) {
+		if err != nil {
+			t.Fatalf("CreateFarmers[%d]: %v", i, err)
+		}
+	}
+	if batch[0].ID == "f1" || batch[0].ID == "" || batch[1].ID == "" || batch[0].ID == batch[1].ID {
+		t.Errorf("CreateFarmers gave IDs %q and %q, want two new ones", batch[0].ID, batch[1].ID)
+	}
+
+	stored, err := farmers.GetFarmer(ctx, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Ramesh Patil" {
+		t.Errorf("farmer f1 = %q, want the first farmer kept", stored.Name)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"backend/internal/geo"
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/internal/search"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+// MaxRadiusKm bounds the radius FarmersNear searches. Wider searches read too
+// many cells of the geo index to answer quickly.
+const MaxRadiusKm = 50
+
+// maxCoverCells is how many geohash cells a radius may be split into before
+// coarser cells are used, see geo.Cover.
+const maxCoverCells = 32
+
+// FarmerDistance is a farmer and how far it is from the point searched
+// around.
+type FarmerDistance struct {
+	models.Farmer
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// TehsilCluster summarises the farmers of one tehsil for visit planning.
+type TehsilCluster struct {
+	State    string `json:"state"`
+	District string `json:"district"`
+	Tehsil   string `json:"tehsil"`
+	Farmers  int    `json:"farmers"`
+	// Located counts the farmers with coordinates. Centre is their average
+	// and RadiusKm the distance of the farthest of them from it.
+	Located  int        `json:"located"`
+	Centre   *geo.Point `json:"centre,omitempty"`
+	RadiusKm float64    `json:"radiusKm"`
+}
+
+// FarmersNear returns the farmers within radiusKm of centre, nearest first,
+// at most limit of them unless limit is 0. Farmers without coordinates are
+// never found.
+func (s *FarmerService) FarmersNear(ctx context.Context, centre geo.Point, radiusKm float64, limit int) ([]FarmerDistance, error) {
+	if centre.Validate() != nil || radiusKm <= 0 || radiusKm > MaxRadiusKm || limit < 0 {
+		return nil, errors.ErrInvalidInput
+	}
+
+	var found []FarmerDistance
+	for _, cell := range geo.Cover(centre, radiusKm, models.GeoCellLength, maxCoverCells) {
+		page := store.Page{Limit: 500}
+		for {
+			farmers, next, err := s.store.ListByGeohash(ctx, cell, page)
+			if err != nil {
+				return nil, err
+			}
+			for _, farmer := range farmers {
+				if farmer.Coordinates == nil {
+					continue
+				}
+				d := geo.Distance(centre, pointOf(farmer.Coordinates))
+				if d <= radiusKm {
+					found = append(found, FarmerDistance{Farmer: farmer, DistanceKm: d})
+				}
+			}
+			if next == "" {
+				break
+			}
+			page.Cursor = next
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].DistanceKm != found[j].DistanceKm {
+			return found[i].DistanceKm < found[j].DistanceKm
+		}
+		return found[i].ID < found[j].ID
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+// ClusterByTehsil groups the farmers of a state or district, or of every
+// state when both are empty, by tehsil, largest cluster first. Farmers
+// without a tehsil form a cluster of their own per district.
+func (s *FarmerService) ClusterByTehsil(ctx context.Context, state, district string) ([]TehsilCluster, error) {
+	q := search.Query{Filters: map[string][]string{}}
+	if state != "" {
+		q.Filters["state"] = []string{state}
+	}
+	if district != "" {
+		q.Filters["district"] = []string{district}
+	}
+	q.Filters = s.canonicalFilters(q.Filters)
+	farmers := s.index.Search(q, 0, 0).Farmers
+
+	type key struct{ state, district, tehsil string }
+	clusters := make(map[key]*TehsilCluster)
+	points := make(map[key][]geo.Point)
+	for _, farmer := range farmers {
+		k := key{farmer.State, farmer.District, farmer.Tehsil}
+		c, ok := clusters[k]
+		if !ok {
+			c = &TehsilCluster{State: farmer.State, District: farmer.District, Tehsil: farmer.Tehsil}
+			clusters[k] = c
+		}
+		c.Farmers++
+		if farmer.Coordinates != nil {
+			points[k] = append(points[k], pointOf(farmer.Coordinates))
+		}
+	}
+
+	result := make([]TehsilCluster, 0, len(clusters))
+	for k, c := range clusters {
+		if ps := points[k]; len(ps) > 0 {
+			var centre geo.Point
+			for _, p := range ps {
+				centre.Lat += p.Lat / float64(len(ps))
+				centre.Lng += p.Lng / float64(len(ps))
+			}
+			for _, p := range ps {
+				c.RadiusKm = max(c.RadiusKm, geo.Distance(centre, p))
+			}
+			c.Located = len(ps)
+			c.Centre = &centre
+		}
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Farmers != b.Farmers {
+			return a.Farmers > b.Farmers
+		}
+		return a.State+"|"+a.District+"|"+a.Tehsil < b.State+"|"+b.District+"|"+b.Tehsil
+	})
+	return result, nil
+}
+
+// GeocodeFarmers places the farmers saved without coordinates at the
+// centroid of their pincode, for farmers saved before coordinates existed or
+// before the centroids were loaded. It returns how many it placed.
+func (s *FarmerService) GeocodeFarmers(ctx context.Context) (int, error) {
+	placed := 0
+	page := store.Page{Limit: 500}
+	for {
+		farmers, next, err := s.store.List(ctx, page)
+		if err != nil {
+			return placed, err
+		}
+		for i := range farmers {
+			farmer := &farmers[i]
+			if farmer.Coordinates != nil {
+				continue
+			}
+			if _, ok := s.locations.Centroid(farmer.Pincode); !ok {
+				continue
+			}
+			err := s.update(ctx, farmer, false)
+			if err == errors.ErrVersionConflict {
+				// Edited meanwhile, which placed the farmer already.
+				continue
+			}
+			if err != nil {
+				return placed, err
+			}
+			placed++
+		}
+		if next == "" {
+			return placed, nil
+		}
+		page.Cursor = next
+	}
+}
+
+// locate fills in the farmer's coordinates and geohash. Coordinates given by
+// hand are kept once checked; otherwise the farmer is placed at the centroid
+// of its pincode, or left off the map when the location master has none.
+func (s *FarmerService) locate(farmer *models.Farmer) error {
+	coordinates, err := geocode(s.locations, farmer.Coordinates, farmer.Pincode)
+	if err != nil {
+		return err
+	}
+	farmer.Coordinates = coordinates
+	farmer.GeoHash, farmer.GeoCell = "", ""
+	if coordinates != nil {
+		farmer.GeoHash = geo.Encode(pointOf(coordinates), geo.MaxPrecision)
+		farmer.GeoCell = farmer.GeoHash[:models.GeoCellLength]
+	}
+	return nil
+}
+
+// geocode returns the coordinates to save for a farmer or partner given
+// coordinates and a pincode, as described at locate.
+func geocode(locations *location.Directory, coordinates *models.GeoPoint, pincode string) (*models.GeoPoint, error) {
+	if coordinates != nil && coordinates.Source != models.GeoSourcePincode {
+		if err := pointOf(coordinates).Validate(); err != nil {
+			return nil, &location.InvalidPlaceError{Problems: []string{err.Error()}}
+		}
+		return &models.GeoPoint{Lat: coordinates.Lat, Lng: coordinates.Lng, Source: models.GeoSourceManual}, nil
+	}
+	if centroid, ok := locations.Centroid(pincode); ok {
+		return &models.GeoPoint{Lat: centroid.Lat, Lng: centroid.Lng, Source: models.GeoSourcePincode}, nil
+	}
+	return nil, nil
+}
+
+func pointOf(coordinates *models.GeoPoint) geo.Point {
+	return geo.Point{Lat: coordinates.Lat, Lng: coordinates.Lng}
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+// OverviewTimeout bounds the time spent gathering one farmer overview. Parts
+// that are not ready by then are left out rather than holding up the screen.
+const OverviewTimeout = 2 * time.Second
+
+// Parts of an overview, as named in FarmerOverview.Unavailable.
+const (
+	PartTickets     = "tickets"
+	PartShoots      = "shoots"
+	PartAssignedCCE = "assignedCce"
+)
+
+// FarmerOverview is everything an agent needs on screen when a farmer calls.
+type FarmerOverview struct {
+	Farmer        *models.Farmer  `json:"farmer"`
+	OpenTickets   []models.Ticket `json:"openTickets"`
+	RecentTickets []models.Ticket `json:"recentTickets"`
+	RecentShoots  []models.Shoot  `json:"recentShoots"`
+	Shoots        ShootSummary    `json:"shootSummary"`
+	AssignedCCE   *AssignedCCE    `json:"assignedCce,omitempty"`
+	Crops         CropSummary     `json:"crops"`
+	// Unavailable names the parts that failed or ran out of time. Their
+	// fields are empty.
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// ShootSummary counts the outcomes of the shoots in RecentShoots.
+type ShootSummary struct {
+	Completed   int        `json:"completed"`
+	Missed      int        `json:"missed"`
+	LastContact *time.Time `json:"lastContact,omitempty"`
+}
+
+// AssignedCCE is the CCE looking after the farmer: the one on the most
+// recently updated open ticket, or on the latest ticket when none is open.
+type AssignedCCE struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	OpenTickets int64   `json:"openTickets"`
+	AvgTime     float64 `json:"avgTime"`
+}
+
+type CropSummary struct {
+	Crops []string `json:"crops"`
+	Count int      `json:"count"`
+}
+
+// OverviewOptions sets how many recent tickets and shoots an overview lists.
+type OverviewOptions struct {
+	Tickets int
+	Shoots  int
+}
+
+type OverviewService struct {
+	farmers *FarmerService
+	tickets *TicketService
+	shoots  *ShootService
+	cces    *CCEService
+}
+
+func NewOverviewService(farmers *FarmerService, tickets *TicketService, shoots *ShootService, cces *CCEService) *OverviewService {
+	return &OverviewService{
+		farmers: farmers,
+		tickets: tickets,
+		shoots:  shoots,
+		cces:    cces,
+	}
+}
+
+// GetOverview gathers the overview of a farmer. The profile is read first, so
+// that the ID of a merged farmer is resolved to the one holding their
+// tickets and shoots. Those are then read concurrently, and the assigned CCE
+// as soon as the tickets are in. Only the profile is required: it fails with
+// errors.ErrNotFound for an unknown farmer, or with the context's error if
+// the profile is not read within OverviewTimeout.
+func (s *OverviewService) GetOverview(ctx context.Context, farmerID string, opts OverviewOptions) (*FarmerOverview, error) {
+	ctx, cancel := context.WithTimeout(ctx, OverviewTimeout)
+	defer cancel()
+
+	farmer, err := s.farmers.GetFarmer(ctx, farmerID)
+	if err != nil {
+		return nil, profileError(ctx, err)
+	}
+	return s.collect(ctx, farmer, s.gather(ctx, farmer.ID, opts)), nil
+}
+
+// GetOverviewByContact is GetOverview for the farmer holding a contact number,
+// for the screen pop of an incoming call. The lookup counts against the same
+// timeout.
+func (s *OverviewService) GetOverviewByContact(ctx context.Context, contact string, opts OverviewOptions) (*FarmerOverview, error) {
+	ctx, cancel := context.WithTimeout(ctx, OverviewTimeout)
+	defer cancel()
+
+	farmer, err := s.farmers.GetFarmerByContact(ctx, contact)
+	if err != nil {
+		return nil, profileError(ctx, err)
+	}
+	return s.collect(ctx, farmer, s.gather(ctx, farmer.ID, opts)), nil
+}
+
+// profileError returns the context's error in place of err once ctx is done,
+// as the stores report a read cut short by the deadline as any other failure.
+func profileError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// part is one finished piece of an overview.
+type part struct {
+	name  string
+	err   error
+	apply func(*FarmerOverview)
+}
+
+// overviewParts is the number of parts gather delivers.
+const overviewParts = 3
+
+// gather starts reading the tickets, the assigned CCE and the shoots of a
+// farmer and delivers each on the returned channel when it is done.
+func (s *OverviewService) gather(ctx context.Context, farmerID string, opts OverviewOptions) <-chan part {
+	parts := make(chan part, overviewParts)
+
+	go func() {
+		tickets, err := s.farmerTickets(ctx, farmerID)
+		if err != nil {
+			parts <- part{name: PartTickets, err: err}
+			parts <- part{name: PartAssignedCCE, err: err}
+			return
+		}
+		open, recent := splitTickets(tickets, opts.Tickets)
+		parts <- part{name: PartTickets, apply: func(o *FarmerOverview) {
+			o.OpenTickets = open
+			o.RecentTickets = recent
+		}}
+
+		cceID := assignedCCEID(open, recent)
+		if cceID == "" {
+			parts <- part{name: PartAssignedCCE, apply: func(*FarmerOverview) {}}
+			return
+		}
+		cce, err := s.cces.GetCCE(ctx, cceID)
+		if err != nil {
+			parts <- part{name: PartAssignedCCE, err: err}
+			return
+		}
+		parts <- part{name: PartAssignedCCE, apply: func(o *FarmerOverview) {
+			o.AssignedCCE = &AssignedCCE{
+				ID:          cce.ID,
+				Name:        cce.Name,
+				OpenTickets: cce.OpenTickets,
+				AvgTime:     cce.AvgTime,
+			}
+		}}
+	}()
+
+	go func() {
+		shoots, _, err := s.shoots.GetShootsByFarmer(ctx, farmerID, store.Page{Limit: int32(opts.Shoots)})
+		if err != nil {
+			parts <- part{name: PartShoots, err: err}
+			return
+		}
+		parts <- part{name: PartShoots, apply: func(o *FarmerOverview) {
+			o.RecentShoots = shoots
+			o.Shoots = summariseShoots(shoots)
+		}}
+	}()
+
+	return parts
+}
+
+// farmerTickets reads every ticket of a farmer, page by page, so that no open
+// ticket is missed.
+func (s *OverviewService) farmerTickets(ctx context.Context, farmerID string) ([]models.Ticket, error) {
+	var tickets []models.Ticket
+	page := store.Page{Limit: 100}
+	for {
+		batch, next, err := s.tickets.GetTicketsByFarmerContact(ctx, &models.Farmer{ID: farmerID}, page)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, batch...)
+		if next == "" {
+			return tickets, nil
+		}
+		page.Cursor = next
+	}
+}
+
+// collect builds the overview from the parts delivered before ctx is done.
+func (s *OverviewService) collect(ctx context.Context, farmer *models.Farmer, parts <-chan part) *FarmerOverview {
+	overview := &FarmerOverview{
+		Farmer:        farmer,
+		OpenTickets:   []models.Ticket{},
+		RecentTickets: []models.Ticket{},
+		RecentShoots:  []models.Shoot{},
+		Crops:         summariseCrops(farmer.Crop),
+	}
+
+	pending := map[string]bool{PartTickets: true, PartShoots: true, PartAssignedCCE: true}
+	for len(pending) > 0 {
+		select {
+		case p := <-parts:
+			delete(pending, p.name)
+			if p.err != nil {
+				overview.Unavailable = append(overview.Unavailable, p.name)
+				continue
+			}
+			p.apply(overview)
+		case <-ctx.Done():
+			for name := range pending {
+				overview.Unavailable = append(overview.Unavailable, name)
+			}
+			pending = nil
+		}
+	}
+	sort.Strings(overview.Unavailable)
+	return overview
+}
+
+// splitTickets returns every open ticket, most recently updated first, and
+// the n latest tickets of any status.
+func splitTickets(tickets []models.Ticket, n int) ([]models.Ticket, []models.Ticket) {
+	open := []models.Ticket{}
+	for _, ticket := range tickets {
+		if ticket.Status != models.TicketStatusClosed {
+			open = append(open, ticket)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].UpdatedAt.After(open[j].UpdatedAt)
+	})
+
+	recent := slices.Clone(tickets)
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].CreatedAt.After(recent[j].CreatedAt)
+	})
+	if recent == nil {
+		recent = []models.Ticket{}
+	}
+	return open, recent[:min(n, len(recent))]
+}
+
+func assignedCCEID(open, recent []models.Ticket) string {
+	for _, tickets := range [][]models.Ticket{open, recent} {
+		for _, ticket := range tickets {
+			if ticket.CCEID != "" {
+				return ticket.CCEID
+			}
+		}
+	}
+	return ""
+}
+
+func summariseShoots(shoots []models.Shoot) ShootSummary {
+	var summary ShootSummary
+	for _, shoot := range shoots {
+		switch shoot.Status {
+		case "completed":
+			summary.Completed++
+		case "missed":
+			summary.Missed++
+		}
+		if summary.LastContact == nil || shoot.Timestamp.After(*summary.LastContact) {
+			t := shoot.Timestamp
+			summary.LastContact = &t
+		}
+	}
+	return summary
+}
+
+// summariseCrops lists the farmer's crops once each, in alphabetical order.
+func summariseCrops(crops []string) CropSummary {
+	seen := make(map[string]bool)
+	summary := CropSummary{Crops: []string{}}
+	for _, crop := range crops {
+		crop = strings.TrimSpace(crop)
+		if crop == "" || seen[strings.ToLower(crop)] {
+			continue
+		}
+		seen[strings.ToLower(crop)] = true
+		summary.Crops = append(summary.Crops, crop)
+	}
+	sort.Strings(summary.Crops)
+	summary.Count = len(summary.Crops)
+	return summary
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"backend/internal/contactpolicy"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+func TestGetOverviewOfMergedFarmer(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	shoots, farmers := newTestShootService(t, stores, &contactpolicy.Policy{MaxPerWeek: 5})
+	audit := NewAuditService(stores.Audit)
+	tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+	overviews := NewOverviewService(farmers, tickets, shoots, NewCCEService(stores.CCE, audit))
+
+	if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f2", Name: "Ramesh Patil", Contact: "9876543211"}); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+	survivor, _ := stores.Farmer.Get(ctx, "f1")
+	duplicate, _ := stores.Farmer.Get(ctx, "f2")
+	if err := farmers.mergeFarmer(ctx, survivor, duplicate); err != nil {
+		t.Fatalf("mergeFarmer: %v", err)
+	}
+
+	// More closed tickets than fit on one page, with the open one last.
+	for i := range 120 {
+		ticket := &models.Ticket{ID: fmt.Sprintf("t%03d", i), FarmerID: "f1", Status: models.TicketStatusClosed}
+		if err := tickets.CreateTicket(ctx, ticket); err != nil {
+			t.Fatalf("CreateTicket(%s): %v", ticket.ID, err)
+		}
+	}
+	if err := tickets.CreateTicket(ctx, &models.Ticket{ID: "t120", FarmerID: "f1"}); err != nil {
+		t.Fatalf("CreateTicket(t120): %v", err)
+	}
+	if err := shoots.CreateShoot(ctx, &models.Shoot{ID: "s1", FarmerID: "f1", Type: "call", Status: "completed"}); err != nil {
+		t.Fatalf("CreateShoot: %v", err)
+	}
+
+	overview, err := overviews.GetOverview(ctx, "f2", OverviewOptions{Tickets: 5, Shoots: 5})
+	if err != nil {
+		t.Fatalf("GetOverview(f2): %v", err)
+	}
+	if overview.Farmer.ID != "f1" {
+		t.Errorf("overview of farmer %s, want f1", overview.Farmer.ID)
+	}
+	if len(overview.Unavailable) > 0 {
+		t.Errorf("unavailable parts %v", overview.Unavailable)
+	}
+	if len(overview.OpenTickets) != 1 || overview.OpenTickets[0].ID != "t120" {
+		t.Errorf("open tickets %v, want t120", overview.OpenTickets)
+	}
+	if len(overview.RecentTickets) != 5 {
+		t.Errorf("%d recent tickets, want 5", len(overview.RecentTickets))
+	}
+	if len(overview.RecentShoots) != 1 || overview.Shoots.Completed != 1 {
+		t.Errorf("recent shoots %v, want s1", overview.RecentShoots)
+	}
+}
+
+// slowFarmers answers reads only once the context is done, and then the way
+// the DynamoDB store does, with errors.ErrInternal.
+type slowFarmers struct {
+	store.FarmerStore
+}
+
+func (s *slowFarmers) Get(ctx context.Context, id string) (*models.Farmer, error) {
+	<-ctx.Done()
+	return nil, errors.ErrInternal
+}
+
+func (s *slowFarmers) GetByContact(ctx context.Context, contact string) (*models.Farmer, error) {
+	<-ctx.Done()
+	return nil, errors.ErrInternal
+}
+
+func TestGetOverviewTimesOut(t *testing.T) {
+	stores := newTestStores(t)
+	stores.Farmer = &slowFarmers{FarmerStore: stores.Farmer}
+	audit := NewAuditService(stores.Audit)
+	farmers := newTestFarmerService(t, stores, nil)
+	tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+	shoots := NewShootService(stores.Shoot, NewConsentService(stores.Consent, farmers), nil)
+	overviews := NewOverviewService(farmers, tickets, shoots, NewCCEService(stores.CCE, audit))
+
+	tests := []struct {
+		name string
+		get  func(ctx context.Context) (*FarmerOverview, error)
+	}{
+		{
+			name: "by ID",
+			get: func(ctx context.Context) (*FarmerOverview, error) {
+				return overviews.GetOverview(ctx, "f1", OverviewOptions{Tickets: 5, Shoots: 5})
+			},
+		},
+		{
+			name: "by contact",
+			get: func(ctx context.Context) (*FarmerOverview, error) {
+				return overviews.GetOverviewByContact(ctx, "9876543210", OverviewOptions{Tickets: 5, Shoots: 5})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if _, err := tt.get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("overview = %v, want context.DeadlineExceeded", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"backend/internal/geo"
+	"backend/internal/location"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+	"backend/pkg/phone"
+
+	"github.com/google/uuid"
+)
+
+// PartnerKinds lists the kinds of partner that can be registered.
+var PartnerKinds = []string{models.PartnerDealer, models.PartnerAgronomist}
+
+// PartnerDistance is a partner and how far it is from a farmer.
+type PartnerDistance struct {
+	models.Partner
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+type PartnerService struct {
+	store     store.PartnerStore
+	farmers   *FarmerService
+	locations *location.Directory
+	audit     *AuditService
+}
+
+func NewPartnerService(partnerStore store.PartnerStore, farmers *FarmerService, locations *location.Directory, audit *AuditService) *PartnerService {
+	return &PartnerService{
+		store:     partnerStore,
+		farmers:   farmers,
+		locations: locations,
+		audit:     audit,
+	}
+}
+
+// CreatePartner registers a dealer or agronomist. Its location is checked and
+// placed on the map as a farmer's is.
+func (s *PartnerService) CreatePartner(ctx context.Context, partner *models.Partner) error {
+	if err := s.prepare(partner); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	partner.ID = uuid.New().String()
+	partner.CreatedAt = now
+	partner.UpdatedAt = now
+	partner.Version = 1
+	if err := s.store.Put(ctx, partner); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityPartner, partner.ID, ActionCreate, nil, partner)
+	return nil
+}
+
+func (s *PartnerService) GetPartner(ctx context.Context, id string) (*models.Partner, error) {
+	return s.store.Get(ctx, id)
+}
+
+// UpdatePartner saves partner if it is still at partner.Version and bumps the
+// version.
+func (s *PartnerService) UpdatePartner(ctx context.Context, partner *models.Partner) error {
+	before, err := s.store.Get(ctx, partner.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.prepare(partner); err != nil {
+		return err
+	}
+	partner.CreatedAt = before.CreatedAt
+	partner.UpdatedAt = time.Now().UTC()
+	if err := s.store.Update(ctx, partner); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityPartner, partner.ID, ActionUpdate, before, partner)
+	return nil
+}
+
+// ListPartners returns one page of the partners of kind, or of every kind
+// when kind is empty.
+func (s *PartnerService) ListPartners(ctx context.Context, kind string, page store.Page) ([]models.Partner, string, error) {
+	if kind != "" && !slices.Contains(PartnerKinds, kind) {
+		return nil, "", errors.ErrInvalidInput
+	}
+	return s.store.List(ctx, kind, page)
+}
+
+// NearestPartners returns the limit partners of kind nearest to the farmer,
+// nearest first; an empty kind looks at every kind. Partners without
+// coordinates are left out. A farmer without coordinates has no nearest
+// partner and fails with errors.ErrConflict.
+//
+// Partners are far fewer than farmers, so they are all read and measured
+// rather than looked up in the geo index.
+func (s *PartnerService) NearestPartners(ctx context.Context, farmerID, kind string, limit int) ([]PartnerDistance, error) {
+	if kind != "" && !slices.Contains(PartnerKinds, kind) || limit <= 0 {
+		return nil, errors.ErrInvalidInput
+	}
+	farmer, err := s.farmers.GetFarmer(ctx, farmerID)
+	if err != nil {
+		return nil, err
+	}
+	if farmer.Coordinates == nil {
+		return nil, errors.ErrConflict
+	}
+	from := pointOf(farmer.Coordinates)
+
+	var nearest []PartnerDistance
+	page := store.Page{Limit: 500}
+	for {
+		partners, next, err := s.store.List(ctx, kind, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, partner := range partners {
+			if partner.Coordinates != nil {
+				nearest = append(nearest, PartnerDistance{
+					Partner:    partner,
+					DistanceKm: geo.Distance(from, pointOf(partner.Coordinates)),
+				})
+			}
+		}
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+
+	sort.Slice(nearest, func(i, j int) bool {
+		if nearest[i].DistanceKm != nearest[j].DistanceKm {
+			return nearest[i].DistanceKm < nearest[j].DistanceKm
+		}
+		return nearest[i].ID < nearest[j].ID
+	})
+	if len(nearest) > limit {
+		nearest = nearest[:limit]
+	}
+	return nearest, nil
+}
+
+// prepare checks a partner about to be saved and fills in its location the
+// way the location master spells it, and its coordinates.
+func (s *PartnerService) prepare(partner *models.Partner) error {
+	if partner.Name == "" || !slices.Contains(PartnerKinds, partner.Kind) {
+		return errors.ErrInvalidInput
+	}
+	if partner.Contact != "" {
+		normalised, err := phone.Normalize(partner.Contact)
+		if err != nil {
+			return errors.ErrInvalidInput
+		}
+		partner.Contact = normalised
+	}
+
+	place, err := s.locations.Resolve(location.Place{
+		State:    partner.State,
+		District: partner.District,
+		Tehsil:   partner.Tehsil,
+		Village:  partner.Village,
+		Pincode:  partner.Pincode,
+	})
+	if err != nil {
+		return err
+	}
+	partner.State = place.State
+	partner.District = place.District
+	partner.Tehsil = place.Tehsil
+	partner.Village = place.Village
+	partner.Pincode = place.Pincode
+
+	partner.Coordinates, err = geocode(s.locations, partner.Coordinates, partner.Pincode)
+	return err
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/pii"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+// sealedFarmers encrypts the personal data of farmers on their way into the
+// store and decrypts it on the way out, so FarmerService works on plaintext
+// while the store only ever holds Farmer.PII. The name and address are
+// blanked in the stored farmer and its numbers replaced by their blind index,
+// which keeps the store's contact uniqueness and GetByContact working
+// without the numbers themselves.
+//
+// Farmers saved before encryption was turned on are read as they are and
+// sealed the next time they are written; see ResealFarmers to seal them all.
+type sealedFarmers struct {
+	store.FarmerStore
+	cipher *pii.Cipher
+}
+
+// farmerPII is what Farmer.PII holds.
+type farmerPII struct {
+	Name     string                 `json:"name,omitempty"`
+	Address  string                 `json:"address,omitempty"`
+	Contact  string                 `json:"contact,omitempty"`
+	Contacts []models.FarmerContact `json:"contacts,omitempty"`
+}
+
+func (s *sealedFarmers) Put(ctx context.Context, farmer *models.Farmer) error {
+	plain, err := s.seal(ctx, farmer)
+	if err != nil {
+		return err
+	}
+	defer plain.restore(farmer)
+	return s.contactError(s.FarmerStore.Put(ctx, farmer), plain)
+}
+
+func (s *sealedFarmers) PutBatch(ctx context.Context, farmers []*models.Farmer) []error {
+	errs := make([]error, len(farmers))
+	plains := make([]*farmerPII, len(farmers))
+	var sealed []*models.Farmer
+	var positions []int
+	for i, farmer := range farmers {
+		plain, err := s.seal(ctx, farmer)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		plains[i] = plain
+		sealed = append(sealed, farmer)
+		positions = append(positions, i)
+	}
+
+	for j, err := range s.FarmerStore.PutBatch(ctx, sealed) {
+		i := positions[j]
+		errs[i] = s.contactError(err, plains[i])
+		plains[i].restore(farmers[i])
+	}
+	return errs
+}
+
+func (s *sealedFarmers) Update(ctx context.Context, farmer *models.Farmer) error {
+	plain, err := s.seal(ctx, farmer)
+	if err != nil {
+		return err
+	}
+	defer plain.restore(farmer)
+	return s.contactError(s.FarmerStore.Update(ctx, farmer), plain)
+}
+
+func (s *sealedFarmers) Merge(ctx context.Context, survivor, loser *models.Farmer) error {
+	plain, err := s.seal(ctx, survivor)
+	if err != nil {
+		return err
+	}
+	defer plain.restore(survivor)
+	loserPlain, err := s.seal(ctx, loser)
+	if err != nil {
+		return err
+	}
+	defer loserPlain.restore(loser)
+	return s.contactError(s.FarmerStore.Merge(ctx, survivor, loser), plain)
+}
+
+func (s *sealedFarmers) Get(ctx context.Context, id string) (*models.Farmer, error) {
+	farmer, err := s.FarmerStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return farmer, s.open(ctx, farmer)
+}
+
+func (s *sealedFarmers) GetDeleted(ctx context.Context, id string) (*models.Farmer, error) {
+	farmer, err := s.FarmerStore.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return farmer, s.open(ctx, farmer)
+}
+
+// GetByContact looks the number up by its blind index, then as it is for
+// farmers not sealed yet.
+func (s *sealedFarmers) GetByContact(ctx context.Context, contact string) (*models.Farmer, error) {
+	farmer, err := s.FarmerStore.GetByContact(ctx, s.cipher.BlindIndex(contact))
+	if err == errors.ErrNotFound {
+		farmer, err = s.FarmerStore.GetByContact(ctx, contact)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return farmer, s.open(ctx, farmer)
+}
+
+func (s *sealedFarmers) ListByGeohash(ctx context.Context, prefix string, page store.Page) ([]models.Farmer, string, error) {
+	return s.openAll(ctx)(s.FarmerStore.ListByGeohash(ctx, prefix, page))
+}
+
+func (s *sealedFarmers) List(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	return s.openAll(ctx)(s.FarmerStore.List(ctx, page))
+}
+
+func (s *sealedFarmers) ListDeleted(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	return s.openAll(ctx)(s.FarmerStore.ListDeleted(ctx, page))
+}
+
+// seal moves the farmer's personal data into Farmer.PII and returns it, for
+// restore to put back once the farmer is written.
+func (s *sealedFarmers) seal(ctx context.Context, farmer *models.Farmer) (*farmerPII, error) {
+	plain := &farmerPII{
+		Name:     farmer.Name,
+		Address:  farmer.Address,
+		Contact:  farmer.Contact,
+		Contacts: farmer.Contacts,
+	}
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.cipher.Seal(ctx, data, []byte(farmer.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt farmer %s: %w", farmer.ID, err)
+	}
+
+	farmer.PII = sealed
+	farmer.Name = ""
+	farmer.Address = ""
+	farmer.Contact = s.cipher.BlindIndex(plain.Contact)
+	farmer.Contacts = nil
+	for _, contact := range plain.Contacts {
+		farmer.Contacts = append(farmer.Contacts, models.FarmerContact{
+			Number:       s.cipher.BlindIndex(contact.Number),
+			Relationship: contact.Relationship,
+			Language:     contact.Language,
+			Primary:      contact.Primary,
+		})
+	}
+	return plain, nil
+}
+
+func (plain *farmerPII) restore(farmer *models.Farmer) {
+	farmer.Name = plain.Name
+	farmer.Address = plain.Address
+	farmer.Contact = plain.Contact
+	farmer.Contacts = plain.Contacts
+}
+
+// open decrypts Farmer.PII into the farmer.
+func (s *sealedFarmers) open(ctx context.Context, farmer *models.Farmer) error {
+	if farmer.PII == nil {
+		return nil
+	}
+	data, err := s.cipher.Open(ctx, farmer.PII, []byte(farmer.ID))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt farmer %s: %w", farmer.ID, err)
+	}
+	var plain farmerPII
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return fmt.Errorf("failed to decode farmer %s: %w", farmer.ID, err)
+	}
+	plain.restore(farmer)
+	return nil
+}
+
+func (s *sealedFarmers) openAll(ctx context.Context) func([]models.Farmer, string, error) ([]models.Farmer, string, error) {
+	return func(farmers []models.Farmer, next string, err error) ([]models.Farmer, string, error) {
+		if err != nil {
+			return nil, "", err
+		}
+		for i := range farmers {
+			if err := s.open(ctx, &farmers[i]); err != nil {
+				return nil, "", err
+			}
+		}
+		return farmers, next, nil
+	}
+}
+
+// contactError puts the number back into a *store.ContactTakenError, which
+// the store raised with the blind index.
+func (s *sealedFarmers) contactError(err error, plain *farmerPII) error {
+	var taken *store.ContactTakenError
+	if !errors.As(err, &taken) {
+		return err
+	}
+	numbers := append([]string{plain.Contact}, contactNumbers(plain.Contacts)...)
+	for _, number := range numbers {
+		if s.cipher.BlindIndex(number) == taken.Contact {
+			taken.Contact = number
+			break
+		}
+	}
+	return err
+}
+
+// auditView returns the farmer as its audit records show it: with the
+// personal data replaced by its blind index when PII is encrypted, so the
+// audit trail tells that a name or number changed without holding it.
+func (s *FarmerService) auditView(farmer *models.Farmer) *models.Farmer {
+	if s.cipher == nil {
+		return farmer
+	}
+	view := *farmer
+	view.Name = s.cipher.BlindIndex(farmer.Name)
+	view.Address = s.cipher.BlindIndex(farmer.Address)
+	view.Contact = s.cipher.BlindIndex(farmer.Contact)
+	view.Contacts = nil
+	for _, contact := range farmer.Contacts {
+		contact.Number = s.cipher.BlindIndex(contact.Number)
+		view.Contacts = append(view.Contacts, contact)
+	}
+	return &view
+}
+
+func contactNumbers(contacts []models.FarmerContact) []string {
+	numbers := make([]string, len(contacts))
+	for i, contact := range contacts {
+		numbers[i] = contact.Number
+	}
+	return numbers
+}
+
+// sealedConsent encrypts the contact number of consent events the way
+// sealedFarmers does the farmer's: the stored event holds its blind index in
+// Contact and the number itself in ConsentEvent.PII.
+type sealedConsent struct {
+	store.ConsentStore
+	cipher *pii.Cipher
+}
+
+func (s *sealedConsent) Put(ctx context.Context, event *models.ConsentEvent) error {
+	contact := event.Contact
+	sealed, err := s.cipher.Seal(ctx, []byte(contact), []byte(event.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt consent event %s: %w", event.ID, err)
+	}
+	event.PII = sealed
+	event.Contact = s.cipher.BlindIndex(contact)
+	defer func() {
+		event.PII = nil
+		event.Contact = contact
+	}()
+	return s.ConsentStore.Put(ctx, event)
+}
+
+func (s *sealedConsent) ListByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.ConsentEvent, string, error) {
+	return s.openAll(ctx)(s.ConsentStore.ListByFarmer(ctx, farmerID, page))
+}
+
+func (s *sealedConsent) ListByTimestamp(ctx context.Context, startDate, endDate time.Time, page store.Page) ([]models.ConsentEvent, string, error) {
+	return s.openAll(ctx)(s.ConsentStore.ListByTimestamp(ctx, startDate, endDate, page))
+}
+
+// openAll decrypts the contact of each event. Events recorded before
+// encryption was turned on are returned as they are.
+func (s *sealedConsent) openAll(ctx context.Context) func([]models.ConsentEvent, string, error) ([]models.ConsentEvent, string, error) {
+	return func(events []models.ConsentEvent, next string, err error) ([]models.ConsentEvent, string, error) {
+		if err != nil {
+			return nil, "", err
+		}
+		for i := range events {
+			event := &events[i]
+			if event.PII == nil {
+				continue
+			}
+			contact, err := s.cipher.Open(ctx, event.PII, []byte(event.ID))
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to decrypt consent event %s: %w", event.ID, err)
+			}
+			event.Contact = string(contact)
+			event.PII = nil
+		}
+		return events, next, nil
+	}
+}
+
+// ResealFarmers encrypts every farmer again under the current data key of the
+// cipher, and seals the farmers saved before encryption was turned on. It
+// works through batch farmers at a time, pausing between batches to spare the
+// table's write capacity, and returns how many farmers it wrote. An
+// interrupted run is simply started again.
+//
+// Farmers in the trash and merged aliases are not listed and keep the key
+// they were sealed with, which is why master keys are never dropped.
+func (s *FarmerService) ResealFarmers(ctx context.Context, batch int, pause time.Duration) (int, error) {
+	if s.cipher == nil {
+		return 0, fmt.Errorf("farmer PII encryption is not configured")
+	}
+
+	resealed := 0
+	page := store.Page{Limit: int32(batch)}
+	for {
+		farmers, next, err := s.store.List(ctx, page)
+		if err != nil {
+			return resealed, err
+		}
+		for i := range farmers {
+			farmer := &farmers[i]
+			err := s.store.Update(ctx, farmer)
+			if err == errors.ErrVersionConflict {
+				// Written meanwhile, under the data key of whoever wrote
+				// it; the next run picks it up.
+				continue
+			}
+			if err != nil {
+				return resealed, err
+			}
+			s.index.Put(*farmer)
+			resealed++
+		}
+		if next == "" {
+			return resealed, nil
+		}
+		page.Cursor = next
+		select {
+		case <-ctx.Done():
+			return resealed, ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+}
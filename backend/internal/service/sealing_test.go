@@ -0,0 +1,165 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/models"
+	"backend/internal/pii"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+func newTestCipher(t *testing.T, keys pii.KeyProvider) *pii.Cipher {
+	t.Helper()
+	cipher, err := pii.NewCipher(keys, []byte("blind-index-test-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher
+}
+
+func TestSealedFarmers(t *testing.T) {
+	ctx := context.Background()
+	keys, err := pii.LoadKeyfile(filepath.Join(t.TempDir(), "keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher := newTestCipher(t, keys)
+	stores := newTestStores(t)
+	farmers := newTestFarmerService(t, stores, cipher)
+
+	farmer := &models.Farmer{
+		ID:      "f1",
+		Name:    "Ramesh Patil",
+		Address: "Near the temple",
+		Contact: "9876543210",
+		Contacts: []models.FarmerContact{
+			{Number: "9876543211", Name: "Suresh Patil", Relationship: "son"},
+		},
+	}
+	if err := farmers.CreateFarmer(ctx, farmer); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+	if farmer.Name != "Ramesh Patil" || farmer.Contact != "+919876543210" {
+		t.Errorf("CreateFarmer left the farmer as %q, %q", farmer.Name, farmer.Contact)
+	}
+
+	stored, err := stores.Farmer.Get(ctx, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.PII == nil || stored.Name != "" || stored.Address != "" {
+		t.Errorf("stored farmer holds name %q and address %q, PII %v", stored.Name, stored.Address, stored.PII)
+	}
+	for _, number := range stored.ContactNumbers() {
+		if number == "+919876543210" || number == "+919876543211" {
+			t.Errorf("stored farmer holds the number %s", number)
+		}
+	}
+	if stored.Contact != cipher.BlindIndex("+919876543210") {
+		t.Errorf("stored contact = %q, want the blind index of the number", stored.Contact)
+	}
+
+	tests := []struct {
+		name    string
+		contact string
+		want    error
+	}{
+		{name: "primary number", contact: "9876543210"},
+		{name: "E.164", contact: "+919876543210"},
+		{name: "secondary number", contact: "98765 43211"},
+		{name: "unknown number", contact: "9876543212", want: errors.ErrNotFound},
+		{name: "blind index", contact: stored.Contact, want: errors.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := farmers.GetFarmerByContact(ctx, tt.contact)
+			if err != tt.want {
+				t.Fatalf("GetFarmerByContact = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if found.ID != "f1" || found.Name != "Ramesh Patil" || found.Address != "Near the temple" || found.Contact != "+919876543210" {
+				t.Errorf("GetFarmerByContact = %s %q, %q, %q", found.ID, found.Name, found.Address, found.Contact)
+			}
+			if len(found.Contacts) != 2 || found.Contacts[1].Number != "+919876543211" || found.Contacts[1].Name != "Suresh Patil" {
+				t.Errorf("GetFarmerByContact contacts = %+v", found.Contacts)
+			}
+		})
+	}
+
+	taken := &models.Farmer{ID: "f2", Name: "Sunita Deshmukh", Contact: "9876543211"}
+	var takenErr *store.ContactTakenError
+	if err := farmers.CreateFarmer(ctx, taken); !errors.As(err, &takenErr) {
+		t.Fatalf("CreateFarmer with a taken number = %v, want *ContactTakenError", err)
+	}
+	if takenErr.FarmerID != "f1" || takenErr.Contact != "+919876543211" {
+		t.Errorf("ContactTakenError names %s and %q, want f1 and the number", takenErr.FarmerID, takenErr.Contact)
+	}
+}
+
+func TestResealFarmers(t *testing.T) {
+	ctx := context.Background()
+	keys, err := pii.LoadKeyfile(filepath.Join(t.TempDir(), "keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := newTestStores(t)
+
+	// f1 is saved before encryption was turned on, f2 after.
+	plain := newTestFarmerService(t, stores, nil)
+	if err := plain.CreateFarmer(ctx, &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210"}); err != nil {
+		t.Fatalf("CreateFarmer(f1): %v", err)
+	}
+	cipher := newTestCipher(t, keys)
+	farmers := newTestFarmerService(t, stores, cipher)
+	if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f2", Name: "Sunita Deshmukh", Contact: "9876543211"}); err != nil {
+		t.Fatalf("CreateFarmer(f2): %v", err)
+	}
+	before, err := stores.Farmer.Get(ctx, "f2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// What rotate-keys does.
+	if err := cipher.Rotate(ctx); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	resealed, err := farmers.ResealFarmers(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("ResealFarmers: %v", err)
+	}
+	if resealed != 2 {
+		t.Errorf("ResealFarmers = %d, want 2", resealed)
+	}
+
+	for _, id := range []string{"f1", "f2"} {
+		stored, err := stores.Farmer.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.PII == nil || stored.Name != "" {
+			t.Fatalf("%s is stored with name %q, PII %v", id, stored.Name, stored.PII)
+		}
+		if bytes.Equal(stored.PII.DataKey, before.PII.DataKey) {
+			t.Errorf("%s is still sealed with the data key from before Rotate", id)
+		}
+	}
+
+	// A server started after the rotation, with no data keys in memory.
+	restarted := newTestFarmerService(t, stores, newTestCipher(t, keys))
+	for contact, name := range map[string]string{"9876543210": "Ramesh Patil", "9876543211": "Sunita Deshmukh"} {
+		found, err := restarted.GetFarmerByContact(ctx, contact)
+		if err != nil {
+			t.Fatalf("GetFarmerByContact(%s): %v", contact, err)
+		}
+		if found.Name != name {
+			t.Errorf("GetFarmerByContact(%s) = %q, want %q", contact, found.Name, name)
+		}
+	}
+}
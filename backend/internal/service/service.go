@@ -1,7 +1,12 @@
 package service
 
 import (
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"crypto/ed25519"
+
+	"backend/internal/contactpolicy"
+	"backend/internal/location"
+	"backend/internal/pii"
+	"backend/internal/store"
 )
 
 type Services struct {
@@ -9,12 +14,43 @@ type Services struct {
 	CCE    *CCEService
 	Ticket *TicketService
 	Shoot  *ShootService
+	Audit  *AuditService
+	// Overview combines the services above into the farmer overview.
+	Overview *OverviewService
+	// Duplicate finds and merges farmers recorded more than once.
+	Duplicate *DuplicateService
+	// Consent records who may be reached on which channel.
+	Consent *ConsentService
+	// Partner registers the dealers and agronomists farmers are sent to.
+	Partner *PartnerService
+	// Catalog holds the crops, varieties and SKUs farmers and tickets refer
+	// to.
+	Catalog *CatalogService
+	// Erasure erases farmers' personal data on request.
+	Erasure *ErasureService
+	// Locations is the location master farmers are checked against.
+	Locations *location.Directory
 }
 
-func NewServices(dbClient *dynamodb.Client) *Services {
-	return &Services{
-		Farmer: NewFarmerService(dbClient),
-		CCE:    NewCCEService(dbClient),
-		Ticket: NewTicketService(dbClient),
+// NewServices wires the services together. A nil policy leaves outbound
+// shoots checked for consent only, and a nil cipher leaves farmer PII
+// unencrypted. Erasure certificates are signed with erasureKey.
+func NewServices(stores *store.Stores, locations *location.Directory, policy *contactpolicy.Policy, cipher *pii.Cipher, erasureKey ed25519.PrivateKey) *Services {
+	audit := NewAuditService(stores.Audit)
+	services := &Services{
+		Farmer: NewFarmerService(stores.Farmer, stores.Catalog, locations, stores.Cursors, audit, cipher),
+		CCE:    NewCCEService(stores.CCE, audit),
+		Ticket: NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit),
+		Audit:  audit,
+
+		Locations: locations,
 	}
+	services.Consent = NewConsentService(stores.Consent, services.Farmer)
+	services.Shoot = NewShootService(stores.Shoot, services.Consent, policy)
+	services.Overview = NewOverviewService(services.Farmer, services.Ticket, services.Shoot, services.CCE)
+	services.Partner = NewPartnerService(stores.Partner, services.Farmer, locations, audit)
+	services.Duplicate = NewDuplicateService(stores.Duplicate, services.Farmer, services.Ticket, services.Shoot)
+	services.Catalog = NewCatalogService(stores.Catalog, stores.Farmer, stores.Ticket, locations, audit)
+	services.Erasure = NewErasureService(services.Farmer, services.Ticket, stores.Consent, audit, erasureKey)
+	return services
 }
@@ -1,105 +1,247 @@
 package service
 
 import (
+	"backend/internal/contactpolicy"
 	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
 	"context"
+	"fmt"
+	"slices"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 )
 
-const ShootTableName = "Shoots"
+// OutreachDeferredError is returned for a shoot the contact policy asks to
+// wait with. It matches errors.ErrConflict.
+type OutreachDeferredError struct {
+	FarmerID string
+	Decision contactpolicy.Decision
+}
+
+func (e *OutreachDeferredError) Error() string {
+	return fmt.Sprintf("contact with farmer %s deferred until %s", e.FarmerID, e.Decision.Until.Format(time.RFC3339))
+}
+
+func (e *OutreachDeferredError) Is(target error) bool {
+	return target == errors.ErrConflict
+}
+
+// maxShootSkew is how far ahead of the server's clock a new shoot may be
+// dated. Shoots may be recorded after they were made, dated when they were
+// made; the contact policy is applied at the time of recording either way.
+const maxShootSkew = 5 * time.Minute
+
+// ErrShootTimestamp is returned for a shoot dated more than maxShootSkew
+// ahead of now.
+var ErrShootTimestamp = errors.New("shoot timestamp is in the future")
+
+// shootAttempts bounds how often CreateShoot checks a shoot again when
+// another shoot of the same farmer was recorded in the meantime.
+const shootAttempts = 5
+
+// shootRetryDelay is how long CreateShoot waits for the farmer's latest shoot
+// to show up in ListByFarmer, which reads an eventually consistent index.
+const shootRetryDelay = 200 * time.Millisecond
 
 type ShootService struct {
-	dbClient *dynamodb.Client
+	store   store.ShootStore
+	consent *ConsentService
+	policy  *contactpolicy.Policy
 }
 
-func NewShootService(dbClient *dynamodb.Client) *ShootService {
+func NewShootService(shootStore store.ShootStore, consent *ConsentService, policy *contactpolicy.Policy) *ShootService {
 	return &ShootService{
-		dbClient: dbClient,
+		store:   shootStore,
+		consent: consent,
+		policy:  policy,
 	}
 }
 
-func (s *ShootService) GetAllShoots(ctx context.Context, shootType string) ([]models.Shoot, error) {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String("Shoots"),
+// CreateShoot records outreach to a farmer. It is refused with a
+// *ConsentError unless the farmer has consented to the shoot's channel, and
+// with an *OutreachDeferredError while the contact policy asks to wait. The
+// policy is applied at the current time, whatever the shoot is dated; a shoot
+// dated in the future fails with ErrShootTimestamp.
+//
+// Merged farmer IDs are resolved first, and the shoot is saved with
+// ShootStore.PutInSequence, so shoots recorded at once for the same farmer,
+// by any of its IDs and on any instance, cannot both pass a cap meant for
+// one. A shoot that keeps losing that race fails with ErrConflict.
+func (s *ShootService) CreateShoot(ctx context.Context, shoot *models.Shoot) error {
+	if shoot.FarmerID == "" {
+		return errors.ErrInvalidInput
+	}
+	now := time.Now().UTC()
+	if shoot.Timestamp.IsZero() {
+		shoot.Timestamp = now
+	}
+	if shoot.Timestamp.Sub(now) > maxShootSkew {
+		return ErrShootTimestamp
+	}
+	if shoot.ID == "" {
+		shoot.ID = uuid.New().String()
 	}
 
-	if shootType != "" {
-		input.FilterExpression = aws.String("#type = :type")
-		input.ExpressionAttributeNames = map[string]string{
-			"#type": "Type",
+	farmer, err := s.consent.farmers.GetFarmer(ctx, shoot.FarmerID)
+	if err != nil {
+		return err
+	}
+	shoot.FarmerID = farmer.ID
+
+	for attempt := 1; ; attempt++ {
+		mark, err := s.store.OutreachMark(ctx, shoot.FarmerID)
+		if err != nil {
+			return err
 		}
-		input.ExpressionAttributeValues = map[string]types.AttributeValue{
-			":type": &types.AttributeValueMemberS{Value: shootType},
+		farmer, decision, history, err := s.checkOutreach(ctx, shoot.FarmerID, shoot.Type, now)
+		if err != nil {
+			return err
+		}
+		if !s.seesMark(history, mark, now) {
+			if attempt == shootAttempts {
+				return errors.ErrConflict
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(shootRetryDelay):
+			}
+			continue
+		}
+		if decision.Outcome == contactpolicy.Deferred {
+			return &OutreachDeferredError{FarmerID: farmer.ID, Decision: decision}
 		}
-	}
 
-	result, err := s.dbClient.Scan(ctx, input)
-	if err != nil {
-		return nil, err
+		err = s.store.PutInSequence(ctx, shoot, mark)
+		if err == errors.ErrConflict && attempt < shootAttempts {
+			continue
+		}
+		return err
 	}
+}
 
-	var shoots []models.Shoot
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &shoots)
-	if err != nil {
-		return nil, err
+// seesMark reports whether history, read after mark, holds the last shoot
+// the mark names, or does not need to because that shoot is older than the
+// policy looks back.
+func (s *ShootService) seesMark(history []models.Shoot, mark store.OutreachMark, now time.Time) bool {
+	if s.policy == nil || mark.LastShootID == "" || !mark.LastAt.After(now.Add(-s.policy.Lookback())) {
+		return true
 	}
+	return slices.ContainsFunc(history, func(shoot models.Shoot) bool { return shoot.ID == mark.LastShootID })
+}
 
-	return shoots, nil
+// CheckOutreach decides whether the farmer may be reached on channel at the
+// given time. Missing consent blocks outright; otherwise the contact policy
+// is applied to the farmer's shoots on every channel. The *ConsentError for
+// a blocked decision is returned along with it.
+func (s *ShootService) CheckOutreach(ctx context.Context, farmerID, channel string, at time.Time) (*models.Farmer, contactpolicy.Decision, error) {
+	farmer, decision, _, err := s.checkOutreach(ctx, farmerID, channel, at)
+	return farmer, decision, err
 }
 
-func (s *ShootService) GetShootsWithDateFilter(ctx context.Context, startDate, endDate time.Time) ([]models.Shoot, error) {
-	input := &dynamodb.ScanInput{
-		TableName:        aws.String("Shoots"),
-		FilterExpression: aws.String("Timestamp BETWEEN :startDate AND :endDate"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":startDate": &types.AttributeValueMemberS{Value: startDate.Format(time.RFC3339)},
-			":endDate":   &types.AttributeValueMemberS{Value: endDate.Format(time.RFC3339)},
-		},
+// checkOutreach is CheckOutreach, also returning the shoots the policy was
+// applied to.
+func (s *ShootService) checkOutreach(ctx context.Context, farmerID, channel string, at time.Time) (*models.Farmer, contactpolicy.Decision, []models.Shoot, error) {
+	farmer, err := s.consent.RequireConsent(ctx, farmerID, channel)
+	var noConsent *ConsentError
+	if errors.As(err, &noConsent) {
+		return nil, contactpolicy.Decision{Outcome: contactpolicy.Blocked, Reasons: []string{noConsent.Error()}}, nil, err
+	}
+	if err != nil {
+		return nil, contactpolicy.Decision{}, nil, err
+	}
+	if s.policy == nil {
+		return farmer, contactpolicy.Decision{Outcome: contactpolicy.Allowed}, nil, nil
 	}
 
-	result, err := s.dbClient.Scan(ctx, input)
+	history, err := s.recentShoots(ctx, farmer.ID, at.Add(-s.policy.Lookback()))
 	if err != nil {
-		return nil, err
+		return nil, contactpolicy.Decision{}, nil, err
 	}
+	return farmer, s.policy.Evaluate(farmer, history, at), history, nil
+}
 
+// recentShoots returns the farmer's shoots made after since, newest first.
+func (s *ShootService) recentShoots(ctx context.Context, farmerID string, since time.Time) ([]models.Shoot, error) {
 	var shoots []models.Shoot
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &shoots)
-	if err != nil {
-		return nil, err
+	page := store.Page{Limit: 100}
+	for {
+		batch, next, err := s.store.ListByFarmer(ctx, farmerID, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, shoot := range batch {
+			if !shoot.Timestamp.After(since) {
+				return shoots, nil
+			}
+			shoots = append(shoots, shoot)
+		}
+		if next == "" {
+			return shoots, nil
+		}
+		page.Cursor = next
 	}
+}
 
-	return shoots, nil
+func (s *ShootService) GetAllShoots(ctx context.Context, shootType string, page store.Page) ([]models.Shoot, string, error) {
+	return s.store.List(ctx, shootType, page)
 }
 
-func (s *ShootService) GetMissedShoots(ctx context.Context) ([]models.Shoot, error) {
-	input := &dynamodb.ScanInput{
-		TableName:        aws.String("Shoots"),
-		FilterExpression: aws.String("#status = :status AND #type = :type"),
-		ExpressionAttributeNames: map[string]string{
-			"#status": "Status",
-			"#type":   "Type",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":status": &types.AttributeValueMemberS{Value: "missed"},
-			":type":   &types.AttributeValueMemberS{Value: "call"},
-		},
-	}
+func (s *ShootService) GetShootsWithDateFilter(ctx context.Context, startDate, endDate time.Time, page store.Page) ([]models.Shoot, string, error) {
+	return s.store.ListByTimestamp(ctx, startDate, endDate, page)
+}
 
-	result, err := s.dbClient.Scan(ctx, input)
-	if err != nil {
-		return nil, err
-	}
+func (s *ShootService) GetMissedShoots(ctx context.Context, page store.Page) ([]models.Shoot, string, error) {
+	return s.store.ListByStatusAndType(ctx, "missed", "call", page)
+}
 
+func (s *ShootService) GetShootsByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.Shoot, string, error) {
+	return s.store.ListByFarmer(ctx, farmerID, page)
+}
+
+// MoveFarmerShoots reassigns every shoot of the farmer from to the farmer to
+// and returns how many it moved. The shoots move oldest first through
+// ShootStore.MoveInSequence, so shoots recorded for the farmer to meanwhile
+// are checked against the ones it takes over.
+func (s *ShootService) MoveFarmerShoots(ctx context.Context, from, to string) (int, error) {
 	var shoots []models.Shoot
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &shoots)
-	if err != nil {
-		return nil, err
+	page := store.Page{Limit: 100}
+	for {
+		batch, next, err := s.store.ListByFarmer(ctx, from, page)
+		if err != nil {
+			return 0, err
+		}
+		shoots = append(shoots, batch...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+
+	slices.Reverse(shoots)
+	for i := range shoots {
+		shoots[i].FarmerID = to
+		if err := s.moveShoot(ctx, &shoots[i]); err != nil {
+			return i, err
+		}
 	}
+	return len(shoots), nil
+}
 
-	return shoots, nil
+// moveShoot saves shoot, reassigned to its new farmer, at the farmer's
+// current mark.
+func (s *ShootService) moveShoot(ctx context.Context, shoot *models.Shoot) error {
+	for attempt := 1; ; attempt++ {
+		mark, err := s.store.OutreachMark(ctx, shoot.FarmerID)
+		if err != nil {
+			return err
+		}
+		err = s.store.MoveInSequence(ctx, shoot, mark)
+		if err == errors.ErrConflict && attempt < shootAttempts {
+			continue
+		}
+		return err
+	}
 }
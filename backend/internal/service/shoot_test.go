@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/contactpolicy"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+// newTestShootService returns a ShootService applying policy, with farmer f1
+// consenting to calls.
+func newTestShootService(t *testing.T, stores *store.Stores, policy *contactpolicy.Policy) (*ShootService, *FarmerService) {
+	t.Helper()
+	ctx := context.Background()
+	farmers := newTestFarmerService(t, stores, nil)
+	consent := NewConsentService(stores.Consent, farmers)
+
+	if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f1", Name: "Ramesh Patil", Contact: "9876543210"}); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+	change := ConsentChange{Channels: []string{"call"}, Status: models.ConsentGranted, Source: "test"}
+	if _, err := consent.SetConsent(ctx, "f1", change); err != nil {
+		t.Fatalf("SetConsent: %v", err)
+	}
+	return NewShootService(stores.Shoot, consent, policy), farmers
+}
+
+func TestCreateShootTimestamp(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	shoots, _ := newTestShootService(t, stores, &contactpolicy.Policy{MaxPerWeek: 5})
+
+	made := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Second)
+	if err := shoots.CreateShoot(ctx, &models.Shoot{ID: "s1", FarmerID: "f1", Type: "call", Timestamp: made}); err != nil {
+		t.Fatalf("CreateShoot dated in the past: %v", err)
+	}
+	recorded, _, err := stores.Shoot.ListByFarmer(ctx, "f1", store.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 1 || !recorded[0].Timestamp.Equal(made) {
+		t.Errorf("recorded shoots %v, want s1 dated %s", recorded, made)
+	}
+
+	future := &models.Shoot{ID: "s2", FarmerID: "f1", Type: "call", Timestamp: time.Now().UTC().Add(time.Hour)}
+	if err := shoots.CreateShoot(ctx, future); err != ErrShootTimestamp {
+		t.Errorf("CreateShoot dated in the future = %v, want ErrShootTimestamp", err)
+	}
+}
+
+func TestCreateShootCapsConcurrentShootsAcrossAliases(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	shoots, farmers := newTestShootService(t, stores, &contactpolicy.Policy{MaxPerDay: 1})
+
+	if err := farmers.CreateFarmer(ctx, &models.Farmer{ID: "f2", Name: "Ramesh Patil", Contact: "9876543211"}); err != nil {
+		t.Fatalf("CreateFarmer: %v", err)
+	}
+	survivor, _ := stores.Farmer.Get(ctx, "f1")
+	duplicate, _ := stores.Farmer.Get(ctx, "f2")
+	if err := farmers.mergeFarmer(ctx, survivor, duplicate); err != nil {
+		t.Fatalf("mergeFarmer: %v", err)
+	}
+
+	const attempts = 8
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		farmerID := "f1"
+		if i%2 == 1 {
+			farmerID = "f2"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = shoots.CreateShoot(ctx, &models.Shoot{FarmerID: farmerID, Type: "call", Status: "completed"})
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		var deferred *OutreachDeferredError
+		switch {
+		case err == nil:
+			created++
+		case errors.As(err, &deferred), err == errors.ErrConflict:
+		default:
+			t.Errorf("CreateShoot: %v", err)
+		}
+	}
+	recorded, _, err := stores.Shoot.ListByFarmer(ctx, "f1", store.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || len(recorded) != 1 {
+		t.Errorf("%d shoots created and %d recorded under a daily cap of 1, want 1", created, len(recorded))
+	}
+}
+
+func TestShootListCursorsStayWithTheirList(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	shoots, _ := newTestShootService(t, stores, nil)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, status := range []string{"missed", "missed", "completed"} {
+		shoot := &models.Shoot{ID: fmt.Sprintf("s%d", i), FarmerID: "f1", Type: "call", Status: status, Timestamp: now.Add(-time.Duration(i) * time.Hour)}
+		if err := stores.Shoot.Put(ctx, shoot); err != nil {
+			t.Fatalf("Put(%s): %v", shoot.ID, err)
+		}
+	}
+
+	_, next, err := shoots.GetAllShoots(ctx, "call", store.Page{Limit: 1})
+	if err != nil || next == "" {
+		t.Fatalf("GetAllShoots = %q, %v; want a next cursor", next, err)
+	}
+	if _, _, err := shoots.GetAllShoots(ctx, "call", store.Page{Limit: 1, Cursor: next}); err != nil {
+		t.Errorf("GetAllShoots with its own cursor: %v", err)
+	}
+
+	page := store.Page{Limit: 1, Cursor: next}
+	if _, _, err := shoots.GetAllShoots(ctx, "whatsapp", page); err != errors.ErrInvalidCursor {
+		t.Errorf("GetAllShoots of another type = %v, want ErrInvalidCursor", err)
+	}
+	if _, _, err := shoots.GetMissedShoots(ctx, page); err != errors.ErrInvalidCursor {
+		t.Errorf("GetMissedShoots = %v, want ErrInvalidCursor", err)
+	}
+	if _, _, err := shoots.GetShootsWithDateFilter(ctx, now.Add(-24*time.Hour), now, page); err != errors.ErrInvalidCursor {
+		t.Errorf("GetShootsWithDateFilter = %v, want ErrInvalidCursor", err)
+	}
+
+	_, next, err = shoots.GetShootsWithDateFilter(ctx, now.Add(-24*time.Hour), now, store.Page{Limit: 1})
+	if err != nil || next == "" {
+		t.Fatalf("GetShootsWithDateFilter = %q, %v; want a next cursor", next, err)
+	}
+	page.Cursor = next
+	if _, _, err := shoots.GetShootsWithDateFilter(ctx, now.Add(-48*time.Hour), now, page); err != errors.ErrInvalidCursor {
+		t.Errorf("GetShootsWithDateFilter over other dates = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestMoveFarmerShootsMovesTheMark(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	shoots, _ := newTestShootService(t, stores, &contactpolicy.Policy{MaxPerDay: 1})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, at := range []time.Time{now.Add(-time.Hour), now.Add(-48 * time.Hour)} {
+		shoot := &models.Shoot{ID: fmt.Sprintf("s%d", i), FarmerID: "f2", Type: "call", Status: "completed", Timestamp: at}
+		if err := stores.Shoot.Put(ctx, shoot); err != nil {
+			t.Fatalf("Put(%s): %v", shoot.ID, err)
+		}
+	}
+
+	// A shoot for f1 checked before the move, saved after it.
+	stale, err := stores.Shoot.OutreachMark(ctx, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := shoots.MoveFarmerShoots(ctx, "f2", "f1")
+	if err != nil || moved != 2 {
+		t.Fatalf("MoveFarmerShoots = %d, %v; want 2 moved", moved, err)
+	}
+	late := &models.Shoot{ID: "s2", FarmerID: "f1", Type: "call", Timestamp: now}
+	if err := stores.Shoot.PutInSequence(ctx, late, stale); err != errors.ErrConflict {
+		t.Errorf("PutInSequence at the mark from before the move = %v, want ErrConflict", err)
+	}
+
+	mark, err := stores.Shoot.OutreachMark(ctx, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mark.Seq != 2 || mark.LastShootID != "s0" {
+		t.Errorf("mark after the move = %+v, want the 2 moved shoots counted and the newest named", mark)
+	}
+	var deferred *OutreachDeferredError
+	if err := shoots.CreateShoot(ctx, &models.Shoot{FarmerID: "f1", Type: "call"}); !errors.As(err, &deferred) {
+		t.Errorf("CreateShoot after taking over today's shoot = %v, want it deferred", err)
+	}
+}
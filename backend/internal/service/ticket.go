@@ -6,184 +6,92 @@ import (
 	"time"
 
 	"backend/internal/models"
-	"backend/pkg/errors"
+	"backend/internal/store"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 )
 
-const TicketTableName = "Tickets"
-
 type TicketService struct {
-	dbClient *dynamodb.Client
+	store store.TicketStore
+	// catalog holds the products tickets are about, see resolveProduct.
+	catalog store.CatalogStore
+	tx      store.Transactions
+	audit   *AuditService
 }
 
-func NewTicketService(dbClient *dynamodb.Client) *TicketService {
+func NewTicketService(ticketStore store.TicketStore, catalog store.CatalogStore, transactions store.Transactions, audit *AuditService) *TicketService {
 	return &TicketService{
-		dbClient: dbClient,
+		store:   ticketStore,
+		catalog: catalog,
+		tx:      transactions,
+		audit:   audit,
 	}
 }
 
+// CreateTicket saves ticket together with the counters of the CCE it is
+// assigned to. The write fails as a whole if the farmer or CCE does not exist.
+// A crop, variety or SKU missing from the catalog fails as a *CatalogError.
 func (s *TicketService) CreateTicket(ctx context.Context, ticket *models.Ticket) error {
-	item, err := attributevalue.MarshalMap(ticket)
-	if err != nil {
-		return errors.ErrInternal
+	if err := resolveProduct(ctx, s.catalog, ticket); err != nil {
+		return err
 	}
-
-	_, err = s.dbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(TicketTableName),
-		Item:      item,
-	})
-	if err != nil {
-		return errors.ErrInternal
+	if ticket.ID == "" {
+		ticket.ID = uuid.New().String()
 	}
-
-	return nil
-}
-
-func (s *TicketService) GetTicket(ctx context.Context, id string) (*models.Ticket, error) {
-	result, err := s.dbClient.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(TicketTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: id},
-		},
-	})
-	if err != nil {
-		return nil, errors.ErrInternal
+	now := time.Now().UTC()
+	ticket.CreatedAt = now
+	ticket.UpdatedAt = now
+	if ticket.Status == "" {
+		ticket.Status = models.TicketStatusOpen
 	}
-	if result.Item == nil {
-		return nil, errors.ErrNotFound
+	ticket.ClosedAt = nil
+	if ticket.Status == models.TicketStatusClosed {
+		ticket.ClosedAt = &now
 	}
-
-	var ticket models.Ticket
-	err = attributevalue.UnmarshalMap(result.Item, &ticket)
-	if err != nil {
-		return nil, errors.ErrInternal
+	ticket.Version = 1
+	ticket.DeletedAt = nil
+	ticket.DeletedBy = ""
+
+	uow := s.tx.Begin()
+	uow.CreateTicket(ticket)
+	if ticket.FarmerID != "" {
+		uow.RequireFarmer(ticket.FarmerID)
 	}
-
-	return &ticket, nil
-}
-
-func (s *TicketService) GetTicketsByFarmerContact(ctx context.Context, farmer *models.Farmer) ([]models.Ticket, error) {
-	// farmer, err := s.farmerService.GetFarmerByContact(ctx, contact)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	result, err := s.dbClient.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String("Tickets"),
-		IndexName:              aws.String("FarmerIDIndex"),
-		KeyConditionExpression: aws.String("FarmerID = :farmerID"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":farmerID": &types.AttributeValueMemberS{Value: farmer.ID},
-		},
-	})
-	if err != nil {
-		return nil, err
+	if ticket.CCEID != "" {
+		uow.UpdateCCE(ticket.CCEID, cceTally(ticket))
 	}
-
-	var tickets []models.Ticket
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &tickets)
-	if err != nil {
-		return nil, err
+	if err := uow.Commit(ctx); err != nil {
+		return err
 	}
 
-	return tickets, nil
+	s.audit.Record(ctx, EntityTicket, ticket.ID, ActionCreate, nil, ticket)
+	return nil
 }
 
-func (s *TicketService) GetTicketsByCCE(ctx context.Context, cceID string) ([]models.Ticket, error) {
-	result, err := s.dbClient.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String("Tickets"),
-		IndexName:              aws.String("CCEIDIndex"),
-		KeyConditionExpression: aws.String("CCEID = :cceID"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":cceID": &types.AttributeValueMemberS{Value: cceID},
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	var tickets []models.Ticket
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &tickets)
-	if err != nil {
-		return nil, err
-	}
-
-	return tickets, nil
+func (s *TicketService) GetTicket(ctx context.Context, id string) (*models.Ticket, error) {
+	return s.store.Get(ctx, id)
 }
 
-func (s *TicketService) GetTicketsByCCEWithDateFilter(ctx context.Context, cceID string, startDate, endDate time.Time) ([]models.Ticket, error) {
-	result, err := s.dbClient.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String("Tickets"),
-		IndexName:              aws.String("CCEIDCreatedAtIndex"),
-		KeyConditionExpression: aws.String("CCEID = :cceID AND CreatedAt BETWEEN :startDate AND :endDate"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":cceID":     &types.AttributeValueMemberS{Value: cceID},
-			":startDate": &types.AttributeValueMemberS{Value: startDate.Format(time.RFC3339)},
-			":endDate":   &types.AttributeValueMemberS{Value: endDate.Format(time.RFC3339)},
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	var tickets []models.Ticket
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &tickets)
-	if err != nil {
-		return nil, err
-	}
-
-	return tickets, nil
+func (s *TicketService) GetTicketsByFarmerContact(ctx context.Context, farmer *models.Farmer, page store.Page) ([]models.Ticket, string, error) {
+	return s.store.ListByFarmer(ctx, farmer.ID, page)
 }
 
-func (s *TicketService) GetTicketsByCCEAndStatus(ctx context.Context, cceID, status string) ([]models.Ticket, error) {
-	result, err := s.dbClient.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String("Tickets"),
-		IndexName:              aws.String("CCEIDStatusIndex"),
-		KeyConditionExpression: aws.String("CCEID = :cceID AND #status = :status"),
-		ExpressionAttributeNames: map[string]string{
-			"#status": "Status",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":cceID":  &types.AttributeValueMemberS{Value: cceID},
-			":status": &types.AttributeValueMemberS{Value: status},
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
+func (s *TicketService) GetTicketsByCCE(ctx context.Context, cceID string, page store.Page) ([]models.Ticket, string, error) {
+	return s.store.ListByCCE(ctx, cceID, page)
+}
 
-	var tickets []models.Ticket
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &tickets)
-	if err != nil {
-		return nil, err
-	}
+func (s *TicketService) GetTicketsByCCEWithDateFilter(ctx context.Context, cceID string, startDate, endDate time.Time, page store.Page) ([]models.Ticket, string, error) {
+	return s.store.ListByCCEAndCreatedAt(ctx, cceID, startDate, endDate, page)
+}
 
-	return tickets, nil
+func (s *TicketService) GetTicketsByCCEAndStatus(ctx context.Context, cceID, status string, page store.Page) ([]models.Ticket, string, error) {
+	return s.store.ListByCCEAndStatus(ctx, cceID, status, page)
 }
 
+// GetTicketsWithStatusAndSort reads every ticket with status, since the sort
+// order does not follow any key the store could page by.
 func (s *TicketService) GetTicketsWithStatusAndSort(ctx context.Context, status, sortBy, sortOrder string) ([]models.Ticket, error) {
-	input := &dynamodb.ScanInput{
-		TableName:        aws.String("Tickets"),
-		FilterExpression: aws.String("#status = :status"),
-		ExpressionAttributeNames: map[string]string{
-			"#status": "Status",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":status": &types.AttributeValueMemberS{Value: status},
-		},
-	}
-
-	result, err := s.dbClient.Scan(ctx, input)
-	if err != nil {
-		return nil, err
-	}
-
-	var tickets []models.Ticket
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &tickets)
+	tickets, _, err := s.store.ListByStatus(ctx, status, store.Page{})
 	if err != nil {
 		return nil, err
 	}
@@ -211,64 +119,184 @@ func (s *TicketService) GetTicketsWithStatusAndSort(ctx context.Context, status,
 	return tickets, nil
 }
 
+// UpdateTicket saves ticket if it is still at ticket.Version and bumps the
+// version. Closing, reopening or reassigning the ticket moves the counters of
+// the CCEs involved in the same write. The product is checked as in
+// CreateTicket when it changed.
 func (s *TicketService) UpdateTicket(ctx context.Context, ticket *models.Ticket) error {
-	item, err := attributevalue.MarshalMap(ticket)
+	before, err := s.store.Get(ctx, ticket.ID)
 	if err != nil {
-		return errors.ErrInternal
+		return err
+	}
+	if ticket.CropID != before.CropID || ticket.VarietyID != before.VarietyID || ticket.SKU != before.SKU {
+		if err := resolveProduct(ctx, s.catalog, ticket); err != nil {
+			return err
+		}
 	}
 
-	_, err = s.dbClient.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: aws.String(TicketTableName),
-		Item:      item,
-	})
-	if err != nil {
-		return errors.ErrInternal
+	ticket.CreatedAt = before.CreatedAt
+	ticket.UpdatedAt = time.Now().UTC()
+	switch {
+	case ticket.Status != models.TicketStatusClosed:
+		ticket.ClosedAt = nil
+	case before.Status != models.TicketStatusClosed || before.ClosedAt == nil:
+		closedAt := ticket.UpdatedAt
+		ticket.ClosedAt = &closedAt
+	default:
+		ticket.ClosedAt = before.ClosedAt
+	}
+
+	uow := s.tx.Begin()
+	uow.UpdateTicket(ticket)
+	if ticket.FarmerID != "" && ticket.FarmerID != before.FarmerID {
+		uow.RequireFarmer(ticket.FarmerID)
+	}
+	for cceID, change := range cceChanges(before, ticket) {
+		uow.UpdateCCE(cceID, change)
+	}
+	if err := uow.Commit(ctx); err != nil {
+		return err
 	}
 
+	s.audit.Record(ctx, EntityTicket, ticket.ID, ActionUpdate, before, ticket)
 	return nil
 }
 
-func (s *TicketService) DeleteTicket(ctx context.Context, id string) error {
-	_, err := s.dbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: aws.String(TicketTableName),
-		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: id},
-		},
-	})
-	if err != nil {
-		return errors.ErrInternal
+// MoveFarmerTickets reassigns every ticket of the farmer from to the farmer
+// to and returns how many it moved. Each ticket is moved with UpdateTicket, so
+// a failure leaves the tickets moved so far with to and calling it again
+// picks up the rest.
+func (s *TicketService) MoveFarmerTickets(ctx context.Context, from, to string) (int, error) {
+	var tickets []models.Ticket
+	page := store.Page{Limit: 100}
+	for {
+		batch, next, err := s.store.ListByFarmer(ctx, from, page)
+		if err != nil {
+			return 0, err
+		}
+		tickets = append(tickets, batch...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
 	}
 
-	return nil
+	for i := range tickets {
+		tickets[i].FarmerID = to
+		if err := s.UpdateTicket(ctx, &tickets[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(tickets), nil
 }
 
-func (s *TicketService) ListTickets(ctx context.Context, limit int32, nextToken string) ([]models.Ticket, string, error) {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(TicketTableName),
-		Limit:     aws.Int32(limit),
+// cceTally is what ticket contributes to the counters of its CCE.
+func cceTally(ticket *models.Ticket) store.CCEChange {
+	change := store.CCEChange{AddTicket: ticket.ID}
+	if ticket.Status != models.TicketStatusClosed {
+		change.OpenTickets = 1
+		return change
 	}
+	change.ClosedTickets = 1
+	if ticket.ClosedAt != nil {
+		change.ResolvedSeconds = int64(ticket.ClosedAt.Sub(ticket.CreatedAt).Seconds())
+	}
+	return change
+}
 
-	if nextToken != "" {
-		input.ExclusiveStartKey = map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: nextToken},
+// cceRemoval takes what ticket contributes off the counters of its CCE.
+func cceRemoval(ticket *models.Ticket) store.CCEChange {
+	tally := cceTally(ticket)
+	return store.CCEChange{
+		RemoveTicket:    ticket.ID,
+		OpenTickets:     -tally.OpenTickets,
+		ClosedTickets:   -tally.ClosedTickets,
+		ResolvedSeconds: -tally.ResolvedSeconds,
+	}
+}
+
+// cceChanges takes the contribution of before away from its CCE and adds that
+// of after to its CCE, leaving out CCEs whose counters end up unchanged.
+func cceChanges(before, after *models.Ticket) map[string]store.CCEChange {
+	changes := map[string]store.CCEChange{}
+	if before.CCEID != "" {
+		changes[before.CCEID] = cceRemoval(before)
+	}
+	if after.CCEID != "" {
+		tally := cceTally(after)
+		change := changes[after.CCEID]
+		if change.RemoveTicket == after.ID {
+			change.RemoveTicket = ""
+		} else {
+			change.AddTicket = after.ID
 		}
+		change.OpenTickets += tally.OpenTickets
+		change.ClosedTickets += tally.ClosedTickets
+		change.ResolvedSeconds += tally.ResolvedSeconds
+		changes[after.CCEID] = change
 	}
 
-	result, err := s.dbClient.Scan(ctx, input)
+	for cceID, change := range changes {
+		if change.IsZero() {
+			delete(changes, cceID)
+		}
+	}
+	return changes
+}
+
+// DeleteTicket moves the ticket to the trash and takes it off the counters of
+// its CCE in the same write.
+func (s *TicketService) DeleteTicket(ctx context.Context, id, deletedBy string) error {
+	ticket, err := s.store.Get(ctx, id)
 	if err != nil {
-		return nil, "", errors.ErrInternal
+		return err
 	}
 
-	var tickets []models.Ticket
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &tickets)
+	uow := s.tx.Begin()
+	uow.DeleteTicket(ticket, deletedBy)
+	if ticket.CCEID != "" {
+		uow.UpdateCCE(ticket.CCEID, cceRemoval(ticket))
+	}
+	if err := uow.Commit(ctx); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityTicket, id, ActionDelete, nil, nil)
+	return nil
+}
+
+func (s *TicketService) ListDeletedTickets(ctx context.Context, page store.Page) ([]models.Ticket, string, error) {
+	return s.store.ListDeleted(ctx, page)
+}
+
+// RestoreTicket takes the ticket out of the trash and puts it back on the
+// counters of its CCE in the same write. It fails as a whole if that CCE is
+// deleted.
+func (s *TicketService) RestoreTicket(ctx context.Context, id string) error {
+	ticket, err := s.store.GetDeleted(ctx, id)
 	if err != nil {
-		return nil, "", errors.ErrInternal
+		return err
 	}
 
-	var newNextToken string
-	if result.LastEvaluatedKey != nil {
-		newNextToken = result.LastEvaluatedKey["ID"].(*types.AttributeValueMemberS).Value
+	uow := s.tx.Begin()
+	uow.RestoreTicket(ticket)
+	if ticket.CCEID != "" {
+		uow.UpdateCCE(ticket.CCEID, cceTally(ticket))
 	}
+	if err := uow.Commit(ctx); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, EntityTicket, id, ActionRestore, nil, nil)
+	return nil
+}
+
+// PurgeTickets removes the tickets deleted before the cutoff for good. Their
+// CCEs no longer count them, see DeleteTicket.
+func (s *TicketService) PurgeTickets(ctx context.Context, before time.Time) (int, error) {
+	return s.store.Purge(ctx, before)
+}
 
-	return tickets, newNextToken, nil
+func (s *TicketService) ListTickets(ctx context.Context, page store.Page) ([]models.Ticket, string, error) {
+	return s.store.List(ctx, page)
 }
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestDeleteAndRestoreTicketMoveCCECounters(t *testing.T) {
+	ctx := context.Background()
+	stores := newTestStores(t)
+	audit := NewAuditService(stores.Audit)
+	cces := NewCCEService(stores.CCE, audit)
+	tickets := NewTicketService(stores.Ticket, stores.Catalog, stores.Transactions, audit)
+
+	if err := cces.CreateCCE(ctx, &models.CCE{ID: "c1", Name: "Asha"}); err != nil {
+		t.Fatalf("CreateCCE: %v", err)
+	}
+	for _, ticket := range []*models.Ticket{
+		{ID: "t1", CCEID: "c1"},
+		{ID: "t2", CCEID: "c1", Status: models.TicketStatusClosed},
+	} {
+		if err := tickets.CreateTicket(ctx, ticket); err != nil {
+			t.Fatalf("CreateTicket(%s): %v", ticket.ID, err)
+		}
+	}
+
+	check := func(step string, open, closed int64, ticketIDs ...string) {
+		t.Helper()
+		cce, err := cces.GetCCE(ctx, "c1")
+		if err != nil {
+			t.Fatalf("%s: GetCCE: %v", step, err)
+		}
+		if cce.OpenTickets != open || cce.ClosedTickets != closed || !slices.Equal(cce.TicketIDs, ticketIDs) {
+			t.Errorf("%s: CCE has %d open, %d closed, tickets %v; want %d, %d, %v",
+				step, cce.OpenTickets, cce.ClosedTickets, cce.TicketIDs, open, closed, ticketIDs)
+		}
+	}
+	check("created", 1, 1, "t1", "t2")
+
+	if err := tickets.DeleteTicket(ctx, "t1", "admin"); err != nil {
+		t.Fatalf("DeleteTicket(t1): %v", err)
+	}
+	if err := tickets.DeleteTicket(ctx, "t2", "admin"); err != nil {
+		t.Fatalf("DeleteTicket(t2): %v", err)
+	}
+	check("deleted", 0, 0)
+
+	if err := tickets.RestoreTicket(ctx, "t1"); err != nil {
+		t.Fatalf("RestoreTicket(t1): %v", err)
+	}
+	check("t1 restored", 1, 0, "t1")
+	if err := tickets.RestoreTicket(ctx, "t2"); err != nil {
+		t.Fatalf("RestoreTicket(t2): %v", err)
+	}
+	check("restored", 1, 1, "t1", "t2")
+
+	if err := tickets.RestoreTicket(ctx, "t2"); err == nil {
+		t.Error("restoring a ticket twice succeeded")
+	}
+	check("restored twice", 1, 1, "t1", "t2")
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"fmt"
+
+	"backend/pkg/errors"
+)
+
+// ContactTakenError is returned when a farmer is written with a contact number
+// that already belongs to another farmer. errors.Is matches ErrConflict.
+type ContactTakenError struct {
+	Contact string
+	// FarmerID is the farmer holding the contact. It may be in the trash, a
+	// deleted farmer keeps its contact until it is purged.
+	FarmerID string
+}
+
+func (e *ContactTakenError) Error() string {
+	return fmt.Sprintf("contact %s already belongs to farmer %s", e.Contact, e.FarmerID)
+}
+
+func (e *ContactTakenError) Is(target error) bool {
+	return target == errors.ErrConflict
+}
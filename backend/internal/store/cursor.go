@@ -0,0 +1,108 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Page asks for one page of a list. A zero Limit returns every remaining item.
+type Page struct {
+	Limit  int32
+	Cursor string
+}
+
+// Cursors turns the position a list stopped at into an opaque token and back.
+// The token carries the whole key the list resumes from, including the index
+// keys of a GSI query, and is signed so clients cannot forge positions.
+type Cursors struct {
+	secret []byte
+}
+
+func NewCursors(secret []byte) *Cursors {
+	return &Cursors{secret: secret}
+}
+
+type cursorPayload struct {
+	Scope string                       `json:"s"`
+	Key   map[string]map[string]string `json:"k"`
+}
+
+// Encode returns the token for key, or "" when key is empty because the list
+// is exhausted. scope names the list the key belongs to; a token is only
+// accepted by Decode for the same scope.
+func (c *Cursors) Encode(scope string, key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	payload := cursorPayload{Scope: scope, Key: make(map[string]map[string]string, len(key))}
+	for name, value := range key {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			payload.Key[name] = map[string]string{"S": v.Value}
+		case *types.AttributeValueMemberN:
+			payload.Key[name] = map[string]string{"N": v.Value}
+		default:
+			return "", errors.ErrInternal
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body)), nil
+}
+
+// Decode verifies a token produced by Encode for the same scope and returns
+// the key to resume from. An empty token decodes to a nil key.
+func (c *Cursors) Decode(scope, cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	body, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, errors.ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(body)) {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, errors.ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Scope != scope || len(payload.Key) == 0 {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	key := make(map[string]types.AttributeValue, len(payload.Key))
+	for name, value := range payload.Key {
+		if s, ok := value["S"]; ok {
+			key[name] = &types.AttributeValueMemberS{Value: s}
+		} else if n, ok := value["N"]; ok {
+			key[name] = &types.AttributeValueMemberN{Value: n}
+		} else {
+			return nil, errors.ErrInvalidCursor
+		}
+	}
+	return key, nil
+}
+
+func (c *Cursors) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
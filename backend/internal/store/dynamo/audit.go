@@ -0,0 +1,97 @@
+package dynamo
+
+import (
+	"context"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type AuditStore struct {
+	client  *dynamodb.Client
+	table   string
+	cursors *store.Cursors
+}
+
+func NewAuditStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *AuditStore {
+	return &AuditStore{
+		client:  client,
+		table:   tables.Name(db.AuditTable),
+		cursors: cursors,
+	}
+}
+
+func (s *AuditStore) Put(ctx context.Context, record *models.AuditRecord) error {
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	})
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *AuditStore) ListByEntity(ctx context.Context, entity, id string, page store.Page) ([]models.AuditRecord, string, error) {
+	entityKey := entity + "#" + id
+	return queryPage[models.AuditRecord](ctx, s.client, s.cursors, "audit/"+entityKey, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("EntityKeyIndex"),
+		KeyConditionExpression: aws.String("EntityKey = :entityKey"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entityKey": &types.AttributeValueMemberS{Value: entityKey},
+		},
+	}, page)
+}
+
+func (s *AuditStore) Redact(ctx context.Context, entity, id string, fields []string) (int, error) {
+	redacted := 0
+	page := store.Page{Limit: 100}
+	for {
+		records, next, err := s.ListByEntity(ctx, entity, id, page)
+		if err != nil {
+			return redacted, err
+		}
+		for _, record := range records {
+			changes, ok := store.RedactChanges(record.Changes, fields)
+			if !ok {
+				continue
+			}
+			value, err := attributevalue.Marshal(changes)
+			if err != nil {
+				return redacted, errors.ErrInternal
+			}
+			_, err = s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:           aws.String(s.table),
+				Key:                 idKey(record.ID),
+				UpdateExpression:    aws.String("SET Changes = :changes"),
+				ConditionExpression: aws.String("attribute_exists(ID)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":changes": value,
+				},
+			})
+			if err != nil {
+				return redacted, errors.ErrInternal
+			}
+			redacted++
+		}
+		if next == "" {
+			return redacted, nil
+		}
+		page.Cursor = next
+	}
+}
@@ -0,0 +1,146 @@
+package dynamo
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchWriteItems is the most writes DynamoDB accepts in one BatchWriteItem.
+const maxBatchWriteItems = 25
+
+// batchWriteAttempts bounds how often unprocessed items are sent again before
+// they are reported as failed.
+const batchWriteAttempts = 5
+
+// PutBatch writes the farmers with BatchWriteItem. A batch cannot carry
+// conditions, so every number is claimed on its own first and a farmer with a
+// number taken is left out of the batch. Claims of farmers the batch could
+// not write are released again.
+func (s *FarmerStore) PutBatch(ctx context.Context, farmers []*models.Farmer) []error {
+	errs := make([]error, len(farmers))
+	index := make(map[string]int, len(farmers))
+
+	var requests []types.WriteRequest
+	for i, farmer := range farmers {
+		if _, ok := index[farmer.ID]; ok {
+			errs[i] = errors.ErrConflict
+			continue
+		}
+		item, err := attributevalue.MarshalMap(farmer)
+		if err != nil {
+			errs[i] = errors.ErrInternal
+			continue
+		}
+		if errs[i] = s.claimAll(ctx, farmer.ContactNumbers(), farmer.ID); errs[i] != nil {
+			continue
+		}
+
+		index[farmer.ID] = i
+		requests = append(requests, types.WriteRequest{
+			PutRequest: &types.PutRequest{Item: item},
+		})
+	}
+
+	for start := 0; start < len(requests); start += maxBatchWriteItems {
+		end := min(start+maxBatchWriteItems, len(requests))
+		for _, failed := range s.batchWrite(ctx, requests[start:end]) {
+			id := failed.PutRequest.Item["ID"].(*types.AttributeValueMemberS).Value
+			i := index[id]
+			errs[i] = errors.ErrInternal
+			for _, contact := range farmers[i].ContactNumbers() {
+				s.unclaim(ctx, contact, id)
+			}
+		}
+	}
+
+	return errs
+}
+
+// batchWrite sends requests until DynamoDB has processed all of them or the
+// attempts run out, and returns the requests that were never written.
+func (s *FarmerStore) batchWrite(ctx context.Context, requests []types.WriteRequest) []types.WriteRequest {
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		result, err := s.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{s.table: requests},
+		})
+		if err != nil {
+			return requests
+		}
+
+		requests = result.UnprocessedItems[s.table]
+		if len(requests) == 0 || attempt == batchWriteAttempts {
+			return requests
+		}
+
+		select {
+		case <-ctx.Done():
+			return requests
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// claimAll claims every number in contacts for farmerID, or none of them: the
+// claims made before one that fails are released again.
+func (s *FarmerStore) claimAll(ctx context.Context, contacts []string, farmerID string) error {
+	for i, contact := range contacts {
+		if err := s.claim(ctx, contact, farmerID); err != nil {
+			for _, claimed := range contacts[:i] {
+				s.unclaim(ctx, claimed, farmerID)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// claim claims contact for farmerID outside of a transaction.
+func (s *FarmerStore) claim(ctx context.Context, contact, farmerID string) error {
+	put := s.claimContact(contact, farmerID).Put
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           put.TableName,
+		Item:                                put.Item,
+		ConditionExpression:                 put.ConditionExpression,
+		ExpressionAttributeValues:           put.ExpressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		taken := &store.ContactTakenError{Contact: contact}
+		if holder, ok := conditionErr.Item["FarmerID"].(*types.AttributeValueMemberS); ok {
+			taken.FarmerID = holder.Value
+		}
+		return taken
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+	return nil
+}
+
+// unclaim releases a claim made by claim. If that fails the claim is left
+// without a farmer and blocks the contact until it is removed by hand.
+func (s *FarmerStore) unclaim(ctx context.Context, contact, farmerID string) {
+	del := s.releaseContact(contact, farmerID).Delete
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 del.TableName,
+		Key:                       del.Key,
+		ConditionExpression:       del.ConditionExpression,
+		ExpressionAttributeValues: del.ExpressionAttributeValues,
+	})
+	if err != nil {
+		log.Printf("Failed to release contact %s of unwritten farmer %s: %v", contact, farmerID, err)
+	}
+}
@@ -0,0 +1,186 @@
+package dynamo
+
+import (
+	"context"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type CatalogStore struct {
+	client    *dynamodb.Client
+	crops     string
+	varieties string
+	cursors   *store.Cursors
+}
+
+func NewCatalogStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *CatalogStore {
+	return &CatalogStore{
+		client:    client,
+		crops:     tables.Name(db.CropsTable),
+		varieties: tables.Name(db.VarietiesTable),
+		cursors:   cursors,
+	}
+}
+
+func (s *CatalogStore) PutCrop(ctx context.Context, crop *models.Crop) error {
+	item, err := attributevalue.MarshalMap(crop)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.crops),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.ErrConflict
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *CatalogStore) UpdateCrop(ctx context.Context, crop *models.Crop) error {
+	expected := crop.Version
+	crop.Version++
+
+	item, err := attributevalue.MarshalMap(crop)
+	if err != nil {
+		crop.Version = expected
+		return errors.ErrInternal
+	}
+
+	if err := putVersioned(ctx, s.client, s.crops, item, expected); err != nil {
+		crop.Version = expected
+		return err
+	}
+
+	return nil
+}
+
+func (s *CatalogStore) GetCrop(ctx context.Context, id string) (*models.Crop, error) {
+	var crop models.Crop
+	if err := s.get(ctx, s.crops, id, &crop); err != nil {
+		return nil, err
+	}
+	return &crop, nil
+}
+
+func (s *CatalogStore) ListCrops(ctx context.Context, page store.Page) ([]models.Crop, string, error) {
+	return scanPage[models.Crop](ctx, s.client, s.cursors, "crops", &dynamodb.ScanInput{
+		TableName: aws.String(s.crops),
+	}, page)
+}
+
+func (s *CatalogStore) DeleteCrop(ctx context.Context, id string) error {
+	return s.delete(ctx, s.crops, id)
+}
+
+func (s *CatalogStore) PutVariety(ctx context.Context, variety *models.Variety) error {
+	item, err := attributevalue.MarshalMap(variety)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.varieties),
+		Item:      item,
+	})
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *CatalogStore) UpdateVariety(ctx context.Context, variety *models.Variety) error {
+	expected := variety.Version
+	variety.Version++
+
+	item, err := attributevalue.MarshalMap(variety)
+	if err != nil {
+		variety.Version = expected
+		return errors.ErrInternal
+	}
+
+	if err := putVersioned(ctx, s.client, s.varieties, item, expected); err != nil {
+		variety.Version = expected
+		return err
+	}
+
+	return nil
+}
+
+func (s *CatalogStore) GetVariety(ctx context.Context, id string) (*models.Variety, error) {
+	var variety models.Variety
+	if err := s.get(ctx, s.varieties, id, &variety); err != nil {
+		return nil, err
+	}
+	return &variety, nil
+}
+
+func (s *CatalogStore) ListVarieties(ctx context.Context, cropID string, page store.Page) ([]models.Variety, string, error) {
+	if cropID == "" {
+		return scanPage[models.Variety](ctx, s.client, s.cursors, "varieties", &dynamodb.ScanInput{
+			TableName: aws.String(s.varieties),
+		}, page)
+	}
+	return queryPage[models.Variety](ctx, s.client, s.cursors, "varieties/"+cropID, &dynamodb.QueryInput{
+		TableName:              aws.String(s.varieties),
+		IndexName:              aws.String("CropIDNameIndex"),
+		KeyConditionExpression: aws.String("CropID = :cropId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cropId": &types.AttributeValueMemberS{Value: cropID},
+		},
+	}, page)
+}
+
+func (s *CatalogStore) DeleteVariety(ctx context.Context, id string) error {
+	return s.delete(ctx, s.varieties, id)
+}
+
+func (s *CatalogStore) get(ctx context.Context, table, id string, out interface{}) error {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key:       idKey(id),
+	})
+	if err != nil {
+		return errors.ErrInternal
+	}
+	if result.Item == nil {
+		return errors.ErrNotFound
+	}
+	if err := attributevalue.UnmarshalMap(result.Item, out); err != nil {
+		return errors.ErrInternal
+	}
+	return nil
+}
+
+// delete removes an item for good; the catalog has no trash.
+func (s *CatalogStore) delete(ctx context.Context, table, id string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(table),
+		Key:                 idKey(id),
+		ConditionExpression: aws.String("attribute_exists(ID)"),
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.ErrNotFound
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type CCEStore struct {
+	client  *dynamodb.Client
+	table   string
+	cursors *store.Cursors
+}
+
+func NewCCEStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *CCEStore {
+	return &CCEStore{
+		client:  client,
+		table:   tables.Name(db.CCEsTable),
+		cursors: cursors,
+	}
+}
+
+func (s *CCEStore) Put(ctx context.Context, cce *models.CCE) error {
+	item, err := attributevalue.MarshalMap(cce)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.ErrConflict
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *CCEStore) Update(ctx context.Context, cce *models.CCE) error {
+	expected := cce.Version
+	cce.Version++
+
+	item, err := attributevalue.MarshalMap(cce)
+	if err != nil {
+		cce.Version = expected
+		return errors.ErrInternal
+	}
+
+	err = putVersioned(ctx, s.client, s.table, item, expected)
+	if err != nil {
+		cce.Version = expected
+		return err
+	}
+
+	return nil
+}
+
+func (s *CCEStore) Get(ctx context.Context, id string) (*models.CCE, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+	if result.Item == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	var cce models.CCE
+	err = attributevalue.UnmarshalMap(result.Item, &cce)
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+	if cce.DeletedAt != nil {
+		return nil, errors.ErrNotFound
+	}
+
+	return &cce, nil
+}
+
+func (s *CCEStore) List(ctx context.Context, page store.Page) ([]models.CCE, string, error) {
+	return scanPage[models.CCE](ctx, s.client, s.cursors, "cces", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: withoutDeleted(""),
+	}, page)
+}
+
+func (s *CCEStore) Delete(ctx context.Context, id, deletedBy string) error {
+	return softDelete(ctx, s.client, s.table, id, deletedBy)
+}
+
+func (s *CCEStore) ListDeleted(ctx context.Context, page store.Page) ([]models.CCE, string, error) {
+	return scanPage[models.CCE](ctx, s.client, s.cursors, "cces/trash", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("attribute_exists(DeletedAt)"),
+	}, page)
+}
+
+func (s *CCEStore) Restore(ctx context.Context, id string) error {
+	return restore(ctx, s.client, s.table, id)
+}
+
+func (s *CCEStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purge(ctx, s.client, s.table, before)
+}
@@ -0,0 +1,106 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type ConsentStore struct {
+	client  *dynamodb.Client
+	table   string
+	cursors *store.Cursors
+}
+
+func NewConsentStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *ConsentStore {
+	return &ConsentStore{
+		client:  client,
+		table:   tables.Name(db.ConsentEventsTable),
+		cursors: cursors,
+	}
+}
+
+func (s *ConsentStore) Put(ctx context.Context, event *models.ConsentEvent) error {
+	item, err := attributevalue.MarshalMap(event)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	})
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *ConsentStore) ListByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.ConsentEvent, string, error) {
+	return queryPage[models.ConsentEvent](ctx, s.client, s.cursors, "consent/farmer/"+farmerID, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("FarmerIDIndex"),
+		KeyConditionExpression: aws.String("FarmerID = :farmerID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":farmerID": &types.AttributeValueMemberS{Value: farmerID},
+		},
+	}, page)
+}
+
+func (s *ConsentStore) ListByTimestamp(ctx context.Context, startDate, endDate time.Time, page store.Page) ([]models.ConsentEvent, string, error) {
+	return scanPage[models.ConsentEvent](ctx, s.client, s.cursors, "consent", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("#timestamp BETWEEN :startDate AND :endDate"),
+		ExpressionAttributeNames: map[string]string{
+			"#timestamp": "Timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":startDate": &types.AttributeValueMemberS{Value: startDate.UTC().Format(time.RFC3339Nano)},
+			":endDate":   &types.AttributeValueMemberS{Value: endDate.UTC().Format(time.RFC3339Nano)},
+		},
+	}, page)
+}
+
+func (s *ConsentStore) Erase(ctx context.Context, farmerID string) (int, error) {
+	erased := 0
+	page := store.Page{Limit: 100}
+	for {
+		events, next, err := s.ListByFarmer(ctx, farmerID, page)
+		if err != nil {
+			return erased, err
+		}
+		for _, event := range events {
+			if event.Contact == "" && event.Reason == "" && event.PII == nil {
+				continue
+			}
+			_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:           aws.String(s.table),
+				Key:                 idKey(event.ID),
+				UpdateExpression:    aws.String("SET Contact = :blank REMOVE Reason, PII"),
+				ConditionExpression: aws.String("attribute_exists(ID)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":blank": &types.AttributeValueMemberS{Value: ""},
+				},
+			})
+			if err != nil {
+				return erased, errors.ErrInternal
+			}
+			erased++
+		}
+		if next == "" {
+			return erased, nil
+		}
+		page.Cursor = next
+	}
+}
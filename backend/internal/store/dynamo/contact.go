@@ -0,0 +1,152 @@
+package dynamo
+
+import (
+	"context"
+	"slices"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Each of a farmer's numbers is claimed by an item in the FarmerContacts table
+// keyed by the number. The claims are written in the same transaction as the
+// farmer, so two farmers can never end up with the same number.
+
+// claimContact writes the claim of farmerID on contact. A farmer may claim its
+// own contact again.
+func (s *FarmerStore) claimContact(contact, farmerID string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String(s.contacts),
+			Item: map[string]types.AttributeValue{
+				"ID":       &types.AttributeValueMemberS{Value: contact},
+				"FarmerID": &types.AttributeValueMemberS{Value: farmerID},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(ID) OR FarmerID = :farmerId"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":farmerId": &types.AttributeValueMemberS{Value: farmerID},
+			},
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		},
+	}
+}
+
+// takeContact moves the claim on contact from the farmer previous to
+// farmerID, as a merge does.
+func (s *FarmerStore) takeContact(contact, farmerID, previous string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String(s.contacts),
+			Item: map[string]types.AttributeValue{
+				"ID":       &types.AttributeValueMemberS{Value: contact},
+				"FarmerID": &types.AttributeValueMemberS{Value: farmerID},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(ID) OR FarmerID IN (:farmerId, :previous)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":farmerId": &types.AttributeValueMemberS{Value: farmerID},
+				":previous": &types.AttributeValueMemberS{Value: previous},
+			},
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		},
+	}
+}
+
+// releaseContact removes the claim of farmerID on contact.
+func (s *FarmerStore) releaseContact(contact, farmerID string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName:           aws.String(s.contacts),
+			Key:                 idKey(contact),
+			ConditionExpression: aws.String("FarmerID = :farmerId"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":farmerId": &types.AttributeValueMemberS{Value: farmerID},
+			},
+		},
+	}
+}
+
+// contactHolder returns the ID of the farmer holding contact, or "" when the
+// contact is free.
+func (s *FarmerStore) contactHolder(ctx context.Context, contact string) (string, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.contacts),
+		Key:            idKey(contact),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", errors.ErrInternal
+	}
+
+	farmerID, _ := result.Item["FarmerID"].(*types.AttributeValueMemberS)
+	if farmerID == nil {
+		return "", nil
+	}
+	return farmerID.Value, nil
+}
+
+// writeWithContacts runs items as one transaction. claims maps the index of
+// each contact claim among items to its number, and versioned lists the
+// indexes of the versioned puts. Any other failed condition, such as that of
+// a farmer created with a taken ID, is returned as errors.ErrConflict.
+func (s *FarmerStore) writeWithContacts(ctx context.Context, items []types.TransactWriteItem, claims map[int]string, versioned ...int) error {
+	_, err := s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+
+	var cancelled *types.TransactionCanceledException
+	if !errors.As(err, &cancelled) {
+		if err != nil {
+			return errors.ErrInternal
+		}
+		return nil
+	}
+
+	for i, reason := range cancelled.CancellationReasons {
+		if aws.ToString(reason.Code) != "ConditionalCheckFailed" {
+			continue
+		}
+		if slices.Contains(versioned, i) {
+			return versionError(reason.Item)
+		}
+		if contact, ok := claims[i]; ok {
+			taken := &store.ContactTakenError{Contact: contact}
+			if farmerID, ok := reason.Item["FarmerID"].(*types.AttributeValueMemberS); ok {
+				taken.FarmerID = farmerID.Value
+			}
+			return taken
+		}
+	}
+	// The ID was taken, or the write raced with another transaction on the
+	// same items.
+	return errors.ErrConflict
+}
+
+// releasePurged removes the claims of a purged farmer on its numbers.
+func (s *FarmerStore) releasePurged(ctx context.Context, item map[string]types.AttributeValue) error {
+	var farmer models.Farmer
+	if err := attributevalue.UnmarshalMap(item, &farmer); err != nil {
+		return err
+	}
+
+	for _, contact := range farmer.ContactNumbers() {
+		_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName:           aws.String(s.contacts),
+			Key:                 idKey(contact),
+			ConditionExpression: aws.String("FarmerID = :farmerId"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":farmerId": item["ID"],
+			},
+		})
+		var conditionErr *types.ConditionalCheckFailedException
+		if err != nil && !errors.As(err, &conditionErr) {
+			return err
+		}
+	}
+	return nil
+}
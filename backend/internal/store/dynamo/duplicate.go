@@ -0,0 +1,121 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type DuplicateStore struct {
+	client  *dynamodb.Client
+	table   string
+	cursors *store.Cursors
+}
+
+func NewDuplicateStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *DuplicateStore {
+	return &DuplicateStore{
+		client:  client,
+		table:   tables.Name(db.DuplicateCandidatesTable),
+		cursors: cursors,
+	}
+}
+
+func (s *DuplicateStore) Put(ctx context.Context, candidate *models.DuplicateCandidate) error {
+	item, err := attributevalue.MarshalMap(candidate)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.ErrConflict
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *DuplicateStore) Get(ctx context.Context, id string) (*models.DuplicateCandidate, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       idKey(id),
+	})
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+	if result.Item == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	var candidate models.DuplicateCandidate
+	if err := attributevalue.UnmarshalMap(result.Item, &candidate); err != nil {
+		return nil, errors.ErrInternal
+	}
+	return &candidate, nil
+}
+
+func (s *DuplicateStore) ListByStatus(ctx context.Context, status string, page store.Page) ([]models.DuplicateCandidate, string, error) {
+	return queryPage[models.DuplicateCandidate](ctx, s.client, s.cursors, "duplicates/"+status, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("StatusCreatedAtIndex"),
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+		},
+	}, page)
+}
+
+func (s *DuplicateStore) Review(ctx context.Context, id, status, reviewedBy string, at time.Time) error {
+	reviewedAt, err := attributevalue.Marshal(at.UTC())
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.table),
+		Key:                 idKey(id),
+		UpdateExpression:    aws.String("SET #status = :status, ReviewedBy = :reviewedBy, ReviewedAt = :reviewedAt"),
+		ConditionExpression: aws.String("#status = :pending"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":     &types.AttributeValueMemberS{Value: status},
+			":pending":    &types.AttributeValueMemberS{Value: models.DuplicateStatusPending},
+			":reviewedBy": &types.AttributeValueMemberS{Value: reviewedBy},
+			":reviewedAt": reviewedAt,
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		if conditionErr.Item == nil {
+			return errors.ErrNotFound
+		}
+		return errors.ErrConflict
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
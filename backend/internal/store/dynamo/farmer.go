@@ -0,0 +1,323 @@
+package dynamo
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// notMerged is the filter that hides the aliases merged farmers leave behind.
+const notMerged = "attribute_not_exists(MergedInto)"
+
+type FarmerStore struct {
+	client   *dynamodb.Client
+	table    string
+	contacts string
+	cursors  *store.Cursors
+}
+
+func NewFarmerStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *FarmerStore {
+	return &FarmerStore{
+		client:   client,
+		table:    tables.Name(db.FarmersTable),
+		contacts: tables.Name(db.FarmerContactsTable),
+		cursors:  cursors,
+	}
+}
+
+func (s *FarmerStore) Put(ctx context.Context, farmer *models.Farmer) error {
+	item, err := attributevalue.MarshalMap(farmer)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	if numbers := farmer.ContactNumbers(); len(numbers) > 0 {
+		items := []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:           aws.String(s.table),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(ID)"),
+			}},
+		}
+		claims := make(map[int]string, len(numbers))
+		for _, number := range numbers {
+			claims[len(items)] = number
+			items = append(items, s.claimContact(number, farmer.ID))
+		}
+		return s.writeWithContacts(ctx, items, claims)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.ErrConflict
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *FarmerStore) Update(ctx context.Context, farmer *models.Farmer) error {
+	current, err := s.Get(ctx, farmer.ID)
+	if err != nil {
+		return err
+	}
+
+	expected := farmer.Version
+	farmer.Version++
+
+	item, err := attributevalue.MarshalMap(farmer)
+	if err != nil {
+		farmer.Version = expected
+		return errors.ErrInternal
+	}
+
+	if slices.Equal(current.ContactNumbers(), farmer.ContactNumbers()) {
+		err = putVersioned(ctx, s.client, s.table, item, expected)
+	} else {
+		err = s.moveContacts(ctx, item, expected, farmer, current)
+	}
+	if err != nil {
+		farmer.Version = expected
+		return err
+	}
+
+	return nil
+}
+
+// moveContacts saves a farmer whose numbers changed from those of current,
+// claiming the new numbers and releasing the dropped ones in the same
+// transaction.
+func (s *FarmerStore) moveContacts(ctx context.Context, item map[string]types.AttributeValue, expected int64, farmer, current *models.Farmer) error {
+	items := []types.TransactWriteItem{
+		{Put: versionedPut(s.table, item, expected)},
+	}
+
+	numbers, old := farmer.ContactNumbers(), current.ContactNumbers()
+	claims := make(map[int]string)
+	for _, number := range numbers {
+		if !slices.Contains(old, number) {
+			claims[len(items)] = number
+			items = append(items, s.claimContact(number, farmer.ID))
+		}
+	}
+	for _, number := range old {
+		if slices.Contains(numbers, number) {
+			continue
+		}
+		// Farmers that shared a contact before claims existed do not hold it.
+		holder, err := s.contactHolder(ctx, number)
+		if err != nil {
+			return err
+		}
+		if holder == farmer.ID {
+			items = append(items, s.releaseContact(number, farmer.ID))
+		}
+	}
+
+	return s.writeWithContacts(ctx, items, claims, 0)
+}
+
+func (s *FarmerStore) Merge(ctx context.Context, survivor, loser *models.Farmer) error {
+	// holders maps each number the pair holds now to which of the two holds
+	// it.
+	holders := make(map[string]string)
+	for _, farmer := range []*models.Farmer{loser, survivor} {
+		current, err := s.Get(ctx, farmer.ID)
+		if err != nil {
+			return err
+		}
+		for _, number := range current.ContactNumbers() {
+			holders[number] = farmer.ID
+		}
+	}
+
+	expected := []int64{survivor.Version, loser.Version}
+	var items []types.TransactWriteItem
+	for i, farmer := range []*models.Farmer{survivor, loser} {
+		stored := *farmer
+		stored.Version++
+		item, err := attributevalue.MarshalMap(&stored)
+		if err != nil {
+			return errors.ErrInternal
+		}
+		items = append(items, types.TransactWriteItem{Put: versionedPut(s.table, item, expected[i])})
+	}
+
+	kept := append(survivor.ContactNumbers(), loser.ContactNumbers()...)
+	claims := make(map[int]string)
+	for _, farmer := range []*models.Farmer{survivor, loser} {
+		for _, number := range farmer.ContactNumbers() {
+			if holders[number] == farmer.ID {
+				continue
+			}
+			claims[len(items)] = number
+			if holders[number] == "" {
+				items = append(items, s.claimContact(number, farmer.ID))
+			} else {
+				items = append(items, s.takeContact(number, farmer.ID, holders[number]))
+			}
+		}
+	}
+	for _, number := range slices.Sorted(maps.Keys(holders)) {
+		if slices.Contains(kept, number) {
+			continue
+		}
+		holder, err := s.contactHolder(ctx, number)
+		if err != nil {
+			return err
+		}
+		if holder == holders[number] {
+			items = append(items, s.releaseContact(number, holder))
+		}
+	}
+
+	if err := s.writeWithContacts(ctx, items, claims, 0, 1); err != nil {
+		return err
+	}
+	survivor.Version++
+	loser.Version++
+	return nil
+}
+
+func (s *FarmerStore) Get(ctx context.Context, id string) (*models.Farmer, error) {
+	farmer, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if farmer.DeletedAt != nil {
+		return nil, errors.ErrNotFound
+	}
+
+	return farmer, nil
+}
+
+func (s *FarmerStore) GetDeleted(ctx context.Context, id string) (*models.Farmer, error) {
+	farmer, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if farmer.DeletedAt == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	return farmer, nil
+}
+
+// get reads the farmer with the ID whether it is deleted or not.
+func (s *FarmerStore) get(ctx context.Context, id string) (*models.Farmer, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+	if result.Item == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	var farmer models.Farmer
+	err = attributevalue.UnmarshalMap(result.Item, &farmer)
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+
+	return &farmer, nil
+}
+
+func (s *FarmerStore) GetByContact(ctx context.Context, contact string) (*models.Farmer, error) {
+	farmerID, err := s.contactHolder(ctx, contact)
+	if err != nil {
+		return nil, err
+	}
+	if farmerID == "" {
+		return nil, errors.ErrNotFound
+	}
+
+	return s.Get(ctx, farmerID)
+}
+
+// UsesCrop scans the table, Crop being a set no index can hold, and stops at
+// the first farmer growing the crop.
+func (s *FarmerStore) UsesCrop(ctx context.Context, cropID string) (bool, error) {
+	paginator := dynamodb.NewScanPaginator(s.client, &dynamodb.ScanInput{
+		TableName:            aws.String(s.table),
+		ProjectionExpression: aws.String("ID"),
+		FilterExpression:     aws.String("contains(Crop, :crop) AND " + notMerged),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":crop": &types.AttributeValueMemberS{Value: cropID},
+		},
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return false, errors.ErrInternal
+		}
+		if len(result.Items) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *FarmerStore) List(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	return scanPage[models.Farmer](ctx, s.client, s.cursors, "farmers", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: withoutDeleted(notMerged),
+	}, page)
+}
+
+func (s *FarmerStore) Delete(ctx context.Context, id, deletedBy string) error {
+	return softDelete(ctx, s.client, s.table, id, deletedBy)
+}
+
+func (s *FarmerStore) ListDeleted(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	return scanPage[models.Farmer](ctx, s.client, s.cursors, "farmers/trash", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("attribute_exists(DeletedAt) AND " + notMerged),
+	}, page)
+}
+
+func (s *FarmerStore) Restore(ctx context.Context, id string) error {
+	return restore(ctx, s.client, s.table, id)
+}
+
+func (s *FarmerStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purgeWith(ctx, s.client, s.table, before, "ID, Contact, Contacts", s.releasePurged)
+}
+
+func (s *FarmerStore) ListByGeohash(ctx context.Context, prefix string, page store.Page) ([]models.Farmer, string, error) {
+	if len(prefix) < models.GeoCellLength {
+		return nil, "", errors.ErrInvalidInput
+	}
+	return queryPage[models.Farmer](ctx, s.client, s.cursors, "farmers/geo/"+prefix, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("GeoCellIndex"),
+		KeyConditionExpression: aws.String("GeoCell = :cell AND begins_with(GeoHash, :prefix)"),
+		FilterExpression:       withoutDeleted(notMerged),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cell":   &types.AttributeValueMemberS{Value: prefix[:models.GeoCellLength]},
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+		},
+	}, page)
+}
@@ -0,0 +1,86 @@
+package dynamo
+
+import (
+	"context"
+
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// fetchFunc reads one batch starting after startKey, evaluating at most limit
+// items when limit is set, and returns the batch with its LastEvaluatedKey.
+type fetchFunc func(ctx context.Context, startKey map[string]types.AttributeValue, limit *int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error)
+
+// readPage keeps fetching until page.Limit items are collected or the table
+// is exhausted, so neither the 1 MB response cap nor a filter expression cuts
+// a page short. The limit of each call is the number of items still missing,
+// which keeps the LastEvaluatedKey on the last returned item.
+func readPage[T any](ctx context.Context, cursors *store.Cursors, scope string, page store.Page, fetch fetchFunc) ([]T, string, error) {
+	startKey, err := cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var items []map[string]types.AttributeValue
+	for {
+		var limit *int32
+		if page.Limit > 0 {
+			limit = aws.Int32(page.Limit - int32(len(items)))
+		}
+
+		batch, lastKey, err := fetch(ctx, startKey, limit)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, batch...)
+		startKey = lastKey
+
+		if len(startKey) == 0 || (page.Limit > 0 && int32(len(items)) >= page.Limit) {
+			break
+		}
+	}
+
+	var out []T
+	err = attributevalue.UnmarshalListOfMaps(items, &out)
+	if err != nil {
+		return nil, "", errors.ErrInternal
+	}
+
+	nextCursor, err := cursors.Encode(scope, startKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return out, nextCursor, nil
+}
+
+func scanPage[T any](ctx context.Context, client *dynamodb.Client, cursors *store.Cursors, scope string, input *dynamodb.ScanInput, page store.Page) ([]T, string, error) {
+	return readPage[T](ctx, cursors, scope, page, func(ctx context.Context, startKey map[string]types.AttributeValue, limit *int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		input.ExclusiveStartKey = startKey
+		input.Limit = limit
+
+		result, err := client.Scan(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	})
+}
+
+func queryPage[T any](ctx context.Context, client *dynamodb.Client, cursors *store.Cursors, scope string, input *dynamodb.QueryInput, page store.Page) ([]T, string, error) {
+	return readPage[T](ctx, cursors, scope, page, func(ctx context.Context, startKey map[string]types.AttributeValue, limit *int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		input.ExclusiveStartKey = startKey
+		input.Limit = limit
+
+		result, err := client.Query(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.Items, result.LastEvaluatedKey, nil
+	})
+}
@@ -0,0 +1,99 @@
+package dynamo
+
+import (
+	"context"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type PartnerStore struct {
+	client  *dynamodb.Client
+	table   string
+	cursors *store.Cursors
+}
+
+func NewPartnerStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *PartnerStore {
+	return &PartnerStore{
+		client:  client,
+		table:   tables.Name(db.PartnersTable),
+		cursors: cursors,
+	}
+}
+
+func (s *PartnerStore) Put(ctx context.Context, partner *models.Partner) error {
+	item, err := attributevalue.MarshalMap(partner)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *PartnerStore) Update(ctx context.Context, partner *models.Partner) error {
+	expected := partner.Version
+	partner.Version++
+
+	item, err := attributevalue.MarshalMap(partner)
+	if err != nil {
+		partner.Version = expected
+		return errors.ErrInternal
+	}
+
+	if err := putVersioned(ctx, s.client, s.table, item, expected); err != nil {
+		partner.Version = expected
+		return err
+	}
+
+	return nil
+}
+
+func (s *PartnerStore) Get(ctx context.Context, id string) (*models.Partner, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key:       idKey(id),
+	})
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+	if result.Item == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	var partner models.Partner
+	if err := attributevalue.UnmarshalMap(result.Item, &partner); err != nil {
+		return nil, errors.ErrInternal
+	}
+	return &partner, nil
+}
+
+func (s *PartnerStore) List(ctx context.Context, kind string, page store.Page) ([]models.Partner, string, error) {
+	if kind == "" {
+		return scanPage[models.Partner](ctx, s.client, s.cursors, "partners", &dynamodb.ScanInput{
+			TableName: aws.String(s.table),
+		}, page)
+	}
+	return queryPage[models.Partner](ctx, s.client, s.cursors, "partners/"+kind, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("KindNameIndex"),
+		KeyConditionExpression: aws.String("Kind = :kind"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":kind": &types.AttributeValueMemberS{Value: kind},
+		},
+	}, page)
+}
@@ -0,0 +1,42 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+
+	"backend/internal/db"
+	"backend/internal/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+type ReportStore struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func NewReportStore(client *dynamodb.Client, tables db.Tables) *ReportStore {
+	return &ReportStore{
+		client: client,
+		table:  tables.Name(db.ReportsTable),
+	}
+}
+
+func (s *ReportStore) Put(ctx context.Context, report *models.Report) error {
+	item, err := attributevalue.MarshalMap(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+
+	return nil
+}
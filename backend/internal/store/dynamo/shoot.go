@@ -0,0 +1,204 @@
+package dynamo
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type ShootStore struct {
+	client  *dynamodb.Client
+	table   string
+	marks   string
+	cursors *store.Cursors
+}
+
+func NewShootStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *ShootStore {
+	return &ShootStore{
+		client:  client,
+		table:   tables.Name(db.ShootsTable),
+		marks:   tables.Name(db.OutreachMarksTable),
+		cursors: cursors,
+	}
+}
+
+// outreachMark is the item of a farmer in the OutreachMarks table.
+type outreachMark struct {
+	ID          string    `dynamodbav:"ID"`
+	Seq         int64     `dynamodbav:"Seq"`
+	LastShootID string    `dynamodbav:"LastShootID"`
+	LastAt      time.Time `dynamodbav:"LastAt"`
+}
+
+func (s *ShootStore) Put(ctx context.Context, shoot *models.Shoot) error {
+	item, err := attributevalue.MarshalMap(shoot)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	return err
+}
+
+func (s *ShootStore) List(ctx context.Context, shootType string, page store.Page) ([]models.Shoot, string, error) {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(s.table),
+	}
+
+	if shootType != "" {
+		input.FilterExpression = aws.String("#type = :type")
+		input.ExpressionAttributeNames = map[string]string{
+			"#type": "Type",
+		}
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":type": &types.AttributeValueMemberS{Value: shootType},
+		}
+	}
+
+	return scanPage[models.Shoot](ctx, s.client, s.cursors, "shoots/type/"+shootType, input, page)
+}
+
+func (s *ShootStore) ListByTimestamp(ctx context.Context, startDate, endDate time.Time, page store.Page) ([]models.Shoot, string, error) {
+	start := startDate.Format(time.RFC3339)
+	end := endDate.Format(time.RFC3339)
+	return scanPage[models.Shoot](ctx, s.client, s.cursors, "shoots/timestamp/"+start+"/"+end, &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("#timestamp BETWEEN :startDate AND :endDate"),
+		ExpressionAttributeNames: map[string]string{
+			"#timestamp": "Timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":startDate": &types.AttributeValueMemberS{Value: start},
+			":endDate":   &types.AttributeValueMemberS{Value: end},
+		},
+	}, page)
+}
+
+func (s *ShootStore) ListByStatusAndType(ctx context.Context, status, shootType string, page store.Page) ([]models.Shoot, string, error) {
+	return scanPage[models.Shoot](ctx, s.client, s.cursors, "shoots/status/"+status+"/type/"+shootType, &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("#status = :status AND #type = :type"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+			"#type":   "Type",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+			":type":   &types.AttributeValueMemberS{Value: shootType},
+		},
+	}, page)
+}
+
+func (s *ShootStore) ListByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.Shoot, string, error) {
+	return queryPage[models.Shoot](ctx, s.client, s.cursors, "shoots/farmer/"+farmerID, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("FarmerIDTimestampIndex"),
+		KeyConditionExpression: aws.String("FarmerID = :farmerID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":farmerID": &types.AttributeValueMemberS{Value: farmerID},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}, page)
+}
+
+func (s *ShootStore) OutreachMark(ctx context.Context, farmerID string) (store.OutreachMark, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.marks),
+		Key:            idKey(farmerID),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return store.OutreachMark{}, errors.ErrInternal
+	}
+	if result.Item == nil {
+		return store.OutreachMark{}, nil
+	}
+
+	var mark outreachMark
+	if err := attributevalue.UnmarshalMap(result.Item, &mark); err != nil {
+		return store.OutreachMark{}, errors.ErrInternal
+	}
+	return store.OutreachMark{Seq: mark.Seq, LastShootID: mark.LastShootID, LastAt: mark.LastAt}, nil
+}
+
+// PutInSequence writes the shoot and the farmer's next mark in one
+// transaction, the mark on condition that it is still at mark.Seq.
+func (s *ShootStore) PutInSequence(ctx context.Context, shoot *models.Shoot, mark store.OutreachMark) error {
+	err := s.sequence(ctx, shoot, mark, "attribute_not_exists(ID)")
+	if err == errors.ErrNotFound {
+		// The shoot ID is taken.
+		return errors.ErrConflict
+	}
+	return err
+}
+
+// MoveInSequence is PutInSequence for a shoot that exists already.
+func (s *ShootStore) MoveInSequence(ctx context.Context, shoot *models.Shoot, mark store.OutreachMark) error {
+	return s.sequence(ctx, shoot, mark, "attribute_exists(ID)")
+}
+
+// sequence writes the shoot on condition and the farmer's next mark in one
+// transaction. It fails with errors.ErrNotFound when the condition on the
+// shoot fails and with errors.ErrConflict when the mark moved on.
+func (s *ShootStore) sequence(ctx context.Context, shoot *models.Shoot, mark store.OutreachMark, condition string) error {
+	item, err := attributevalue.MarshalMap(shoot)
+	if err != nil {
+		return errors.ErrInternal
+	}
+	next, err := attributevalue.MarshalMap(&outreachMark{
+		ID:          shoot.FarmerID,
+		Seq:         mark.Seq + 1,
+		LastShootID: shoot.ID,
+		LastAt:      shoot.Timestamp,
+	})
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	markPut := &types.Put{
+		TableName:           aws.String(s.marks),
+		Item:                next,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	}
+	if mark.Seq > 0 {
+		markPut.ConditionExpression = aws.String("Seq = :seq")
+		markPut.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":seq": &types.AttributeValueMemberN{Value: strconv.FormatInt(mark.Seq, 10)},
+		}
+	}
+
+	_, err = s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:           aws.String(s.table),
+				Item:                item,
+				ConditionExpression: aws.String(condition),
+			}},
+			{Put: markPut},
+		},
+	})
+	var cancelled *types.TransactionCanceledException
+	if errors.As(err, &cancelled) {
+		if len(cancelled.CancellationReasons) > 0 && aws.ToString(cancelled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+			return errors.ErrNotFound
+		}
+		return errors.ErrConflict
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+	return nil
+}
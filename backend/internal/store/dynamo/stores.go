@@ -0,0 +1,29 @@
+// Package dynamo implements the store interfaces on top of DynamoDB.
+package dynamo
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// NewStores returns DynamoDB stores that use the tables of one environment and
+// sign their list cursors with cursors.
+func NewStores(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *store.Stores {
+	return &store.Stores{
+		Farmer:    NewFarmerStore(client, tables, cursors),
+		CCE:       NewCCEStore(client, tables, cursors),
+		Ticket:    NewTicketStore(client, tables, cursors),
+		Shoot:     NewShootStore(client, tables, cursors),
+		Report:    NewReportStore(client, tables),
+		Audit:     NewAuditStore(client, tables, cursors),
+		Duplicate: NewDuplicateStore(client, tables, cursors),
+		Consent:   NewConsentStore(client, tables, cursors),
+		Partner:   NewPartnerStore(client, tables, cursors),
+		Catalog:   NewCatalogStore(client, tables, cursors),
+
+		Transactions: NewTransactions(client, tables),
+		Cursors:      cursors,
+	}
+}
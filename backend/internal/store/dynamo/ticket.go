@@ -0,0 +1,225 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type TicketStore struct {
+	client  *dynamodb.Client
+	table   string
+	cursors *store.Cursors
+}
+
+func NewTicketStore(client *dynamodb.Client, tables db.Tables, cursors *store.Cursors) *TicketStore {
+	return &TicketStore{
+		client:  client,
+		table:   tables.Name(db.TicketsTable),
+		cursors: cursors,
+	}
+}
+
+func (s *TicketStore) Put(ctx context.Context, ticket *models.Ticket) error {
+	item, err := attributevalue.MarshalMap(ticket)
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+func (s *TicketStore) Update(ctx context.Context, ticket *models.Ticket) error {
+	expected := ticket.Version
+	ticket.Version++
+
+	item, err := attributevalue.MarshalMap(ticket)
+	if err != nil {
+		ticket.Version = expected
+		return errors.ErrInternal
+	}
+
+	err = putVersioned(ctx, s.client, s.table, item, expected)
+	if err != nil {
+		ticket.Version = expected
+		return err
+	}
+
+	return nil
+}
+
+func (s *TicketStore) Get(ctx context.Context, id string) (*models.Ticket, error) {
+	ticket, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.DeletedAt != nil {
+		return nil, errors.ErrNotFound
+	}
+
+	return ticket, nil
+}
+
+func (s *TicketStore) GetDeleted(ctx context.Context, id string) (*models.Ticket, error) {
+	ticket, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.DeletedAt == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	return ticket, nil
+}
+
+// get reads the ticket with the ID whether it is deleted or not.
+func (s *TicketStore) get(ctx context.Context, id string) (*models.Ticket, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"ID": &types.AttributeValueMemberS{Value: id},
+		},
+	})
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+	if result.Item == nil {
+		return nil, errors.ErrNotFound
+	}
+
+	var ticket models.Ticket
+	err = attributevalue.UnmarshalMap(result.Item, &ticket)
+	if err != nil {
+		return nil, errors.ErrInternal
+	}
+
+	return &ticket, nil
+}
+
+func (s *TicketStore) ListByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.Ticket, string, error) {
+	return queryPage[models.Ticket](ctx, s.client, s.cursors, "tickets/farmer/"+farmerID, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("FarmerIDIndex"),
+		KeyConditionExpression: aws.String("FarmerID = :farmerID"),
+		FilterExpression:       withoutDeleted(""),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":farmerID": &types.AttributeValueMemberS{Value: farmerID},
+		},
+	}, page)
+}
+
+func (s *TicketStore) ListByCCE(ctx context.Context, cceID string, page store.Page) ([]models.Ticket, string, error) {
+	return queryPage[models.Ticket](ctx, s.client, s.cursors, "tickets/cce/"+cceID, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("CCEIDIndex"),
+		KeyConditionExpression: aws.String("CCEID = :cceID"),
+		FilterExpression:       withoutDeleted(""),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cceID": &types.AttributeValueMemberS{Value: cceID},
+		},
+	}, page)
+}
+
+func (s *TicketStore) ListByCCEAndCreatedAt(ctx context.Context, cceID string, startDate, endDate time.Time, page store.Page) ([]models.Ticket, string, error) {
+	start := startDate.Format(time.RFC3339)
+	end := endDate.Format(time.RFC3339)
+	return queryPage[models.Ticket](ctx, s.client, s.cursors, "tickets/cce/"+cceID+"/created/"+start+"/"+end, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("CCEIDCreatedAtIndex"),
+		KeyConditionExpression: aws.String("CCEID = :cceID AND CreatedAt BETWEEN :startDate AND :endDate"),
+		FilterExpression:       withoutDeleted(""),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cceID":     &types.AttributeValueMemberS{Value: cceID},
+			":startDate": &types.AttributeValueMemberS{Value: start},
+			":endDate":   &types.AttributeValueMemberS{Value: end},
+		},
+	}, page)
+}
+
+func (s *TicketStore) ListByCCEAndStatus(ctx context.Context, cceID, status string, page store.Page) ([]models.Ticket, string, error) {
+	return queryPage[models.Ticket](ctx, s.client, s.cursors, "tickets/cce/"+cceID+"/status/"+status, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("CCEIDStatusIndex"),
+		KeyConditionExpression: aws.String("CCEID = :cceID AND #status = :status"),
+		FilterExpression:       withoutDeleted(""),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cceID":  &types.AttributeValueMemberS{Value: cceID},
+			":status": &types.AttributeValueMemberS{Value: status},
+		},
+	}, page)
+}
+
+func (s *TicketStore) ListByStatus(ctx context.Context, status string, page store.Page) ([]models.Ticket, string, error) {
+	return scanPage[models.Ticket](ctx, s.client, s.cursors, "tickets", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: withoutDeleted("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+		},
+	}, page)
+}
+
+func (s *TicketStore) UsesCrop(ctx context.Context, cropID string) (bool, error) {
+	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		IndexName:              aws.String("CropIDIndex"),
+		KeyConditionExpression: aws.String("CropID = :cropID"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cropID": &types.AttributeValueMemberS{Value: cropID},
+		},
+		Limit: aws.Int32(1),
+	})
+	if err != nil {
+		return false, errors.ErrInternal
+	}
+	return len(result.Items) > 0, nil
+}
+
+func (s *TicketStore) List(ctx context.Context, page store.Page) ([]models.Ticket, string, error) {
+	return scanPage[models.Ticket](ctx, s.client, s.cursors, "tickets", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: withoutDeleted(""),
+	}, page)
+}
+
+func (s *TicketStore) Delete(ctx context.Context, id, deletedBy string) error {
+	return softDelete(ctx, s.client, s.table, id, deletedBy)
+}
+
+func (s *TicketStore) ListDeleted(ctx context.Context, page store.Page) ([]models.Ticket, string, error) {
+	return scanPage[models.Ticket](ctx, s.client, s.cursors, "tickets/trash", &dynamodb.ScanInput{
+		TableName:        aws.String(s.table),
+		FilterExpression: aws.String("attribute_exists(DeletedAt)"),
+	}, page)
+}
+
+func (s *TicketStore) Restore(ctx context.Context, id string) error {
+	return restore(ctx, s.client, s.table, id)
+}
+
+func (s *TicketStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	return purge(ctx, s.client, s.table, before)
+}
@@ -0,0 +1,142 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// notDeleted is the filter that hides soft-deleted items from reads.
+const notDeleted = "attribute_not_exists(DeletedAt)"
+
+// withoutDeleted combines filter with notDeleted.
+func withoutDeleted(filter string) *string {
+	if filter == "" {
+		return aws.String(notDeleted)
+	}
+	return aws.String("(" + filter + ") AND " + notDeleted)
+}
+
+func idKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"ID": &types.AttributeValueMemberS{Value: id},
+	}
+}
+
+// softDelete marks an item deleted. The version is bumped so that updates
+// based on an earlier read fail.
+func softDelete(ctx context.Context, client *dynamodb.Client, table, id, deletedBy string) error {
+	deletedAt, err := attributevalue.Marshal(time.Now().UTC())
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	_, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(table),
+		Key:                 idKey(id),
+		UpdateExpression:    aws.String("SET DeletedAt = :deletedAt, DeletedBy = :deletedBy ADD #version :one"),
+		ConditionExpression: aws.String("attribute_exists(ID) AND " + notDeleted),
+		ExpressionAttributeNames: map[string]string{
+			"#version": "Version",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deletedAt": deletedAt,
+			":deletedBy": &types.AttributeValueMemberS{Value: deletedBy},
+			":one":       &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	return trashError(err)
+}
+
+// restore takes an item out of the trash.
+func restore(ctx context.Context, client *dynamodb.Client, table, id string) error {
+	_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(table),
+		Key:                 idKey(id),
+		UpdateExpression:    aws.String("REMOVE DeletedAt, DeletedBy ADD #version :one"),
+		ConditionExpression: aws.String("attribute_exists(DeletedAt)"),
+		ExpressionAttributeNames: map[string]string{
+			"#version": "Version",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	return trashError(err)
+}
+
+// purge removes the items deleted before cutoff for good. Each delete checks
+// the cutoff again, so an item restored in the meantime is left alone.
+func purge(ctx context.Context, client *dynamodb.Client, table string, before time.Time) (int, error) {
+	return purgeWith(ctx, client, table, before, "ID", nil)
+}
+
+// purgeWith is purge for items that leave something behind elsewhere. The
+// attributes in projection of every removed item are passed to purged.
+func purgeWith(ctx context.Context, client *dynamodb.Client, table string, before time.Time, projection string, purged func(context.Context, map[string]types.AttributeValue) error) (int, error) {
+	cutoff, err := attributevalue.Marshal(before.UTC())
+	if err != nil {
+		return 0, errors.ErrInternal
+	}
+	values := map[string]types.AttributeValue{
+		":before": cutoff,
+	}
+
+	count := 0
+	paginator := dynamodb.NewScanPaginator(client, &dynamodb.ScanInput{
+		TableName:                 aws.String(table),
+		ProjectionExpression:      aws.String(projection),
+		FilterExpression:          aws.String("DeletedAt < :before"),
+		ExpressionAttributeValues: values,
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return count, err
+		}
+
+		for _, item := range result.Items {
+			_, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName:                 aws.String(table),
+				Key:                       map[string]types.AttributeValue{"ID": item["ID"]},
+				ConditionExpression:       aws.String("DeletedAt < :before"),
+				ExpressionAttributeValues: values,
+			})
+			var conditionErr *types.ConditionalCheckFailedException
+			if errors.As(err, &conditionErr) {
+				continue
+			}
+			if err != nil {
+				return count, err
+			}
+			count++
+
+			if purged != nil {
+				if err := purged(ctx, item); err != nil {
+					return count, err
+				}
+			}
+		}
+	}
+
+	return count, nil
+}
+
+// trashError maps a failed trash condition to ErrNotFound: the item is missing
+// or already in the state the caller asked for.
+func trashError(err error) error {
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return errors.ErrNotFound
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend/internal/db"
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxTransactItems is the most writes DynamoDB accepts in one transaction.
+const maxTransactItems = 100
+
+type Transactions struct {
+	client *dynamodb.Client
+	tables db.Tables
+}
+
+func NewTransactions(client *dynamodb.Client, tables db.Tables) *Transactions {
+	return &Transactions{
+		client: client,
+		tables: tables,
+	}
+}
+
+func (t *Transactions) Begin() store.UnitOfWork {
+	return &unitOfWork{
+		client: t.client,
+		tables: t.tables,
+	}
+}
+
+// unitOfWork builds one TransactWriteItems call. writes[i] describes items[i]
+// so a cancellation reason can be traced back to the write it belongs to.
+type unitOfWork struct {
+	client    *dynamodb.Client
+	tables    db.Tables
+	items     []types.TransactWriteItem
+	writes    []store.ConditionFailure
+	committed []func()
+	err       error
+}
+
+func (u *unitOfWork) add(item types.TransactWriteItem, write store.ConditionFailure) {
+	u.items = append(u.items, item)
+	u.writes = append(u.writes, write)
+}
+
+func (u *unitOfWork) CreateTicket(ticket *models.Ticket) {
+	item, err := attributevalue.MarshalMap(ticket)
+	if err != nil {
+		u.err = err
+		return
+	}
+
+	u.add(types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(u.tables.Name(db.TicketsTable)),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(ID)"),
+		},
+	}, store.ConditionFailure{
+		Entity:    "ticket",
+		ID:        ticket.ID,
+		Condition: "ticket ID is not taken",
+		Err:       errors.ErrConflict,
+	})
+}
+
+func (u *unitOfWork) UpdateTicket(ticket *models.Ticket) {
+	expected := ticket.Version
+	stored := *ticket
+	stored.Version++
+	item, err := attributevalue.MarshalMap(&stored)
+	if err != nil {
+		u.err = err
+		return
+	}
+
+	u.add(types.TransactWriteItem{
+		Put: versionedPut(u.tables.Name(db.TicketsTable), item, expected),
+	}, store.ConditionFailure{
+		Entity:    "ticket",
+		ID:        ticket.ID,
+		Condition: fmt.Sprintf("ticket is at version %d", expected),
+		Err:       errors.ErrVersionConflict,
+	})
+	u.committed = append(u.committed, func() {
+		ticket.Version = stored.Version
+	})
+}
+
+func (u *unitOfWork) DeleteTicket(ticket *models.Ticket, deletedBy string) {
+	deletedAt, err := attributevalue.Marshal(time.Now().UTC())
+	if err != nil {
+		u.err = err
+		return
+	}
+
+	expected := ticket.Version
+	condition := "attribute_exists(ID) AND " + notDeleted + " AND #version = :expected"
+	if expected == 0 {
+		condition = "attribute_exists(ID) AND " + notDeleted + " AND (attribute_not_exists(#version) OR #version = :expected)"
+	}
+	u.add(types.TransactWriteItem{
+		Update: &types.Update{
+			TableName:           aws.String(u.tables.Name(db.TicketsTable)),
+			Key:                 idKey(ticket.ID),
+			UpdateExpression:    aws.String("SET DeletedAt = :deletedAt, DeletedBy = :deletedBy ADD #version :one"),
+			ConditionExpression: aws.String(condition),
+			ExpressionAttributeNames: map[string]string{
+				"#version": "Version",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":deletedAt": deletedAt,
+				":deletedBy": &types.AttributeValueMemberS{Value: deletedBy},
+				":expected":  &types.AttributeValueMemberN{Value: strconv.FormatInt(expected, 10)},
+				":one":       &types.AttributeValueMemberN{Value: "1"},
+			},
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		},
+	}, store.ConditionFailure{
+		Entity:    "ticket",
+		ID:        ticket.ID,
+		Condition: fmt.Sprintf("ticket is at version %d", expected),
+		Err:       errors.ErrVersionConflict,
+	})
+	u.committed = append(u.committed, func() {
+		ticket.Version = expected + 1
+	})
+}
+
+func (u *unitOfWork) RestoreTicket(ticket *models.Ticket) {
+	expected := ticket.Version
+	condition := "attribute_exists(DeletedAt) AND #version = :expected"
+	if expected == 0 {
+		condition = "attribute_exists(DeletedAt) AND (attribute_not_exists(#version) OR #version = :expected)"
+	}
+	u.add(types.TransactWriteItem{
+		Update: &types.Update{
+			TableName:           aws.String(u.tables.Name(db.TicketsTable)),
+			Key:                 idKey(ticket.ID),
+			UpdateExpression:    aws.String("REMOVE DeletedAt, DeletedBy ADD #version :one"),
+			ConditionExpression: aws.String(condition),
+			ExpressionAttributeNames: map[string]string{
+				"#version": "Version",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":expected": &types.AttributeValueMemberN{Value: strconv.FormatInt(expected, 10)},
+				":one":      &types.AttributeValueMemberN{Value: "1"},
+			},
+		},
+	}, store.ConditionFailure{
+		Entity:    "ticket",
+		ID:        ticket.ID,
+		Condition: fmt.Sprintf("ticket is deleted at version %d", expected),
+		Err:       errors.ErrNotFound,
+	})
+	u.committed = append(u.committed, func() {
+		ticket.Version = expected + 1
+	})
+}
+
+func (u *unitOfWork) RequireFarmer(id string) {
+	u.add(types.TransactWriteItem{
+		ConditionCheck: &types.ConditionCheck{
+			TableName:           aws.String(u.tables.Name(db.FarmersTable)),
+			Key:                 idKey(id),
+			ConditionExpression: aws.String("attribute_exists(ID) AND " + notDeleted + " AND " + notMerged),
+		},
+	}, store.ConditionFailure{
+		Entity:    "farmer",
+		ID:        id,
+		Condition: "farmer exists and is not merged",
+		Err:       errors.ErrNotFound,
+	})
+}
+
+func (u *unitOfWork) UpdateCCE(id string, change store.CCEChange) {
+	expression := "ADD #version :one, OpenTickets :open, ClosedTickets :closed, ResolvedSeconds :resolved"
+	values := map[string]types.AttributeValue{
+		":one":      &types.AttributeValueMemberN{Value: "1"},
+		":open":     &types.AttributeValueMemberN{Value: strconv.FormatInt(change.OpenTickets, 10)},
+		":closed":   &types.AttributeValueMemberN{Value: strconv.FormatInt(change.ClosedTickets, 10)},
+		":resolved": &types.AttributeValueMemberN{Value: strconv.FormatInt(change.ResolvedSeconds, 10)},
+	}
+	if change.AddTicket != "" {
+		expression += ", TicketIDs :addTicket"
+		values[":addTicket"] = &types.AttributeValueMemberSS{Value: []string{change.AddTicket}}
+	}
+	if change.RemoveTicket != "" {
+		expression += " DELETE TicketIDs :removeTicket"
+		values[":removeTicket"] = &types.AttributeValueMemberSS{Value: []string{change.RemoveTicket}}
+	}
+
+	u.add(types.TransactWriteItem{
+		Update: &types.Update{
+			TableName:           aws.String(u.tables.Name(db.CCEsTable)),
+			Key:                 idKey(id),
+			UpdateExpression:    aws.String(expression),
+			ConditionExpression: aws.String("attribute_exists(ID) AND " + notDeleted),
+			ExpressionAttributeNames: map[string]string{
+				"#version": "Version",
+			},
+			ExpressionAttributeValues: values,
+		},
+	}, store.ConditionFailure{
+		Entity:    "cce",
+		ID:        id,
+		Condition: "CCE exists",
+		Err:       errors.ErrNotFound,
+	})
+}
+
+func (u *unitOfWork) Commit(ctx context.Context) error {
+	if u.err != nil {
+		return errors.ErrInternal
+	}
+	if len(u.items) == 0 {
+		return nil
+	}
+	if len(u.items) > maxTransactItems {
+		return fmt.Errorf("unit of work has %d writes, more than the %d a transaction allows", len(u.items), maxTransactItems)
+	}
+
+	_, err := u.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: u.items,
+	})
+
+	var cancelled *types.TransactionCanceledException
+	if errors.As(err, &cancelled) {
+		return u.transactionError(cancelled.CancellationReasons)
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	for _, committed := range u.committed {
+		committed()
+	}
+	return nil
+}
+
+// transactionError picks the writes that caused a cancellation. A reason of
+// None means the write itself was fine and only failed with the others.
+func (u *unitOfWork) transactionError(reasons []types.CancellationReason) error {
+	txErr := &store.TransactionError{}
+	for i, reason := range reasons {
+		code := aws.ToString(reason.Code)
+		if i >= len(u.writes) || code == "" || code == "None" {
+			continue
+		}
+
+		failure := u.writes[i]
+		failure.Reason = code
+		switch {
+		case code != "ConditionalCheckFailed":
+			failure.Err = errors.ErrConflict
+		case failure.Err == errors.ErrVersionConflict:
+			failure.Err = versionError(reason.Item)
+		}
+		txErr.Failures = append(txErr.Failures, failure)
+	}
+
+	if len(txErr.Failures) == 0 {
+		return errors.ErrInternal
+	}
+	return txErr
+}
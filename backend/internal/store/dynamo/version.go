@@ -0,0 +1,68 @@
+package dynamo
+
+import (
+	"context"
+	"strconv"
+
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// putVersioned replaces the item with the same ID only if the stored item is
+// still at version expected and not deleted. Items written before versioning
+// have no Version attribute and count as version 0.
+func putVersioned(ctx context.Context, client *dynamodb.Client, table string, item map[string]types.AttributeValue, expected int64) error {
+	put := versionedPut(table, item, expected)
+	_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           put.TableName,
+		Item:                                put.Item,
+		ConditionExpression:                 put.ConditionExpression,
+		ExpressionAttributeNames:            put.ExpressionAttributeNames,
+		ExpressionAttributeValues:           put.ExpressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return versionError(conditionErr.Item)
+	}
+	if err != nil {
+		return errors.ErrInternal
+	}
+
+	return nil
+}
+
+// versionedPut is the put of putVersioned, for use in a transaction.
+func versionedPut(table string, item map[string]types.AttributeValue, expected int64) *types.Put {
+	condition := notDeleted + " AND #version = :expected"
+	if expected == 0 {
+		condition = "attribute_exists(ID) AND " + notDeleted + " AND (attribute_not_exists(#version) OR #version = :expected)"
+	}
+
+	return &types.Put{
+		TableName:           aws.String(table),
+		Item:                item,
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#version": "Version",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expected": &types.AttributeValueMemberN{Value: strconv.FormatInt(expected, 10)},
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+}
+
+// versionError explains a failed versioned put from the item it found: a
+// missing or deleted item is ErrNotFound, anything else was changed by
+// someone else.
+func versionError(old map[string]types.AttributeValue) error {
+	if _, deleted := old["DeletedAt"]; old == nil || deleted {
+		return errors.ErrNotFound
+	}
+	return errors.ErrVersionConflict
+}
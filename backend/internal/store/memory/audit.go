@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+type AuditStore struct {
+	db *db
+}
+
+func (s *AuditStore) Put(ctx context.Context, record *models.AuditRecord) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := *record
+	stored.Changes = append([]models.FieldChange(nil), record.Changes...)
+	s.db.audit[record.ID] = stored
+	return nil
+}
+
+func (s *AuditStore) ListByEntity(ctx context.Context, entity, id string, page store.Page) ([]models.AuditRecord, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	entityKey := entity + "#" + id
+	var records []models.AuditRecord
+	for _, recordID := range sortedKeys(s.db.audit) {
+		if record := s.db.audit[recordID]; record.EntityKey == entityKey {
+			records = append(records, record)
+		}
+	}
+	return paginate(s.db.cursors, "audit/"+entityKey, records, func(record *models.AuditRecord) string {
+		return record.ID
+	}, page)
+}
+
+func (s *AuditStore) Redact(ctx context.Context, entity, id string, fields []string) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	entityKey := entity + "#" + id
+	redacted := 0
+	for recordID, record := range s.db.audit {
+		if record.EntityKey != entityKey {
+			continue
+		}
+		if changes, ok := store.RedactChanges(record.Changes, fields); ok {
+			record.Changes = changes
+			s.db.audit[recordID] = record
+			redacted++
+		}
+	}
+	return redacted, nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type CatalogStore struct {
+	db *db
+}
+
+func (s *CatalogStore) PutCrop(ctx context.Context, crop *models.Crop) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.crops[crop.ID]; ok {
+		return errors.ErrConflict
+	}
+	s.db.crops[crop.ID] = cloneCrop(*crop)
+	return nil
+}
+
+func (s *CatalogStore) UpdateCrop(ctx context.Context, crop *models.Crop) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.crops[crop.ID]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	if stored.Version != crop.Version {
+		return errors.ErrVersionConflict
+	}
+
+	crop.Version++
+	s.db.crops[crop.ID] = cloneCrop(*crop)
+	return nil
+}
+
+func (s *CatalogStore) GetCrop(ctx context.Context, id string) (*models.Crop, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	crop, ok := s.db.crops[id]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	crop = cloneCrop(crop)
+	return &crop, nil
+}
+
+func (s *CatalogStore) ListCrops(ctx context.Context, page store.Page) ([]models.Crop, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var crops []models.Crop
+	for _, id := range sortedKeys(s.db.crops) {
+		crops = append(crops, cloneCrop(s.db.crops[id]))
+	}
+	return paginate(s.db.cursors, "crops", crops, func(crop *models.Crop) string {
+		return crop.ID
+	}, page)
+}
+
+func (s *CatalogStore) DeleteCrop(ctx context.Context, id string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.crops[id]; !ok {
+		return errors.ErrNotFound
+	}
+	delete(s.db.crops, id)
+	return nil
+}
+
+func (s *CatalogStore) PutVariety(ctx context.Context, variety *models.Variety) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.varieties[variety.ID] = cloneVariety(*variety)
+	return nil
+}
+
+func (s *CatalogStore) UpdateVariety(ctx context.Context, variety *models.Variety) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.varieties[variety.ID]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	if stored.Version != variety.Version {
+		return errors.ErrVersionConflict
+	}
+
+	variety.Version++
+	s.db.varieties[variety.ID] = cloneVariety(*variety)
+	return nil
+}
+
+func (s *CatalogStore) GetVariety(ctx context.Context, id string) (*models.Variety, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	variety, ok := s.db.varieties[id]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	variety = cloneVariety(variety)
+	return &variety, nil
+}
+
+func (s *CatalogStore) ListVarieties(ctx context.Context, cropID string, page store.Page) ([]models.Variety, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var varieties []models.Variety
+	for _, id := range sortedKeys(s.db.varieties) {
+		if variety := s.db.varieties[id]; cropID == "" || variety.CropID == cropID {
+			varieties = append(varieties, cloneVariety(variety))
+		}
+	}
+	position := func(variety *models.Variety) string {
+		return variety.Name + "|" + variety.ID
+	}
+	if cropID == "" {
+		position = func(variety *models.Variety) string {
+			return variety.ID
+		}
+	}
+	sort.Slice(varieties, func(i, j int) bool {
+		return position(&varieties[i]) < position(&varieties[j])
+	})
+	return paginate(s.db.cursors, "varieties/"+cropID, varieties, position, page)
+}
+
+func (s *CatalogStore) DeleteVariety(ctx context.Context, id string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.varieties[id]; !ok {
+		return errors.ErrNotFound
+	}
+	delete(s.db.varieties, id)
+	return nil
+}
+
+func cloneCrop(crop models.Crop) models.Crop {
+	crop.Aliases = cloneStrings(crop.Aliases)
+	return crop
+}
+
+func cloneVariety(variety models.Variety) models.Variety {
+	variety.Seasons = cloneStrings(variety.Seasons)
+	variety.Regions = cloneStrings(variety.Regions)
+	variety.SKUs = slices.Clone(variety.SKUs)
+	return variety
+}
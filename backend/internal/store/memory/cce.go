@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type CCEStore struct {
+	db *db
+}
+
+func (s *CCEStore) Put(ctx context.Context, cce *models.CCE) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.cces[cce.ID]; ok {
+		return errors.ErrConflict
+	}
+	s.db.cces[cce.ID] = cloneCCE(*cce)
+	return nil
+}
+
+func (s *CCEStore) Update(ctx context.Context, cce *models.CCE) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.cces[cce.ID]
+	if !ok || stored.DeletedAt != nil {
+		return errors.ErrNotFound
+	}
+	if stored.Version != cce.Version {
+		return errors.ErrVersionConflict
+	}
+
+	cce.Version++
+	s.db.cces[cce.ID] = cloneCCE(*cce)
+	return nil
+}
+
+func (s *CCEStore) Get(ctx context.Context, id string) (*models.CCE, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	cce, ok := s.db.cces[id]
+	if !ok || cce.DeletedAt != nil {
+		return nil, errors.ErrNotFound
+	}
+	cce = cloneCCE(cce)
+	return &cce, nil
+}
+
+func (s *CCEStore) List(ctx context.Context, page store.Page) ([]models.CCE, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var cces []models.CCE
+	for _, id := range sortedKeys(s.db.cces) {
+		if cce := s.db.cces[id]; cce.DeletedAt == nil {
+			cces = append(cces, cloneCCE(cce))
+		}
+	}
+	return paginate(s.db.cursors, "cces", cces, func(cce *models.CCE) string {
+		return cce.ID
+	}, page)
+}
+
+func (s *CCEStore) Delete(ctx context.Context, id, deletedBy string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	cce, ok := s.db.cces[id]
+	if !ok || cce.DeletedAt != nil {
+		return errors.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	cce.DeletedAt = &now
+	cce.DeletedBy = deletedBy
+	cce.Version++
+	s.db.cces[id] = cce
+	return nil
+}
+
+func (s *CCEStore) ListDeleted(ctx context.Context, page store.Page) ([]models.CCE, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var cces []models.CCE
+	for _, id := range sortedKeys(s.db.cces) {
+		if cce := s.db.cces[id]; cce.DeletedAt != nil {
+			cces = append(cces, cloneCCE(cce))
+		}
+	}
+	return paginate(s.db.cursors, "cces/trash", cces, func(cce *models.CCE) string {
+		return cce.ID
+	}, page)
+}
+
+func (s *CCEStore) Restore(ctx context.Context, id string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	cce, ok := s.db.cces[id]
+	if !ok || cce.DeletedAt == nil {
+		return errors.ErrNotFound
+	}
+
+	cce.DeletedAt = nil
+	cce.DeletedBy = ""
+	cce.Version++
+	s.db.cces[id] = cce
+	return nil
+}
+
+func (s *CCEStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	purged := 0
+	for id, cce := range s.db.cces {
+		if cce.DeletedAt != nil && cce.DeletedAt.Before(before) {
+			delete(s.db.cces, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func cloneCCE(cce models.CCE) models.CCE {
+	if cce.Farmers != nil {
+		farmers := make([]models.Farmer, len(cce.Farmers))
+		for i, farmer := range cce.Farmers {
+			farmers[i] = cloneFarmer(farmer)
+		}
+		cce.Farmers = farmers
+	}
+	if cce.Tickets != nil {
+		cce.Tickets = append([]models.Ticket(nil), cce.Tickets...)
+	}
+	cce.TicketIDs = cloneStrings(cce.TicketIDs)
+	return cce
+}
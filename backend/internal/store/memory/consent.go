@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+)
+
+type ConsentStore struct {
+	db *db
+}
+
+func (s *ConsentStore) Put(ctx context.Context, event *models.ConsentEvent) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.consent[event.ID] = *event
+	return nil
+}
+
+func (s *ConsentStore) ListByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.ConsentEvent, string, error) {
+	events := s.filter(func(event *models.ConsentEvent) bool {
+		return event.FarmerID == farmerID
+	})
+	return paginate(s.db.cursors, "consent/farmer/"+farmerID, events, consentPosition, page)
+}
+
+func (s *ConsentStore) ListByTimestamp(ctx context.Context, startDate, endDate time.Time, page store.Page) ([]models.ConsentEvent, string, error) {
+	events := s.filter(func(event *models.ConsentEvent) bool {
+		return !event.Timestamp.Before(startDate) && !event.Timestamp.After(endDate)
+	})
+	return paginate(s.db.cursors, "consent", events, consentPosition, page)
+}
+
+func (s *ConsentStore) filter(match func(*models.ConsentEvent) bool) []models.ConsentEvent {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var events []models.ConsentEvent
+	for _, id := range sortedKeys(s.db.consent) {
+		event := s.db.consent[id]
+		if match(&event) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func consentPosition(event *models.ConsentEvent) string {
+	return event.ID
+}
+
+func (s *ConsentStore) Erase(ctx context.Context, farmerID string) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	erased := 0
+	for id, event := range s.db.consent {
+		if event.FarmerID != farmerID || event.Contact == "" && event.Reason == "" && event.PII == nil {
+			continue
+		}
+		event.Contact = ""
+		event.Reason = ""
+		event.PII = nil
+		s.db.consent[id] = event
+		erased++
+	}
+	return erased, nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type DuplicateStore struct {
+	db *db
+}
+
+func (s *DuplicateStore) Put(ctx context.Context, candidate *models.DuplicateCandidate) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.duplicates[candidate.ID]; ok {
+		return errors.ErrConflict
+	}
+	s.db.duplicates[candidate.ID] = cloneCandidate(*candidate)
+	return nil
+}
+
+func (s *DuplicateStore) Get(ctx context.Context, id string) (*models.DuplicateCandidate, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	candidate, ok := s.db.duplicates[id]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	candidate = cloneCandidate(candidate)
+	return &candidate, nil
+}
+
+func (s *DuplicateStore) ListByStatus(ctx context.Context, status string, page store.Page) ([]models.DuplicateCandidate, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var candidates []models.DuplicateCandidate
+	for _, candidate := range s.db.duplicates {
+		if candidate.Status == status {
+			candidates = append(candidates, cloneCandidate(candidate))
+		}
+	}
+	position := func(candidate *models.DuplicateCandidate) string {
+		return timePosition(candidate.CreatedAt, candidate.ID)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return position(&candidates[i]) < position(&candidates[j])
+	})
+	return paginate(s.db.cursors, "duplicates/"+status, candidates, position, page)
+}
+
+func (s *DuplicateStore) Review(ctx context.Context, id, status, reviewedBy string, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	candidate, ok := s.db.duplicates[id]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	if candidate.Status != models.DuplicateStatusPending {
+		return errors.ErrConflict
+	}
+
+	at = at.UTC()
+	candidate.Status = status
+	candidate.ReviewedBy = reviewedBy
+	candidate.ReviewedAt = &at
+	s.db.duplicates[id] = candidate
+	return nil
+}
+
+func cloneCandidate(candidate models.DuplicateCandidate) models.DuplicateCandidate {
+	candidate.Reasons = cloneStrings(candidate.Reasons)
+	return candidate
+}
@@ -0,0 +1,307 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type FarmerStore struct {
+	db *db
+}
+
+func (s *FarmerStore) Put(ctx context.Context, farmer *models.Farmer) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.farmers[farmer.ID]; ok {
+		return errors.ErrConflict
+	}
+	if err := s.claimContacts(farmer.ContactNumbers(), farmer.ID); err != nil {
+		return err
+	}
+
+	s.db.farmers[farmer.ID] = cloneFarmer(*farmer)
+	return nil
+}
+
+func (s *FarmerStore) PutBatch(ctx context.Context, farmers []*models.Farmer) []error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	errs := make([]error, len(farmers))
+	for i, farmer := range farmers {
+		if _, ok := s.db.farmers[farmer.ID]; ok {
+			errs[i] = errors.ErrConflict
+			continue
+		}
+		if errs[i] = s.claimContacts(farmer.ContactNumbers(), farmer.ID); errs[i] != nil {
+			continue
+		}
+		s.db.farmers[farmer.ID] = cloneFarmer(*farmer)
+	}
+	return errs
+}
+
+func (s *FarmerStore) Update(ctx context.Context, farmer *models.Farmer) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.farmers[farmer.ID]
+	if !ok || stored.DeletedAt != nil {
+		return errors.ErrNotFound
+	}
+	if stored.Version != farmer.Version {
+		return errors.ErrVersionConflict
+	}
+	numbers := farmer.ContactNumbers()
+	if err := s.claimContacts(numbers, farmer.ID); err != nil {
+		return err
+	}
+	for _, number := range stored.ContactNumbers() {
+		if !slices.Contains(numbers, number) {
+			s.releaseContact(number, farmer.ID)
+		}
+	}
+
+	farmer.Version++
+	s.db.farmers[farmer.ID] = cloneFarmer(*farmer)
+	return nil
+}
+
+func (s *FarmerStore) Merge(ctx context.Context, survivor, loser *models.Farmer) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	pair := []*models.Farmer{survivor, loser}
+	var held []string
+	for _, farmer := range pair {
+		stored, ok := s.db.farmers[farmer.ID]
+		if !ok || stored.DeletedAt != nil {
+			return errors.ErrNotFound
+		}
+		if stored.Version != farmer.Version {
+			return errors.ErrVersionConflict
+		}
+		held = append(held, stored.ContactNumbers()...)
+	}
+	for _, farmer := range pair {
+		for _, number := range farmer.ContactNumbers() {
+			if holder, ok := s.db.contacts[number]; ok && holder != survivor.ID && holder != loser.ID {
+				return &store.ContactTakenError{Contact: number, FarmerID: holder}
+			}
+		}
+	}
+
+	for _, number := range held {
+		s.releaseContact(number, survivor.ID)
+		s.releaseContact(number, loser.ID)
+	}
+	for _, farmer := range pair {
+		for _, number := range farmer.ContactNumbers() {
+			s.db.contacts[number] = farmer.ID
+		}
+		farmer.Version++
+		s.db.farmers[farmer.ID] = cloneFarmer(*farmer)
+	}
+	return nil
+}
+
+func (s *FarmerStore) Get(ctx context.Context, id string) (*models.Farmer, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	farmer, ok := s.db.farmers[id]
+	if !ok || farmer.DeletedAt != nil {
+		return nil, errors.ErrNotFound
+	}
+	farmer = cloneFarmer(farmer)
+	return &farmer, nil
+}
+
+func (s *FarmerStore) GetDeleted(ctx context.Context, id string) (*models.Farmer, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	farmer, ok := s.db.farmers[id]
+	if !ok || farmer.DeletedAt == nil {
+		return nil, errors.ErrNotFound
+	}
+	farmer = cloneFarmer(farmer)
+	return &farmer, nil
+}
+
+func (s *FarmerStore) GetByContact(ctx context.Context, contact string) (*models.Farmer, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	farmer, ok := s.db.farmers[s.db.contacts[contact]]
+	if !ok || farmer.DeletedAt != nil {
+		return nil, errors.ErrNotFound
+	}
+	farmer = cloneFarmer(farmer)
+	return &farmer, nil
+}
+
+func (s *FarmerStore) UsesCrop(ctx context.Context, cropID string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, farmer := range s.db.farmers {
+		if farmer.MergedInto == "" && slices.Contains(farmer.Crop, cropID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *FarmerStore) List(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var farmers []models.Farmer
+	for _, id := range sortedKeys(s.db.farmers) {
+		farmer := s.db.farmers[id]
+		if farmer.DeletedAt == nil && farmer.MergedInto == "" {
+			farmers = append(farmers, cloneFarmer(farmer))
+		}
+	}
+	return paginate(s.db.cursors, "farmers", farmers, farmerPosition, page)
+}
+
+func (s *FarmerStore) Delete(ctx context.Context, id, deletedBy string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	farmer, ok := s.db.farmers[id]
+	if !ok || farmer.DeletedAt != nil {
+		return errors.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	farmer.DeletedAt = &now
+	farmer.DeletedBy = deletedBy
+	farmer.Version++
+	s.db.farmers[id] = farmer
+	return nil
+}
+
+func (s *FarmerStore) ListDeleted(ctx context.Context, page store.Page) ([]models.Farmer, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var farmers []models.Farmer
+	for _, id := range sortedKeys(s.db.farmers) {
+		if farmer := s.db.farmers[id]; farmer.DeletedAt != nil && farmer.MergedInto == "" {
+			farmers = append(farmers, cloneFarmer(farmer))
+		}
+	}
+	return paginate(s.db.cursors, "farmers/trash", farmers, func(farmer *models.Farmer) string {
+		return farmer.ID
+	}, page)
+}
+
+func (s *FarmerStore) Restore(ctx context.Context, id string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	farmer, ok := s.db.farmers[id]
+	if !ok || farmer.DeletedAt == nil {
+		return errors.ErrNotFound
+	}
+
+	farmer.DeletedAt = nil
+	farmer.DeletedBy = ""
+	farmer.Version++
+	s.db.farmers[id] = farmer
+	return nil
+}
+
+func (s *FarmerStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	purged := 0
+	for id, farmer := range s.db.farmers {
+		if farmer.DeletedAt != nil && farmer.DeletedAt.Before(before) {
+			delete(s.db.farmers, id)
+			for _, number := range farmer.ContactNumbers() {
+				s.releaseContact(number, id)
+			}
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// claimContacts records every number in contacts as belonging to farmerID, or
+// none of them if one is held by another farmer. The caller must hold the
+// write lock.
+func (s *FarmerStore) claimContacts(contacts []string, farmerID string) error {
+	for _, contact := range contacts {
+		if holder, ok := s.db.contacts[contact]; ok && holder != farmerID {
+			return &store.ContactTakenError{Contact: contact, FarmerID: holder}
+		}
+	}
+	for _, contact := range contacts {
+		s.db.contacts[contact] = farmerID
+	}
+	return nil
+}
+
+// releaseContact frees contact if farmerID holds it. The caller must hold the
+// write lock.
+func (s *FarmerStore) releaseContact(contact, farmerID string) {
+	if contact != "" && s.db.contacts[contact] == farmerID {
+		delete(s.db.contacts, contact)
+	}
+}
+
+func farmerPosition(farmer *models.Farmer) string {
+	return farmer.ID
+}
+
+func cloneFarmer(farmer models.Farmer) models.Farmer {
+	farmer.Crop = cloneStrings(farmer.Crop)
+	farmer.Consent = maps.Clone(farmer.Consent)
+	farmer.Contacts = slices.Clone(farmer.Contacts)
+	if farmer.Coordinates != nil {
+		coordinates := *farmer.Coordinates
+		farmer.Coordinates = &coordinates
+	}
+	if farmer.PII != nil {
+		sealed := *farmer.PII
+		farmer.PII = &sealed
+	}
+	return farmer
+}
+
+func (s *FarmerStore) ListByGeohash(ctx context.Context, prefix string, page store.Page) ([]models.Farmer, string, error) {
+	if len(prefix) < models.GeoCellLength {
+		return nil, "", errors.ErrInvalidInput
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var farmers []models.Farmer
+	for _, farmer := range s.db.farmers {
+		if farmer.DeletedAt == nil && farmer.MergedInto == "" && strings.HasPrefix(farmer.GeoHash, prefix) {
+			farmers = append(farmers, cloneFarmer(farmer))
+		}
+	}
+	position := func(farmer *models.Farmer) string {
+		return farmer.GeoHash + "|" + farmer.ID
+	}
+	sort.Slice(farmers, func(i, j int) bool {
+		return position(&farmers[i]) < position(&farmers[j])
+	})
+	return paginate(s.db.cursors, "farmers/geo/"+prefix, farmers, position, page)
+}
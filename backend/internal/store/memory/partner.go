@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type PartnerStore struct {
+	db *db
+}
+
+func (s *PartnerStore) Put(ctx context.Context, partner *models.Partner) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.partners[partner.ID] = clonePartner(*partner)
+	return nil
+}
+
+func (s *PartnerStore) Update(ctx context.Context, partner *models.Partner) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.partners[partner.ID]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	if stored.Version != partner.Version {
+		return errors.ErrVersionConflict
+	}
+
+	partner.Version++
+	s.db.partners[partner.ID] = clonePartner(*partner)
+	return nil
+}
+
+func (s *PartnerStore) Get(ctx context.Context, id string) (*models.Partner, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	partner, ok := s.db.partners[id]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	partner = clonePartner(partner)
+	return &partner, nil
+}
+
+func (s *PartnerStore) List(ctx context.Context, kind string, page store.Page) ([]models.Partner, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var partners []models.Partner
+	for _, id := range sortedKeys(s.db.partners) {
+		if partner := s.db.partners[id]; kind == "" || partner.Kind == kind {
+			partners = append(partners, clonePartner(partner))
+		}
+	}
+	position := func(partner *models.Partner) string {
+		return partner.Name + "|" + partner.ID
+	}
+	if kind == "" {
+		position = func(partner *models.Partner) string {
+			return partner.ID
+		}
+	}
+	sort.Slice(partners, func(i, j int) bool {
+		return position(&partners[i]) < position(&partners[j])
+	})
+	return paginate(s.db.cursors, "partners/"+kind, partners, position, page)
+}
+
+func clonePartner(partner models.Partner) models.Partner {
+	if partner.Coordinates != nil {
+		coordinates := *partner.Coordinates
+		partner.Coordinates = &coordinates
+	}
+	return partner
+}
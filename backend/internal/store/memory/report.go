@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"context"
+
+	"backend/internal/models"
+)
+
+type ReportStore struct {
+	db *db
+}
+
+func (s *ReportStore) Put(ctx context.Context, report *models.Report) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := *report
+	stored.CCEReports = make(map[string]*models.CCEReport, len(report.CCEReports))
+	for id, cceReport := range report.CCEReports {
+		r := *cceReport
+		stored.CCEReports[id] = &r
+	}
+	s.db.reports[report.ID] = stored
+	return nil
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type ShootStore struct {
+	db *db
+}
+
+func (s *ShootStore) Put(ctx context.Context, shoot *models.Shoot) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.shoots[shoot.ID] = *shoot
+	return nil
+}
+
+func (s *ShootStore) OutreachMark(ctx context.Context, farmerID string) (store.OutreachMark, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.db.outreach[farmerID], nil
+}
+
+func (s *ShootStore) PutInSequence(ctx context.Context, shoot *models.Shoot, mark store.OutreachMark) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.shoots[shoot.ID]; ok || s.db.outreach[shoot.FarmerID].Seq != mark.Seq {
+		return errors.ErrConflict
+	}
+	s.db.shoots[shoot.ID] = *shoot
+	s.db.outreach[shoot.FarmerID] = store.OutreachMark{
+		Seq:         mark.Seq + 1,
+		LastShootID: shoot.ID,
+		LastAt:      shoot.Timestamp,
+	}
+	return nil
+}
+
+func (s *ShootStore) MoveInSequence(ctx context.Context, shoot *models.Shoot, mark store.OutreachMark) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.shoots[shoot.ID]; !ok {
+		return errors.ErrNotFound
+	}
+	if s.db.outreach[shoot.FarmerID].Seq != mark.Seq {
+		return errors.ErrConflict
+	}
+	s.db.shoots[shoot.ID] = *shoot
+	s.db.outreach[shoot.FarmerID] = store.OutreachMark{
+		Seq:         mark.Seq + 1,
+		LastShootID: shoot.ID,
+		LastAt:      shoot.Timestamp,
+	}
+	return nil
+}
+
+func (s *ShootStore) List(ctx context.Context, shootType string, page store.Page) ([]models.Shoot, string, error) {
+	shoots := s.filter(func(sh *models.Shoot) bool {
+		return shootType == "" || sh.Type == shootType
+	})
+	return paginate(s.db.cursors, "shoots/type/"+shootType, shoots, shootPosition, page)
+}
+
+func (s *ShootStore) ListByTimestamp(ctx context.Context, startDate, endDate time.Time, page store.Page) ([]models.Shoot, string, error) {
+	shoots := s.filter(func(sh *models.Shoot) bool {
+		return !sh.Timestamp.Before(startDate) && !sh.Timestamp.After(endDate)
+	})
+	scope := "shoots/timestamp/" + startDate.Format(time.RFC3339) + "/" + endDate.Format(time.RFC3339)
+	return paginate(s.db.cursors, scope, shoots, shootPosition, page)
+}
+
+func (s *ShootStore) ListByStatusAndType(ctx context.Context, status, shootType string, page store.Page) ([]models.Shoot, string, error) {
+	shoots := s.filter(func(sh *models.Shoot) bool {
+		return sh.Status == status && sh.Type == shootType
+	})
+	return paginate(s.db.cursors, "shoots/status/"+status+"/type/"+shootType, shoots, shootPosition, page)
+}
+
+func (s *ShootStore) ListByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.Shoot, string, error) {
+	shoots := s.filter(func(sh *models.Shoot) bool {
+		return sh.FarmerID == farmerID
+	})
+	position := func(sh *models.Shoot) string {
+		return newestFirstPosition(sh.Timestamp, sh.ID)
+	}
+	sort.Slice(shoots, func(i, j int) bool {
+		return position(&shoots[i]) < position(&shoots[j])
+	})
+	return paginate(s.db.cursors, "shoots/farmer/"+farmerID, shoots, position, page)
+}
+
+func (s *ShootStore) filter(match func(*models.Shoot) bool) []models.Shoot {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var shoots []models.Shoot
+	for _, id := range sortedKeys(s.db.shoots) {
+		shoot := s.db.shoots[id]
+		if match(&shoot) {
+			shoots = append(shoots, shoot)
+		}
+	}
+	return shoots
+}
+
+func shootPosition(shoot *models.Shoot) string {
+	return shoot.ID
+}
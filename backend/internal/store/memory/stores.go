@@ -0,0 +1,143 @@
+// Package memory implements the store interfaces in process. Nothing is
+// persisted, which makes it suitable for local runs and unit tests.
+package memory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// db holds every table behind a single lock so the stores stay consistent
+// with each other.
+type db struct {
+	mu         sync.RWMutex
+	farmers    map[string]models.Farmer
+	cces       map[string]models.CCE
+	tickets    map[string]models.Ticket
+	shoots     map[string]models.Shoot
+	reports    map[string]models.Report
+	audit      map[string]models.AuditRecord
+	duplicates map[string]models.DuplicateCandidate
+	consent    map[string]models.ConsentEvent
+	partners   map[string]models.Partner
+	crops      map[string]models.Crop
+	varieties  map[string]models.Variety
+	// contacts maps each claimed contact number to the farmer holding it.
+	contacts map[string]string
+	// outreach holds the outreach mark of each farmer, see
+	// ShootStore.PutInSequence.
+	outreach map[string]store.OutreachMark
+	cursors  *store.Cursors
+}
+
+func newDB(cursors *store.Cursors) *db {
+	return &db{
+		cursors:    cursors,
+		farmers:    make(map[string]models.Farmer),
+		cces:       make(map[string]models.CCE),
+		tickets:    make(map[string]models.Ticket),
+		shoots:     make(map[string]models.Shoot),
+		reports:    make(map[string]models.Report),
+		audit:      make(map[string]models.AuditRecord),
+		duplicates: make(map[string]models.DuplicateCandidate),
+		consent:    make(map[string]models.ConsentEvent),
+		partners:   make(map[string]models.Partner),
+		crops:      make(map[string]models.Crop),
+		varieties:  make(map[string]models.Variety),
+		contacts:   make(map[string]string),
+		outreach:   make(map[string]store.OutreachMark),
+	}
+}
+
+// NewStores returns empty in-memory stores that sign their list cursors with
+// cursors.
+func NewStores(cursors *store.Cursors) *store.Stores {
+	data := newDB(cursors)
+	return &store.Stores{
+		Farmer:    &FarmerStore{db: data},
+		CCE:       &CCEStore{db: data},
+		Ticket:    &TicketStore{db: data},
+		Shoot:     &ShootStore{db: data},
+		Report:    &ReportStore{db: data},
+		Audit:     &AuditStore{db: data},
+		Duplicate: &DuplicateStore{db: data},
+		Consent:   &ConsentStore{db: data},
+		Partner:   &PartnerStore{db: data},
+		Catalog:   &CatalogStore{db: data},
+
+		Transactions: &Transactions{db: data},
+		Cursors:      cursors,
+	}
+}
+
+// sortedKeys returns the keys of m in ascending order, which is the order the
+// paginated List methods walk a table in.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// paginate returns the page of items that follows page.Cursor together with
+// the cursor of the next page. items must be sorted by position, which plays
+// the part of the key DynamoDB records in a LastEvaluatedKey, so a cursor
+// still resumes in the right place when the item it points at is deleted.
+func paginate[T any](cursors *store.Cursors, scope string, items []T, position func(*T) string, page store.Page) ([]T, string, error) {
+	key, err := cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if key != nil {
+		after, ok := key["Position"].(*types.AttributeValueMemberS)
+		if !ok {
+			return nil, "", errors.ErrInvalidCursor
+		}
+		start := sort.Search(len(items), func(i int) bool {
+			return position(&items[i]) > after.Value
+		})
+		items = items[start:]
+	}
+
+	if page.Limit <= 0 || int(page.Limit) >= len(items) {
+		return items, "", nil
+	}
+	items = items[:page.Limit]
+
+	nextCursor, err := cursors.Encode(scope, map[string]types.AttributeValue{
+		"Position": &types.AttributeValueMemberS{Value: position(&items[len(items)-1])},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return items, nextCursor, nil
+}
+
+// timePosition orders items by t and then by id. The fixed-width layout keeps
+// the lexical order of positions equal to their chronological order.
+func timePosition(t time.Time, id string) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z") + "#" + id
+}
+
+// newestFirstPosition orders items by t, latest first, and then by id.
+func newestFirstPosition(t time.Time, id string) string {
+	return fmt.Sprintf("%019d#%s", math.MaxInt64-t.UnixNano(), id)
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
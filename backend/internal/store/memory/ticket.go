@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type TicketStore struct {
+	db *db
+}
+
+func (s *TicketStore) Put(ctx context.Context, ticket *models.Ticket) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.tickets[ticket.ID] = *ticket
+	return nil
+}
+
+func (s *TicketStore) Update(ctx context.Context, ticket *models.Ticket) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.tickets[ticket.ID]
+	if !ok || stored.DeletedAt != nil {
+		return errors.ErrNotFound
+	}
+	if stored.Version != ticket.Version {
+		return errors.ErrVersionConflict
+	}
+
+	ticket.Version++
+	s.db.tickets[ticket.ID] = *ticket
+	return nil
+}
+
+func (s *TicketStore) Get(ctx context.Context, id string) (*models.Ticket, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	ticket, ok := s.db.tickets[id]
+	if !ok || ticket.DeletedAt != nil {
+		return nil, errors.ErrNotFound
+	}
+	return &ticket, nil
+}
+
+func (s *TicketStore) GetDeleted(ctx context.Context, id string) (*models.Ticket, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	ticket, ok := s.db.tickets[id]
+	if !ok || ticket.DeletedAt == nil {
+		return nil, errors.ErrNotFound
+	}
+	return &ticket, nil
+}
+
+func (s *TicketStore) ListByFarmer(ctx context.Context, farmerID string, page store.Page) ([]models.Ticket, string, error) {
+	tickets := s.filter(func(t *models.Ticket) bool {
+		return t.FarmerID == farmerID
+	})
+	return paginate(s.db.cursors, "tickets/farmer/"+farmerID, tickets, ticketPosition, page)
+}
+
+func (s *TicketStore) ListByCCE(ctx context.Context, cceID string, page store.Page) ([]models.Ticket, string, error) {
+	tickets := s.filter(func(t *models.Ticket) bool {
+		return t.CCEID == cceID
+	})
+	return paginate(s.db.cursors, "tickets/cce/"+cceID, tickets, ticketPosition, page)
+}
+
+func (s *TicketStore) ListByCCEAndCreatedAt(ctx context.Context, cceID string, startDate, endDate time.Time, page store.Page) ([]models.Ticket, string, error) {
+	tickets := s.filter(func(t *models.Ticket) bool {
+		return t.CCEID == cceID && !t.CreatedAt.Before(startDate) && !t.CreatedAt.After(endDate)
+	})
+	// The CCEIDCreatedAtIndex returns tickets in creation order.
+	position := func(t *models.Ticket) string {
+		return timePosition(t.CreatedAt, t.ID)
+	}
+	sort.SliceStable(tickets, func(i, j int) bool {
+		return position(&tickets[i]) < position(&tickets[j])
+	})
+	scope := "tickets/cce/" + cceID + "/created/" + startDate.Format(time.RFC3339) + "/" + endDate.Format(time.RFC3339)
+	return paginate(s.db.cursors, scope, tickets, position, page)
+}
+
+func (s *TicketStore) ListByCCEAndStatus(ctx context.Context, cceID, status string, page store.Page) ([]models.Ticket, string, error) {
+	tickets := s.filter(func(t *models.Ticket) bool {
+		return t.CCEID == cceID && t.Status == status
+	})
+	return paginate(s.db.cursors, "tickets/cce/"+cceID+"/status/"+status, tickets, ticketPosition, page)
+}
+
+func (s *TicketStore) ListByStatus(ctx context.Context, status string, page store.Page) ([]models.Ticket, string, error) {
+	tickets := s.filter(func(t *models.Ticket) bool {
+		return t.Status == status
+	})
+	return paginate(s.db.cursors, "tickets", tickets, ticketPosition, page)
+}
+
+func (s *TicketStore) UsesCrop(ctx context.Context, cropID string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, ticket := range s.db.tickets {
+		if ticket.CropID == cropID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *TicketStore) List(ctx context.Context, page store.Page) ([]models.Ticket, string, error) {
+	tickets := s.filter(func(t *models.Ticket) bool {
+		return true
+	})
+	return paginate(s.db.cursors, "tickets", tickets, ticketPosition, page)
+}
+
+func (s *TicketStore) Delete(ctx context.Context, id, deletedBy string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	ticket, ok := s.db.tickets[id]
+	if !ok || ticket.DeletedAt != nil {
+		return errors.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	ticket.DeletedAt = &now
+	ticket.DeletedBy = deletedBy
+	ticket.Version++
+	s.db.tickets[id] = ticket
+	return nil
+}
+
+func (s *TicketStore) ListDeleted(ctx context.Context, page store.Page) ([]models.Ticket, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var tickets []models.Ticket
+	for _, id := range sortedKeys(s.db.tickets) {
+		if ticket := s.db.tickets[id]; ticket.DeletedAt != nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return paginate(s.db.cursors, "tickets/trash", tickets, func(ticket *models.Ticket) string {
+		return ticket.ID
+	}, page)
+}
+
+func (s *TicketStore) Restore(ctx context.Context, id string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	ticket, ok := s.db.tickets[id]
+	if !ok || ticket.DeletedAt == nil {
+		return errors.ErrNotFound
+	}
+
+	ticket.DeletedAt = nil
+	ticket.DeletedBy = ""
+	ticket.Version++
+	s.db.tickets[id] = ticket
+	return nil
+}
+
+func (s *TicketStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	purged := 0
+	for id, ticket := range s.db.tickets {
+		if ticket.DeletedAt != nil && ticket.DeletedAt.Before(before) {
+			delete(s.db.tickets, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *TicketStore) filter(match func(*models.Ticket) bool) []models.Ticket {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var tickets []models.Ticket
+	for _, id := range sortedKeys(s.db.tickets) {
+		ticket := s.db.tickets[id]
+		if ticket.DeletedAt == nil && match(&ticket) {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets
+}
+
+func ticketPosition(ticket *models.Ticket) string {
+	return ticket.ID
+}
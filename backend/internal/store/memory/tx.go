@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/store"
+	"backend/pkg/errors"
+)
+
+type Transactions struct {
+	db *db
+}
+
+func (t *Transactions) Begin() store.UnitOfWork {
+	return &unitOfWork{db: t.db}
+}
+
+// txWrite is one write of a unit of work. check runs for every write before
+// any apply does, all under the write lock, which gives the same all-or-nothing
+// behaviour as TransactWriteItems.
+type txWrite struct {
+	failure store.ConditionFailure
+	check   func(d *db) error
+	apply   func(d *db)
+}
+
+type unitOfWork struct {
+	db     *db
+	writes []txWrite
+}
+
+func (u *unitOfWork) CreateTicket(ticket *models.Ticket) {
+	stored := *ticket
+	u.writes = append(u.writes, txWrite{
+		failure: store.ConditionFailure{
+			Entity:    "ticket",
+			ID:        ticket.ID,
+			Condition: "ticket ID is not taken",
+		},
+		check: func(d *db) error {
+			if _, ok := d.tickets[stored.ID]; ok {
+				return errors.ErrConflict
+			}
+			return nil
+		},
+		apply: func(d *db) {
+			d.tickets[stored.ID] = stored
+		},
+	})
+}
+
+func (u *unitOfWork) UpdateTicket(ticket *models.Ticket) {
+	stored := *ticket
+	stored.Version++
+	u.writes = append(u.writes, txWrite{
+		failure: store.ConditionFailure{
+			Entity:    "ticket",
+			ID:        ticket.ID,
+			Condition: fmt.Sprintf("ticket is at version %d", ticket.Version),
+		},
+		check: func(d *db) error {
+			current, ok := d.tickets[stored.ID]
+			if !ok || current.DeletedAt != nil {
+				return errors.ErrNotFound
+			}
+			if current.Version != stored.Version-1 {
+				return errors.ErrVersionConflict
+			}
+			return nil
+		},
+		apply: func(d *db) {
+			d.tickets[stored.ID] = stored
+			ticket.Version = stored.Version
+		},
+	})
+}
+
+func (u *unitOfWork) DeleteTicket(ticket *models.Ticket, deletedBy string) {
+	expected := ticket.Version
+	deletedAt := time.Now().UTC()
+	u.writes = append(u.writes, txWrite{
+		failure: store.ConditionFailure{
+			Entity:    "ticket",
+			ID:        ticket.ID,
+			Condition: fmt.Sprintf("ticket is at version %d", expected),
+		},
+		check: func(d *db) error {
+			current, ok := d.tickets[ticket.ID]
+			if !ok || current.DeletedAt != nil {
+				return errors.ErrNotFound
+			}
+			if current.Version != expected {
+				return errors.ErrVersionConflict
+			}
+			return nil
+		},
+		apply: func(d *db) {
+			stored := d.tickets[ticket.ID]
+			stored.DeletedAt = &deletedAt
+			stored.DeletedBy = deletedBy
+			stored.Version++
+			d.tickets[ticket.ID] = stored
+			ticket.Version = stored.Version
+		},
+	})
+}
+
+func (u *unitOfWork) RestoreTicket(ticket *models.Ticket) {
+	expected := ticket.Version
+	u.writes = append(u.writes, txWrite{
+		failure: store.ConditionFailure{
+			Entity:    "ticket",
+			ID:        ticket.ID,
+			Condition: fmt.Sprintf("ticket is deleted at version %d", expected),
+		},
+		check: func(d *db) error {
+			current, ok := d.tickets[ticket.ID]
+			if !ok || current.DeletedAt == nil || current.Version != expected {
+				return errors.ErrNotFound
+			}
+			return nil
+		},
+		apply: func(d *db) {
+			stored := d.tickets[ticket.ID]
+			stored.DeletedAt = nil
+			stored.DeletedBy = ""
+			stored.Version++
+			d.tickets[ticket.ID] = stored
+			ticket.Version = stored.Version
+		},
+	})
+}
+
+func (u *unitOfWork) RequireFarmer(id string) {
+	u.writes = append(u.writes, txWrite{
+		failure: store.ConditionFailure{
+			Entity:    "farmer",
+			ID:        id,
+			Condition: "farmer exists and is not merged",
+		},
+		check: func(d *db) error {
+			if farmer, ok := d.farmers[id]; !ok || farmer.DeletedAt != nil || farmer.MergedInto != "" {
+				return errors.ErrNotFound
+			}
+			return nil
+		},
+		apply: func(d *db) {},
+	})
+}
+
+func (u *unitOfWork) UpdateCCE(id string, change store.CCEChange) {
+	u.writes = append(u.writes, txWrite{
+		failure: store.ConditionFailure{
+			Entity:    "cce",
+			ID:        id,
+			Condition: "CCE exists",
+		},
+		check: func(d *db) error {
+			if cce, ok := d.cces[id]; !ok || cce.DeletedAt != nil {
+				return errors.ErrNotFound
+			}
+			return nil
+		},
+		apply: func(d *db) {
+			cce := cloneCCE(d.cces[id])
+			if change.AddTicket != "" && !slices.Contains(cce.TicketIDs, change.AddTicket) {
+				cce.TicketIDs = append(cce.TicketIDs, change.AddTicket)
+				slices.Sort(cce.TicketIDs)
+			}
+			if change.RemoveTicket != "" {
+				cce.TicketIDs = slices.DeleteFunc(cce.TicketIDs, func(ticketID string) bool {
+					return ticketID == change.RemoveTicket
+				})
+			}
+			cce.OpenTickets += change.OpenTickets
+			cce.ClosedTickets += change.ClosedTickets
+			cce.ResolvedSeconds += change.ResolvedSeconds
+			cce.Version++
+			d.cces[id] = cce
+		},
+	})
+}
+
+func (u *unitOfWork) Commit(ctx context.Context) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	txErr := &store.TransactionError{}
+	for _, w := range u.writes {
+		if err := w.check(u.db); err != nil {
+			failure := w.failure
+			failure.Reason = "ConditionalCheckFailed"
+			failure.Err = err
+			txErr.Failures = append(txErr.Failures, failure)
+		}
+	}
+	if len(txErr.Failures) > 0 {
+		return txErr
+	}
+
+	for _, w := range u.writes {
+		w.apply(u.db)
+	}
+	return nil
+}
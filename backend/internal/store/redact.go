@@ -0,0 +1,29 @@
+package store
+
+import (
+	"slices"
+
+	"backend/internal/models"
+)
+
+// RedactChanges returns a copy of changes with the values of fields replaced
+// by models.Erased, and whether any value was replaced. Values that are
+// already erased, or were never set, are left alone.
+func RedactChanges(changes []models.FieldChange, fields []string) ([]models.FieldChange, bool) {
+	redacted := slices.Clone(changes)
+	changed := false
+	for i, change := range redacted {
+		if !slices.Contains(fields, change.Field) {
+			continue
+		}
+		if change.Old != nil && change.Old != models.Erased {
+			redacted[i].Old = models.Erased
+			changed = true
+		}
+		if change.New != nil && change.New != models.Erased {
+			redacted[i].New = models.Erased
+			changed = true
+		}
+	}
+	return redacted, changed
+}
@@ -0,0 +1,228 @@
+// Package store defines the persistence interfaces the services are built on.
+// The dynamo package implements them on top of DynamoDB and the memory package
+// keeps everything in process, for local runs and unit tests.
+//
+// Update methods replace an entity only if it is still at the Version the
+// caller read, increment Version on success and fail with
+// errors.ErrVersionConflict when another write got there first.
+//
+// Delete moves an entity to the trash instead of removing it: Get, Update and
+// every List method treat it as missing until Restore takes it out again, and
+// Purge removes it for good once it has been deleted for long enough.
+//
+// Every List method returns one page of results together with the cursor of
+// the next page, which is empty once the list is exhausted.
+package store
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/models"
+)
+
+// FarmerStore persists farmers. Put creates a farmer and fails with
+// errors.ErrConflict when the ID is taken, even by a deleted farmer or an
+// alias. No two farmers share a contact number: Put and Update fail with a
+// *ContactTakenError when one of the farmer's numbers (see
+// Farmer.ContactNumbers) is held by another farmer, and a number stays held
+// until the farmer drops it or is purged.
+//
+// A farmer merged into another (MergedInto set) stays behind as an alias: Get
+// still returns it so callers can follow MergedInto, but List and ListDeleted
+// leave it out.
+type FarmerStore interface {
+	Put(ctx context.Context, farmer *models.Farmer) error
+	// PutBatch saves many new farmers at once and returns the outcome of each,
+	// in the order of farmers. A failed farmer does not stop the others.
+	// Unlike Put it does not reliably refuse a taken ID, so callers must give
+	// every farmer a freshly generated ID.
+	PutBatch(ctx context.Context, farmers []*models.Farmer) []error
+	Update(ctx context.Context, farmer *models.Farmer) error
+	// Merge saves both farmers of a merge at once, each if it is still at
+	// its Version: survivor and loser, which is now an alias of survivor.
+	// Numbers survivor takes over from loser change hands; either both
+	// farmers are written or neither is.
+	Merge(ctx context.Context, survivor, loser *models.Farmer) error
+	Get(ctx context.Context, id string) (*models.Farmer, error)
+	// GetDeleted returns a farmer in the trash, which Get treats as missing.
+	GetDeleted(ctx context.Context, id string) (*models.Farmer, error)
+	// GetByContact returns the farmer holding the E.164 contact number, which
+	// may be any of the farmer's numbers.
+	GetByContact(ctx context.Context, contact string) (*models.Farmer, error)
+	// ListByGeohash returns the farmers whose GeoHash starts with prefix,
+	// which must be at least models.GeoCellLength characters long.
+	ListByGeohash(ctx context.Context, prefix string, page Page) ([]models.Farmer, string, error)
+	// UsesCrop reports whether any farmer, in the trash or not, grows the
+	// catalog crop.
+	UsesCrop(ctx context.Context, cropID string) (bool, error)
+	List(ctx context.Context, page Page) ([]models.Farmer, string, error)
+	Delete(ctx context.Context, id, deletedBy string) error
+	ListDeleted(ctx context.Context, page Page) ([]models.Farmer, string, error)
+	Restore(ctx context.Context, id string) error
+	// Purge removes the farmers deleted before the cutoff and returns how
+	// many it removed.
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// CCEStore persists customer care executives.
+type CCEStore interface {
+	// Put creates a CCE and fails with errors.ErrConflict if the ID is taken.
+	Put(ctx context.Context, cce *models.CCE) error
+	Update(ctx context.Context, cce *models.CCE) error
+	Get(ctx context.Context, id string) (*models.CCE, error)
+	List(ctx context.Context, page Page) ([]models.CCE, string, error)
+	Delete(ctx context.Context, id, deletedBy string) error
+	ListDeleted(ctx context.Context, page Page) ([]models.CCE, string, error)
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// TicketStore persists tickets. Tickets are moved to and from the trash
+// through UnitOfWork, which keeps the counters of their CCE in step; Delete
+// and Restore leave the counters alone.
+type TicketStore interface {
+	Put(ctx context.Context, ticket *models.Ticket) error
+	Update(ctx context.Context, ticket *models.Ticket) error
+	Get(ctx context.Context, id string) (*models.Ticket, error)
+	// GetDeleted returns a ticket in the trash, which Get treats as missing.
+	GetDeleted(ctx context.Context, id string) (*models.Ticket, error)
+	ListByFarmer(ctx context.Context, farmerID string, page Page) ([]models.Ticket, string, error)
+	ListByCCE(ctx context.Context, cceID string, page Page) ([]models.Ticket, string, error)
+	ListByCCEAndCreatedAt(ctx context.Context, cceID string, startDate, endDate time.Time, page Page) ([]models.Ticket, string, error)
+	ListByCCEAndStatus(ctx context.Context, cceID, status string, page Page) ([]models.Ticket, string, error)
+	ListByStatus(ctx context.Context, status string, page Page) ([]models.Ticket, string, error)
+	// UsesCrop reports whether any ticket, in the trash or not, is about the
+	// catalog crop.
+	UsesCrop(ctx context.Context, cropID string) (bool, error)
+	List(ctx context.Context, page Page) ([]models.Ticket, string, error)
+	Delete(ctx context.Context, id, deletedBy string) error
+	ListDeleted(ctx context.Context, page Page) ([]models.Ticket, string, error)
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// ShootStore persists outreach attempts (calls and WhatsApp messages).
+type ShootStore interface {
+	Put(ctx context.Context, shoot *models.Shoot) error
+	// List returns every shoot, or only those of shootType when it is set.
+	List(ctx context.Context, shootType string, page Page) ([]models.Shoot, string, error)
+	ListByTimestamp(ctx context.Context, startDate, endDate time.Time, page Page) ([]models.Shoot, string, error)
+	ListByStatusAndType(ctx context.Context, status, shootType string, page Page) ([]models.Shoot, string, error)
+	// ListByFarmer returns the shoots of one farmer, newest first.
+	ListByFarmer(ctx context.Context, farmerID string, page Page) ([]models.Shoot, string, error)
+	// OutreachMark returns where the farmer's outreach stands, see
+	// PutInSequence. A farmer without sequenced shoots has the zero mark.
+	OutreachMark(ctx context.Context, farmerID string) (OutreachMark, error)
+	// PutInSequence saves a new shoot of the farmer only if the farmer's
+	// mark is still at mark.Seq, and moves the mark on to the shoot. It fails
+	// with errors.ErrConflict when another shoot was sequenced first, so two
+	// shoots checked against the same history cannot both be saved.
+	PutInSequence(ctx context.Context, shoot *models.Shoot, mark OutreachMark) error
+	// MoveInSequence saves a shoot that exists already, now of the farmer
+	// shoot.FarmerID, on the same condition and moving the mark on the same
+	// way as PutInSequence, so shoots a merge moves over count against the
+	// caps of the farmer they move to. It fails with errors.ErrNotFound if
+	// the shoot is gone.
+	MoveInSequence(ctx context.Context, shoot *models.Shoot, mark OutreachMark) error
+}
+
+// OutreachMark counts the shoots saved for a farmer through
+// ShootStore.PutInSequence and MoveInSequence and names the last of them.
+type OutreachMark struct {
+	Seq         int64
+	LastShootID string
+	LastAt      time.Time
+}
+
+// ReportStore persists generated reports.
+type ReportStore interface {
+	Put(ctx context.Context, report *models.Report) error
+}
+
+// AuditStore persists the audit trail. Records are never changed once written,
+// except by Redact when personal data is erased.
+type AuditStore interface {
+	Put(ctx context.Context, record *models.AuditRecord) error
+	// ListByEntity returns the history of one entity, oldest change first.
+	ListByEntity(ctx context.Context, entity, id string, page Page) ([]models.AuditRecord, string, error)
+	// Redact replaces the old and new values of fields in the history of one
+	// entity with models.Erased and returns how many records it changed.
+	Redact(ctx context.Context, entity, id string, fields []string) (int, error)
+}
+
+// DuplicateStore persists the queue of likely duplicate farmers.
+type DuplicateStore interface {
+	// Put queues a new candidate. It fails with errors.ErrConflict if the pair
+	// was queued before, whatever became of it, so dismissed pairs stay
+	// dismissed.
+	Put(ctx context.Context, candidate *models.DuplicateCandidate) error
+	Get(ctx context.Context, id string) (*models.DuplicateCandidate, error)
+	// ListByStatus returns the candidates in one status, oldest first.
+	ListByStatus(ctx context.Context, status string, page Page) ([]models.DuplicateCandidate, string, error)
+	// Review moves a pending candidate to status. It fails with
+	// errors.ErrConflict if the candidate is no longer pending.
+	Review(ctx context.Context, id, status, reviewedBy string, at time.Time) error
+}
+
+// ConsentStore persists the history of consent changes. Events are never
+// changed once written, except by Erase.
+type ConsentStore interface {
+	Put(ctx context.Context, event *models.ConsentEvent) error
+	// ListByFarmer returns the consent history of one farmer, oldest first.
+	ListByFarmer(ctx context.Context, farmerID string, page Page) ([]models.ConsentEvent, string, error)
+	// ListByTimestamp returns the events between startDate and endDate, in no
+	// particular order.
+	ListByTimestamp(ctx context.Context, startDate, endDate time.Time, page Page) ([]models.ConsentEvent, string, error)
+	// Erase blanks the contact number and reason of the farmer's events,
+	// keeping what was consented to and when, and returns how many events it
+	// changed.
+	Erase(ctx context.Context, farmerID string) (int, error)
+}
+
+// PartnerStore persists dealers and agronomists.
+type PartnerStore interface {
+	Put(ctx context.Context, partner *models.Partner) error
+	Update(ctx context.Context, partner *models.Partner) error
+	Get(ctx context.Context, id string) (*models.Partner, error)
+	// List returns the partners of one kind ordered by name, or every partner
+	// in no particular order when kind is empty.
+	List(ctx context.Context, kind string, page Page) ([]models.Partner, string, error)
+}
+
+// CatalogStore persists the product catalog: crops and their varieties.
+type CatalogStore interface {
+	// PutCrop fails with errors.ErrConflict if the crop ID is taken.
+	PutCrop(ctx context.Context, crop *models.Crop) error
+	UpdateCrop(ctx context.Context, crop *models.Crop) error
+	GetCrop(ctx context.Context, id string) (*models.Crop, error)
+	// ListCrops returns the crops in no particular order.
+	ListCrops(ctx context.Context, page Page) ([]models.Crop, string, error)
+	DeleteCrop(ctx context.Context, id string) error
+	PutVariety(ctx context.Context, variety *models.Variety) error
+	UpdateVariety(ctx context.Context, variety *models.Variety) error
+	GetVariety(ctx context.Context, id string) (*models.Variety, error)
+	// ListVarieties returns the varieties of one crop ordered by name, or
+	// every variety in no particular order when cropID is empty.
+	ListVarieties(ctx context.Context, cropID string, page Page) ([]models.Variety, string, error)
+	DeleteVariety(ctx context.Context, id string) error
+}
+
+// Stores bundles one implementation of every store.
+type Stores struct {
+	Farmer    FarmerStore
+	CCE       CCEStore
+	Ticket    TicketStore
+	Shoot     ShootStore
+	Report    ReportStore
+	Audit     AuditStore
+	Duplicate DuplicateStore
+	Consent   ConsentStore
+	Partner   PartnerStore
+	Catalog   CatalogStore
+	// Transactions writes to several of the stores above at once.
+	Transactions Transactions
+	// Cursors signs the list cursors of the stores, and of lists served from
+	// outside them such as farmer search.
+	Cursors *Cursors
+}
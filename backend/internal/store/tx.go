@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"backend/internal/models"
+)
+
+// Transactions starts units of work.
+type Transactions interface {
+	Begin() UnitOfWork
+}
+
+// UnitOfWork collects writes to several tables and commits them all or none.
+// Nothing is written until Commit, and a unit of work may touch each item at
+// most once. A cancelled commit returns a *TransactionError.
+type UnitOfWork interface {
+	// CreateTicket adds a ticket whose ID is not taken yet.
+	CreateTicket(ticket *models.Ticket)
+	// UpdateTicket replaces a ticket that is still at ticket.Version, and
+	// bumps ticket.Version once the commit succeeds.
+	UpdateTicket(ticket *models.Ticket)
+	// DeleteTicket moves a ticket that is still at ticket.Version to the
+	// trash, and bumps ticket.Version once the commit succeeds.
+	DeleteTicket(ticket *models.Ticket, deletedBy string)
+	// RestoreTicket takes a ticket that is still at ticket.Version out of the
+	// trash, and bumps ticket.Version once the commit succeeds.
+	RestoreTicket(ticket *models.Ticket)
+	// RequireFarmer fails the commit unless the farmer exists and was not
+	// merged into another.
+	RequireFarmer(id string)
+	// UpdateCCE applies change to a CCE that exists and bumps its version.
+	UpdateCCE(id string, change CCEChange)
+	Commit(ctx context.Context) error
+}
+
+// CCEChange adjusts the ticket bookkeeping of a CCE.
+type CCEChange struct {
+	AddTicket       string
+	RemoveTicket    string
+	OpenTickets     int64
+	ClosedTickets   int64
+	ResolvedSeconds int64
+}
+
+// IsZero reports whether the change leaves the CCE as it is.
+func (c CCEChange) IsZero() bool {
+	return c == CCEChange{}
+}
+
+// TransactionError is returned when a unit of work is cancelled. It lists the
+// writes whose conditions failed; errors.Is matches the Err of each of them.
+type TransactionError struct {
+	Failures []ConditionFailure
+}
+
+// ConditionFailure is one write of a cancelled unit of work.
+type ConditionFailure struct {
+	Entity string
+	ID     string
+	// Condition describes what had to hold for the write to go ahead.
+	Condition string
+	// Reason is the cancellation code, such as ConditionalCheckFailed or
+	// TransactionConflict.
+	Reason string
+	// Err is ErrNotFound, ErrConflict or ErrVersionConflict.
+	Err error
+}
+
+func (e *TransactionError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		failures[i] = fmt.Sprintf("%s %s: %s (%s)", f.Entity, f.ID, f.Condition, f.Reason)
+	}
+	return "transaction cancelled: " + strings.Join(failures, "; ")
+}
+
+func (e *TransactionError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		if f.Err != nil {
+			errs = append(errs, f.Err)
+		}
+	}
+	return errs
+}
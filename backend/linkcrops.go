@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"backend/internal/config"
+	"backend/internal/location"
+	"backend/internal/service"
+)
+
+// runLinkCropsCommand implements the "link-crops" subcommand, which replaces
+// the free text crops of farmers saved before the product catalog with catalog
+// crop IDs, and lists the crops the catalog is missing.
+func runLinkCropsCommand(cfg *config.Config) {
+	stores, err := initializeStores(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize stores: %v", err)
+	}
+	locations, err := location.LoadFiles(cfg.Location.DirectoryFiles)
+	if err != nil {
+		log.Fatalf("Failed to load location directory: %v", err)
+	}
+	policy, err := loadContactPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid contact policy: %v", err)
+	}
+	cipher, err := loadPIICipher(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up PII encryption: %v", err)
+	}
+	services := service.NewServices(stores, locations, policy, cipher, erasureKey(cfg))
+
+	linked, unknown, err := services.Farmer.LinkCrops(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to link crops after linking %d farmers: %v", linked, err)
+	}
+	fmt.Printf("%d farmers linked\n", linked)
+
+	crops := make([]string, 0, len(unknown))
+	for crop := range unknown {
+		crops = append(crops, crop)
+	}
+	sort.Strings(crops)
+	for _, crop := range crops {
+		fmt.Printf("not in the catalog: %q, grown by %d farmers\n", crop, unknown[crop])
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,13 +14,18 @@ import (
 
 	"backend/internal/api"
 	"backend/internal/config"
+	"backend/internal/contactpolicy"
 	"backend/internal/db"
+	"backend/internal/location"
+	"backend/internal/masking"
+	"backend/internal/pii"
 	"backend/internal/reports"
 	"backend/internal/service"
+	"backend/internal/store"
+	"backend/internal/store/dynamo"
+	"backend/internal/store/memory"
 
 	"github.com/robfig/cron/v3"
-
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 )
 
 func main() {
@@ -32,25 +39,83 @@ func main() {
 	}
 	log.Println("1")
 
-	// Initialize DynamoDB client
-	dbClient, err := db.NewDynamoDBClient(context.Background(), cfg.AWS.Region)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		runBackupCommand(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		runRestoreCommand(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-farmers" {
+		runImportCommand(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "geocode-farmers" {
+		runGeocodeCommand(cfg)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "link-crops" {
+		runLinkCropsCommand(cfg)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		runRotateKeysCommand(cfg, os.Args[2:])
+		return
+	}
+
+	// Initialize the stores for the configured database driver
+	stores, err := initializeStores(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create DynamoDB client: %v", err)
+		log.Fatalf("Failed to initialize stores: %v", err)
 	}
 	log.Println("2")
 
-	// Run the migrations
-	if err := db.RunMigrations(dbClient); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	// Load the location master
+	locations, err := location.LoadFiles(cfg.Location.DirectoryFiles)
+	if err != nil {
+		log.Fatalf("Failed to load location directory: %v", err)
 	}
 
-	log.Println("Migrations completed successfully")
+	// Load the rules outbound shoots are checked against
+	policy, err := loadContactPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid contact policy: %v", err)
+	}
+
+	// Set up the encryption of farmer PII
+	cipher, err := loadPIICipher(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up PII encryption: %v", err)
+	}
 
 	// Initialize services
-	services := initializeServices(dbClient)
+	services := service.NewServices(stores, locations, policy, cipher, erasureKey(cfg))
+
+	// Build the farmer search index before serving searches
+	if err := services.Farmer.RebuildSearchIndex(context.Background()); err != nil {
+		log.Fatalf("Failed to build farmer search index: %v", err)
+	}
+
+	// Set up the masking of PII in responses
+	masker, err := masking.New(cfg.Masking.Fields, cfg.Masking.Roles)
+	if err != nil {
+		log.Fatalf("Invalid masking configuration: %v", err)
+	}
+	var accessLog *masking.AccessLog
+	if cfg.Masking.AccessLog != "" {
+		accessLog, err = masking.OpenAccessLog(cfg.Masking.AccessLog)
+		if err != nil {
+			log.Fatalf("Failed to open PII access log: %v", err)
+		}
+	}
 
 	// Set up router
-	router := api.SetupRouter(services)
+	router := api.SetupRouter(services, masker, accessLog)
 
 	// Create server
 	srv := &http.Server{
@@ -71,7 +136,7 @@ func main() {
 	log.Println("4")
 
 	// Initialize report generator
-	reportGenerator := reports.NewReportGenerator(services.Shoot, services.CCE, dbClient)
+	reportGenerator := reports.NewReportGenerator(services.Shoot, services.CCE, stores.Report)
 
 	// Initialize mailer
 	mailer := reports.NewMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password)
@@ -111,6 +176,37 @@ func main() {
 
 	log.Println("8")
 
+	// Purge records that have been in the trash for longer than the retention
+	_, err = c.AddFunc(cfg.Trash.PurgeSchedule, func() {
+		purgeTrash(services, cfg.Trash.Retention)
+	})
+	if err != nil {
+		log.Printf("Failed to set up trash purge cron job: %v", err)
+	}
+
+	// Rebuild the farmer search index to pick up other instances' writes
+	_, err = c.AddFunc(cfg.Search.RefreshSchedule, func() {
+		if err := services.Farmer.RebuildSearchIndex(context.Background()); err != nil {
+			log.Printf("Failed to rebuild farmer search index: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to set up search index cron job: %v", err)
+	}
+
+	// Queue likely duplicate farmers for review
+	_, err = c.AddFunc(cfg.Duplicates.Schedule, func() {
+		queued, err := services.Duplicate.FindDuplicates(context.Background())
+		if err != nil {
+			log.Printf("Failed to look for duplicate farmers: %v", err)
+			return
+		}
+		log.Printf("Queued %d likely duplicate farmers for review", queued)
+	})
+	if err != nil {
+		log.Printf("Failed to set up duplicate detection cron job: %v", err)
+	}
+
 	c.Start()
 
 	// Wait for interrupt signal to gracefully shutdown the server
@@ -142,11 +238,82 @@ func main() {
 	log.Printf("Server exiting: %v", err)
 }
 
-func initializeServices(dbClient *dynamodb.Client) *service.Services {
-	return &service.Services{
-		Farmer: service.NewFarmerService(dbClient),
-		CCE:    service.NewCCEService(dbClient),
-		Ticket: service.NewTicketService(dbClient),
+func initializeStores(cfg *config.Config) (*store.Stores, error) {
+	cursors := store.NewCursors([]byte(cfg.Server.CursorSecret))
+
+	if cfg.Database.Driver == config.DriverMemory {
+		log.Println("Using in-memory stores, data will not be persisted")
+		return memory.NewStores(cursors), nil
+	}
+
+	// Initialize DynamoDB client
+	dbClient, err := db.NewDynamoDBClient(context.Background(), cfg.AWS.Region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DynamoDB client: %w", err)
+	}
+
+	tables := db.NewTables(cfg.Database.TablePrefix)
+
+	// Run the migrations
+	if err := db.RunMigrations(dbClient, tables); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	log.Println("Migrations completed successfully")
+
+	return dynamo.NewStores(dbClient, tables, cursors), nil
+}
+
+func loadContactPolicy(cfg *config.Config) (*contactpolicy.Policy, error) {
+	c := cfg.ContactPolicy
+	return contactpolicy.Load(c.QuietHours, c.RegionQuietHours, c.MaxPerDay, c.MaxPerWeek, c.MinGapAfterCall)
+}
+
+// loadPIICipher returns the cipher farmer PII is encrypted with, or nil when
+// no key provider is configured.
+func loadPIICipher(ctx context.Context, cfg *config.Config) (*pii.Cipher, error) {
+	var keys pii.KeyProvider
+	var err error
+	switch cfg.PII.KeyProvider {
+	case "":
+		return nil, nil
+	case config.KeyProviderKeyfile:
+		keys, err = pii.LoadKeyfile(cfg.PII.Keyfile)
+	case config.KeyProviderKMS:
+		keys, err = pii.NewKMS(ctx, cfg.AWS.Region, cfg.PII.KMSEndpoint, cfg.PII.KMSKeyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pii.NewCipher(keys, []byte(cfg.PII.BlindIndexKey))
+}
+
+// erasureKey returns the key erasure certificates are signed with, derived
+// from the configured secret so every instance signs alike.
+func erasureKey(cfg *config.Config) ed25519.PrivateKey {
+	seed := sha256.Sum256([]byte(cfg.Erasure.SigningSecret))
+	return ed25519.NewKeyFromSeed(seed[:])
+}
+
+func purgeTrash(services *service.Services, retention time.Duration) {
+	ctx := context.Background()
+	before := time.Now().UTC().Add(-retention)
+
+	purges := []struct {
+		name  string
+		purge func(context.Context, time.Time) (int, error)
+	}{
+		{"farmers", services.Farmer.PurgeFarmers},
+		{"CCEs", services.CCE.PurgeCCEs},
+		{"tickets", services.Ticket.PurgeTickets},
+	}
+	for _, p := range purges {
+		n, err := p.purge(ctx, before)
+		if err != nil {
+			log.Printf("Failed to purge deleted %s: %v", p.name, err)
+			continue
+		}
+		log.Printf("Purged %d deleted %s", n, p.name)
 	}
 }
 
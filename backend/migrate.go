@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"backend/internal/config"
+	"backend/internal/db"
+)
+
+const migrateUsage = `Usage:
+  backend migrate status
+  backend migrate up [-target VERSION] [-dry-run]
+  backend migrate down -target VERSION [-dry-run]`
+
+// runMigrateCommand implements the "migrate" subcommand. It only applies to
+// the DynamoDB driver; the in-memory stores have no schema.
+func runMigrateCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+	if cfg.Database.Driver != config.DriverDynamoDB {
+		log.Fatalf("Migrations only apply to the %s driver, configured driver is %s", config.DriverDynamoDB, cfg.Database.Driver)
+	}
+
+	action := args[0]
+	flags := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	target := flags.Int("target", -1, "version to migrate to")
+	dryRun := flags.Bool("dry-run", false, "print the plan without running it")
+	flags.Parse(args[1:])
+
+	ctx := context.Background()
+
+	dbClient, err := db.NewDynamoDBClient(ctx, cfg.AWS.Region)
+	if err != nil {
+		log.Fatalf("Failed to create DynamoDB client: %v", err)
+	}
+	migrator := db.NewMigrator(dbClient, db.NewTables(cfg.Database.TablePrefix))
+
+	switch action {
+	case "status":
+		printMigrationStatus(ctx, migrator)
+		return
+	case "up":
+		if *target < 0 {
+			*target = db.LatestVersion()
+		}
+	case "down":
+		if *target < 0 {
+			log.Fatalf("migrate down requires -target")
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	steps, err := migrator.Plan(ctx, *target)
+	if err != nil {
+		log.Fatalf("Failed to plan migrations: %v", err)
+	}
+	if action == "up" && len(steps) > 0 && steps[0].Direction == db.DirectionDown {
+		log.Fatalf("Target version %d is below the current version, use migrate down", *target)
+	}
+	if action == "down" && len(steps) > 0 && steps[0].Direction == db.DirectionUp {
+		log.Fatalf("Target version %d is above the current version, use migrate up", *target)
+	}
+
+	if len(steps) == 0 {
+		fmt.Println("Nothing to do")
+		return
+	}
+	for _, step := range steps {
+		fmt.Printf("%-4s %4d  %-6s  %s\n", step.Direction, step.Migration.Version, step.Migration.Kind(), step.Migration.Description)
+	}
+	if *dryRun {
+		return
+	}
+
+	if err := migrator.Migrate(ctx, *target); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	fmt.Printf("Schema is at version %d\n", *target)
+}
+
+func printMigrationStatus(ctx context.Context, migrator *db.Migrator) {
+	currentVersion, states, err := migrator.Status(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+
+	fmt.Printf("Current version: %d (latest %d)\n", currentVersion, db.LatestVersion())
+	for _, state := range states {
+		status := "pending"
+		if state.Applied {
+			status = "applied"
+		} else if state.Progress != nil {
+			status = "running"
+		}
+		fmt.Printf("%-8s %4d  %-6s  %s", status, state.Migration.Version, state.Migration.Kind(), state.Migration.Description)
+		if state.Progress != nil {
+			fmt.Printf(" (%d items processed, %d updated)", state.Progress.Processed, state.Progress.Updated)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,25 @@
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the claims of a verified token.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims stored by NewContext, if any.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
+
+// UserID returns the ID of the authenticated user, or "" for anonymous
+// requests.
+func UserID(ctx context.Context) string {
+	if claims, ok := FromContext(ctx); ok {
+		return claims.UserID
+	}
+	return ""
+}
@@ -8,14 +8,28 @@ import (
 
 var secretKey = []byte("") // In a real application, this should be securely stored
 
+// Roles a token can carry. An admin holds every other role as well.
+const (
+	RoleAdmin      = "admin"
+	RoleSupervisor = "supervisor"
+	RoleCCE        = "cce"
+	// RoleTempCCE is a temporary CCE, who sees farmer PII masked by default.
+	RoleTempCCE = "temp_cce"
+	// RoleReport is held by report recipients, who see farmer PII masked by
+	// default.
+	RoleReport = "report"
+)
+
 type Claims struct {
 	UserID string `json:"user_id"`
+	Role   string `json:"role"`
 	jwt.StandardClaims
 }
 
-func GenerateToken(userID string) (string, error) {
+func GenerateToken(userID, role string) (string, error) {
 	claims := &Claims{
-		UserID: userID}
+		UserID: userID,
+		Role:   role}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(secretKey)
 }
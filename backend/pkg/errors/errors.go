@@ -7,10 +7,16 @@ import (
 )
 
 var (
-	ErrNotFound     = errors.New("resource not found")
-	ErrInvalidInput = errors.New("invalid input")
-	ErrUnauthorized = errors.New("unauthorized")
-	ErrInternal     = errors.New("internal server error")
+	ErrNotFound      = errors.New("resource not found")
+	ErrInvalidInput  = errors.New("invalid input")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrInternal      = errors.New("internal server error")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrVersionConflict means the entity changed since the version the
+	// caller read.
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrConflict means the write clashes with data that already exists.
+	ErrConflict = errors.New("conflict")
 )
 
 func WriteJSONError(w http.ResponseWriter, status int, message string) {
@@ -22,3 +28,11 @@ func WriteJSONError(w http.ResponseWriter, status int, message string) {
 func New(err string) error {
 	return errors.New(err)
 }
+
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
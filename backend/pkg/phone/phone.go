@@ -0,0 +1,75 @@
+// Package phone normalises phone numbers to E.164 using Indian numbering
+// rules.
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// CountryCode is the calling code assumed for numbers written without one.
+const CountryCode = "91"
+
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Normalize converts a phone number as typed by a person into E.164 form.
+//
+// Spaces, dashes, dots and brackets are ignored. Numbers without a country
+// code are treated as Indian: a ten digit national number, optionally written
+// with the 0 trunk prefix ("098xxxxxxxx") or with the country code but no plus
+// ("9198xxxxxxxx", "009198xxxxxxxx"). National numbers start with 2-9; mobile
+// numbers start with 6-9 and landlines with their STD code. Numbers that
+// already carry a plus and a non-Indian country code are kept as long as they
+// have a plausible length.
+func Normalize(raw string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", ErrInvalidNumber
+		}
+	}
+	digits := b.String()
+
+	if strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+		if strings.HasPrefix(digits, CountryCode) {
+			return national(digits[len(CountryCode):])
+		}
+		// E.164 allows at most 15 digits including the country code.
+		if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+			return "", ErrInvalidNumber
+		}
+		return "+" + digits, nil
+	}
+
+	switch {
+	case len(digits) == 14 && strings.HasPrefix(digits, "00"+CountryCode):
+		return national(digits[4:])
+	case len(digits) == 12 && strings.HasPrefix(digits, CountryCode):
+		return national(digits[2:])
+	case len(digits) == 11 && digits[0] == '0':
+		return national(digits[1:])
+	default:
+		return national(digits)
+	}
+}
+
+// national validates a ten digit Indian national number and prefixes the
+// country code.
+func national(digits string) (string, error) {
+	if len(digits) != 10 || digits[0] < '2' {
+		return "", ErrInvalidNumber
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidNumber
+		}
+	}
+	return "+" + CountryCode + digits, nil
+}
@@ -0,0 +1,47 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{name: "national mobile", raw: "9876543210", want: "+919876543210"},
+		{name: "separators", raw: " 98765-432.10 ", want: "+919876543210"},
+		{name: "brackets", raw: "(0)98765 43210", want: "+919876543210"},
+		{name: "trunk prefix", raw: "09876543210", want: "+919876543210"},
+		{name: "country code without plus", raw: "919876543210", want: "+919876543210"},
+		{name: "international prefix", raw: "00919876543210", want: "+919876543210"},
+		{name: "e164", raw: "+91 98765 43210", want: "+919876543210"},
+		{name: "landline", raw: "022 2345 6789", want: "+912223456789"},
+		{name: "foreign", raw: "+44 20 7946 0958", want: "+442079460958"},
+		{name: "national starting with 1", raw: "1876543210", wantErr: true},
+		{name: "too short", raw: "98765", wantErr: true},
+		{name: "too long", raw: "98765432101", wantErr: true},
+		{name: "indian with plus too short", raw: "+91987654321", wantErr: true},
+		{name: "foreign too short", raw: "+4412345", wantErr: true},
+		{name: "foreign too long", raw: "+4412345678901234", wantErr: true},
+		{name: "foreign starting with 0", raw: "+0123456789", wantErr: true},
+		{name: "plus in the middle", raw: "98765+43210", wantErr: true},
+		{name: "letters", raw: "98765abcde", wantErr: true},
+		{name: "empty", raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw)
+			if tt.wantErr {
+				if err != ErrInvalidNumber {
+					t.Fatalf("Normalize(%q) = %q, %v; want ErrInvalidNumber", tt.raw, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+// Package requestid carries the ID of the HTTP request being served through a
+// context, so that code far from the handler can tag what it records.
+package requestid
+
+import "context"
+
+// Header is the header the request ID is read from and echoed back in.
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored by NewContext, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -26,3 +26,10 @@ func RespondWithError(w http.ResponseWriter, err error) {
 		RespondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": errors.ErrInternal.Error()})
 	}
 }
+
+// ListResponse is the envelope every list endpoint responds with. NextCursor
+// is left out once the last page has been returned.
+type ListResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
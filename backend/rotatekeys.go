@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/location"
+	"backend/internal/pii"
+	"backend/internal/service"
+)
+
+// runRotateKeysCommand implements the "rotate-keys" subcommand. It switches
+// to a new data key and re-encrypts the stored farmers with it in batches.
+// With -new-master the keyfile provider first gains a new master key; KMS
+// rotates its master keys itself. Restart the servers afterwards so they too
+// generate their data keys under the new master key.
+func runRotateKeysCommand(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	newMaster := flags.Bool("new-master", false, "add a new master key to the keyfile first")
+	batch := flags.Int("batch", 100, "farmers re-encrypted per batch")
+	pause := flags.Duration("pause", time.Second, "pause between batches")
+	flags.Parse(args)
+
+	if cfg.PII.KeyProvider == "" {
+		log.Fatalf("PII encryption is not configured, set pii.keyProvider")
+	}
+	if *batch <= 0 {
+		log.Fatalf("Batch size must be positive")
+	}
+	if *newMaster {
+		if cfg.PII.KeyProvider != config.KeyProviderKeyfile {
+			log.Fatalf("-new-master only applies to the keyfile provider")
+		}
+		keys, err := pii.LoadKeyfile(cfg.PII.Keyfile)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", cfg.PII.Keyfile, err)
+		}
+		id, err := keys.AddKey()
+		if err != nil {
+			log.Fatalf("Failed to add a master key to %s: %v", cfg.PII.Keyfile, err)
+		}
+		fmt.Printf("Master key %s added to %s\n", id, cfg.PII.Keyfile)
+	}
+
+	ctx := context.Background()
+	stores, err := initializeStores(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize stores: %v", err)
+	}
+	locations, err := location.LoadFiles(cfg.Location.DirectoryFiles)
+	if err != nil {
+		log.Fatalf("Failed to load location directory: %v", err)
+	}
+	policy, err := loadContactPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Invalid contact policy: %v", err)
+	}
+	cipher, err := loadPIICipher(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up PII encryption: %v", err)
+	}
+	if err := cipher.Rotate(ctx); err != nil {
+		log.Fatalf("Failed to rotate data key: %v", err)
+	}
+	services := service.NewServices(stores, locations, policy, cipher, erasureKey(cfg))
+
+	resealed, err := services.Farmer.ResealFarmers(ctx, *batch, *pause)
+	if err != nil {
+		log.Fatalf("Failed to re-encrypt farmers after %d: %v", resealed, err)
+	}
+	fmt.Printf("%d farmers re-encrypted\n", resealed)
+}